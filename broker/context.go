@@ -0,0 +1,19 @@
+package broker
+
+import "context"
+
+type eventContext interface {
+	Context() context.Context
+}
+
+// EventContext returns the context carried by the event, which holds the consumer span,
+// context.Background() if the broker doesn't support it.
+func EventContext(e Event) context.Context {
+	if ec, ok := e.(eventContext); ok {
+		if ctx := ec.Context(); ctx != nil {
+			return ctx
+		}
+	}
+
+	return context.Background()
+}
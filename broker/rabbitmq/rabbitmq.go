@@ -5,6 +5,11 @@ import (
 	"errors"
 	"github.com/streadway/amqp"
 	"github.com/valeamoris/go-ezio/broker"
+	"github.com/valeamoris/go-ezio/core/requestid"
+	"github.com/valeamoris/go-ezio/core/trace"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.7.0"
+	oteltrace "go.opentelemetry.io/otel/trace"
 	"sync"
 	"time"
 )
@@ -115,6 +120,7 @@ type publication struct {
 	m   *broker.Message
 	t   string
 	err error
+	ctx context.Context
 }
 
 func (p *publication) Topic() string {
@@ -133,6 +139,10 @@ func (p *publication) Error() error {
 	return p.err
 }
 
+func (p *publication) Context() context.Context {
+	return p.ctx
+}
+
 func (r *rbroker) Init(opts ...broker.Option) error {
 	for _, o := range opts {
 		o(&r.opts)
@@ -167,6 +177,18 @@ func (r *rbroker) Connect() error {
 	return r.conn.Connect(r.opts.Secure, &conf)
 }
 
+// Connected reports whether the underlying connection is alive,
+// it turns false while reconnecting.
+func (r *rbroker) Connected() bool {
+	if r.conn == nil {
+		return false
+	}
+
+	r.conn.Lock()
+	defer r.conn.Unlock()
+	return r.conn.connected
+}
+
 func (r *rbroker) Disconnect() error {
 	if r.conn == nil {
 		return errors.New("connection is nil")
@@ -187,6 +209,14 @@ func (r *rbroker) Publish(topic string, msg *broker.Message, opts ...broker.Publ
 		o(&options)
 	}
 
+	ctx := options.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	ctx, span := trace.Tracer().Start(ctx, topic+" publish",
+		oteltrace.WithSpanKind(oteltrace.SpanKindProducer),
+		oteltrace.WithAttributes(messagingAttributes(topic)...))
+
 	if options.Context != nil {
 		if value, ok := options.Context.Value(deliveryMode{}).(uint8); ok {
 			m.DeliveryMode = value
@@ -200,12 +230,24 @@ func (r *rbroker) Publish(topic string, msg *broker.Message, opts ...broker.Publ
 	for k, v := range msg.Header {
 		m.Headers[k] = v
 	}
+	carrier := make(map[string]string)
+	trace.Inject(ctx, carrier)
+	if len(requestid.FromHeader(msg.Header)) == 0 {
+		requestid.Inject(ctx, carrier)
+	}
+	for k, v := range carrier {
+		m.Headers[k] = v
+	}
 
 	if r.conn == nil {
-		return errors.New("connection is nil")
+		err := errors.New("connection is nil")
+		trace.End(span, err)
+		return err
 	}
 
-	return r.conn.Publish(r.conn.exchange.Name, topic, m)
+	err := r.conn.Publish(r.conn.exchange.Name, topic, m)
+	trace.End(span, err)
+	return err
 }
 
 func (r *rbroker) Subscribe(topic string, handler broker.Handler, opts ...broker.SubscribeOption) (broker.Subscriber, error) {
@@ -262,8 +304,13 @@ func (r *rbroker) Subscribe(topic string, handler broker.Handler, opts ...broker
 			Header: header,
 			Body:   msg.Body,
 		}
-		p := &publication{d: msg, m: m, t: msg.RoutingKey}
+		spanCtx, span := trace.Tracer().Start(trace.Extract(ctx, header), msg.RoutingKey+" receive",
+			oteltrace.WithSpanKind(oteltrace.SpanKindConsumer),
+			oteltrace.WithAttributes(messagingAttributes(msg.RoutingKey)...))
+		spanCtx = requestid.Extract(spanCtx, header)
+		p := &publication{d: msg, m: m, t: msg.RoutingKey, ctx: spanCtx}
 		p.err = handler(p)
+		trace.End(span, p.err)
 
 		// process error
 		if p.err != nil && r.opts.ErrorHandler != nil {
@@ -312,6 +359,14 @@ func NewBroker(opts ...broker.Option) broker.Broker {
 	}
 }
 
+func messagingAttributes(topic string) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		semconv.MessagingSystemKey.String("rabbitmq"),
+		semconv.MessagingDestinationKindTopic,
+		semconv.MessagingDestinationKey.String(topic),
+	}
+}
+
 func (r *rbroker) getExchange() Exchange {
 	ex := DefaultExchange
 	if e, ok := r.opts.Context.Value(exchangeKey{}).(string); ok {
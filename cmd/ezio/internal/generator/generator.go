@@ -0,0 +1,435 @@
+// Package generator writes the rest service from the spec, types.go and routes.go are always regenerated,
+// the other files are created only if missing, so the user logic is never overwritten.
+package generator
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"go/format"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+	"unicode"
+
+	"github.com/valeamoris/go-ezio/cmd/ezio/internal/spec"
+)
+
+const goVersion = "1.15"
+
+var (
+	ErrNoModule = errors.New("no go.mod found, specify the module")
+	identifier  = regexp.MustCompile(`[A-Za-z_][A-Za-z0-9_]*`)
+)
+
+type (
+	Config struct {
+		// 输出目录
+		Dir string
+		// 找不到go.mod时使用，并创建go.mod
+		Module string
+	}
+
+	generator struct {
+		spec   *spec.Spec
+		dir    string
+		module string
+		types  map[string]bool
+		// 生成的文件，相对于dir
+		written []string
+	}
+
+	routeData struct {
+		Method          string
+		MethodConst     string
+		Path            string
+		Summary         string
+		HandlerRef      string
+		RequestLiteral  string
+		ResponseLiteral string
+		Doc             bool
+	}
+
+	groupData struct {
+		Prefix      string
+		Jwt         string
+		Shedding    bool
+		Middlewares string
+		Timeout     int64
+		Tag         string
+		Routes      []routeData
+	}
+
+	handlerData struct {
+		Module          string
+		Package         string
+		Dir             string
+		LogicPackage    string
+		Name            string
+		RequestType     string
+		RequestPointer  bool
+		ResponseType    string
+		ResponsePointer bool
+	}
+)
+
+// Generate writes the service into c.Dir and returns the written files.
+func Generate(s *spec.Spec, c Config) ([]string, error) {
+	dir, err := filepath.Abs(c.Dir)
+	if err != nil {
+		return nil, err
+	}
+
+	g := &generator{
+		spec:  s,
+		dir:   dir,
+		types: make(map[string]bool),
+	}
+	for _, t := range s.Types {
+		g.types[t.Name] = true
+	}
+	if err := g.resolveModule(c.Module); err != nil {
+		return nil, err
+	}
+
+	for _, step := range []func() error{
+		g.genEtc,
+		g.genMain,
+		g.genConfig,
+		g.genSvc,
+		g.genMiddlewares,
+		g.genTypes,
+		g.genRoutes,
+		g.genHandlers,
+	} {
+		if err := step(); err != nil {
+			return nil, err
+		}
+	}
+
+	return g.written, nil
+}
+
+// resolveModule finds the import path of dir from the go.mod in dir or its parents.
+func (g *generator) resolveModule(module string) error {
+	for dir := g.dir; ; dir = filepath.Dir(dir) {
+		name, err := moduleName(filepath.Join(dir, "go.mod"))
+		if err == nil {
+			rel, err := filepath.Rel(dir, g.dir)
+			if err != nil {
+				return err
+			}
+			g.module = path.Join(name, filepath.ToSlash(rel))
+			return nil
+		}
+		if !os.IsNotExist(err) {
+			return err
+		}
+		if filepath.Dir(dir) == dir {
+			break
+		}
+	}
+
+	if len(module) == 0 {
+		return ErrNoModule
+	}
+
+	g.module = module
+	content := fmt.Sprintf("module %s\n\ngo %s\n", module, goVersion)
+	return g.write("go.mod", []byte(content), false)
+}
+
+func moduleName(filename string) (string, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "module ") {
+			return strings.Trim(strings.TrimSpace(line[len("module "):]), `"`), nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+
+	return "", fmt.Errorf("%s: no module declared", filename)
+}
+
+func (g *generator) genEtc() error {
+	secret := make([]byte, 16)
+	if _, err := rand.Read(secret); err != nil {
+		return err
+	}
+
+	return g.execute(filepath.Join("etc", g.spec.Service+".yaml"), etcTemplate, map[string]interface{}{
+		"Service": g.spec.Service,
+		"Jwts":    g.spec.JwtNames(),
+		"Secret":  hex.EncodeToString(secret),
+	}, false)
+}
+
+func (g *generator) genMain() error {
+	name := strings.TrimSuffix(g.spec.Service, "-api")
+	name = strings.ReplaceAll(name, "-", "")
+	return g.execute(name+".go", mainTemplate, map[string]interface{}{
+		"Module":  g.module,
+		"Service": g.spec.Service,
+	}, false)
+}
+
+func (g *generator) genConfig() error {
+	return g.execute(filepath.Join("internal", "config", "config.go"), configTemplate, map[string]interface{}{
+		"Jwts": g.spec.JwtNames(),
+	}, false)
+}
+
+func (g *generator) genSvc() error {
+	return g.execute(filepath.Join("internal", "svc", "servicecontext.go"), svcTemplate, map[string]interface{}{
+		"Module":      g.module,
+		"Middlewares": middlewareNames(g.spec.MiddlewareNames()),
+	}, false)
+}
+
+func (g *generator) genMiddlewares() error {
+	for _, name := range middlewareNames(g.spec.MiddlewareNames()) {
+		filename := filepath.Join("internal", "middleware", strings.ToLower(name)+"middleware.go")
+		if err := g.execute(filename, middlewareTemplate, map[string]interface{}{
+			"Name": name,
+		}, false); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (g *generator) genTypes() error {
+	types := make([]spec.Type, len(g.spec.Types))
+	copy(types, g.spec.Types)
+	sort.SliceStable(types, func(i, j int) bool {
+		return types[i].Name < types[j].Name
+	})
+
+	return g.execute(filepath.Join("internal", "types", "types.go"), typesTemplate, map[string]interface{}{
+		"Types": types,
+	}, true)
+}
+
+func (g *generator) genRoutes() error {
+	imports := map[string]bool{
+		`"net/http"`: true,
+		fmt.Sprintf("%q", g.module+"/internal/svc"): true,
+		`"github.com/valeamoris/go-ezio/rest"`:      true,
+	}
+
+	var groups []groupData
+	for _, group := range g.spec.Groups {
+		data := groupData{
+			Prefix:   group.Prefix,
+			Jwt:      group.Jwt,
+			Shedding: group.Shedding,
+			Timeout:  group.Timeout,
+			Tag:      group.Name,
+		}
+		if len(group.Jwt) > 0 {
+			imports[`"github.com/dgrijalva/jwt-go"`] = true
+		}
+		if group.Timeout > 0 {
+			imports[`"time"`] = true
+		}
+		if len(group.Middlewares) > 0 {
+			var refs []string
+			for _, name := range middlewareNames(group.Middlewares) {
+				refs = append(refs, "serverCtx."+name)
+			}
+			data.Middlewares = strings.Join(refs, ", ")
+		}
+
+		pkg := ""
+		if len(group.Name) > 0 {
+			pkg = path.Base(group.Name) + "."
+			imports[fmt.Sprintf("%q", g.module+"/internal/handler/"+group.Name)] = true
+		}
+		for _, route := range group.Routes {
+			rd := routeData{
+				Method:          route.Method,
+				MethodConst:     methodConst(route.Method),
+				Path:            route.Path,
+				Summary:         route.Summary,
+				HandlerRef:      pkg + handlerName(route.Handler) + "Handler",
+				RequestLiteral:  g.literal(route.Request),
+				ResponseLiteral: g.literal(route.Response),
+			}
+			rd.Doc = len(rd.Summary) > 0 || len(rd.RequestLiteral) > 0 || len(rd.ResponseLiteral) > 0
+			if len(rd.RequestLiteral) > 0 || len(rd.ResponseLiteral) > 0 {
+				imports[fmt.Sprintf("%q", g.module+"/internal/types")] = true
+			}
+			data.Routes = append(data.Routes, rd)
+		}
+		groups = append(groups, data)
+	}
+
+	// 标准库在前，其余的分为一组
+	var std, others []string
+	for imp := range imports {
+		if strings.Contains(imp, ".") {
+			others = append(others, imp)
+		} else {
+			std = append(std, imp)
+		}
+	}
+	sort.Strings(std)
+	sort.Strings(others)
+
+	return g.execute(filepath.Join("internal", "handler", "routes.go"), routesTemplate, map[string]interface{}{
+		"StdImports": std,
+		"Imports":    others,
+		"Groups":     groups,
+	}, true)
+}
+
+func (g *generator) genHandlers() error {
+	for _, group := range g.spec.Groups {
+		for _, route := range group.Routes {
+			data := handlerData{
+				Module:       g.module,
+				Package:      "handler",
+				LogicPackage: "logic",
+				Name:         handlerName(route.Handler),
+			}
+			if len(group.Name) > 0 {
+				data.Package = path.Base(group.Name)
+				data.LogicPackage = data.Package
+				data.Dir = "/" + group.Name
+			}
+			if len(route.Request) > 0 {
+				data.RequestType = g.qualify(route.Request)
+				data.RequestPointer = g.types[route.Request]
+			}
+			if len(route.Response) > 0 {
+				data.ResponseType = g.qualify(route.Response)
+				data.ResponsePointer = g.types[route.Response]
+			}
+
+			filename := strings.ToLower(data.Name)
+			handlerFile := filepath.Join("internal", "handler", group.Name, filename+"handler.go")
+			if err := g.execute(handlerFile, handlerTemplate, data, false); err != nil {
+				return err
+			}
+			logicFile := filepath.Join("internal", "logic", group.Name, filename+"logic.go")
+			if err := g.execute(logicFile, logicTemplate, data, false); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (g *generator) execute(filename, text string, data interface{}, overwrite bool) error {
+	tpl, err := template.New(filename).Parse(text)
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	if err := tpl.Execute(&buf, data); err != nil {
+		return err
+	}
+
+	content := buf.Bytes()
+	if strings.HasSuffix(filename, ".go") {
+		if content, err = format.Source(content); err != nil {
+			return fmt.Errorf("%s: %w", filename, err)
+		}
+	}
+
+	return g.write(filename, content, overwrite)
+}
+
+func (g *generator) write(filename string, content []byte, overwrite bool) error {
+	target := filepath.Join(g.dir, filename)
+	if !overwrite {
+		if _, err := os.Stat(target); err == nil {
+			return nil
+		} else if !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(target, content, 0644); err != nil {
+		return err
+	}
+
+	g.written = append(g.written, filename)
+	return nil
+}
+
+// qualify prefixes the declared types with the types package, like []User to []types.User.
+func (g *generator) qualify(expr string) string {
+	return identifier.ReplaceAllStringFunc(expr, func(name string) string {
+		if g.types[name] {
+			return "types." + name
+		}
+		return name
+	})
+}
+
+// literal returns the zero value literal of the type for the openapi document.
+func (g *generator) literal(expr string) string {
+	expr = strings.TrimLeft(expr, "*")
+	switch {
+	case len(expr) == 0:
+		return ""
+	case strings.HasPrefix(expr, "[]"), strings.HasPrefix(expr, "map["), g.types[expr]:
+		return g.qualify(expr) + "{}"
+	default:
+		return ""
+	}
+}
+
+func methodConst(method string) string {
+	method = strings.ToLower(method)
+	return "http.Method" + strings.ToUpper(method[:1]) + method[1:]
+}
+
+// handlerName converts the handler like getUserHandler to GetUser.
+func handlerName(name string) string {
+	name = strings.TrimSuffix(strings.TrimSuffix(name, "Handler"), "handler")
+	return exported(name)
+}
+
+func middlewareNames(names []string) []string {
+	result := make([]string, 0, len(names))
+	for _, name := range names {
+		result = append(result, exported(strings.TrimSuffix(name, "Middleware")))
+	}
+
+	return result
+}
+
+func exported(name string) string {
+	runes := []rune(name)
+	if len(runes) == 0 {
+		return name
+	}
+
+	runes[0] = unicode.ToUpper(runes[0])
+	return string(runes)
+}
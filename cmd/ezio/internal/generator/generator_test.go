@@ -0,0 +1,116 @@
+package generator
+
+import (
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valeamoris/go-ezio/cmd/ezio/internal/spec"
+)
+
+var userSpec = &spec.Spec{
+	Service: "user-api",
+	Types: []spec.Type{
+		{Name: "User", Fields: []spec.Field{{Name: "Name", Type: "string", Tag: "`json:\"name\"`"}}},
+		{Name: "GetUserReq", Fields: []spec.Field{{Name: "Id", Type: "int64", Tag: "`path:\"id\"`"}}},
+	},
+	Groups: []spec.Group{
+		{
+			Name:        "user",
+			Prefix:      "/api",
+			Jwt:         "Auth",
+			Shedding:    true,
+			Middlewares: []string{"Log"},
+			Timeout:     2000,
+			Routes: []spec.Route{
+				{Method: "GET", Path: "/users/:id", Handler: "getUser", Request: "GetUserReq", Response: "User", Summary: "get user"},
+				{Method: "GET", Path: "/users", Handler: "listUsers", Response: "[]User"},
+			},
+		},
+		{
+			Routes: []spec.Route{{Method: "GET", Path: "/ping", Handler: "pingHandler"}},
+		},
+	},
+}
+
+func TestGenerate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ezio")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/demo\n"), 0644))
+
+	out := filepath.Join(dir, "user")
+	files, err := Generate(userSpec, Config{Dir: out})
+	assert.Nil(t, err)
+	assert.ElementsMatch(t, []string{
+		"etc/user-api.yaml",
+		"user.go",
+		"internal/config/config.go",
+		"internal/svc/servicecontext.go",
+		"internal/middleware/logmiddleware.go",
+		"internal/types/types.go",
+		"internal/handler/routes.go",
+		"internal/handler/user/getuserhandler.go",
+		"internal/logic/user/getuserlogic.go",
+		"internal/handler/user/listusershandler.go",
+		"internal/logic/user/listuserslogic.go",
+		"internal/handler/pinghandler.go",
+		"internal/logic/pinglogic.go",
+	}, files)
+
+	fset := token.NewFileSet()
+	for _, file := range files {
+		if strings.HasSuffix(file, ".go") {
+			_, err := parser.ParseFile(fset, filepath.Join(out, file), nil, parser.AllErrors)
+			assert.Nil(t, err, file)
+		}
+	}
+
+	routes := read(t, out, "internal/handler/routes.go")
+	assert.True(t, strings.HasPrefix(routes, codeGenerated))
+	assert.Contains(t, routes, `"example.com/demo/user/internal/handler/user"`)
+	assert.Contains(t, routes, "rest.WithJwt(serverCtx.Config.Auth.AccessSecret, jwt.MapClaims{})")
+	assert.Contains(t, routes, "rest.WithMiddlewares(serverCtx.Log)")
+	assert.Contains(t, routes, "rest.WithTimeout(2000*time.Millisecond)")
+	assert.Contains(t, routes, "Response: []types.User{}")
+	assert.Contains(t, routes, "Handler: PingHandler(serverCtx)")
+	assert.Contains(t, read(t, out, "internal/logic/user/listuserslogic.go"),
+		"func (l *ListUsersLogic) ListUsers() (resp []types.User, err error)")
+	assert.Contains(t, read(t, out, "internal/logic/user/getuserlogic.go"),
+		"func (l *GetUserLogic) GetUser(req *types.GetUserReq) (resp *types.User, err error)")
+
+	// 重新生成时只覆盖types.go和routes.go
+	logic := filepath.Join(out, "internal/logic/user/getuserlogic.go")
+	assert.Nil(t, ioutil.WriteFile(logic, []byte("package user\n"), 0644))
+	files, err = Generate(userSpec, Config{Dir: out})
+	assert.Nil(t, err)
+	assert.ElementsMatch(t, []string{"internal/types/types.go", "internal/handler/routes.go"}, files)
+	assert.Equal(t, "package user\n", read(t, out, "internal/logic/user/getuserlogic.go"))
+}
+
+func TestGenerateModule(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ezio")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	s := &spec.Spec{Service: "ping"}
+	_, err = Generate(s, Config{Dir: dir})
+	assert.Equal(t, ErrNoModule, err)
+
+	files, err := Generate(s, Config{Dir: dir, Module: "example.com/ping"})
+	assert.Nil(t, err)
+	assert.Contains(t, files, "go.mod")
+	assert.Equal(t, "module example.com/ping\n\ngo 1.15\n", read(t, dir, "go.mod"))
+	assert.Contains(t, read(t, dir, "ping.go"), `"example.com/ping/internal/handler"`)
+}
+
+func read(t *testing.T, dir, filename string) string {
+	content, err := ioutil.ReadFile(filepath.Join(dir, filename))
+	assert.Nil(t, err)
+	return string(content)
+}
@@ -0,0 +1,252 @@
+package generator
+
+const codeGenerated = "// Code generated by ezio. DO NOT EDIT.\n"
+
+const mainTemplate = `package main
+
+import (
+	"flag"
+	"fmt"
+
+	"{{.Module}}/internal/config"
+	"{{.Module}}/internal/handler"
+	"{{.Module}}/internal/svc"
+	"github.com/valeamoris/go-ezio/rest"
+	"github.com/zeromicro/go-zero/core/conf"
+)
+
+var configFile = flag.String("f", "etc/{{.Service}}.yaml", "the config file")
+
+func main() {
+	flag.Parse()
+
+	var c config.Config
+	conf.MustLoad(*configFile, &c)
+
+	server := rest.MustNewServer(c.Conf)
+	defer server.Stop()
+
+	ctx := svc.NewServiceContext(c)
+	handler.RegisterHandlers(server, ctx)
+
+	fmt.Printf("Starting server at %s:%d...\n", c.Host, c.Port)
+	server.Start()
+}
+`
+
+const configTemplate = `package config
+
+import "github.com/valeamoris/go-ezio/rest"
+
+type Config struct {
+	rest.Conf
+{{- range .Jwts}}
+	{{.}} struct {
+		AccessSecret string
+		AccessExpire int64
+	}
+{{- end}}
+}
+`
+
+const etcTemplate = `Name: {{.Service}}
+Host: 0.0.0.0
+Port: 8888
+{{- range .Jwts}}
+{{.}}:
+  AccessSecret: {{$.Secret}}
+  AccessExpire: 86400
+{{- end}}
+`
+
+const svcTemplate = `package svc
+
+import (
+	"{{.Module}}/internal/config"
+{{- if .Middlewares}}
+	"{{.Module}}/internal/middleware"
+	"github.com/valeamoris/go-ezio/rest"
+{{- end}}
+)
+
+type ServiceContext struct {
+	Config config.Config
+{{- range .Middlewares}}
+	{{.}} rest.Middleware
+{{- end}}
+}
+
+func NewServiceContext(c config.Config) *ServiceContext {
+	return &ServiceContext{
+		Config: c,
+{{- range .Middlewares}}
+		{{.}}: middleware.New{{.}}Middleware().Handle,
+{{- end}}
+	}
+}
+`
+
+const middlewareTemplate = `package middleware
+
+import "github.com/valeamoris/go-ezio/rest"
+
+type {{.Name}}Middleware struct {
+}
+
+func New{{.Name}}Middleware() *{{.Name}}Middleware {
+	return &{{.Name}}Middleware{}
+}
+
+func (m *{{.Name}}Middleware) Handle(next rest.HandlerFunc) rest.HandlerFunc {
+	return func(ctx rest.Context) error {
+		// todo: generate middleware implement function, delete after code implementation
+
+		return next(ctx)
+	}
+}
+`
+
+const typesTemplate = codeGenerated + `
+package types
+{{range .Types}}
+type {{.Name}} struct {
+{{- range .Fields}}
+	{{if .Name}}{{.Name}} {{end}}{{.Type}}{{if .Tag}} {{.Tag}}{{end}}
+{{- end}}
+}
+{{end}}`
+
+const routesTemplate = codeGenerated + `
+package handler
+
+import (
+{{- range .StdImports}}
+	{{.}}
+{{- end}}
+{{range .Imports}}
+	{{.}}
+{{- end}}
+)
+
+func RegisterHandlers(server *rest.Server, serverCtx *svc.ServiceContext) {
+{{- range .Groups}}
+	server.Group(
+		rest.Group{
+			Prefix: {{printf "%q" .Prefix}},
+			Routes: []rest.Route{
+			{{- range .Routes}}
+				{
+					Method:  {{.MethodConst}},
+					Path:    {{printf "%q" .Path}},
+					Handler: {{.HandlerRef}}(serverCtx),
+				},
+			{{- end}}
+			},
+		},
+	{{- if .Jwt}}
+		rest.WithJwt(serverCtx.Config.{{.Jwt}}.AccessSecret, jwt.MapClaims{}),
+	{{- end}}
+	{{- if .Shedding}}
+		rest.WithShedding(),
+	{{- end}}
+	{{- if .Middlewares}}
+		rest.WithMiddlewares({{.Middlewares}}),
+	{{- end}}
+	{{- if .Timeout}}
+		rest.WithTimeout({{.Timeout}} * time.Millisecond),
+	{{- end}}
+	{{- if .Tag}}
+		rest.WithTags({{printf "%q" .Tag}}),
+	{{- end}}
+	{{- range .Routes}}
+		{{- if .Doc}}
+		rest.WithDoc({{.MethodConst}}, {{printf "%q" .Path}}, rest.RouteDoc{
+			{{- if .Summary}}
+			Summary:  {{printf "%q" .Summary}},
+			{{- end}}
+			{{- if .RequestLiteral}}
+			Request:  {{.RequestLiteral}},
+			{{- end}}
+			{{- if .ResponseLiteral}}
+			Response: {{.ResponseLiteral}},
+			{{- end}}
+		}),
+		{{- end}}
+	{{- end}}
+	)
+{{- end}}
+}
+`
+
+const handlerTemplate = `package {{.Package}}
+
+import (
+	"net/http"
+
+	"{{.Module}}/internal/logic{{.Dir}}"
+	"{{.Module}}/internal/svc"
+{{- if .RequestType}}
+	"{{.Module}}/internal/types"
+{{- end}}
+	"github.com/valeamoris/go-ezio/rest"
+)
+
+func {{.Name}}Handler(svcCtx *svc.ServiceContext) rest.HandlerFunc {
+	return func(ctx rest.Context) error {
+	{{- if .RequestType}}
+		var req {{.RequestType}}
+		if err := rest.Bind(ctx, &req); err != nil {
+			return err
+		}
+	{{end}}
+		l := {{.LogicPackage}}.New{{.Name}}Logic(ctx.Request().Context(), svcCtx)
+	{{- if .ResponseType}}
+		resp, err := l.{{.Name}}({{if .RequestType}}{{if .RequestPointer}}&{{end}}req{{end}})
+		if err != nil {
+			return err
+		}
+
+		return ctx.JSON(http.StatusOK, resp)
+	{{- else}}
+		if err := l.{{.Name}}({{if .RequestType}}{{if .RequestPointer}}&{{end}}req{{end}}); err != nil {
+			return err
+		}
+
+		return ctx.NoContent(http.StatusOK)
+	{{- end}}
+	}
+}
+`
+
+const logicTemplate = `package {{.LogicPackage}}
+
+import (
+	"context"
+
+	"{{.Module}}/internal/svc"
+{{- if or .RequestType .ResponseType}}
+	"{{.Module}}/internal/types"
+{{- end}}
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+type {{.Name}}Logic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func New{{.Name}}Logic(ctx context.Context, svcCtx *svc.ServiceContext) *{{.Name}}Logic {
+	return &{{.Name}}Logic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+func (l *{{.Name}}Logic) {{.Name}}({{if .RequestType}}req {{if .RequestPointer}}*{{end}}{{.RequestType}}{{end}}) {{if .ResponseType}}(resp {{if .ResponsePointer}}*{{end}}{{.ResponseType}}, err error){{else}}error{{end}} {
+	// todo: add your logic here and delete this line
+
+	return{{if not .ResponseType}} nil{{end}}
+}
+`
@@ -0,0 +1,387 @@
+package parser
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"github.com/valeamoris/go-ezio/cmd/ezio/internal/spec"
+)
+
+type apiParser struct {
+	tokens []token
+	pos    int
+	spec   *spec.Spec
+	// 下一个service使用的@server配置
+	server map[string]string
+}
+
+// ParseApiFile parses the go-zero .api file, imports and inline struct types are not supported.
+func ParseApiFile(filename string) (*spec.Spec, error) {
+	content, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseApi(string(content))
+}
+
+func ParseApi(content string) (*spec.Spec, error) {
+	tokens, err := tokenize(content)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &apiParser{
+		tokens: tokens,
+		spec:   &spec.Spec{Info: make(map[string]string)},
+	}
+	if err := p.parse(); err != nil {
+		return nil, err
+	}
+	if len(p.spec.Service) == 0 {
+		return nil, fmt.Errorf("no service defined")
+	}
+
+	return p.spec, nil
+}
+
+func (p *apiParser) parse() error {
+	for {
+		p.skipNewlines()
+		tok := p.next()
+		switch {
+		case tok.kind == tokenEOF:
+			return nil
+		case tok.text == "syntax":
+			if _, err := p.expect("="); err != nil {
+				return err
+			}
+			if _, err := p.expectKind(tokenString); err != nil {
+				return err
+			}
+		case tok.text == "info":
+			info, err := p.parseKeyValues()
+			if err != nil {
+				return err
+			}
+			for k, v := range info {
+				p.spec.Info[k] = v
+			}
+		case tok.text == "type":
+			if err := p.parseTypes(); err != nil {
+				return err
+			}
+		case tok.text == "@server":
+			server, err := p.parseKeyValues()
+			if err != nil {
+				return err
+			}
+			p.server = server
+		case tok.text == "service":
+			if err := p.parseService(); err != nil {
+				return err
+			}
+		case tok.text == "import":
+			return p.errorf(tok, "imports are not supported, merge the files first")
+		default:
+			return p.errorf(tok, "unexpected %q", tok.text)
+		}
+	}
+}
+
+func (p *apiParser) parseKeyValues() (map[string]string, error) {
+	if _, err := p.expect("("); err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]string)
+	for {
+		p.skipNewlines()
+		tok := p.next()
+		if tok.text == ")" && tok.kind == tokenPunct {
+			return values, nil
+		}
+		if tok.kind != tokenWord {
+			return nil, p.errorf(tok, "expect key, got %q", tok.text)
+		}
+		if _, err := p.expect(":"); err != nil {
+			return nil, err
+		}
+
+		var parts []string
+		for p.peek().kind != tokenNewline && !p.peekPunct(")") && p.peek().kind != tokenEOF {
+			parts = append(parts, p.next().text)
+		}
+		values[tok.text] = strings.Join(parts, " ")
+	}
+}
+
+func (p *apiParser) parseTypes() error {
+	if !p.peekPunct("(") {
+		return p.parseType()
+	}
+
+	p.next()
+	for {
+		p.skipNewlines()
+		if p.peekPunct(")") {
+			p.next()
+			return nil
+		}
+		if err := p.parseType(); err != nil {
+			return err
+		}
+	}
+}
+
+func (p *apiParser) parseType() error {
+	name, err := p.expectKind(tokenWord)
+	if err != nil {
+		return err
+	}
+	if p.peek().text == "struct" {
+		p.next()
+	}
+	if _, err := p.expect("{"); err != nil {
+		return err
+	}
+
+	typ := spec.Type{Name: name.text}
+	for {
+		p.skipNewlines()
+		if p.peekPunct("}") {
+			p.next()
+			break
+		}
+
+		fieldName, err := p.expectKind(tokenWord)
+		if err != nil {
+			return err
+		}
+		if p.peek().kind == tokenNewline || p.peekPunct("}") {
+			typ.Fields = append(typ.Fields, spec.Field{Type: fieldName.text})
+			continue
+		}
+
+		fieldType, err := p.parseFieldType()
+		if err != nil {
+			return err
+		}
+		field := spec.Field{Name: fieldName.text, Type: fieldType}
+		if p.peek().kind == tokenRaw {
+			field.Tag = p.next().text
+		}
+		typ.Fields = append(typ.Fields, field)
+	}
+
+	p.spec.Types = append(p.spec.Types, typ)
+	return nil
+}
+
+func (p *apiParser) parseFieldType() (string, error) {
+	tok := p.next()
+	switch {
+	case tok.kind == tokenPunct && tok.text == "*":
+		elem, err := p.parseFieldType()
+		return "*" + elem, err
+	case tok.kind == tokenPunct && tok.text == "[":
+		if _, err := p.expect("]"); err != nil {
+			return "", err
+		}
+		elem, err := p.parseFieldType()
+		return "[]" + elem, err
+	case tok.text == "map":
+		if _, err := p.expect("["); err != nil {
+			return "", err
+		}
+		key, err := p.parseFieldType()
+		if err != nil {
+			return "", err
+		}
+		if _, err := p.expect("]"); err != nil {
+			return "", err
+		}
+		elem, err := p.parseFieldType()
+		return "map[" + key + "]" + elem, err
+	case tok.text == "interface" && p.peekPunct("{"):
+		p.next()
+		if _, err := p.expect("}"); err != nil {
+			return "", err
+		}
+		return "interface{}", nil
+	case tok.kind == tokenWord:
+		return tok.text, nil
+	default:
+		return "", p.errorf(tok, "unsupported type %q", tok.text)
+	}
+}
+
+func (p *apiParser) parseService() error {
+	name, err := p.expectKind(tokenWord)
+	if err != nil {
+		return err
+	}
+	if len(p.spec.Service) > 0 && p.spec.Service != name.text {
+		return p.errorf(name, "service %q conflicts with %q", name.text, p.spec.Service)
+	}
+	p.spec.Service = name.text
+
+	group, err := newGroup(p.server)
+	if err != nil {
+		return p.errorf(name, err.Error())
+	}
+	p.server = nil
+
+	if _, err := p.expect("{"); err != nil {
+		return err
+	}
+
+	var route spec.Route
+	for {
+		p.skipNewlines()
+		tok := p.next()
+		switch {
+		case tok.kind == tokenPunct && tok.text == "}":
+			p.spec.Groups = append(p.spec.Groups, group)
+			return nil
+		case tok.text == "@doc":
+			if p.peekPunct("(") {
+				doc, err := p.parseKeyValues()
+				if err != nil {
+					return err
+				}
+				route.Summary = doc["summary"]
+			} else {
+				summary, err := p.expectKind(tokenString)
+				if err != nil {
+					return err
+				}
+				route.Summary = summary.text
+			}
+		case tok.text == "@handler":
+			handler, err := p.expectKind(tokenWord)
+			if err != nil {
+				return err
+			}
+			route.Handler = handler.text
+		case tok.kind == tokenWord:
+			if err := p.parseRoute(tok, &route); err != nil {
+				return err
+			}
+			group.Routes = append(group.Routes, route)
+			route = spec.Route{}
+		default:
+			return p.errorf(tok, "unexpected %q", tok.text)
+		}
+	}
+}
+
+func (p *apiParser) parseRoute(method token, route *spec.Route) error {
+	path, err := p.expectKind(tokenPath)
+	if err != nil {
+		return err
+	}
+	route.Method = strings.ToUpper(method.text)
+	route.Path = path.text
+
+	if p.peekPunct("(") {
+		p.next()
+		if route.Request, err = p.parseFieldType(); err != nil {
+			return err
+		}
+		if _, err := p.expect(")"); err != nil {
+			return err
+		}
+	}
+	if p.peek().text == "returns" {
+		p.next()
+		if _, err := p.expect("("); err != nil {
+			return err
+		}
+		if route.Response, err = p.parseFieldType(); err != nil {
+			return err
+		}
+		if _, err := p.expect(")"); err != nil {
+			return err
+		}
+	}
+	if len(route.Handler) == 0 {
+		route.Handler = handlerName(route.Method, route.Path)
+	}
+
+	return nil
+}
+
+func newGroup(server map[string]string) (spec.Group, error) {
+	group := spec.Group{
+		Name:   server["group"],
+		Prefix: server["prefix"],
+		Jwt:    server["jwt"],
+	}
+	if middlewares, ok := server["middleware"]; ok {
+		for _, name := range strings.Split(middlewares, ",") {
+			if name = strings.TrimSpace(name); len(name) > 0 {
+				group.Middlewares = append(group.Middlewares, name)
+			}
+		}
+	}
+	if shedding, ok := server["shedding"]; ok {
+		group.Shedding = shedding != "false"
+	}
+	if timeout, ok := server["timeout"]; ok {
+		d, err := time.ParseDuration(strings.ReplaceAll(timeout, " ", ""))
+		if err != nil {
+			return group, fmt.Errorf("bad timeout %q", timeout)
+		}
+		group.Timeout = d.Milliseconds()
+	}
+
+	return group, nil
+}
+
+func (p *apiParser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokenEOF {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *apiParser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *apiParser) peekPunct(text string) bool {
+	tok := p.peek()
+	return tok.kind == tokenPunct && tok.text == text
+}
+
+func (p *apiParser) skipNewlines() {
+	for p.peek().kind == tokenNewline {
+		p.next()
+	}
+}
+
+func (p *apiParser) expect(text string) (token, error) {
+	tok := p.next()
+	if tok.text != text {
+		return tok, p.errorf(tok, "expect %q, got %q", text, tok.text)
+	}
+
+	return tok, nil
+}
+
+func (p *apiParser) expectKind(kind tokenKind) (token, error) {
+	tok := p.next()
+	if tok.kind != kind {
+		return tok, p.errorf(tok, "unexpected %q", tok.text)
+	}
+
+	return tok, nil
+}
+
+func (p *apiParser) errorf(tok token, format string, args ...interface{}) error {
+	return fmt.Errorf("line %d: %s", tok.line, fmt.Sprintf(format, args...))
+}
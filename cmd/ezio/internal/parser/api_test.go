@@ -0,0 +1,152 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valeamoris/go-ezio/cmd/ezio/internal/spec"
+)
+
+const userApi = `syntax = "v1"
+
+info(
+	title: "user api"
+	author: someone
+)
+
+type (
+	GetUserReq {
+		Id int64 ` + "`path:\"id\"`" + `
+	}
+
+	User {
+		Id    int64                  ` + "`json:\"id\"`" + `
+		Tags  []string               ` + "`json:\"tags,optional\"`" + `
+		Meta  map[string]interface{} ` + "`json:\"meta,optional\"`" + `
+		Owner *User                  // no tag
+	}
+)
+
+type CreateUserReq struct {
+	User
+}
+
+/* the user routes */
+@server(
+	group: user
+	prefix: /api/v1
+	jwt: Auth
+	middleware: Log, Check
+	shedding: true
+	timeout: 2s
+)
+service user-api {
+	@doc "get user"
+	@handler getUser
+	get /users/:id (GetUserReq) returns (User)
+
+	@doc(
+		summary: "create user"
+	)
+	@handler createUser
+	post /users (CreateUserReq)
+
+	get /users returns ([]User)
+}
+
+service user-api {
+	@handler ping
+	get /ping
+}
+`
+
+func TestParseApi(t *testing.T) {
+	s, err := ParseApi(userApi)
+	assert.Nil(t, err)
+	assert.Equal(t, "user-api", s.Service)
+	assert.Equal(t, "user api", s.Info["title"])
+	assert.Equal(t, []spec.Type{
+		{Name: "GetUserReq", Fields: []spec.Field{{Name: "Id", Type: "int64", Tag: "`path:\"id\"`"}}},
+		{Name: "User", Fields: []spec.Field{
+			{Name: "Id", Type: "int64", Tag: "`json:\"id\"`"},
+			{Name: "Tags", Type: "[]string", Tag: "`json:\"tags,optional\"`"},
+			{Name: "Meta", Type: "map[string]interface{}", Tag: "`json:\"meta,optional\"`"},
+			{Name: "Owner", Type: "*User"},
+		}},
+		{Name: "CreateUserReq", Fields: []spec.Field{{Type: "User"}}},
+	}, s.Types)
+	assert.Equal(t, []spec.Group{
+		{
+			Name:        "user",
+			Prefix:      "/api/v1",
+			Jwt:         "Auth",
+			Shedding:    true,
+			Middlewares: []string{"Log", "Check"},
+			Timeout:     2000,
+			Routes: []spec.Route{
+				{Method: "GET", Path: "/users/:id", Handler: "getUser", Request: "GetUserReq", Response: "User", Summary: "get user"},
+				{Method: "POST", Path: "/users", Handler: "createUser", Request: "CreateUserReq", Summary: "create user"},
+				{Method: "GET", Path: "/users", Handler: "getUsers", Response: "[]User"},
+			},
+		},
+		{
+			Routes: []spec.Route{{Method: "GET", Path: "/ping", Handler: "ping"}},
+		},
+	}, s.Groups)
+	assert.Equal(t, []string{"Auth"}, s.JwtNames())
+	assert.Equal(t, []string{"Log", "Check"}, s.MiddlewareNames())
+}
+
+func TestParseApiErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		err     string
+	}{
+		{
+			name:    "no service",
+			content: "type A {}",
+			err:     "no service defined",
+		},
+		{
+			name:    "import",
+			content: `import "base.api"`,
+			err:     "line 1: imports are not supported, merge the files first",
+		},
+		{
+			name:    "bad timeout",
+			content: "@server(\ntimeout: 3x\n)\nservice a {\n}",
+			err:     `line 4: bad timeout "3x"`,
+		},
+		{
+			name:    "conflict service",
+			content: "service a {\n}\nservice b {\n}",
+			err:     `line 3: service "b" conflicts with "a"`,
+		},
+		{
+			name:    "unclosed string",
+			content: `info(title: "a`,
+		},
+		{
+			name:    "missing path",
+			content: "service a {\nget (Req)\n}",
+			err:     `line 2: unexpected "("`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := ParseApi(test.content)
+			assert.NotNil(t, err)
+			if len(test.err) > 0 {
+				assert.Equal(t, test.err, err.Error())
+			}
+		})
+	}
+}
+
+func TestHandlerName(t *testing.T) {
+	assert.Equal(t, "getUsersId", handlerName("GET", "/users/:id"))
+	assert.Equal(t, "postUserProfiles", handlerName("POST", "/user-profiles"))
+	assert.Equal(t, "UserName", exported("user_name"))
+}
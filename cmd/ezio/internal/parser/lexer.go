@@ -0,0 +1,138 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+const (
+	tokenEOF tokenKind = iota
+	tokenNewline
+	tokenWord
+	tokenString
+	tokenRaw
+	tokenPath
+	tokenPunct
+)
+
+type (
+	tokenKind int
+
+	token struct {
+		kind tokenKind
+		text string
+		line int
+	}
+
+	lexer struct {
+		src  []rune
+		pos  int
+		line int
+	}
+)
+
+// tokenize splits the .api content into tokens, the comments are dropped, newlines are kept
+// since the fields and the server options are separated by lines.
+func tokenize(content string) ([]token, error) {
+	l := &lexer{src: []rune(content), line: 1}
+	var tokens []token
+	for {
+		tok, err := l.next()
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, tok)
+		if tok.kind == tokenEOF {
+			return tokens, nil
+		}
+	}
+}
+
+func (l *lexer) next() (token, error) {
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		switch {
+		case c == '\n':
+			l.pos++
+			l.line++
+			return token{kind: tokenNewline, text: "\n", line: l.line - 1}, nil
+		case unicode.IsSpace(c):
+			l.pos++
+		case c == '/' && l.peek(1) == '/':
+			for l.pos < len(l.src) && l.src[l.pos] != '\n' {
+				l.pos++
+			}
+		case c == '/' && l.peek(1) == '*':
+			end := strings.Index(string(l.src[l.pos+2:]), "*/")
+			if end < 0 {
+				return token{}, fmt.Errorf("line %d: unclosed comment", l.line)
+			}
+			comment := l.src[l.pos : l.pos+2+end+2]
+			l.line += strings.Count(string(comment), "\n")
+			l.pos += len(comment)
+		case c == '"':
+			return l.quoted('"', tokenString)
+		case c == '`':
+			return l.quoted('`', tokenRaw)
+		case c == '/':
+			return l.read(tokenPath, func(r rune) bool {
+				return !unicode.IsSpace(r) && r != '(' && r != ')'
+			}), nil
+		case isWordRune(c) || c == '@':
+			return l.read(tokenWord, func(r rune) bool {
+				return isWordRune(r) || r == '@'
+			}), nil
+		default:
+			l.pos++
+			return token{kind: tokenPunct, text: string(c), line: l.line}, nil
+		}
+	}
+
+	return token{kind: tokenEOF, line: l.line}, nil
+}
+
+func (l *lexer) peek(n int) rune {
+	if l.pos+n < len(l.src) {
+		return l.src[l.pos+n]
+	}
+
+	return 0
+}
+
+func (l *lexer) quoted(quote rune, kind tokenKind) (token, error) {
+	start := l.pos
+	line := l.line
+	l.pos++
+	for l.pos < len(l.src) && l.src[l.pos] != quote {
+		if l.src[l.pos] == '\\' && quote == '"' {
+			l.pos++
+		} else if l.src[l.pos] == '\n' {
+			l.line++
+		}
+		l.pos++
+	}
+	if l.pos >= len(l.src) {
+		return token{}, fmt.Errorf("line %d: unclosed %c", line, quote)
+	}
+	l.pos++
+
+	text := string(l.src[start:l.pos])
+	if kind == tokenString {
+		text = strings.Trim(text, `"`)
+	}
+	return token{kind: kind, text: text, line: line}, nil
+}
+
+func (l *lexer) read(kind tokenKind, accept func(r rune) bool) token {
+	start := l.pos
+	for l.pos < len(l.src) && accept(l.src[l.pos]) {
+		l.pos++
+	}
+
+	return token{kind: kind, text: string(l.src[start:l.pos]), line: l.line}
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '-' || r == '.'
+}
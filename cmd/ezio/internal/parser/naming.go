@@ -0,0 +1,39 @@
+package parser
+
+import (
+	"strings"
+	"unicode"
+)
+
+// handlerName derives the handler from the route, like GET /users/:id to getUsersId.
+func handlerName(method, path string) string {
+	var b strings.Builder
+	b.WriteString(strings.ToLower(method))
+	for _, segment := range strings.FieldsFunc(path, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		b.WriteString(exported(segment))
+	}
+
+	return b.String()
+}
+
+// exported converts user_name or user-name to UserName.
+func exported(name string) string {
+	var b strings.Builder
+	upper := true
+	for _, r := range name {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		}
+		if upper {
+			b.WriteRune(unicode.ToUpper(r))
+			upper = false
+		} else {
+			b.WriteRune(r)
+		}
+	}
+
+	return b.String()
+}
@@ -0,0 +1,470 @@
+package parser
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/valeamoris/go-ezio/cmd/ezio/internal/spec"
+	"github.com/valeamoris/go-ezio/rest/openapi"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	refPrefix = "#/components/schemas/"
+	// bearer认证的路由使用的jwt配置名
+	defaultJwt = "Auth"
+)
+
+var methodOrder = []string{"get", "head", "post", "put", "patch", "delete", "options"}
+
+type openApiConverter struct {
+	doc   *openapi.Document
+	spec  *spec.Spec
+	types map[string]bool
+}
+
+// ParseOpenApiFile parses the OpenAPI 3 document in JSON or YAML, decided by the extension.
+func ParseOpenApiFile(filename string) (*spec.Spec, error) {
+	content, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".yaml", ".yml":
+		if content, err = yamlToJson(content); err != nil {
+			return nil, err
+		}
+	}
+
+	return ParseOpenApi(content)
+}
+
+// ParseOpenApi parses the OpenAPI 3 document in JSON.
+func ParseOpenApi(content []byte) (*spec.Spec, error) {
+	var doc openapi.Document
+	if err := json.Unmarshal(content, &doc); err != nil {
+		return nil, err
+	}
+
+	c := &openApiConverter{
+		doc: &doc,
+		spec: &spec.Spec{
+			Service: serviceName(doc.Info.Title),
+			Info: map[string]string{
+				"title":   doc.Info.Title,
+				"desc":    doc.Info.Description,
+				"version": doc.Info.Version,
+			},
+		},
+		types: make(map[string]bool),
+	}
+	if err := c.convert(); err != nil {
+		return nil, err
+	}
+
+	return c.spec, nil
+}
+
+func (c *openApiConverter) convert() error {
+	names := make([]string, 0, len(c.doc.Components.Schemas))
+	for name := range c.doc.Components.Schemas {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		c.types[exported(name)] = true
+	}
+	for _, name := range names {
+		if err := c.addType(exported(name), c.doc.Components.Schemas[name]); err != nil {
+			return err
+		}
+	}
+
+	paths := make([]string, 0, len(c.doc.Paths))
+	for path := range c.doc.Paths {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	groups := make(map[string]int)
+	for _, path := range paths {
+		item := c.doc.Paths[path]
+		for _, method := range methodOrder {
+			op, ok := (*item)[method]
+			if !ok {
+				continue
+			}
+
+			route, err := c.convertRoute(method, path, op)
+			if err != nil {
+				return err
+			}
+
+			var group spec.Group
+			if len(op.Tags) > 0 {
+				group.Name = packageName(op.Tags[0])
+			}
+			if c.isBearer(op.Security) {
+				group.Jwt = defaultJwt
+			}
+			key := group.Name + "/" + group.Jwt
+			index, ok := groups[key]
+			if !ok {
+				index = len(c.spec.Groups)
+				groups[key] = index
+				c.spec.Groups = append(c.spec.Groups, group)
+			}
+			c.spec.Groups[index].Routes = append(c.spec.Groups[index].Routes, route)
+		}
+	}
+
+	return nil
+}
+
+func (c *openApiConverter) convertRoute(method, path string, op *openapi.Operation) (spec.Route, error) {
+	route := spec.Route{
+		Method:  strings.ToUpper(method),
+		Path:    echoPath(path),
+		Summary: op.Summary,
+	}
+	if len(op.OperationID) > 0 {
+		name := exported(op.OperationID)
+		route.Handler = strings.ToLower(name[:1]) + name[1:]
+	} else {
+		route.Handler = handlerName(route.Method, route.Path)
+	}
+	typeName := exported(route.Handler)
+
+	request, err := c.convertRequest(typeName+"Req", op)
+	if err != nil {
+		return route, err
+	}
+	route.Request = request
+
+	response, err := c.convertResponse(typeName+"Resp", op)
+	if err != nil {
+		return route, err
+	}
+	route.Response = response
+
+	return route, nil
+}
+
+func (c *openApiConverter) convertRequest(name string, op *openapi.Operation) (string, error) {
+	var body *openapi.Schema
+	if op.RequestBody != nil {
+		if media, ok := op.RequestBody.Content["application/json"]; ok {
+			body = media.Schema
+		}
+	}
+	if len(op.Parameters) == 0 {
+		if body == nil {
+			return "", nil
+		}
+		if len(body.Ref) > 0 {
+			return refName(body.Ref), nil
+		}
+	}
+
+	typ := spec.Type{Name: name}
+	for _, param := range op.Parameters {
+		source := param.In
+		switch source {
+		case "path":
+		case "query":
+			source = "form"
+		case "header":
+		default:
+			return "", fmt.Errorf("%s: unsupported parameter in %s", name, param.In)
+		}
+
+		fieldType, err := c.goType(name+exported(param.Name), param.Schema)
+		if err != nil {
+			return "", err
+		}
+		typ.Fields = append(typ.Fields, spec.Field{
+			Name: exported(param.Name),
+			Type: fieldType,
+			Tag:  tag(source, param.Name, param.Required || param.In == "path", param.Schema),
+		})
+	}
+
+	if body != nil {
+		if len(body.Ref) > 0 {
+			typ.Fields = append(typ.Fields, spec.Field{Type: refName(body.Ref)})
+		} else {
+			fields, err := c.properties(name, body)
+			if err != nil {
+				return "", err
+			}
+			typ.Fields = append(typ.Fields, fields...)
+		}
+	}
+
+	c.spec.Types = append(c.spec.Types, typ)
+	return name, nil
+}
+
+func (c *openApiConverter) convertResponse(name string, op *openapi.Operation) (string, error) {
+	codes := make([]string, 0, len(op.Responses))
+	for code := range op.Responses {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+
+	for _, code := range codes {
+		if !strings.HasPrefix(code, "2") {
+			continue
+		}
+		media, ok := op.Responses[code].Content["application/json"]
+		if !ok || media.Schema == nil {
+			continue
+		}
+		return c.goType(name, media.Schema)
+	}
+
+	return "", nil
+}
+
+func (c *openApiConverter) addType(name string, schema *openapi.Schema) error {
+	fields, err := c.properties(name, schema)
+	if err != nil {
+		return err
+	}
+
+	c.spec.Types = append(c.spec.Types, spec.Type{Name: name, Fields: fields})
+	return nil
+}
+
+func (c *openApiConverter) properties(name string, schema *openapi.Schema) ([]spec.Field, error) {
+	required := make(map[string]bool)
+	for _, prop := range schema.Required {
+		required[prop] = true
+	}
+
+	props := make([]string, 0, len(schema.Properties))
+	for prop := range schema.Properties {
+		props = append(props, prop)
+	}
+	sort.Strings(props)
+
+	var fields []spec.Field
+	for _, prop := range props {
+		propSchema := schema.Properties[prop]
+		fieldType, err := c.goType(name+exported(prop), propSchema)
+		if err != nil {
+			return nil, err
+		}
+		fields = append(fields, spec.Field{
+			Name: exported(prop),
+			Type: fieldType,
+			Tag:  tag("json", prop, required[prop], propSchema),
+		})
+	}
+
+	return fields, nil
+}
+
+// goType returns the go type of the schema, the inline objects are declared as the given name.
+func (c *openApiConverter) goType(name string, schema *openapi.Schema) (string, error) {
+	if schema == nil {
+		return "interface{}", nil
+	}
+	if len(schema.Ref) > 0 {
+		if !strings.HasPrefix(schema.Ref, refPrefix) {
+			return "", fmt.Errorf("%s: unsupported reference %s", name, schema.Ref)
+		}
+		return refName(schema.Ref), nil
+	}
+
+	switch schema.Type {
+	case "string":
+		if schema.Format == "byte" {
+			return "[]byte", nil
+		}
+		return "string", nil
+	case "integer":
+		if schema.Format == "int32" {
+			return "int", nil
+		}
+		return "int64", nil
+	case "number":
+		if schema.Format == "float" {
+			return "float32", nil
+		}
+		return "float64", nil
+	case "boolean":
+		return "bool", nil
+	case "array":
+		elem, err := c.goType(name+"Item", schema.Items)
+		if err != nil {
+			return "", err
+		}
+		return "[]" + elem, nil
+	case "object":
+		if len(schema.Properties) == 0 {
+			if schema.AdditionalProperties != nil {
+				elem, err := c.goType(name+"Value", schema.AdditionalProperties)
+				if err != nil {
+					return "", err
+				}
+				return "map[string]" + elem, nil
+			}
+			return "map[string]interface{}", nil
+		}
+		if c.types[name] {
+			return "", fmt.Errorf("duplicate type %s", name)
+		}
+		c.types[name] = true
+		if err := c.addType(name, schema); err != nil {
+			return "", err
+		}
+		return name, nil
+	default:
+		return "interface{}", nil
+	}
+}
+
+func (c *openApiConverter) isBearer(security []openapi.SecurityRequirement) bool {
+	for _, requirement := range security {
+		for name := range requirement {
+			scheme, ok := c.doc.Components.SecuritySchemes[name]
+			if ok && scheme.Type == "http" && strings.EqualFold(scheme.Scheme, "bearer") {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// tag builds the go-zero style tag, like `json:"name,optional,options=a|b,range=[1:10]"`.
+func tag(source, name string, required bool, schema *openapi.Schema) string {
+	segments := []string{name}
+	if !required {
+		segments = append(segments, "optional")
+	}
+	if schema != nil {
+		if len(schema.Enum) > 0 {
+			options := make([]string, 0, len(schema.Enum))
+			for _, val := range schema.Enum {
+				options = append(options, fmt.Sprint(val))
+			}
+			segments = append(segments, "options="+strings.Join(options, "|"))
+		}
+		if schema.Default != nil {
+			segments = append(segments, "default="+fmt.Sprint(schema.Default))
+		}
+		if schema.Minimum != nil || schema.Maximum != nil {
+			segments = append(segments, "range="+rangeOf(schema))
+		}
+	}
+
+	return fmt.Sprintf("`%s:%q`", source, strings.Join(segments, ","))
+}
+
+func rangeOf(schema *openapi.Schema) string {
+	var b strings.Builder
+	if schema.ExclusiveMinimum {
+		b.WriteByte('(')
+	} else {
+		b.WriteByte('[')
+	}
+	if schema.Minimum != nil {
+		b.WriteString(strconv.FormatFloat(*schema.Minimum, 'f', -1, 64))
+	}
+	b.WriteByte(':')
+	if schema.Maximum != nil {
+		b.WriteString(strconv.FormatFloat(*schema.Maximum, 'f', -1, 64))
+	}
+	if schema.ExclusiveMaximum {
+		b.WriteByte(')')
+	} else {
+		b.WriteByte(']')
+	}
+
+	return b.String()
+}
+
+func refName(ref string) string {
+	return exported(strings.TrimPrefix(ref, refPrefix))
+}
+
+// echoPath converts /users/{id} to /users/:id.
+func echoPath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			segments[i] = ":" + segment[1:len(segment)-1]
+		}
+	}
+
+	return strings.Join(segments, "/")
+}
+
+// serviceName converts the title like User API to user-api.
+func serviceName(title string) string {
+	var words []string
+	for _, word := range strings.FieldsFunc(strings.ToLower(title), func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9')
+	}) {
+		words = append(words, word)
+	}
+	if len(words) == 0 {
+		return "service"
+	}
+
+	return strings.Join(words, "-")
+}
+
+// packageName converts the tag like User Profile to userprofile.
+func packageName(name string) string {
+	return strings.ReplaceAll(serviceName(name), "-", "")
+}
+
+// yamlToJson converts the YAML document to JSON, the map keys of YAML are not always strings.
+func yamlToJson(content []byte) ([]byte, error) {
+	var doc interface{}
+	if err := yaml.Unmarshal(content, &doc); err != nil {
+		return nil, err
+	}
+
+	doc, err := jsonValue(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(doc)
+}
+
+func jsonValue(val interface{}) (interface{}, error) {
+	switch v := val.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			converted, err := jsonValue(item)
+			if err != nil {
+				return nil, err
+			}
+			m[fmt.Sprint(key)] = converted
+		}
+		return m, nil
+	case []interface{}:
+		for i, item := range v {
+			converted, err := jsonValue(item)
+			if err != nil {
+				return nil, err
+			}
+			v[i] = converted
+		}
+		return v, nil
+	default:
+		return v, nil
+	}
+}
@@ -0,0 +1,143 @@
+package parser
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valeamoris/go-ezio/cmd/ezio/internal/spec"
+)
+
+const petStore = `openapi: 3.0.3
+info:
+  title: Pet Store
+  version: 1.0.0
+paths:
+  /pets:
+    get:
+      tags: [pets]
+      operationId: listPets
+      summary: list pets
+      parameters:
+        - name: limit
+          in: query
+          schema: {type: integer, format: int32, minimum: 1, maximum: 100, default: 20}
+        - name: status
+          in: query
+          schema: {type: string, enum: [available, sold]}
+      responses:
+        "200":
+          description: ok
+          content:
+            application/json:
+              schema:
+                type: array
+                items: {$ref: "#/components/schemas/Pet"}
+    post:
+      tags: [pets]
+      security: [{bearer: []}]
+      requestBody:
+        content:
+          application/json:
+            schema: {$ref: "#/components/schemas/Pet"}
+      responses:
+        "201":
+          description: created
+          content:
+            application/json:
+              schema:
+                type: object
+                properties:
+                  id: {type: integer}
+  /pets/{petId}:
+    put:
+      tags: [pets]
+      security: [{bearer: []}]
+      parameters:
+        - {name: petId, in: path, required: true, schema: {type: string}}
+      requestBody:
+        content:
+          application/json:
+            schema: {$ref: "#/components/schemas/Pet"}
+      responses:
+        "204": {description: done}
+components:
+  securitySchemes:
+    bearer: {type: http, scheme: bearer}
+  schemas:
+    Pet:
+      type: object
+      required: [name]
+      properties:
+        name: {type: string}
+        owner:
+          type: object
+          properties:
+            name: {type: string}
+`
+
+func TestParseOpenApiFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ezio")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "openapi.yaml")
+	assert.Nil(t, ioutil.WriteFile(filename, []byte(petStore), 0644))
+
+	s, err := ParseOpenApiFile(filename)
+	assert.Nil(t, err)
+	assert.Equal(t, "pet-store", s.Service)
+	assert.Equal(t, []spec.Type{
+		{Name: "PetOwner", Fields: []spec.Field{
+			{Name: "Name", Type: "string", Tag: "`json:\"name,optional\"`"},
+		}},
+		{Name: "Pet", Fields: []spec.Field{
+			{Name: "Name", Type: "string", Tag: "`json:\"name\"`"},
+			{Name: "Owner", Type: "PetOwner", Tag: "`json:\"owner,optional\"`"},
+		}},
+		{Name: "ListPetsReq", Fields: []spec.Field{
+			{Name: "Limit", Type: "int", Tag: "`form:\"limit,optional,default=20,range=[1:100]\"`"},
+			{Name: "Status", Type: "string", Tag: "`form:\"status,optional,options=available|sold\"`"},
+		}},
+		{Name: "PostPetsResp", Fields: []spec.Field{
+			{Name: "Id", Type: "int64", Tag: "`json:\"id,optional\"`"},
+		}},
+		{Name: "PutPetsPetIdReq", Fields: []spec.Field{
+			{Name: "PetId", Type: "string", Tag: "`path:\"petId\"`"},
+			{Type: "Pet"},
+		}},
+	}, s.Types)
+	assert.Equal(t, []spec.Group{
+		{
+			Name: "pets",
+			Routes: []spec.Route{
+				{Method: "GET", Path: "/pets", Handler: "listPets", Request: "ListPetsReq", Response: "[]Pet", Summary: "list pets"},
+			},
+		},
+		{
+			Name: "pets",
+			Jwt:  "Auth",
+			Routes: []spec.Route{
+				{Method: "POST", Path: "/pets", Handler: "postPets", Request: "Pet", Response: "PostPetsResp"},
+				{Method: "PUT", Path: "/pets/:petId", Handler: "putPetsPetId", Request: "PutPetsPetIdReq"},
+			},
+		},
+	}, s.Groups)
+}
+
+func TestParseOpenApiErrors(t *testing.T) {
+	_, err := ParseOpenApi([]byte(`{"paths": {"/a": {"get": {"parameters": [{"name": "c", "in": "cookie"}]}}}}`))
+	assert.Equal(t, "GetAReq: unsupported parameter in cookie", err.Error())
+
+	_, err = ParseOpenApi([]byte(`{"components": {"schemas": {"A": {"type": "object", "properties": {
+		"b": {"$ref": "other.yaml#/B"}}}}}}`))
+	assert.Equal(t, "AB: unsupported reference other.yaml#/B", err.Error())
+}
+
+func TestEchoPath(t *testing.T) {
+	assert.Equal(t, "/users/:id/books/:bookId", echoPath("/users/{id}/books/{bookId}"))
+	assert.Equal(t, "user-profile-api", serviceName("User Profile API"))
+	assert.Equal(t, "userprofile", packageName("User Profile"))
+}
@@ -0,0 +1,79 @@
+// Package spec is the service description shared by the parsers and the generator.
+package spec
+
+type (
+	Spec struct {
+		// 服务名，如user-api
+		Service string
+		Info    map[string]string
+		Types   []Type
+		Groups  []Group
+	}
+
+	Type struct {
+		Name   string
+		Fields []Field
+	}
+
+	Field struct {
+		// 为空时是内嵌的类型
+		Name string
+		Type string
+		// 完整的tag，如`json:"name,optional"`
+		Tag string
+	}
+
+	Group struct {
+		// handler和logic的子目录，为空时放在根目录
+		Name   string
+		Prefix string
+		// jwt的配置名，如Auth
+		Jwt         string
+		Shedding    bool
+		Middlewares []string
+		// milliseconds
+		Timeout int64
+		Routes  []Route
+	}
+
+	Route struct {
+		Method   string
+		Path     string
+		Handler  string
+		Request  string
+		Response string
+		Summary  string
+	}
+)
+
+// JwtNames returns the distinct jwt config names of the groups.
+func (s *Spec) JwtNames() []string {
+	return s.distinct(func(g Group) []string {
+		if len(g.Jwt) == 0 {
+			return nil
+		}
+		return []string{g.Jwt}
+	})
+}
+
+// MiddlewareNames returns the distinct middleware names of the groups.
+func (s *Spec) MiddlewareNames() []string {
+	return s.distinct(func(g Group) []string {
+		return g.Middlewares
+	})
+}
+
+func (s *Spec) distinct(names func(g Group) []string) []string {
+	var result []string
+	seen := make(map[string]bool)
+	for _, g := range s.Groups {
+		for _, name := range names(g) {
+			if !seen[name] {
+				seen[name] = true
+				result = append(result, name)
+			}
+		}
+	}
+
+	return result
+}
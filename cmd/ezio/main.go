@@ -0,0 +1,63 @@
+// Command ezio scaffolds the rest service from a go-zero .api file or an OpenAPI 3 document.
+//
+//	ezio -api user.api -dir ./user
+//	ezio -openapi openapi.yaml -dir ./user -module example.com/user
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/valeamoris/go-ezio/cmd/ezio/internal/generator"
+	"github.com/valeamoris/go-ezio/cmd/ezio/internal/parser"
+	"github.com/valeamoris/go-ezio/cmd/ezio/internal/spec"
+)
+
+var (
+	apiFile     = flag.String("api", "", "the go-zero .api file")
+	openApiFile = flag.String("openapi", "", "the OpenAPI 3 document in JSON or YAML")
+	dir         = flag.String("dir", ".", "the output directory")
+	module      = flag.String("module", "", "the module path, used to create go.mod if not found")
+)
+
+func main() {
+	flag.Parse()
+
+	var (
+		s   *spec.Spec
+		err error
+	)
+	switch {
+	case len(*apiFile) > 0 && len(*openApiFile) > 0:
+		fail(fmt.Errorf("-api and -openapi can't be used together"))
+	case len(*apiFile) > 0:
+		s, err = parser.ParseApiFile(*apiFile)
+	case len(*openApiFile) > 0:
+		s, err = parser.ParseOpenApiFile(*openApiFile)
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		fail(err)
+	}
+
+	files, err := generator.Generate(s, generator.Config{
+		Dir:    *dir,
+		Module: *module,
+	})
+	if err != nil {
+		fail(err)
+	}
+
+	for _, file := range files {
+		fmt.Println(file)
+	}
+	fmt.Println("Done.")
+}
+
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, err)
+	os.Exit(1)
+}
@@ -0,0 +1,132 @@
+package limit
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	red "github.com/go-redis/redis/v8"
+	"github.com/valeamoris/go-ezio/core/stores/redis"
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+const defaultKeyPrefix = "ratelimit:"
+
+var ErrUnknownReply = errors.New("unknown reply from rate limit script")
+
+type (
+	Result struct {
+		Allowed   bool
+		Limit     int
+		Remaining int
+		// 被拒绝时需要等待的时间
+		RetryAfter time.Duration
+		// 配额完全恢复的时间
+		ResetAfter time.Duration
+		// 是否由本地限流器降级处理
+		Fallback bool
+	}
+
+	// A Limiter decides whether the request identified by key is allowed.
+	Limiter interface {
+		Allow(ctx context.Context, key string) (Result, error)
+	}
+
+	Option func(o *options)
+
+	options struct {
+		prefix   string
+		fallback bool
+	}
+
+	// redisLimiter runs the script on redis, and falls back to the local token bucket on redis errors.
+	redisLimiter struct {
+		store  redis.Node
+		script string
+		limit  int
+		args   func(now time.Time) []interface{}
+		parse  func(vals []interface{}) (Result, error)
+		local  *localLimiter
+		opts   options
+	}
+)
+
+// 限流key的前缀，默认为ratelimit:
+func WithKeyPrefix(prefix string) Option {
+	return func(o *options) {
+		o.prefix = prefix
+	}
+}
+
+// redis不可用时直接返回错误，而不是降级到本地限流
+func WithoutFallback() Option {
+	return func(o *options) {
+		o.fallback = false
+	}
+}
+
+func newOptions(opts []Option) options {
+	o := options{
+		prefix:   defaultKeyPrefix,
+		fallback: true,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+func (l *redisLimiter) Allow(ctx context.Context, key string) (Result, error) {
+	if l.store == nil {
+		return l.allowLocally(key)
+	}
+
+	resp, err := l.store.Eval(ctx, l.script, []string{l.opts.prefix + key}, l.args(time.Now())...).Result()
+	if err != nil && err != red.Nil {
+		if !l.opts.fallback {
+			return Result{}, err
+		}
+
+		logx.Errorf("rate limiter fell back to local, redis error: %s", err.Error())
+		return l.allowLocally(key)
+	}
+
+	vals, ok := resp.([]interface{})
+	if !ok {
+		return Result{}, ErrUnknownReply
+	}
+
+	result, err := l.parse(vals)
+	if err != nil {
+		return Result{}, err
+	}
+
+	result.Limit = l.limit
+	return result, nil
+}
+
+func (l *redisLimiter) allowLocally(key string) (Result, error) {
+	result := l.local.allow(key)
+	result.Limit = l.limit
+	result.Fallback = true
+	return result, nil
+}
+
+func parseReply(vals []interface{}) (allowed bool, remaining int, retryMillis int64, err error) {
+	if len(vals) < 3 {
+		return false, 0, 0, ErrUnknownReply
+	}
+
+	code, ok1 := vals[0].(int64)
+	left, ok2 := vals[1].(int64)
+	retry, ok3 := vals[2].(int64)
+	if !ok1 || !ok2 || !ok3 {
+		return false, 0, 0, ErrUnknownReply
+	}
+
+	return code == 1, int(left), retry, nil
+}
+
+func millis(t time.Time) int64 {
+	return t.UnixNano() / int64(time.Millisecond)
+}
@@ -0,0 +1,145 @@
+package limit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/valeamoris/go-ezio/core/stores/redis"
+)
+
+func TestTokenLimiter(t *testing.T) {
+	runOnRedis(t, func(store redis.Node, _ *miniredis.Miniredis) {
+		l, err := NewTokenLimiter(store, 1, 3)
+		assert.Nil(t, err)
+		for i := 0; i < 3; i++ {
+			result, err := l.Allow(context.Background(), "a")
+			assert.Nil(t, err)
+			assert.True(t, result.Allowed)
+			assert.Equal(t, 3, result.Limit)
+			assert.Equal(t, 2-i, result.Remaining)
+		}
+
+		result, err := l.Allow(context.Background(), "a")
+		assert.Nil(t, err)
+		assert.False(t, result.Allowed)
+		assert.True(t, result.RetryAfter > 0 && result.RetryAfter <= time.Second)
+		assert.False(t, result.Fallback)
+
+		result, err = l.Allow(context.Background(), "b")
+		assert.Nil(t, err)
+		assert.True(t, result.Allowed)
+	})
+}
+
+func TestTokenLimiter_InvalidRate(t *testing.T) {
+	_, err := NewTokenLimiter(nil, 0, 1)
+	assert.Equal(t, ErrInvalidTokenRate, err)
+	_, err = NewTokenLimiter(nil, 1, -1)
+	assert.Equal(t, ErrInvalidTokenRate, err)
+
+	l, err := NewTokenLimiter(nil, 2, 0)
+	assert.Nil(t, err)
+	result, err := l.Allow(context.Background(), "a")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, result.Limit)
+}
+
+func TestPeriodLimiter(t *testing.T) {
+	runOnRedis(t, func(store redis.Node, s *miniredis.Miniredis) {
+		l, err := NewPeriodLimiter(store, time.Second, 2)
+		assert.Nil(t, err)
+		for i := 0; i < 2; i++ {
+			result, err := l.Allow(context.Background(), "a")
+			assert.Nil(t, err)
+			assert.True(t, result.Allowed)
+		}
+
+		result, err := l.Allow(context.Background(), "a")
+		assert.Nil(t, err)
+		assert.False(t, result.Allowed)
+		assert.Equal(t, 0, result.Remaining)
+		assert.True(t, result.RetryAfter > 0)
+
+		s.FastForward(time.Second)
+		result, err = l.Allow(context.Background(), "a")
+		assert.Nil(t, err)
+		assert.True(t, result.Allowed)
+	})
+}
+
+func TestWindowLimiter_Invalid(t *testing.T) {
+	_, err := NewPeriodLimiter(nil, 0, 1)
+	assert.Equal(t, ErrInvalidWindow, err)
+	_, err = NewPeriodLimiter(nil, time.Microsecond, 1)
+	assert.Equal(t, ErrInvalidWindow, err)
+	_, err = NewPeriodLimiter(nil, time.Second, 0)
+	assert.Equal(t, ErrInvalidWindow, err)
+	_, err = NewSlidingWindowLimiter(nil, -time.Second, 1)
+	assert.Equal(t, ErrInvalidWindow, err)
+	_, err = NewSlidingWindowLimiter(nil, time.Second, -1)
+	assert.Equal(t, ErrInvalidWindow, err)
+}
+
+func TestSlidingWindowLimiter(t *testing.T) {
+	runOnRedis(t, func(store redis.Node, _ *miniredis.Miniredis) {
+		l, err := NewSlidingWindowLimiter(store, time.Minute, 2, WithKeyPrefix("sliding:"))
+		assert.Nil(t, err)
+		for i := 0; i < 2; i++ {
+			result, err := l.Allow(context.Background(), "a")
+			assert.Nil(t, err)
+			assert.True(t, result.Allowed)
+			assert.Equal(t, 1-i, result.Remaining)
+		}
+
+		result, err := l.Allow(context.Background(), "a")
+		assert.Nil(t, err)
+		assert.False(t, result.Allowed)
+		assert.True(t, result.RetryAfter > 0 && result.RetryAfter <= time.Minute)
+	})
+}
+
+func TestLimiter_Fallback(t *testing.T) {
+	runOnRedis(t, func(store redis.Node, s *miniredis.Miniredis) {
+		s.Close()
+
+		l, err := NewTokenLimiter(store, 1, 1)
+		assert.Nil(t, err)
+		result, err := l.Allow(context.Background(), "a")
+		assert.Nil(t, err)
+		assert.True(t, result.Allowed)
+		assert.True(t, result.Fallback)
+		result, err = l.Allow(context.Background(), "a")
+		assert.Nil(t, err)
+		assert.False(t, result.Allowed)
+		assert.True(t, result.RetryAfter > 0)
+
+		l, err = NewTokenLimiter(store, 1, 1, WithoutFallback())
+		assert.Nil(t, err)
+		_, err = l.Allow(context.Background(), "a")
+		assert.NotNil(t, err)
+	})
+}
+
+func TestLimiter_NoStore(t *testing.T) {
+	l, err := NewPeriodLimiter(nil, time.Minute, 1)
+	assert.Nil(t, err)
+	result, err := l.Allow(context.Background(), "a")
+	assert.Nil(t, err)
+	assert.True(t, result.Allowed)
+	result, err = l.Allow(context.Background(), "a")
+	assert.Nil(t, err)
+	assert.False(t, result.Allowed)
+}
+
+func runOnRedis(t *testing.T, fn func(store redis.Node, s *miniredis.Miniredis)) {
+	s, err := miniredis.Run()
+	assert.Nil(t, err)
+	defer s.Close()
+
+	store, err := redis.NewRedis(s.Addr(), redis.NodeType)
+	assert.Nil(t, err)
+	fn(store, s)
+}
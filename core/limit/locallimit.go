@@ -0,0 +1,69 @@
+package limit
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/zeromicro/go-zero/core/collection"
+)
+
+const localExpiry = time.Minute
+
+type (
+	// localLimiter is the in-process token bucket used when redis is unavailable,
+	// the quota is per instance, not shared across the cluster.
+	localLimiter struct {
+		rate    float64
+		burst   int
+		buckets *collection.Cache
+	}
+
+	bucket struct {
+		lock   sync.Mutex
+		tokens float64
+		last   time.Time
+	}
+)
+
+func newLocalLimiter(rate float64, burst int) *localLimiter {
+	buckets, err := collection.NewCache(localExpiry)
+	if err != nil {
+		panic(err)
+	}
+
+	return &localLimiter{
+		rate:    rate,
+		burst:   burst,
+		buckets: buckets,
+	}
+}
+
+func (l *localLimiter) allow(key string) Result {
+	val, _ := l.buckets.Take(key, func() (interface{}, error) {
+		return &bucket{
+			tokens: float64(l.burst),
+			last:   time.Now(),
+		}, nil
+	})
+	b := val.(*bucket)
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	now := time.Now()
+	b.tokens = math.Min(float64(l.burst), b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return Result{
+			Allowed:   true,
+			Remaining: int(b.tokens),
+		}
+	}
+
+	return Result{
+		RetryAfter: time.Duration((1 - b.tokens) / l.rate * float64(time.Second)),
+	}
+}
@@ -0,0 +1,65 @@
+package limit
+
+import (
+	"errors"
+	"time"
+
+	"github.com/valeamoris/go-ezio/core/stores/redis"
+)
+
+// fixed window, KEYS[1] as the counter of current window
+const periodScript = `local limit = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local current = redis.call("INCRBY", KEYS[1], 1)
+if current == 1 then
+    redis.call("PEXPIRE", KEYS[1], window)
+end
+local ttl = redis.call("PTTL", KEYS[1])
+if ttl < 0 then
+    redis.call("PEXPIRE", KEYS[1], window)
+    ttl = window
+end
+if current <= limit then
+    return {1, limit - current, ttl}
+else
+    return {0, 0, ttl}
+end`
+
+// ErrInvalidWindow means the period or window of the limiter is shorter than a millisecond,
+// or the quota isn't positive.
+var ErrInvalidWindow = errors.New("window of limiter must be at least a millisecond, and quota must be positive")
+
+// NewPeriodLimiter returns a fixed window limiter, which allows quota requests in each period.
+func NewPeriodLimiter(store redis.Node, period time.Duration, quota int, opts ...Option) (Limiter, error) {
+	// 脚本按毫秒过期，为0时PEXPIRE报错
+	if period.Milliseconds() <= 0 || quota <= 0 {
+		return nil, ErrInvalidWindow
+	}
+
+	return &redisLimiter{
+		store:  store,
+		script: periodScript,
+		limit:  quota,
+		args: func(now time.Time) []interface{} {
+			return []interface{}{quota, period.Milliseconds()}
+		},
+		parse: func(vals []interface{}) (Result, error) {
+			allowed, remaining, ttl, err := parseReply(vals)
+			if err != nil {
+				return Result{}, err
+			}
+
+			result := Result{
+				Allowed:    allowed,
+				Remaining:  remaining,
+				ResetAfter: time.Duration(ttl) * time.Millisecond,
+			}
+			if !allowed {
+				result.RetryAfter = result.ResetAfter
+			}
+			return result, nil
+		},
+		local: newLocalLimiter(float64(quota)/period.Seconds(), quota),
+		opts:  newOptions(opts),
+	}, nil
+}
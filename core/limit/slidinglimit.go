@@ -0,0 +1,60 @@
+package limit
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/valeamoris/go-ezio/core/stores/redis"
+	"github.com/zeromicro/go-zero/core/stringx"
+)
+
+// sliding window log, KEYS[1] as the sorted set of request timestamps
+const slidingScript = `local limit = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+redis.call("ZREMRANGEBYSCORE", KEYS[1], "-inf", now - window)
+local count = redis.call("ZCARD", KEYS[1])
+if count < limit then
+    redis.call("ZADD", KEYS[1], now, ARGV[4])
+    redis.call("PEXPIRE", KEYS[1], window)
+    return {1, limit - count - 1, 0}
+end
+local retry = window
+local oldest = redis.call("ZRANGE", KEYS[1], 0, 0, "WITHSCORES")
+if oldest[2] ~= nil then
+    retry = tonumber(oldest[2]) + window - now
+end
+return {0, 0, retry}`
+
+// NewSlidingWindowLimiter returns a limiter which allows quota requests in any window.
+func NewSlidingWindowLimiter(store redis.Node, window time.Duration, quota int, opts ...Option) (Limiter, error) {
+	if window.Milliseconds() <= 0 || quota <= 0 {
+		return nil, ErrInvalidWindow
+	}
+
+	return &redisLimiter{
+		store:  store,
+		script: slidingScript,
+		limit:  quota,
+		args: func(now time.Time) []interface{} {
+			ts := millis(now)
+			return []interface{}{quota, window.Milliseconds(), ts,
+				strconv.FormatInt(ts, 10) + "-" + stringx.Randn(8)}
+		},
+		parse: func(vals []interface{}) (Result, error) {
+			allowed, remaining, retry, err := parseReply(vals)
+			if err != nil {
+				return Result{}, err
+			}
+
+			return Result{
+				Allowed:    allowed,
+				Remaining:  remaining,
+				RetryAfter: time.Duration(retry) * time.Millisecond,
+				ResetAfter: window,
+			}, nil
+		},
+		local: newLocalLimiter(float64(quota)/window.Seconds(), quota),
+		opts:  newOptions(opts),
+	}, nil
+}
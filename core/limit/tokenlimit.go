@@ -0,0 +1,79 @@
+package limit
+
+import (
+	"errors"
+	"math"
+	"time"
+
+	"github.com/valeamoris/go-ezio/core/stores/redis"
+)
+
+// KEYS[1] as the bucket key, one hash with tokens and timestamp, so that it works with cluster
+const tokenScript = `local rate = tonumber(ARGV[1])
+local capacity = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local requested = tonumber(ARGV[4])
+local ttl = math.ceil(capacity / rate * 2)
+local bucket = redis.call("HMGET", KEYS[1], "tokens", "ts")
+local tokens = tonumber(bucket[1])
+if tokens == nil then
+    tokens = capacity
+end
+local last = tonumber(bucket[2])
+if last == nil then
+    last = 0
+end
+
+local delta = math.max(0, now - last)
+tokens = math.min(capacity, tokens + delta * rate / 1000)
+local allowed = 0
+local retry = 0
+if tokens >= requested then
+    tokens = tokens - requested
+    allowed = 1
+else
+    retry = math.ceil((requested - tokens) * 1000 / rate)
+end
+
+redis.call("HMSET", KEYS[1], "tokens", tokens, "ts", now)
+redis.call("EXPIRE", KEYS[1], ttl)
+return {allowed, math.floor(tokens), retry}`
+
+// ErrInvalidTokenRate means the rate of the token bucket isn't positive or the burst is negative.
+var ErrInvalidTokenRate = errors.New("rate of token limiter must be positive, and burst mustn't be negative")
+
+// NewTokenLimiter returns a token bucket limiter, refilled with rate tokens per second,
+// allowing bursts of at most burst requests, burst defaults to rate.
+func NewTokenLimiter(store redis.Node, rate, burst int, opts ...Option) (Limiter, error) {
+	// rate为0时脚本中除0，本地的RetryAfter是Inf
+	if rate <= 0 || burst < 0 {
+		return nil, ErrInvalidTokenRate
+	}
+	if burst == 0 {
+		burst = rate
+	}
+
+	return &redisLimiter{
+		store:  store,
+		script: tokenScript,
+		limit:  burst,
+		args: func(now time.Time) []interface{} {
+			return []interface{}{rate, burst, millis(now), 1}
+		},
+		parse: func(vals []interface{}) (Result, error) {
+			allowed, remaining, retry, err := parseReply(vals)
+			if err != nil {
+				return Result{}, err
+			}
+
+			return Result{
+				Allowed:    allowed,
+				Remaining:  remaining,
+				RetryAfter: time.Duration(retry) * time.Millisecond,
+				ResetAfter: time.Duration(math.Ceil(float64(burst-remaining)/float64(rate)*1000)) * time.Millisecond,
+			}, nil
+		},
+		local: newLocalLimiter(float64(rate), burst),
+		opts:  newOptions(opts),
+	}, nil
+}
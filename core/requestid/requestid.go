@@ -0,0 +1,69 @@
+package requestid
+
+import (
+	"context"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// Header is the header to carry the request id, in http requests and broker messages,
+// the same as echo.HeaderXRequestID.
+const Header = "X-Request-ID"
+
+type requestIdKey struct{}
+
+func New() string {
+	return uuid.New().String()
+}
+
+func NewContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIdKey{}, id)
+}
+
+// FromContext returns the request id carried by ctx, empty if absent.
+func FromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+
+	id, _ := ctx.Value(requestIdKey{}).(string)
+	return id
+}
+
+// Inject writes the request id of ctx into header, the existing one is kept.
+func Inject(ctx context.Context, header map[string]string) {
+	id := FromContext(ctx)
+	if len(id) == 0 {
+		return
+	}
+
+	if len(FromHeader(header)) == 0 {
+		header[Header] = id
+	}
+}
+
+// Extract returns a context carrying the request id in header if any.
+func Extract(ctx context.Context, header map[string]string) context.Context {
+	if id := FromHeader(header); len(id) > 0 {
+		return NewContext(ctx, id)
+	}
+
+	return ctx
+}
+
+// FromHeader returns the request id in header, the key is case insensitive like http headers,
+// since the messages might be published with X-Request-Id or X-Request-ID.
+func FromHeader(header map[string]string) string {
+	if id, ok := header[Header]; ok {
+		return id
+	}
+
+	for k, v := range header {
+		if strings.EqualFold(k, Header) {
+			return v
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,40 @@
+package requestid
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestId(t *testing.T) {
+	assert.Empty(t, FromContext(context.Background()))
+	assert.Empty(t, FromContext(nil))
+
+	id := New()
+	assert.Len(t, id, 36)
+	ctx := NewContext(context.Background(), id)
+	assert.Equal(t, id, FromContext(ctx))
+
+	header := make(map[string]string)
+	Inject(context.Background(), header)
+	assert.Empty(t, header)
+	Inject(ctx, header)
+	assert.Equal(t, id, header[Header])
+
+	header[Header] = "kept"
+	Inject(ctx, header)
+	assert.Equal(t, "kept", header[Header])
+
+	assert.Equal(t, "kept", FromContext(Extract(context.Background(), header)))
+	assert.Empty(t, FromContext(Extract(context.Background(), map[string]string{})))
+}
+
+func TestRequestId_CaseInsensitive(t *testing.T) {
+	header := map[string]string{"x-request-id": "abc"}
+	assert.Equal(t, "abc", FromHeader(header))
+	assert.Equal(t, "abc", FromContext(Extract(context.Background(), header)))
+
+	Inject(NewContext(context.Background(), "def"), header)
+	assert.Equal(t, map[string]string{"x-request-id": "abc"}, header)
+}
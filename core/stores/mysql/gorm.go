@@ -45,6 +45,11 @@ func NewMysql(datasource string, c *Config) (*DB, error) {
 	if err != nil {
 		return nil, err
 	}
+	// 引入tracing插件
+	err = conn.Use(NewTracingPlugin())
+	if err != nil {
+		return nil, err
+	}
 	return conn, nil
 }
 
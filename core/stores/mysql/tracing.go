@@ -0,0 +1,84 @@
+package mysql
+
+import (
+	"github.com/valeamoris/go-ezio/core/requestid"
+	"github.com/valeamoris/go-ezio/core/trace"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.7.0"
+	oteltrace "go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+const (
+	gormSpanKey    = "gorm:span_key"
+	spanNamePrefix = "gorm "
+)
+
+var requestIdKey = attribute.Key("request.id")
+
+type tracingPlugin struct {
+	*gorm.DB
+}
+
+func NewTracingPlugin() *tracingPlugin {
+	return &tracingPlugin{}
+}
+
+func (t *tracingPlugin) Name() string {
+	return "gorm:db_tracing"
+}
+
+func (t *tracingPlugin) Initialize(db *gorm.DB) error {
+	t.DB = db
+	t.registerCallbacks()
+	return nil
+}
+
+func (t *tracingPlugin) registerCallbacks() {
+	t.Callback().Create().Before("*").Register("gorm:db_tracing:create:before", t.before("create"))
+	t.Callback().Create().After("*").Register("gorm:db_tracing:create:after", t.after)
+	t.Callback().Query().Before("*").Register("gorm:db_tracing:query:before", t.before("query"))
+	t.Callback().Query().After("*").Register("gorm:db_tracing:query:after", t.after)
+	t.Callback().Update().Before("*").Register("gorm:db_tracing:update:before", t.before("update"))
+	t.Callback().Update().After("*").Register("gorm:db_tracing:update:after", t.after)
+	t.Callback().Delete().Before("*").Register("gorm:db_tracing:delete:before", t.before("delete"))
+	t.Callback().Delete().After("*").Register("gorm:db_tracing:delete:after", t.after)
+	t.Callback().Row().Before("*").Register("gorm:db_tracing:row:before", t.before("row"))
+	t.Callback().Row().After("*").Register("gorm:db_tracing:row:after", t.after)
+	t.Callback().Raw().Before("*").Register("gorm:db_tracing:raw:before", t.before("raw"))
+	t.Callback().Raw().After("*").Register("gorm:db_tracing:raw:after", t.after)
+}
+
+func (t *tracingPlugin) before(operation string) func(db *gorm.DB) {
+	return func(db *gorm.DB) {
+		if db.Statement == nil || db.Statement.Context == nil {
+			return
+		}
+
+		_, span := trace.Tracer().Start(db.Statement.Context, spanNamePrefix+operation,
+			oteltrace.WithSpanKind(oteltrace.SpanKindClient),
+			oteltrace.WithAttributes(
+				semconv.DBSystemMySQL,
+				semconv.DBOperationKey.String(operation),
+				semconv.DBSQLTableKey.String(db.Statement.Table),
+			))
+		if id := requestid.FromContext(db.Statement.Context); len(id) > 0 {
+			span.SetAttributes(requestIdKey.String(id))
+		}
+		db.Set(gormSpanKey, span)
+	}
+}
+
+func (t *tracingPlugin) after(db *gorm.DB) {
+	i, ok := db.Get(gormSpanKey)
+	if !ok {
+		return
+	}
+
+	span := i.(oteltrace.Span)
+	err := db.Error
+	if err == gorm.ErrRecordNotFound {
+		err = nil
+	}
+	trace.End(span, err, semconv.DBStatementKey.String(db.Statement.SQL.String()))
+}
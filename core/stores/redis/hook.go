@@ -2,7 +2,12 @@ package redis
 
 import (
 	"context"
+	"github.com/valeamoris/go-ezio/core/requestid"
+	"github.com/valeamoris/go-ezio/core/trace"
 	"github.com/zeromicro/go-zero/core/breaker"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.7.0"
+	oteltrace "go.opentelemetry.io/otel/trace"
 	"strings"
 	"time"
 
@@ -13,7 +18,15 @@ import (
 )
 
 const (
-	redisContextKey = "redisContextKey"
+	redisContextKey         = "redisContextKey"
+	redisPipelineContextKey = "redisPipelineContextKey"
+	spanNamePrefix          = "redis "
+)
+
+var (
+	dbSystemRedis = semconv.DBSystemRedis
+	cmdsKey       = attribute.Key("db.redis.cmds")
+	requestIdKey  = attribute.Key("request.id")
 )
 
 type hook struct {
@@ -23,6 +36,9 @@ type hook struct {
 type hookContainer struct {
 	start   time.Duration
 	promise breaker.Promise
+	span    oteltrace.Span
+	// 透传http请求的request id
+	requestId string
 }
 
 func (h hook) BeforeProcess(ctx context.Context, cmd red.Cmder) (context.Context, error) {
@@ -30,9 +46,17 @@ func (h hook) BeforeProcess(ctx context.Context, cmd red.Cmder) (context.Context
 	if err != nil {
 		return ctx, err
 	}
+	ctx, span := trace.Tracer().Start(ctx, spanNamePrefix+cmd.Name(),
+		oteltrace.WithSpanKind(oteltrace.SpanKindClient),
+		oteltrace.WithAttributes(dbSystemRedis, semconv.DBOperationKey.String(cmd.Name())))
 	c := &hookContainer{
-		start:   timex.Now(),
-		promise: p,
+		start:     timex.Now(),
+		promise:   p,
+		span:      span,
+		requestId: requestid.FromContext(ctx),
+	}
+	if len(c.requestId) > 0 {
+		span.SetAttributes(requestIdKey.String(c.requestId))
 	}
 	return context.WithValue(ctx, redisContextKey, c), nil
 }
@@ -53,22 +77,52 @@ func (h hook) AfterProcess(ctx context.Context, cmd red.Cmder) error {
 				}
 				buf.WriteString(mapping.Repr(arg))
 			}
-			logx.WithDuration(duration).Slowf("[REDIS] slowcall on executing: %s", buf.String())
+			if len(container.requestId) > 0 {
+				logx.WithDuration(duration).Slowf("[REDIS] slowcall on executing: %s, request id: %s",
+					buf.String(), container.requestId)
+			} else {
+				logx.WithDuration(duration).Slowf("[REDIS] slowcall on executing: %s", buf.String())
+			}
 		}
 	}()
 	err := cmd.Err()
 	if acceptable(err) {
 		container.promise.Accept()
+		trace.End(container.span, nil)
 	} else {
 		container.promise.Reject(err.Error())
+		trace.End(container.span, err)
 	}
 	return nil
 }
 
 func (h hook) BeforeProcessPipeline(ctx context.Context, cmds []red.Cmder) (context.Context, error) {
-	return ctx, nil
+	ctx, span := trace.Tracer().Start(ctx, spanNamePrefix+"pipeline",
+		oteltrace.WithSpanKind(oteltrace.SpanKindClient),
+		oteltrace.WithAttributes(dbSystemRedis, cmdsKey.StringSlice(cmdNames(cmds))))
+	return context.WithValue(ctx, redisPipelineContextKey, span), nil
 }
 
 func (h hook) AfterProcessPipeline(ctx context.Context, cmds []red.Cmder) error {
+	span, ok := ctx.Value(redisPipelineContextKey).(oteltrace.Span)
+	if !ok {
+		return nil
+	}
+
+	for _, cmd := range cmds {
+		if err := cmd.Err(); !acceptable(err) {
+			trace.End(span, err)
+			return nil
+		}
+	}
+	trace.End(span, nil)
 	return nil
 }
+
+func cmdNames(cmds []red.Cmder) []string {
+	names := make([]string, 0, len(cmds))
+	for _, cmd := range cmds {
+		names = append(names, cmd.Name())
+	}
+	return names
+}
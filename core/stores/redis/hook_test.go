@@ -0,0 +1,50 @@
+package redis
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valeamoris/go-ezio/core/requestid"
+	"github.com/valeamoris/go-ezio/core/trace"
+)
+
+func TestHook_Tracing(t *testing.T) {
+	assert.Nil(t, trace.StartAgent(trace.Conf{Name: "redis", Sampler: 1, Batcher: trace.BatcherMemory}))
+	defer trace.StopAgent(context.Background())
+	exporter := trace.MemoryExporter()
+
+	runOnRedis(t, func(ctx context.Context, client Node) {
+		ctx, span := trace.Tracer().Start(ctx, "parent")
+		assert.Nil(t, client.Set(ctx, "a", "b", 0).Err())
+		_, err := client.Pipelined(ctx, func(p Pipeliner) error {
+			p.Get(ctx, "a")
+			p.Exists(ctx, "b")
+			return nil
+		})
+		assert.Nil(t, err)
+		span.End()
+
+		spans := exporter.GetSpans()
+		assert.Len(t, spans, 3)
+		assert.Equal(t, "redis set", spans[0].Name)
+		assert.Equal(t, span.SpanContext().TraceID(), spans[0].SpanContext.TraceID())
+		assert.Equal(t, span.SpanContext().SpanID(), spans[0].Parent.SpanID())
+		assert.Equal(t, "redis pipeline", spans[1].Name)
+	})
+}
+
+func TestHook_RequestId(t *testing.T) {
+	assert.Nil(t, trace.StartAgent(trace.Conf{Name: "redis", Sampler: 1, Batcher: trace.BatcherMemory}))
+	defer trace.StopAgent(context.Background())
+	exporter := trace.MemoryExporter()
+
+	runOnRedis(t, func(ctx context.Context, client Node) {
+		ctx = requestid.NewContext(ctx, "abc")
+		assert.Nil(t, client.Set(ctx, "a", "b", 0).Err())
+
+		spans := exporter.GetSpans()
+		assert.Len(t, spans, 1)
+		assert.Contains(t, spans[0].Attributes, requestIdKey.String("abc"))
+	})
+}
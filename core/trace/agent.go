@@ -0,0 +1,103 @@
+package trace
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/zeromicro/go-zero/core/logx"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.7.0"
+)
+
+var (
+	provider *sdktrace.TracerProvider
+	memory   *tracetest.InMemoryExporter
+	lock     sync.Mutex
+)
+
+// StartAgent sets up the global tracer provider and the W3C traceparent propagator,
+// calling it more than once replaces the previous provider.
+func StartAgent(c Conf) error {
+	lock.Lock()
+	defer lock.Unlock()
+
+	opts := []sdktrace.TracerProviderOption{
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(c.Sampler))),
+		sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceNameKey.String(c.Name))),
+	}
+
+	switch c.Batcher {
+	case BatcherMemory:
+		memory = tracetest.NewInMemoryExporter()
+		// 同步导出，方便测试中立即拿到span
+		opts = append(opts, sdktrace.WithSyncer(memory))
+	case BatcherOtlp, "":
+		if len(c.Endpoint) > 0 {
+			exp, err := createOtlpExporter(c)
+			if err != nil {
+				logx.Error(err)
+				return err
+			}
+
+			opts = append(opts, sdktrace.WithBatcher(exp))
+		}
+	default:
+		return fmt.Errorf("unknown exporter: %s", c.Batcher)
+	}
+
+	setProvider(sdktrace.NewTracerProvider(opts...))
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{}, propagation.Baggage{}))
+	otel.SetErrorHandler(otel.ErrorHandlerFunc(func(err error) {
+		logx.Errorf("[otel] error: %v", err)
+	}))
+
+	return nil
+}
+
+// StopAgent flushes the pending spans and shuts down the provider.
+func StopAgent(ctx context.Context) error {
+	lock.Lock()
+	defer lock.Unlock()
+
+	if provider == nil {
+		return nil
+	}
+
+	err := provider.Shutdown(ctx)
+	provider = nil
+	return err
+}
+
+// MemoryExporter returns the exporter used with the memory batcher, nil for others.
+func MemoryExporter() *tracetest.InMemoryExporter {
+	lock.Lock()
+	defer lock.Unlock()
+
+	return memory
+}
+
+func createOtlpExporter(c Conf) (sdktrace.SpanExporter, error) {
+	opts := []otlptracehttp.Option{
+		otlptracehttp.WithEndpoint(c.Endpoint),
+	}
+	if c.Insecure {
+		opts = append(opts, otlptracehttp.WithInsecure())
+	}
+
+	return otlptracehttp.New(context.Background(), opts...)
+}
+
+func setProvider(tp *sdktrace.TracerProvider) {
+	if provider != nil {
+		_ = provider.Shutdown(context.Background())
+	}
+	provider = tp
+	otel.SetTracerProvider(tp)
+}
@@ -0,0 +1,42 @@
+package trace
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/codes"
+)
+
+func TestStartAgent_Memory(t *testing.T) {
+	assert.Nil(t, StartAgent(Conf{Name: "test", Sampler: 1, Batcher: BatcherMemory}))
+	defer StopAgent(context.Background())
+
+	ctx, span := Tracer().Start(context.Background(), "parent")
+	assert.NotEmpty(t, TraceIDFromContext(ctx))
+
+	header := make(map[string]string)
+	Inject(ctx, header)
+	assert.Contains(t, header, "traceparent")
+	assert.Equal(t, TraceIDFromContext(ctx), TraceIDFromContext(Extract(context.Background(), header)))
+
+	End(span, errors.New("boom"))
+	spans := MemoryExporter().GetSpans()
+	assert.Len(t, spans, 1)
+	assert.Equal(t, codes.Error, spans[0].Status.Code)
+}
+
+func TestStartAgent_Unknown(t *testing.T) {
+	assert.NotNil(t, StartAgent(Conf{Batcher: "unknown"}))
+}
+
+func TestStartAgent_Otlp(t *testing.T) {
+	assert.Nil(t, StartAgent(Conf{Name: "test", Endpoint: "localhost:4318", Sampler: 1, Insecure: true}))
+	assert.Nil(t, StopAgent(context.Background()))
+	assert.Nil(t, StopAgent(context.Background()))
+}
+
+func TestTraceIDFromContext_Empty(t *testing.T) {
+	assert.Empty(t, TraceIDFromContext(context.Background()))
+}
@@ -0,0 +1,17 @@
+package trace
+
+const (
+	TracerName = "go-ezio"
+
+	BatcherOtlp   = "otlp"
+	BatcherMemory = "memory"
+)
+
+// Conf is the opentelemetry config, Endpoint is the otlp http endpoint like localhost:4318.
+type Conf struct {
+	Name     string  `json:",optional"`
+	Endpoint string  `json:",optional"`
+	Sampler  float64 `json:",default=1.0"`
+	Batcher  string  `json:",default=otlp,options=otlp|memory"`
+	Insecure bool    `json:",optional"`
+}
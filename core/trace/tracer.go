@@ -0,0 +1,48 @@
+package trace
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Tracer returns the tracer of the current global provider.
+func Tracer() trace.Tracer {
+	return otel.GetTracerProvider().Tracer(TracerName)
+}
+
+// Inject writes the span context of ctx into the headers with the global propagator.
+func Inject(ctx context.Context, header map[string]string) {
+	otel.GetTextMapPropagator().Inject(ctx, propagation.MapCarrier(header))
+}
+
+// Extract reads the span context from the headers with the global propagator.
+func Extract(ctx context.Context, header map[string]string) context.Context {
+	return otel.GetTextMapPropagator().Extract(ctx, propagation.MapCarrier(header))
+}
+
+// End records the error if any and ends the span.
+func End(span trace.Span, err error, attrs ...attribute.KeyValue) {
+	if len(attrs) > 0 {
+		span.SetAttributes(attrs...)
+	}
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// TraceIDFromContext returns the otel trace id of ctx, empty if not sampled or absent.
+func TraceIDFromContext(ctx context.Context) string {
+	spanCtx := trace.SpanContextFromContext(ctx)
+	if spanCtx.HasTraceID() {
+		return spanCtx.TraceID().String()
+	}
+
+	return ""
+}
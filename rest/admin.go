@@ -0,0 +1,248 @@
+package rest
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/pprof"
+	"reflect"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/valeamoris/go-ezio/rest/middleware"
+	"github.com/zeromicro/go-zero/core/logx"
+	"github.com/zeromicro/go-zero/core/stat"
+)
+
+const maskedValue = "******"
+
+// 需要脱敏的配置字段，按字段名小写后包含匹配
+var secretFields = []string{"pass", "secret", "token", "apikey", "privatekey"}
+
+type (
+	groupInfo struct {
+		Prefix   string      `json:"prefix"`
+		Jwt      bool        `json:"jwt"`
+		ApiKey   bool        `json:"apiKey"`
+		Hmac     bool        `json:"hmac"`
+		Authz    bool        `json:"authz"`
+		Shedding bool        `json:"shedding"`
+		Timeout  bool        `json:"timeout"`
+		Priority bool        `json:"priority"`
+		Static   string      `json:"static,omitempty"`
+		Routes   []routeInfo `json:"routes"`
+	}
+
+	routeInfo struct {
+		Method      string   `json:"method"`
+		Path        string   `json:"path"`
+		Roles       []string `json:"roles,omitempty"`
+		Permissions []string `json:"permissions,omitempty"`
+	}
+
+	runtimeStats struct {
+		Uptime       string `json:"uptime"`
+		Goroutines   int    `json:"goroutines"`
+		CpuUsage     int64  `json:"cpuUsage"`
+		Alloc        uint64 `json:"alloc"`
+		TotalAlloc   uint64 `json:"totalAlloc"`
+		Sys          uint64 `json:"sys"`
+		HeapObjects  uint64 `json:"heapObjects"`
+		NumGC        uint32 `json:"numGC"`
+		PauseTotalNs uint64 `json:"pauseTotalNs"`
+	}
+)
+
+func (s *engine) adminEnabled() bool {
+	return s.conf.Admin.Port > 0
+}
+
+func (s *engine) startAdmin() {
+	if !s.adminEnabled() {
+		return
+	}
+
+	s.adminLock.Lock()
+	defer s.adminLock.Unlock()
+	if s.stopped {
+		return
+	}
+
+	admin := s.newAdmin()
+	s.admin = admin
+	addr := fmt.Sprintf("%s:%d", s.conf.Admin.Host, s.conf.Admin.Port)
+	go func() {
+		if err := admin.Start(addr); err != nil && err != http.ErrServerClosed {
+			logx.Errorf("admin server on %s stopped: %s", addr, err.Error())
+		}
+	}()
+}
+
+// stopAdmin returns the started admin server to stop, the admin server isn't started after it.
+func (s *engine) stopAdmin() *echo.Echo {
+	s.adminLock.Lock()
+	defer s.adminLock.Unlock()
+
+	s.stopped = true
+	return s.admin
+}
+
+func (s *engine) newAdmin() *echo.Echo {
+	admin := echo.New()
+	admin.HideBanner = true
+	admin.HidePort = true
+	admin.IPExtractor = s.Echo.IPExtractor
+	if s.adminFilter != nil {
+		admin.Use(middleware.IPFilterMiddleware(s.adminFilter))
+	}
+
+	gatherer := s.gatherer
+	if gatherer == nil {
+		gatherer = prometheus.DefaultGatherer
+	}
+	admin.GET("/metrics", echo.WrapHandler(promhttp.HandlerFor(gatherer, promhttp.HandlerOpts{})))
+
+	// pprof
+	admin.GET("/debug/pprof/", echo.WrapHandler(http.HandlerFunc(pprof.Index)))
+	admin.GET("/debug/pprof/cmdline", echo.WrapHandler(http.HandlerFunc(pprof.Cmdline)))
+	admin.GET("/debug/pprof/profile", echo.WrapHandler(http.HandlerFunc(pprof.Profile)))
+	admin.GET("/debug/pprof/symbol", echo.WrapHandler(http.HandlerFunc(pprof.Symbol)))
+	admin.POST("/debug/pprof/symbol", echo.WrapHandler(http.HandlerFunc(pprof.Symbol)))
+	admin.GET("/debug/pprof/trace", echo.WrapHandler(http.HandlerFunc(pprof.Trace)))
+	admin.GET("/debug/pprof/:name", echo.WrapHandler(http.HandlerFunc(pprof.Index)))
+
+	admin.GET("/routes", func(ctx echo.Context) error {
+		return ctx.JSON(http.StatusOK, s.routeInfos())
+	})
+	admin.GET("/stats", func(ctx echo.Context) error {
+		return ctx.JSON(http.StatusOK, s.runtimeStats())
+	})
+	admin.GET("/config", func(ctx echo.Context) error {
+		return ctx.JSON(http.StatusOK, maskSecrets(reflect.ValueOf(s.conf)))
+	})
+	s.bindDocs(admin)
+
+	return admin
+}
+
+func (s *engine) routeInfos() []groupInfo {
+	infos := make([]groupInfo, 0, len(s.groups))
+	for _, g := range s.groups {
+		info := groupInfo{
+			Prefix:   g.Prefix,
+			Jwt:      g.jwt.enabled,
+			ApiKey:   g.apiKey.enabled,
+			Hmac:     g.hmac.enabled,
+			Authz:    g.authz.enabled || hasRequirements(g.Routes),
+			Shedding: g.shedding,
+			Timeout:  s.groupTimeout(g) > 0,
+			Priority: g.priority,
+			Routes:   make([]routeInfo, 0, len(g.Routes)),
+		}
+		if g.static.enabled {
+			info.Static = g.static.prefix
+		}
+		for _, route := range g.Routes {
+			info.Routes = append(info.Routes, routeInfo{
+				Method:      route.Method,
+				Path:        g.Prefix + route.Path,
+				Roles:       route.Roles,
+				Permissions: route.Permissions,
+			})
+		}
+		infos = append(infos, info)
+	}
+
+	return infos
+}
+
+func (s *engine) runtimeStats() runtimeStats {
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+
+	return runtimeStats{
+		Uptime:       time.Since(s.startTime).Truncate(time.Second).String(),
+		Goroutines:   runtime.NumGoroutine(),
+		CpuUsage:     stat.CpuUsage(),
+		Alloc:        m.Alloc,
+		TotalAlloc:   m.TotalAlloc,
+		Sys:          m.Sys,
+		HeapObjects:  m.HeapObjects,
+		NumGC:        m.NumGC,
+		PauseTotalNs: m.PauseTotalNs,
+	}
+}
+
+// maskSecrets converts the config into a map, masking the fields that look like secrets.
+func maskSecrets(v reflect.Value) interface{} {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		return maskSecrets(v.Elem())
+	case reflect.Struct:
+		m := make(map[string]interface{})
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if len(field.PkgPath) > 0 {
+				continue
+			}
+
+			value := v.Field(i)
+			if field.Anonymous && value.Kind() == reflect.Struct {
+				if embedded, ok := maskSecrets(value).(map[string]interface{}); ok {
+					for k, val := range embedded {
+						m[k] = val
+					}
+				}
+				continue
+			}
+
+			if isSecretField(field.Name) && !value.IsZero() {
+				m[field.Name] = maskedValue
+			} else {
+				m[field.Name] = maskSecrets(value)
+			}
+		}
+		return m
+	case reflect.Slice, reflect.Array:
+		items := make([]interface{}, 0, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			items = append(items, maskSecrets(v.Index(i)))
+		}
+		return items
+	case reflect.Map:
+		m := make(map[string]interface{}, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			key := fmt.Sprint(iter.Key().Interface())
+			if isSecretField(key) {
+				m[key] = maskedValue
+			} else {
+				m[key] = maskSecrets(iter.Value())
+			}
+		}
+		return m
+	default:
+		if !v.IsValid() {
+			return nil
+		}
+		return v.Interface()
+	}
+}
+
+func isSecretField(name string) bool {
+	lower := strings.ToLower(name)
+	for _, secret := range secretFields {
+		if strings.Contains(lower, secret) {
+			return true
+		}
+	}
+
+	return false
+}
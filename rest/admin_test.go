@@ -0,0 +1,94 @@
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func serveAdmin(t *testing.T, srv *Server, path string) *httptest.ResponseRecorder {
+	assert.Nil(t, srv.engine.bind())
+	resp := httptest.NewRecorder()
+	srv.engine.newAdmin().ServeHTTP(resp, httptest.NewRequest(http.MethodGet, path, nil))
+	return resp
+}
+
+func TestAdminDefaultHost(t *testing.T) {
+	assert.Equal(t, "127.0.0.1", newTestConf(t, 0).Admin.Host)
+}
+
+func TestAdminConfig(t *testing.T) {
+	c := newTestConf(t, 0)
+	c.Signature.PrivateKeys = []PrivateKeyConf{{Fingerprint: "fp", KeyFile: "key.pem"}}
+	srv := newTestServer(t, c)
+
+	resp := serveAdmin(t, srv, "/config")
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.NotContains(t, resp.Body.String(), "key.pem")
+
+	var config map[string]interface{}
+	assert.Nil(t, json.Unmarshal(resp.Body.Bytes(), &config))
+	assert.Equal(t, "rest", config["Name"])
+	assert.Equal(t, maskedValue, config["Signature"].(map[string]interface{})["PrivateKeys"])
+}
+
+func TestMaskSecrets(t *testing.T) {
+	masked := maskSecrets(reflect.ValueOf(struct {
+		Name      string
+		ApiKey    string
+		Empty     string `json:"-"`
+		Token     string
+		Nested    *struct{ Password string }
+		Headers   map[string]string
+		Addresses []string
+	}{
+		Name:      "svc",
+		ApiKey:    "key",
+		Nested:    &struct{ Password string }{Password: "pass"},
+		Headers:   map[string]string{"X-Token": "token", "Accept": "json"},
+		Addresses: []string{"a", "b"},
+	})).(map[string]interface{})
+
+	assert.Equal(t, "svc", masked["Name"])
+	assert.Equal(t, maskedValue, masked["ApiKey"])
+	// 空的secret不需要脱敏
+	assert.Equal(t, "", masked["Token"])
+	assert.Equal(t, maskedValue, masked["Nested"].(map[string]interface{})["Password"])
+	assert.Equal(t, map[string]interface{}{"X-Token": maskedValue, "Accept": "json"}, masked["Headers"])
+	assert.Equal(t, []interface{}{"a", "b"}, masked["Addresses"])
+}
+
+func TestAdminRoutes(t *testing.T) {
+	srv := newTestServer(t, newTestConf(t, 0))
+	srv.Group(Group{
+		Prefix: "/users",
+		Routes: []Route{{Method: http.MethodDelete, Path: "/:id", Handler: func(ctx Context) error {
+			return nil
+		}, Roles: []string{"admin"}}},
+	}, WithJwt("admin-secret", nil))
+
+	resp := serveAdmin(t, srv, "/routes")
+	assert.Equal(t, http.StatusOK, resp.Code)
+	var groups []groupInfo
+	assert.Nil(t, json.Unmarshal(resp.Body.Bytes(), &groups))
+	assert.Len(t, groups, 2)
+	assert.Equal(t, []routeInfo{{Method: http.MethodGet, Path: "/ping"}}, groups[0].Routes)
+	assert.Equal(t, "/users", groups[1].Prefix)
+	assert.True(t, groups[1].Jwt)
+	assert.True(t, groups[1].Authz)
+	assert.Equal(t, []routeInfo{{Method: http.MethodDelete, Path: "/users/:id", Roles: []string{"admin"}}},
+		groups[1].Routes)
+}
+
+func TestAdminStats(t *testing.T) {
+	resp := serveAdmin(t, newTestServer(t, newTestConf(t, 0)), "/stats")
+	assert.Equal(t, http.StatusOK, resp.Code)
+	var stats runtimeStats
+	assert.Nil(t, json.Unmarshal(resp.Body.Bytes(), &stats))
+	assert.True(t, stats.Goroutines > 0)
+	assert.NotEmpty(t, stats.Uptime)
+}
@@ -0,0 +1,9 @@
+package rest
+
+import "github.com/valeamoris/go-ezio/rest/binding"
+
+// Bind fills v with the path, query, header and json body of the request and validates it,
+// the field errors are returned as a 400 response, see binding.Bind.
+func Bind(ctx Context, v interface{}) error {
+	return binding.Bind(ctx, v)
+}
@@ -0,0 +1,283 @@
+package binding
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	InPath   = "path"
+	InQuery  = "query"
+	InHeader = "header"
+	InBody   = "body"
+)
+
+var errNotStructPointer = errors.New("binding: v must be a non-nil pointer to struct")
+
+type (
+	checker struct {
+		errs []FieldError
+	}
+
+	binder struct {
+		checker
+		ctx  echo.Context
+		data map[string]interface{}
+		// 解析body时类型错误的字段，不再重复校验
+		typeErrors map[string]bool
+		malformed  bool
+	}
+)
+
+// Bind fills v with the path, query (and form), header and json body of the request,
+// applies the defaults and validates the go-zero style tags, like:
+//
+//	type Request struct {
+//		Id     int64  `path:"id"`
+//		Page   int    `form:"page,default=1,range=[1:100]"`
+//		Token  string `header:"X-Token,optional"`
+//		Status string `json:"status,options=on|off"`
+//	}
+//
+// the returned error is an *echo.HTTPError with code 400, whose message is a *ValidationError.
+func Bind(ctx echo.Context, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return errNotStructPointer
+	}
+
+	b := &binder{
+		ctx:        ctx,
+		typeErrors: make(map[string]bool),
+	}
+	if err := b.bindBody(v); err != nil {
+		return err
+	}
+	// body无法解析时，其余字段的错误没有意义
+	if !b.malformed {
+		if err := b.bindStruct(rv.Elem(), "", b.data); err != nil {
+			return err
+		}
+	}
+
+	if len(b.errs) > 0 {
+		lang := language(ctx.Request().Header.Get(headerAcceptLanguage))
+		return toHTTPError(newValidationError(b.errs, lang))
+	}
+
+	return nil
+}
+
+func (b *binder) bindBody(v interface{}) error {
+	req := b.ctx.Request()
+	contentType := req.Header.Get(echo.HeaderContentType)
+	switch {
+	case strings.HasPrefix(contentType, echo.MIMEApplicationJSON):
+		if req.Body == nil {
+			return nil
+		}
+
+		body, err := ioutil.ReadAll(req.Body)
+		if err != nil {
+			return err
+		}
+		// 后续的handler可能再次读取
+		req.Body = ioutil.NopCloser(bytes.NewReader(body))
+		if len(bytes.TrimSpace(body)) == 0 {
+			return nil
+		}
+
+		if err := json.Unmarshal(body, v); err != nil {
+			var typeErr *json.UnmarshalTypeError
+			if errors.As(err, &typeErr) && len(typeErr.Field) > 0 {
+				b.typeErrors[typeErr.Field] = true
+				b.add(typeErr.Field, InBody, ReasonType, "")
+			} else {
+				b.malformed = true
+				b.add(InBody, InBody, ReasonInvalid, "")
+				return nil
+			}
+		}
+		_ = json.Unmarshal(body, &b.data)
+	case strings.HasPrefix(contentType, echo.MIMEApplicationForm),
+		strings.HasPrefix(contentType, echo.MIMEMultipartForm):
+		if _, err := b.ctx.FormParams(); err != nil {
+			// BodyLimit中间件的413原样返回，不是格式错误
+			var he *echo.HTTPError
+			if errors.As(err, &he) && he.Code == http.StatusRequestEntityTooLarge {
+				return he
+			}
+
+			b.malformed = true
+			b.add(InBody, InBody, ReasonInvalid, "")
+		}
+	}
+
+	return nil
+}
+
+func (b *binder) bindStruct(sv reflect.Value, prefix string, data map[string]interface{}) error {
+	st := sv.Type()
+	for i := 0; i < st.NumField(); i++ {
+		sf := st.Field(i)
+		fv := sv.Field(i)
+		if len(sf.PkgPath) > 0 && !sf.Anonymous {
+			continue
+		}
+
+		key, tag, ok := fieldSource(sf)
+		if !ok {
+			if sf.Anonymous && fv.Kind() == reflect.Struct {
+				if err := b.bindStruct(fv, prefix, data); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		if tag == "-" {
+			continue
+		}
+
+		opts, err := parseTag(sf, tag)
+		if err != nil {
+			return err
+		}
+
+		if key == jsonKey {
+			if err := b.bindJSONField(fv, prefix+opts.name, opts, data); err != nil {
+				return err
+			}
+			continue
+		}
+
+		in, values := b.lookup(key, opts.name)
+		if len(values) == 0 {
+			b.missing(fv, opts.name, in, opts)
+		} else if err := setValues(fv, values); err != nil {
+			b.add(opts.name, in, ReasonType, "")
+		} else {
+			b.check(fv, opts.name, in, opts)
+		}
+	}
+
+	return nil
+}
+
+func (b *binder) bindJSONField(fv reflect.Value, path string, opts fieldOptions,
+	data map[string]interface{}) error {
+	if b.typeErrors[path] {
+		return nil
+	}
+
+	raw, ok := data[opts.name]
+	if !ok || raw == nil {
+		b.missing(fv, path, InBody, opts)
+		return nil
+	}
+
+	b.check(fv, path, InBody, opts)
+	for fv.Kind() == reflect.Ptr && !fv.IsNil() {
+		fv = fv.Elem()
+	}
+
+	switch fv.Kind() {
+	case reflect.Struct:
+		if nested, ok := raw.(map[string]interface{}); ok {
+			return b.bindStruct(fv, path+".", nested)
+		}
+	case reflect.Slice:
+		items, ok := raw.([]interface{})
+		if !ok {
+			return nil
+		}
+		for i := 0; i < fv.Len() && i < len(items); i++ {
+			elem := fv.Index(i)
+			for elem.Kind() == reflect.Ptr && !elem.IsNil() {
+				elem = elem.Elem()
+			}
+			if nested, ok := items[i].(map[string]interface{}); ok && elem.Kind() == reflect.Struct {
+				if err := b.bindStruct(elem, path+"["+strconv.Itoa(i)+"].", nested); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+func (b *binder) lookup(key, name string) (string, []string) {
+	req := b.ctx.Request()
+	switch key {
+	case pathKey:
+		for i, param := range b.ctx.ParamNames() {
+			if param == name {
+				return InPath, []string{b.ctx.ParamValues()[i]}
+			}
+		}
+		return InPath, nil
+	case headerKey:
+		return InHeader, req.Header.Values(name)
+	default:
+		// form包括query和表单body
+		if req.Form == nil {
+			_ = req.ParseForm()
+		}
+		return InQuery, req.Form[name]
+	}
+}
+
+func (b *binder) missing(fv reflect.Value, path, in string, opts fieldOptions) {
+	switch {
+	case opts.hasDefault:
+		if err := setString(fv, opts.defaultValue); err != nil {
+			b.add(path, in, ReasonType, "")
+		}
+	case !opts.optional:
+		b.add(path, in, ReasonRequired, "")
+	}
+}
+
+func (c *checker) check(fv reflect.Value, path, in string, opts fieldOptions) {
+	for _, v := range scalars(fv) {
+		if len(opts.options) > 0 {
+			if s, ok := stringOf(v); ok && !contains(opts.options, s) {
+				c.add(path, in, ReasonOptions, strings.Join(opts.options, "|"))
+				return
+			}
+		}
+		if opts.rng != nil {
+			if n, ok := numberOf(v); ok && !opts.rng.contains(n) {
+				c.add(path, in, ReasonRange, opts.rng.repr)
+				return
+			}
+		}
+	}
+}
+
+func (c *checker) add(field, in, reason, param string) {
+	c.errs = append(c.errs, FieldError{
+		Field:  field,
+		In:     in,
+		Reason: reason,
+		param:  param,
+	})
+}
+
+func contains(options []string, s string) bool {
+	for _, option := range options {
+		if option == s {
+			return true
+		}
+	}
+
+	return false
+}
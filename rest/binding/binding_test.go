@@ -0,0 +1,166 @@
+package binding
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+type (
+	item struct {
+		Name  string `json:"name"`
+		Count int    `json:"count,range=[1:10]"`
+	}
+
+	bindRequest struct {
+		Id      int64         `path:"id"`
+		Page    int           `form:"page,default=1,range=[1:100]"`
+		Tags    []string      `form:"tag,optional"`
+		Timeout time.Duration `form:"timeout,optional"`
+		Token   string        `header:"X-Token"`
+		Status  string        `json:"status,options=on|off"`
+		Note    *string       `json:"note,optional"`
+		Items   []item        `json:"items,optional"`
+	}
+)
+
+func newContext(method, target, body string) (echo.Context, *httptest.ResponseRecorder) {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	if len(body) > 0 {
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	}
+	resp := httptest.NewRecorder()
+	ctx := echo.New().NewContext(req, resp)
+	ctx.SetParamNames("id")
+	ctx.SetParamValues("7")
+	return ctx, resp
+}
+
+func TestBind(t *testing.T) {
+	ctx, _ := newContext(http.MethodPost, "/users/7?tag=a&tag=b&timeout=1s",
+		`{"status":"on","note":"hi","items":[{"name":"x","count":2}]}`)
+	ctx.Request().Header.Set("X-Token", "abc")
+
+	var req bindRequest
+	assert.Nil(t, Bind(ctx, &req))
+	assert.Equal(t, int64(7), req.Id)
+	assert.Equal(t, 1, req.Page)
+	assert.Equal(t, []string{"a", "b"}, req.Tags)
+	assert.Equal(t, time.Second, req.Timeout)
+	assert.Equal(t, "abc", req.Token)
+	assert.Equal(t, "on", req.Status)
+	assert.Equal(t, "hi", *req.Note)
+	assert.Equal(t, []item{{Name: "x", Count: 2}}, req.Items)
+}
+
+// tooLargeBody fails like the body of BodyLimit middleware after the limit
+type tooLargeBody struct {
+	*strings.Reader
+}
+
+func (b tooLargeBody) Read(p []byte) (int, error) {
+	n, err := b.Reader.Read(p)
+	if err == io.EOF {
+		return n, echo.ErrStatusRequestEntityTooLarge
+	}
+	return n, err
+}
+
+func TestBind_BodyTooLarge(t *testing.T) {
+	tests := []struct {
+		contentType string
+		body        string
+	}{
+		{echo.MIMEApplicationForm, "page=1&tag=a"},
+		{echo.MIMEMultipartForm + "; boundary=foo", "--foo\r\nContent-Disposition: form-data; name=\"page\"\r\n\r\n1"},
+	}
+
+	for _, test := range tests {
+		req := httptest.NewRequest(http.MethodPost, "/users/7", tooLargeBody{strings.NewReader(test.body)})
+		req.Header.Set(echo.HeaderContentType, test.contentType)
+		ctx := echo.New().NewContext(req, httptest.NewRecorder())
+		ctx.SetParamNames("id")
+		ctx.SetParamValues("7")
+
+		var v bindRequest
+		assert.Equal(t, echo.ErrStatusRequestEntityTooLarge, Bind(ctx, &v), test.contentType)
+	}
+}
+
+func TestBind_FieldErrors(t *testing.T) {
+	ctx, _ := newContext(http.MethodPost, "/users/7?page=0",
+		`{"status":"maybe","items":[{"count":20}]}`)
+
+	var req bindRequest
+	err := Bind(ctx, &req)
+	he, ok := err.(*echo.HTTPError)
+	assert.True(t, ok)
+	assert.Equal(t, http.StatusBadRequest, he.Code)
+
+	errs := FieldErrors(err)
+	assert.ElementsMatch(t, []FieldError{
+		{Field: "page", In: InQuery, Reason: ReasonRange, Message: "page must be in range [1:100]", param: "[1:100]"},
+		{Field: "X-Token", In: InHeader, Reason: ReasonRequired, Message: "X-Token is required"},
+		{Field: "status", In: InBody, Reason: ReasonOptions, Message: "status must be one of on|off", param: "on|off"},
+		{Field: "items[0].name", In: InBody, Reason: ReasonRequired, Message: "items[0].name is required"},
+		{Field: "items[0].count", In: InBody, Reason: ReasonRange, Message: "items[0].count must be in range [1:10]", param: "[1:10]"},
+	}, errs)
+}
+
+func TestBind_TypeError(t *testing.T) {
+	ctx, _ := newContext(http.MethodPost, "/users/7?page=abc", `{"status":1}`)
+	ctx.Request().Header.Set("X-Token", "abc")
+
+	var req bindRequest
+	errs := FieldErrors(Bind(ctx, &req))
+	assert.ElementsMatch(t, []FieldError{
+		{Field: "status", In: InBody, Reason: ReasonType, Message: "status has an invalid value"},
+		{Field: "page", In: InQuery, Reason: ReasonType, Message: "page has an invalid value"},
+	}, errs)
+
+	ctx, _ = newContext(http.MethodPost, "/users/7", `{bad json`)
+	errs = FieldErrors(Bind(ctx, &req))
+	assert.Len(t, errs, 1)
+	assert.Equal(t, ReasonInvalid, errs[0].Reason)
+}
+
+func TestBind_Localized(t *testing.T) {
+	ctx, resp := newContext(http.MethodPost, "/users/7", `{"status":"on"}`)
+	ctx.Request().Header.Set(headerAcceptLanguage, "zh-CN,zh;q=0.9,en;q=0.8")
+
+	var req bindRequest
+	err := Bind(ctx, &req)
+	ctx.Echo().DefaultHTTPErrorHandler(err, ctx)
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+
+	var body ValidationError
+	assert.Nil(t, json.Unmarshal(resp.Body.Bytes(), &body))
+	assert.Equal(t, "参数校验失败", body.Message)
+	assert.Equal(t, []FieldError{
+		{Field: "X-Token", In: InHeader, Reason: ReasonRequired, Message: "X-Token不能为空"},
+	}, body.Errors)
+}
+
+func TestBind_NotPointer(t *testing.T) {
+	ctx, _ := newContext(http.MethodGet, "/", "")
+	assert.Equal(t, errNotStructPointer, Bind(ctx, bindRequest{}))
+}
+
+func TestRegisterMessages(t *testing.T) {
+	RegisterMessages("fr", map[string]string{
+		ReasonRequired: "{field} est obligatoire",
+	})
+	assert.Equal(t, "fr", language("fr-FR"))
+	assert.Equal(t, DefaultLanguage, language("de"))
+
+	verr := newValidationError([]FieldError{{Field: "a", Reason: ReasonRequired}, {Field: "b", Reason: ReasonType}}, "fr")
+	assert.Equal(t, "a est obligatoire", verr.Errors[0].Message)
+	assert.Equal(t, "b has an invalid value", verr.Errors[1].Message)
+}
@@ -0,0 +1,166 @@
+package binding
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	ReasonRequired = "required"
+	ReasonOptions  = "options"
+	ReasonRange    = "range"
+	ReasonType     = "type"
+	ReasonInvalid  = "invalid"
+
+	// ValidationError的整体描述
+	messageValidation = "validation"
+
+	DefaultLanguage = "en"
+
+	headerAcceptLanguage = "Accept-Language"
+)
+
+var (
+	messagesLock sync.RWMutex
+	messages     = map[string]map[string]string{
+		"en": {
+			messageValidation: "validation failed",
+			ReasonRequired:    "{field} is required",
+			ReasonOptions:     "{field} must be one of {param}",
+			ReasonRange:       "{field} must be in range {param}",
+			ReasonType:        "{field} has an invalid value",
+			ReasonInvalid:     "{field} is malformed",
+		},
+		"zh": {
+			messageValidation: "参数校验失败",
+			ReasonRequired:    "{field}不能为空",
+			ReasonOptions:     "{field}必须是{param}之一",
+			ReasonRange:       "{field}必须在{param}范围内",
+			ReasonType:        "{field}的值无效",
+			ReasonInvalid:     "{field}格式错误",
+		},
+	}
+)
+
+type (
+	FieldError struct {
+		// 字段路径，如items[0].name
+		Field string `json:"field"`
+		// path、query、header或body
+		In      string `json:"in"`
+		Reason  string `json:"reason"`
+		Message string `json:"message"`
+		param   string
+	}
+
+	ValidationError struct {
+		Message string       `json:"message"`
+		Errors  []FieldError `json:"errors"`
+	}
+)
+
+// RegisterMessages adds or overrides the message templates of the language,
+// keyed by the reasons, {field} and {param} are replaced.
+func RegisterMessages(lang string, templates map[string]string) {
+	messagesLock.Lock()
+	defer messagesLock.Unlock()
+
+	lang = strings.ToLower(lang)
+	catalog, ok := messages[lang]
+	if !ok {
+		catalog = make(map[string]string)
+		messages[lang] = catalog
+	}
+	for reason, tmpl := range templates {
+		catalog[reason] = tmpl
+	}
+}
+
+// FieldErrors returns the field errors carried by err, which is returned by Bind or Validate.
+func FieldErrors(err error) []FieldError {
+	var he *echo.HTTPError
+	if errors.As(err, &he) {
+		err = he.Internal
+	}
+
+	var verr *ValidationError
+	if errors.As(err, &verr) {
+		return verr.Errors
+	}
+
+	return nil
+}
+
+func (e *ValidationError) Error() string {
+	var b strings.Builder
+	b.WriteString(e.Message)
+	for i, fe := range e.Errors {
+		if i == 0 {
+			b.WriteString(": ")
+		} else {
+			b.WriteString("; ")
+		}
+		b.WriteString(fe.Message)
+	}
+
+	return b.String()
+}
+
+func newValidationError(errs []FieldError, lang string) *ValidationError {
+	messagesLock.RLock()
+	defer messagesLock.RUnlock()
+
+	catalog := messages[lang]
+	fallback := messages[DefaultLanguage]
+	lookup := func(key string) string {
+		if tmpl, ok := catalog[key]; ok {
+			return tmpl
+		}
+		return fallback[key]
+	}
+
+	for i := range errs {
+		errs[i].Message = strings.NewReplacer("{field}", errs[i].Field, "{param}", errs[i].param).
+			Replace(lookup(errs[i].Reason))
+	}
+
+	return &ValidationError{
+		Message: lookup(messageValidation),
+		Errors:  errs,
+	}
+}
+
+func toHTTPError(err *ValidationError) error {
+	return &echo.HTTPError{
+		Code:     http.StatusBadRequest,
+		Message:  err,
+		Internal: err,
+	}
+}
+
+// language picks the first supported language of Accept-Language, like zh-CN for zh.
+func language(acceptLanguage string) string {
+	messagesLock.RLock()
+	defer messagesLock.RUnlock()
+
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag := strings.ToLower(strings.TrimSpace(strings.Split(part, ";")[0]))
+		if len(tag) == 0 {
+			continue
+		}
+		if _, ok := messages[tag]; ok {
+			return tag
+		}
+		if i := strings.IndexAny(tag, "-_"); i > 0 {
+			if _, ok := messages[tag[:i]]; ok {
+				return tag[:i]
+			}
+		}
+	}
+
+	return DefaultLanguage
+}
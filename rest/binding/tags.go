@@ -0,0 +1,226 @@
+package binding
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+const (
+	pathKey   = "path"
+	formKey   = "form"
+	headerKey = "header"
+	jsonKey   = "json"
+
+	optionalOption = "optional"
+	defaultOption  = "default="
+	optionsOption  = "options="
+	rangeOption    = "range="
+)
+
+var (
+	// 按优先级查找字段的tag
+	sourceKeys = []string{pathKey, formKey, headerKey, jsonKey}
+
+	errBadRange = errors.New("bad range")
+)
+
+type (
+	// fieldOptions is parsed from go-zero style tags, like `json:"name,optional,range=[0:10]"`.
+	fieldOptions struct {
+		name         string
+		optional     bool
+		hasDefault   bool
+		defaultValue string
+		options      []string
+		rng          *numberRange
+	}
+
+	numberRange struct {
+		repr         string
+		left, right  float64
+		hasLeft      bool
+		hasRight     bool
+		leftInclude  bool
+		rightInclude bool
+	}
+)
+
+func fieldSource(field reflect.StructField) (string, string, bool) {
+	for _, key := range sourceKeys {
+		if tag, ok := field.Tag.Lookup(key); ok {
+			return key, tag, true
+		}
+	}
+
+	return "", "", false
+}
+
+func parseTag(field reflect.StructField, tag string) (fieldOptions, error) {
+	segments := splitTag(tag)
+	opts := fieldOptions{
+		name: strings.TrimSpace(segments[0]),
+	}
+	if len(opts.name) == 0 {
+		opts.name = field.Name
+	}
+
+	for _, segment := range segments[1:] {
+		segment = strings.TrimSpace(segment)
+		switch {
+		case segment == optionalOption:
+			opts.optional = true
+		case strings.HasPrefix(segment, defaultOption):
+			opts.hasDefault = true
+			opts.defaultValue = segment[len(defaultOption):]
+		case strings.HasPrefix(segment, optionsOption):
+			opts.options = parseOptions(segment[len(optionsOption):])
+		case strings.HasPrefix(segment, rangeOption):
+			rng, err := parseRange(segment[len(rangeOption):])
+			if err != nil {
+				return opts, fmt.Errorf("field %s: %w", field.Name, err)
+			}
+			opts.rng = rng
+		}
+	}
+
+	return opts, nil
+}
+
+// splitTag splits the tag by commas outside of brackets.
+func splitTag(tag string) []string {
+	var segments []string
+	var depth, start int
+	for i, c := range tag {
+		switch c {
+		case '[', '(':
+			depth++
+		case ']', ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				segments = append(segments, tag[start:i])
+				start = i + 1
+			}
+		}
+	}
+
+	return append(segments, tag[start:])
+}
+
+// options=a|b|c or options=[a,b,c]
+func parseOptions(val string) []string {
+	if strings.HasPrefix(val, "[") && strings.HasSuffix(val, "]") {
+		return strings.Split(val[1:len(val)-1], ",")
+	}
+
+	return strings.Split(val, "|")
+}
+
+// range=[1:10), either side can be omitted, like [1:]
+func parseRange(val string) (*numberRange, error) {
+	if len(val) < 3 {
+		return nil, errBadRange
+	}
+
+	rng := &numberRange{repr: val}
+	switch val[0] {
+	case '[':
+		rng.leftInclude = true
+	case '(':
+	default:
+		return nil, errBadRange
+	}
+	switch val[len(val)-1] {
+	case ']':
+		rng.rightInclude = true
+	case ')':
+	default:
+		return nil, errBadRange
+	}
+
+	bounds := strings.Split(val[1:len(val)-1], ":")
+	if len(bounds) != 2 {
+		return nil, errBadRange
+	}
+
+	var err error
+	if left := strings.TrimSpace(bounds[0]); len(left) > 0 {
+		if rng.left, err = strconv.ParseFloat(left, 64); err != nil {
+			return nil, errBadRange
+		}
+		rng.hasLeft = true
+	}
+	if right := strings.TrimSpace(bounds[1]); len(right) > 0 {
+		if rng.right, err = strconv.ParseFloat(right, 64); err != nil {
+			return nil, errBadRange
+		}
+		rng.hasRight = true
+	}
+
+	return rng, nil
+}
+
+func (r *numberRange) contains(v float64) bool {
+	if r.hasLeft && (v < r.left || !r.leftInclude && v == r.left) {
+		return false
+	}
+	if r.hasRight && (v > r.right || !r.rightInclude && v == r.right) {
+		return false
+	}
+
+	return true
+}
+
+// Tag is the parsed go-zero style tag of a field, for the tools like the openapi generator.
+type Tag struct {
+	// path、form、header或json
+	Source       string
+	Name         string
+	Optional     bool
+	HasDefault   bool
+	Default      string
+	Options      []string
+	Min          *float64
+	Max          *float64
+	ExclusiveMin bool
+	ExclusiveMax bool
+}
+
+// ParseField parses the tag of the field, false if the field has no source tag or is ignored by "-".
+func ParseField(field reflect.StructField) (Tag, bool, error) {
+	source, tag, ok := fieldSource(field)
+	if !ok || tag == "-" {
+		return Tag{}, false, nil
+	}
+
+	opts, err := parseTag(field, tag)
+	if err != nil {
+		return Tag{}, false, err
+	}
+
+	t := Tag{
+		Source:     source,
+		Name:       opts.name,
+		Optional:   opts.optional,
+		HasDefault: opts.hasDefault,
+		Default:    opts.defaultValue,
+		Options:    opts.options,
+	}
+	if rng := opts.rng; rng != nil {
+		if rng.hasLeft {
+			left := rng.left
+			t.Min = &left
+			t.ExclusiveMin = !rng.leftInclude
+		}
+		if rng.hasRight {
+			right := rng.right
+			t.Max = &right
+			t.ExclusiveMax = !rng.rightInclude
+		}
+	}
+
+	return t, true, nil
+}
@@ -0,0 +1,134 @@
+package binding
+
+import (
+	"reflect"
+	"strconv"
+)
+
+// Validator validates the go-zero style tags of the bound struct, it's the default echo.Validator.
+// Without the request, the zero values are treated as missing, and the defaults are applied to them.
+type Validator struct {
+	// 错误信息的语言，默认en
+	Language string
+}
+
+func NewValidator() *Validator {
+	return &Validator{
+		Language: DefaultLanguage,
+	}
+}
+
+func (v *Validator) Validate(i interface{}) error {
+	rv := reflect.ValueOf(i)
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return nil
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var c checker
+	if err := c.validateStruct(rv, ""); err != nil {
+		return err
+	}
+	if len(c.errs) > 0 {
+		lang := v.Language
+		if len(lang) == 0 {
+			lang = DefaultLanguage
+		}
+		return toHTTPError(newValidationError(c.errs, lang))
+	}
+
+	return nil
+}
+
+func (c *checker) validateStruct(sv reflect.Value, prefix string) error {
+	st := sv.Type()
+	for i := 0; i < st.NumField(); i++ {
+		sf := st.Field(i)
+		fv := sv.Field(i)
+		if len(sf.PkgPath) > 0 && !sf.Anonymous {
+			continue
+		}
+
+		key, tag, ok := fieldSource(sf)
+		if !ok {
+			if sf.Anonymous && fv.Kind() == reflect.Struct {
+				if err := c.validateStruct(fv, prefix); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		if tag == "-" {
+			continue
+		}
+
+		opts, err := parseTag(sf, tag)
+		if err != nil {
+			return err
+		}
+
+		path := prefix + opts.name
+		in := sourceIn(key)
+		if fv.IsZero() {
+			switch {
+			case opts.hasDefault && fv.CanSet():
+				if err := setString(fv, opts.defaultValue); err != nil {
+					c.add(path, in, ReasonType, "")
+				}
+			case !opts.optional && !opts.hasDefault:
+				c.add(path, in, ReasonRequired, "")
+			}
+			continue
+		}
+
+		c.check(fv, path, in, opts)
+		if err := c.validateNested(fv, path); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (c *checker) validateNested(fv reflect.Value, path string) error {
+	for fv.Kind() == reflect.Ptr && !fv.IsNil() {
+		fv = fv.Elem()
+	}
+
+	switch fv.Kind() {
+	case reflect.Struct:
+		return c.validateStruct(fv, path+".")
+	case reflect.Slice:
+		for i := 0; i < fv.Len(); i++ {
+			elem := fv.Index(i)
+			for elem.Kind() == reflect.Ptr && !elem.IsNil() {
+				elem = elem.Elem()
+			}
+			if elem.Kind() == reflect.Struct {
+				if err := c.validateStruct(elem, path+"["+strconv.Itoa(i)+"]."); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+func sourceIn(key string) string {
+	switch key {
+	case pathKey:
+		return InPath
+	case formKey:
+		return InQuery
+	case headerKey:
+		return InHeader
+	default:
+		return InBody
+	}
+}
@@ -0,0 +1,56 @@
+package binding
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidator(t *testing.T) {
+	type nested struct {
+		Level int `json:"level,options=1|2|3"`
+	}
+	type request struct {
+		Name   string  `json:"name"`
+		Size   int     `json:"size,default=10,range=(0:100]"`
+		Ratio  float64 `json:"ratio,optional,range=[0:1]"`
+		Nested nested  `json:"nested,optional"`
+		Ignore string  `json:"-"`
+	}
+
+	v := NewValidator()
+	req := request{Name: "kevin"}
+	assert.Nil(t, v.Validate(&req))
+	assert.Equal(t, 10, req.Size)
+
+	req = request{Ratio: 2, Nested: nested{Level: 5}}
+	errs := FieldErrors(v.Validate(&req))
+	assert.Equal(t, []string{"name", "ratio", "nested.level"}, fields(errs))
+	assert.Equal(t, ReasonRange, errs[1].Reason)
+	assert.Equal(t, ReasonOptions, errs[2].Reason)
+}
+
+func TestParseRange(t *testing.T) {
+	rng, err := parseRange("(0:10]")
+	assert.Nil(t, err)
+	assert.False(t, rng.contains(0))
+	assert.True(t, rng.contains(10))
+
+	rng, err = parseRange("[1:]")
+	assert.Nil(t, err)
+	assert.True(t, rng.contains(1000))
+	assert.False(t, rng.contains(0))
+
+	for _, bad := range []string{"", "0:1", "[a:1]", "[0,1]"} {
+		_, err = parseRange(bad)
+		assert.NotNil(t, err, bad)
+	}
+}
+
+func fields(errs []FieldError) []string {
+	var names []string
+	for _, e := range errs {
+		names = append(names, e.Field)
+	}
+	return names
+}
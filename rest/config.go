@@ -17,6 +17,138 @@ type (
 		PrivateKeys []PrivateKeyConf
 	}
 
+	HealthConf struct {
+		Disabled      bool   `json:",optional"`
+		LivenessPath  string `json:",default=/healthz"`
+		ReadinessPath string `json:",default=/readyz"`
+		// milliseconds
+		Timeout int64 `json:",default=1000"`
+		// milliseconds, 负数代表不缓存，没有配置Health时为0，使用默认值
+		CacheDuration int64 `json:",default=1000"`
+		// milliseconds, Shutdown时readiness先返回失败，等待负载均衡摘除实例之后再停止接收请求
+		DrainDelay int64 `json:",optional"`
+	}
+
+	// 管理端口，提供metrics、pprof、路由列表等，Port为0时不开启
+	AdminConf struct {
+		// pprof和配置没有认证，默认只监听回环地址，对外开放时配合Allow使用
+		Host string   `json:",default=127.0.0.1"`
+		Port int      `json:",optional"`
+		Docs DocsConf `json:",optional"`
+		// 允许访问管理端口的CIDR或IP，internal代表回环地址和私有网络，为空时不限制
+		Allow []string `json:",optional"`
+	}
+
+	// openapi文档，在管理端口的/openapi.json、/openapi.yaml和/docs提供
+	DocsConf struct {
+		Enabled bool `json:",optional"`
+		// 默认为服务名
+		Title       string `json:",optional"`
+		Version     string `json:",default=1.0.0"`
+		Description string `json:",optional"`
+	}
+
+	// 没有配置Trace时默认使用jaeger，const采样全部请求
+	TraceConf struct {
+		Disabled bool `json:",optional"`
+		// jaeger基于opentracing，otel使用W3C traceparent传播
+		Backend string `json:",default=jaeger,options=jaeger|otel"`
+		Sampler string `json:",default=const,options=const|probabilistic|ratelimiting|remote"`
+		// const为0或1，probabilistic为采样率，ratelimiting为每秒采样数
+		SamplerRate float64 `json:",default=1"`
+		// http(s)开头的为collector地址，否则为agent的host:port
+		Endpoint    string `json:",optional"`
+		Propagation string `json:",default=b3,options=b3|w3c|jaeger"`
+		// otel的exporter，otlp的Endpoint为host:port
+		Batcher  string `json:",default=otlp,options=otlp|memory"`
+		Insecure bool   `json:",optional"`
+	}
+
+	AccessLogConf struct {
+		// 为空时使用默认的脱敏header、json字段和uri中的query参数，api key所在的header和query参数总是脱敏
+		RedactHeaders []string `json:",optional"`
+		RedactFields  []string `json:",optional"`
+		RedactQuery   []string `json:",optional"`
+		// 记录请求和响应body的最大字节数，0代表不记录，Verbose时默认4096
+		MaxBodyBytes int `json:",optional"`
+		// 成功请求的采样率
+		SampleRate float64 `json:",default=1,range=[0:1]"`
+	}
+
+	// 响应压缩，按Accept-Encoding协商br、zstd、gzip和deflate
+	CompressConf struct {
+		Enabled   bool `json:",optional"`
+		MinLength int  `json:",default=1024"`
+		// 为空时使用默认的Content-Type和编码
+		ContentTypes []string `json:",optional"`
+		Encodings    []string `json:",optional"`
+	}
+
+	// SSE和WebSocket的长连接，通过rest.WithStreaming开启
+	StreamConf struct {
+		// 每个连接缓冲的消息数，满了之后视为慢消费者并断开
+		SendBuffer int `json:",default=64"`
+		// milliseconds, SSE的注释心跳和WebSocket的ping间隔，负数代表关闭
+		Heartbeat int64 `json:",default=15000"`
+		// milliseconds, WebSocket单条消息的写超时
+		WriteTimeout int64 `json:",default=10000"`
+		// WebSocket读取的最大消息
+		ReadLimit int64 `json:",default=65536"`
+	}
+
+	// 跨域、安全响应头和CSRF
+	SecurityConf struct {
+		Cors    CorsConf            `json:",optional"`
+		Headers SecurityHeadersConf `json:",optional"`
+		Csrf    CsrfConf            `json:",optional"`
+	}
+
+	// 跨域，分组可以通过rest.WithCors覆盖
+	CorsConf struct {
+		Enabled bool `json:",optional"`
+		// *代表全部，https://*.example.com代表example.com的子域名
+		AllowOrigins []string `json:",optional"`
+		// 为空时允许GET、HEAD、POST、PUT、PATCH和DELETE
+		AllowMethods []string `json:",optional"`
+		// 为空时允许预检请求中的全部header
+		AllowHeaders  []string `json:",optional"`
+		ExposeHeaders []string `json:",optional"`
+		// 不能和*同时使用
+		AllowCredentials bool `json:",optional"`
+		// seconds, 预检结果的缓存时间，默认600，负数代表不缓存
+		MaxAge int `json:",default=600"`
+	}
+
+	// 安全响应头的基线，默认开启
+	SecurityHeadersConf struct {
+		Disabled bool `json:",optional"`
+		// seconds, Strict-Transport-Security只在https请求中返回，默认一年，负数代表不返回
+		HSTSMaxAge            int64 `json:",default=31536000"`
+		HSTSIncludeSubdomains bool  `json:",optional"`
+		HSTSPreload           bool  `json:",optional"`
+		// 为空时不返回
+		ContentSecurityPolicy string `json:",optional"`
+		// 默认DENY
+		FrameOptions string `json:",default=DENY"`
+		// 默认strict-origin-when-cross-origin
+		ReferrerPolicy string `json:",default=strict-origin-when-cross-origin"`
+	}
+
+	// 双重提交cookie的CSRF保护，通过rest.WithCsrf对使用cookie认证的分组开启
+	CsrfConf struct {
+		// 默认_csrf
+		CookieName   string `json:",default=_csrf"`
+		CookieDomain string `json:",optional"`
+		CookiePath   string `json:",default=/"`
+		// SameSite为none时必须开启
+		CookieSecure bool   `json:",optional"`
+		SameSite     string `json:",default=lax,options=lax|strict|none"`
+		// seconds, 默认一天
+		MaxAge int `json:",default=86400"`
+		// 非安全方法需要带上的header，默认X-CSRF-Token
+		HeaderName string `json:",default=X-CSRF-Token"`
+	}
+
 	// Why not name it as Conf, because we need to consider usage like:
 	// type Config struct {
 	//     zrpc.RpcConf
@@ -36,5 +168,16 @@ type (
 		Timeout      int64         `json:",default=3000"`
 		CpuThreshold int64         `json:",default=900,range=[0:1000]"`
 		Signature    SignatureConf `json:",optional"`
+		Health       HealthConf    `json:",optional"`
+		Admin        AdminConf     `json:",optional"`
+		Trace        TraceConf     `json:",optional"`
+		AccessLog    AccessLogConf `json:",optional"`
+		Compress     CompressConf  `json:",optional"`
+		Stream       StreamConf    `json:",optional"`
+		Security     SecurityConf  `json:",optional"`
+		// 可信代理的CIDR或IP，internal代表回环地址和私有网络，只有来自它们的请求才使用RealIPHeader中的客户端地址，
+		// 为空时使用连接的地址
+		TrustedProxies []string `json:",optional"`
+		RealIPHeader   string   `json:",default=X-Forwarded-For,options=X-Forwarded-For|X-Real-IP"`
 	}
 )
@@ -1,22 +1,40 @@
 package rest
 
 import (
+	"context"
 	"fmt"
 	"github.com/labstack/echo/v4"
 	echoMiddleware "github.com/labstack/echo/v4/middleware"
-	"github.com/labstack/gommon/bytes"
+	"github.com/opentracing/opentracing-go"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/valeamoris/go-ezio/core/limit"
+	"github.com/valeamoris/go-ezio/core/stores/redis"
+	"github.com/valeamoris/go-ezio/rest/binding"
+	"github.com/valeamoris/go-ezio/rest/errorx"
+	"github.com/valeamoris/go-ezio/rest/health"
 	"github.com/valeamoris/go-ezio/rest/middleware"
+	"github.com/valeamoris/go-ezio/rest/stream"
 	"github.com/zeromicro/go-zero/core/breaker"
 	"github.com/zeromicro/go-zero/core/load"
+	"github.com/zeromicro/go-zero/core/logx"
+	"github.com/zeromicro/go-zero/core/service"
 	"github.com/zeromicro/go-zero/core/stat"
 	"github.com/zeromicro/go-zero/core/sysx"
 	"io"
+	"net/http"
+	"sync"
 	"time"
 )
 
 // 1000m代表100%
 const topCpuUsage = 1000
 
+type closerFunc func() error
+
+func (f closerFunc) Close() error {
+	return f()
+}
+
 type (
 	engine struct {
 		conf Conf
@@ -28,14 +46,52 @@ type (
 		closers         []io.Closer
 		groups          []Group
 		rejectHandler   func(promise breaker.Promise, err error)
+		health          *health.Registry
+		// 管理端口，在Start的goroutine中创建，adminLock保护
+		admin     *echo.Echo
+		adminLock sync.Mutex
+		// Shutdown或Close之后不再启动管理端口
+		stopped   bool
+		gatherer  prometheus.Gatherer
+		startTime time.Time
+		// prometheus的registry、namespace和分桶
+		prometheusOpts []middleware.PrometheusOption
+		// 外部注入的tracer，优先于配置
+		tracer opentracing.Tracer
+		// 限流等共享状态的存储
+		redis redis.Node
+		// prometheus的namespace
+		namespace string
+		// SSE和WebSocket的连接，在NewServer中创建，Shutdown可以在Start之前或同时调用
+		streams *stream.Hub
+		// 管理端口的访问限制
+		adminFilter *middleware.IPFilter
+		// 路由只绑定一次，Start和Handler共用
+		bindOnce sync.Once
+		bindErr  error
 	}
 )
 
+const (
+	defaultMaxBodyBytes  = 4096
+	defaultLivenessPath  = "/healthz"
+	defaultReadinessPath = "/readyz"
+)
+
 func newEngine(conf Conf) *engine {
 	srv := &engine{
-		conf: conf,
-		Echo: echo.New(),
+		conf:      conf,
+		Echo:      echo.New(),
+		health:    health.NewRegistry(),
+		startTime: time.Now(),
 	}
+	// 默认支持go-zero风格的tag校验，可以通过WithValidator替换
+	srv.Validator = binding.NewValidator()
+	// 统一的错误响应，生产环境隐藏5xx的错误信息，可以通过WithErrorHandler替换
+	srv.HTTPErrorHandler = errorx.ErrorHandler(conf.Mode == service.ProMode || conf.Mode == service.PreMode)
+	// 没有配置Health时嵌套的默认值不会生效，0使用health的默认值
+	srv.health.SetDefaults(time.Duration(conf.Health.Timeout)*time.Millisecond,
+		time.Duration(conf.Health.CacheDuration)*time.Millisecond)
 	if conf.CpuThreshold > 0 {
 		srv.shedder = load.NewAdaptiveShedder(load.WithCpuThreshold(conf.CpuThreshold))
 		srv.priorityShedder = load.NewAdaptiveShedder(load.WithCpuThreshold(
@@ -54,22 +110,64 @@ func (s *engine) bindGroup(g Group, metrics *stat.Metrics) error {
 
 	group := s.Group(g.Prefix)
 
+	// 放在最前，拒绝的请求不占用其他资源
+	if g.ipFilter != nil {
+		group.Use(middleware.IPFilterMiddleware(g.ipFilter))
+	}
+
 	if g.shedding {
 		// 自定义负载保护
 		group.Use(middleware.SheddingMiddleware(s.getShedder(g.priority), metrics))
 	}
 
-	if !g.timeoutDisabled {
+	if g.streaming {
+		if g.idempotency.enabled || g.cache.enabled {
+			return fmt.Errorf("streaming group %q can't use idempotency or cache", g.Prefix)
+		}
+		group.Use(stream.Middleware(s.streams))
+	} else if timeout := s.groupTimeout(g); timeout > 0 {
 		// 超时
-		group.Use(middleware.TimeoutMiddleware(time.Duration(s.conf.Timeout) * time.Millisecond))
+		group.Use(middleware.TimeoutMiddleware(timeout))
 	}
 
-	// JWT的认证中间件
-	if g.jwt.enabled {
-		conf := echoMiddleware.DefaultJWTConfig
-		conf.Claims = g.jwt.claims
-		conf.SigningKey = []byte(g.jwt.secret)
-		group.Use(echoMiddleware.JWTWithConfig(conf))
+	if g.csrf {
+		group.Use(middleware.CsrfMiddleware(s.csrfOptions()))
+	}
+
+	if err := s.bindAuth(group, g); err != nil {
+		return err
+	}
+	if authz := authzMiddleware(g); authz != nil {
+		group.Use(authz)
+	}
+
+	// 限流，放在认证之后才能按subject限流
+	if g.rateLimit.enabled {
+		limiter, err := s.getLimiter(g)
+		if err != nil {
+			return fmt.Errorf("rate limit of group %q: %w", g.Prefix, err)
+		}
+		group.Use(middleware.RateLimitMiddleware(limiter, g.rateLimit.keyFunc))
+	}
+
+	if g.idempotency.enabled {
+		if s.redis == nil {
+			return fmt.Errorf("idempotency of group %q requires redis, set it with rest.WithRedis", g.Prefix)
+		}
+
+		// 锁在请求执行期间续期，关闭超时的分组使用默认的过期时间
+		group.Use(middleware.IdempotencyMiddleware(s.redis, middleware.IdempotencyOptions{
+			TTL:        g.idempotency.ttl,
+			LockExpire: s.groupTimeout(g),
+		}))
+	}
+
+	if g.cache.enabled {
+		if s.redis == nil {
+			return fmt.Errorf("cache of group %q requires redis, set it with rest.WithRedis", g.Prefix)
+		}
+
+		group.Use(middleware.CacheMiddleware(s.redis, g.cache.opts))
 	}
 
 	if g.static.enabled {
@@ -92,6 +190,98 @@ func (s *engine) bindGroup(g Group, metrics *stat.Metrics) error {
 	return nil
 }
 
+// 认证中间件，JWT、api key和hmac只能选择一种
+func (s *engine) bindAuth(group *echo.Group, g Group) error {
+	var methods int
+	for _, enabled := range []bool{g.jwt.enabled, g.apiKey.enabled, g.hmac.enabled} {
+		if enabled {
+			methods++
+		}
+	}
+	if methods > 1 {
+		return fmt.Errorf("group %q can only use one of jwt, api key and hmac", g.Prefix)
+	}
+
+	switch {
+	case g.jwt.enabled:
+		conf := echoMiddleware.DefaultJWTConfig
+		conf.Claims = g.jwt.claims
+		conf.SigningKey = []byte(g.jwt.secret)
+		group.Use(echoMiddleware.JWTWithConfig(conf))
+	case g.apiKey.enabled:
+		group.Use(middleware.ApiKeyMiddleware(g.apiKey.store, g.apiKey.opts))
+	case g.hmac.enabled:
+		if s.redis == nil {
+			return fmt.Errorf("hmac of group %q requires redis, set it with rest.WithRedis", g.Prefix)
+		}
+		group.Use(middleware.HmacMiddleware(g.hmac.keys, s.redis, g.hmac.opts))
+	}
+
+	return nil
+}
+
+// 授权放在认证之后，缓存和幂等之前，未授权的请求不能拿到缓存的响应
+func authzMiddleware(g Group) echo.MiddlewareFunc {
+	if !g.authz.enabled && !hasRequirements(g.Routes) {
+		return nil
+	}
+
+	routes := make(map[string]Requirement)
+	for _, route := range g.Routes {
+		if len(route.Roles) > 0 || len(route.Permissions) > 0 {
+			routes[middleware.RouteKey(route.Method, g.Prefix+route.Path)] = Requirement{
+				Roles:       route.Roles,
+				Permissions: route.Permissions,
+			}
+		}
+	}
+
+	policy := g.authz.policy
+	if policy == nil {
+		policy = middleware.NewRbacPolicy(RbacRules{})
+	}
+	opts := g.authz.opts
+	if g.apiKey.enabled && len(g.apiKey.opts.Query) > 0 {
+		redactQuery := opts.RedactQuery
+		if redactQuery == nil {
+			redactQuery = middleware.DefaultRedactQuery
+		}
+		opts.RedactQuery = append(append([]string(nil), redactQuery...), g.apiKey.opts.Query)
+	}
+	return middleware.AuthzMiddleware(policy, opts, routes)
+}
+
+func hasRequirements(routes []Route) bool {
+	for _, route := range routes {
+		if len(route.Roles) > 0 || len(route.Permissions) > 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// groupTimeout is the timeout of the requests in the group, 0 means no timeout.
+func (s *engine) groupTimeout(g Group) time.Duration {
+	if g.streaming || g.timeoutDisabled {
+		return 0
+	}
+	if g.timeout > 0 {
+		return g.timeout
+	}
+
+	return time.Duration(s.conf.Timeout) * time.Millisecond
+}
+
+func (s *engine) getLimiter(g Group) (limit.Limiter, error) {
+	if g.rateLimit.limiter != nil {
+		return g.rateLimit.limiter, nil
+	}
+
+	return limit.NewTokenLimiter(s.redis, g.rateLimit.rate, g.rateLimit.burst,
+		limit.WithKeyPrefix(fmt.Sprintf("ratelimit:%s:", g.Prefix)))
+}
+
 func (s *engine) getShedder(priority bool) load.Shedder {
 	if priority && s.priorityShedder != nil {
 		return s.priorityShedder
@@ -108,27 +298,55 @@ func (s *engine) bindRoute(g *echo.Group, metrics *stat.Metrics, route Route) {
 func (s *engine) bindRoutes() error {
 	metrics := s.createMetrics()
 
-	traceMiddleware, closer := middleware.TracingMiddleware(sysx.Hostname())
-	s.closers = append(s.closers, closer)
+	// RealIP只信任来自可信代理的转发header
+	extractor, err := middleware.RealIPExtractor(s.conf.RealIPHeader, s.conf.TrustedProxies)
+	if err != nil {
+		return fmt.Errorf("invalid trusted proxies: %w", err)
+	}
+	s.Echo.IPExtractor = extractor
+	if len(s.conf.Admin.Allow) > 0 {
+		if s.adminFilter, err = middleware.NewIPFilter(middleware.IPRules{Allow: s.conf.Admin.Allow}); err != nil {
+			return fmt.Errorf("invalid admin allow list: %w", err)
+		}
+	}
 
+	// request id，需要放在追踪和日志前
+	s.Echo.Use(middleware.RequestIdMiddleware)
 	// 追踪
-	s.Echo.Use(traceMiddleware)
+	if tracer := s.getTracer(); tracer != nil {
+		s.Echo.Use(middleware.TracingMiddleware(tracer))
+	} else if s.otelEnabled() {
+		s.Echo.Use(middleware.OtelMiddleware(s.serviceName()))
+	}
+	// 响应压缩，放在日志前，日志记录的是压缩前的body
+	if s.conf.Compress.Enabled {
+		s.Echo.Use(middleware.CompressMiddleware(middleware.CompressOptions{
+			MinLength:    s.conf.Compress.MinLength,
+			ContentTypes: s.conf.Compress.ContentTypes,
+			Encodings:    s.conf.Compress.Encodings,
+		}))
+	}
 	// 日志记录
 	s.Echo.Use(s.getLogMiddleware())
+	// 安全响应头和跨域，错误响应也需要带上
+	if err := s.bindSecurity(); err != nil {
+		return err
+	}
+	// prometheus监控，放在最大连接数前，超出连接数丢弃的请求也要统计
+	s.Echo.Use(middleware.PrometheusMiddleware(s.prometheusOpts...))
 	// 单连接最大连接数
 	s.Echo.Use(middleware.MaxConnMiddleware(s.conf.MaxConns))
 	// recover恢复
 	s.Echo.Use(middleware.RecoverMiddleware)
 	// 数据统计
 	s.Echo.Use(middleware.MetricMiddleware(metrics))
-	// 全局初始化prometheus中间件
-	// prometheus监控
-	s.Echo.Use(middleware.PrometheusMiddleware())
 
-	// 最大body limit
-	s.Echo.Use(echoMiddleware.BodyLimit(bytes.Format(s.conf.MaxBytes)))
-	// gzip request的支持
+	// 最大body limit，分组可以通过WithMaxBytes覆盖
+	s.Echo.Use(middleware.BodyLimitMiddleware(s.conf.MaxBytes, s.routeBodyLimits()))
+	// gzip request的支持，解压后同样受body limit限制
 	s.Echo.Use(middleware.GunzipMiddleware)
+
+	s.bindHealth()
 	for _, fr := range s.groups {
 		if err := s.bindGroup(fr, metrics); err != nil {
 			return err
@@ -137,6 +355,42 @@ func (s *engine) bindRoutes() error {
 	return nil
 }
 
+func (s *engine) routeBodyLimits() map[string]int64 {
+	limits := make(map[string]int64)
+	for _, g := range s.groups {
+		if g.maxBytes <= 0 {
+			continue
+		}
+		for _, route := range g.Routes {
+			limits[middleware.RouteKey(route.Method, g.Prefix+route.Path)] = g.maxBytes
+		}
+	}
+
+	return limits
+}
+
+func (s *engine) newStreamHub() *stream.Hub {
+	return stream.NewHub(s.streamOptions())
+}
+
+// streamOptions converts Conf.Stream, the zero values are the defaults of stream.Options,
+// since they aren't applied if there is no Stream in the config.
+func (s *engine) streamOptions() stream.Options {
+	registerer := prometheus.DefaultRegisterer
+	if r, ok := s.gatherer.(prometheus.Registerer); ok {
+		registerer = r
+	}
+
+	return stream.Options{
+		SendBuffer:   s.conf.Stream.SendBuffer,
+		Heartbeat:    time.Duration(s.conf.Stream.Heartbeat) * time.Millisecond,
+		WriteTimeout: time.Duration(s.conf.Stream.WriteTimeout) * time.Millisecond,
+		ReadLimit:    s.conf.Stream.ReadLimit,
+		Registerer:   registerer,
+		Namespace:    s.namespace,
+	}
+}
+
 func (s *engine) createMetrics() *stat.Metrics {
 	var metrics *stat.Metrics
 
@@ -149,21 +403,161 @@ func (s *engine) createMetrics() *stat.Metrics {
 	return metrics
 }
 
+// traceConf fills the defaults of TraceConf, which aren't applied if there is no Trace in the config.
+func (s *engine) traceConf() TraceConf {
+	conf := s.conf.Trace
+	if len(conf.Backend) == 0 {
+		conf.Backend = backendJaeger
+	}
+	if len(conf.Sampler) == 0 {
+		conf.Sampler = defaultSampler
+		conf.SamplerRate = 1
+	}
+	if len(conf.Propagation) == 0 {
+		conf.Propagation = propagationB3
+	}
+	if len(conf.Batcher) == 0 {
+		conf.Batcher = defaultBatcher
+	}
+
+	return conf
+}
+
+func (s *engine) getTracer() opentracing.Tracer {
+	if s.tracer == nil {
+		conf := s.traceConf()
+		if conf.Disabled || conf.Backend == backendOtel {
+			return nil
+		}
+
+		tracer, closer, err := newTracer(s.serviceName(), conf)
+		if err != nil {
+			logx.Errorf("tracing disabled, could not initialize tracer: %s", err.Error())
+			return nil
+		}
+		s.tracer = tracer
+		s.closers = append(s.closers, closer)
+	}
+
+	// 业务代码中通过opentracing.StartSpanFromContext创建的span依赖全局tracer
+	opentracing.SetGlobalTracer(s.tracer)
+	return s.tracer
+}
+
+func (s *engine) otelEnabled() bool {
+	conf := s.traceConf()
+	if conf.Disabled || conf.Backend != backendOtel {
+		return false
+	}
+
+	if err := startOtelAgent(s.serviceName(), conf); err != nil {
+		logx.Errorf("tracing disabled, could not start otel agent: %s", err.Error())
+		return false
+	}
+	s.closers = append(s.closers, closerFunc(stopOtelAgent))
+	return true
+}
+
+func (s *engine) serviceName() string {
+	if len(s.conf.Name) > 0 {
+		return s.conf.Name
+	}
+
+	return sysx.Hostname()
+}
+
+// apiKeyParams returns the headers and the query params carrying the api keys, which are redacted in the logs.
+func (s *engine) apiKeyParams() (headers, queries []string) {
+	for _, g := range s.groups {
+		if !g.apiKey.enabled {
+			continue
+		}
+
+		header := g.apiKey.opts.Header
+		if len(header) == 0 {
+			header = middleware.HeaderApiKey
+		}
+		headers = append(headers, header)
+		if len(g.apiKey.opts.Query) > 0 {
+			queries = append(queries, g.apiKey.opts.Query)
+		}
+	}
+
+	return
+}
+
 func (s *engine) getLogMiddleware() echo.MiddlewareFunc {
-	if s.conf.Verbose {
-		return middleware.DetailedLogMiddleware
-	} else {
-		return middleware.LogMiddleware
+	opts := middleware.AccessLogOptions{
+		MaxBodyBytes: s.conf.AccessLog.MaxBodyBytes,
+		SampleRate:   s.conf.AccessLog.SampleRate,
+		Headers:      s.conf.Verbose,
+	}
+	redactHeaders := middleware.DefaultRedactHeaders
+	if len(s.conf.AccessLog.RedactHeaders) > 0 {
+		redactHeaders = s.conf.AccessLog.RedactHeaders
+	}
+	if len(s.conf.AccessLog.RedactFields) > 0 {
+		opts.RedactFields = s.conf.AccessLog.RedactFields
+	}
+	redactQuery := middleware.DefaultRedactQuery
+	if len(s.conf.AccessLog.RedactQuery) > 0 {
+		redactQuery = s.conf.AccessLog.RedactQuery
+	}
+	headers, queries := s.apiKeyParams()
+	opts.RedactHeaders = append(append([]string(nil), redactHeaders...), headers...)
+	opts.RedactQuery = append(append([]string(nil), redactQuery...), queries...)
+	if s.conf.Verbose && opts.MaxBodyBytes == 0 {
+		opts.MaxBodyBytes = defaultMaxBodyBytes
+	}
+
+	return middleware.AccessLogMiddleware(opts)
+}
+
+// 健康检查，liveness和readiness
+func (s *engine) bindHealth() {
+	if s.conf.Health.Disabled {
+		return
+	}
+
+	livenessPath := s.conf.Health.LivenessPath
+	if len(livenessPath) == 0 {
+		livenessPath = defaultLivenessPath
+	}
+	readinessPath := s.conf.Health.ReadinessPath
+	if len(readinessPath) == 0 {
+		readinessPath = defaultReadinessPath
+	}
+
+	s.Echo.GET(livenessPath, healthHandler(s.health.Liveness))
+	s.Echo.GET(readinessPath, healthHandler(s.health.Readiness))
+}
+
+func healthHandler(probe func(ctx context.Context) health.Report) echo.HandlerFunc {
+	return func(ctx echo.Context) error {
+		report := probe(ctx.Request().Context())
+		if report.Status != health.StatusUp {
+			return ctx.JSON(http.StatusServiceUnavailable, report)
+		}
+
+		return ctx.JSON(http.StatusOK, report)
 	}
 }
 
 // todo 签名
 func (s *engine) signatureVerifier() {}
 
+func (s *engine) bind() error {
+	s.bindOnce.Do(func() {
+		s.bindErr = s.bindRoutes()
+	})
+	return s.bindErr
+}
+
 func (s *engine) startGroup() error {
-	if err := s.bindRoutes(); err != nil {
+	if err := s.bind(); err != nil {
 		return err
 	}
+	s.startAdmin()
 	return s.Echo.Start(fmt.Sprintf("%s:%d", s.conf.Host, s.conf.Port))
 }
 
@@ -171,9 +565,40 @@ func (s *engine) Start() error {
 	return s.startGroup()
 }
 
+func (s *engine) Shutdown(ctx context.Context) error {
+	s.health.MarkShuttingDown()
+	s.drain(ctx)
+	// http.Server的Shutdown不会等待hijack的连接，而SSE的请求不会自己结束
+	s.streams.Close()
+	if admin := s.stopAdmin(); admin != nil {
+		defer admin.Shutdown(ctx)
+	}
+	return s.Echo.Shutdown(ctx)
+}
+
+// drain waits for the load balancer to see the failed readiness, the requests are still served meanwhile.
+func (s *engine) drain(ctx context.Context) {
+	delay := time.Duration(s.conf.Health.DrainDelay) * time.Millisecond
+	if delay <= 0 {
+		return
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+}
+
 func (s *engine) Close() error {
+	s.health.MarkShuttingDown()
+	s.streams.Close()
 	for _, closer := range s.closers {
 		closer.Close()
 	}
+	if admin := s.stopAdmin(); admin != nil {
+		admin.Close()
+	}
 	return s.Echo.Close()
 }
@@ -0,0 +1,156 @@
+package errorx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	red "github.com/go-redis/redis/v8"
+	"github.com/labstack/echo/v4"
+	"github.com/valeamoris/go-ezio/rest/binding"
+	"github.com/zeromicro/go-zero/core/breaker"
+	"gorm.io/gorm"
+)
+
+// 业务码，默认为http状态码*100，同一状态下的细分错误递增
+const (
+	CodeBadRequest         = 40000
+	CodeValidation         = 40001
+	CodeUnauthorized       = 40100
+	CodeForbidden          = 40300
+	CodeNotFound           = 40400
+	CodeConflict           = 40900
+	CodeTooManyRequests    = 42900
+	CodeClientClosed       = 49900
+	CodeInternal           = 50000
+	CodeServiceUnavailable = 50300
+	CodeTimeout            = 50400
+
+	// nginx的约定，客户端在响应前断开
+	StatusClientClosedRequest = 499
+)
+
+var (
+	ErrBadRequest         = New(http.StatusBadRequest, CodeBadRequest, "bad request")
+	ErrUnauthorized       = New(http.StatusUnauthorized, CodeUnauthorized, "unauthorized")
+	ErrForbidden          = New(http.StatusForbidden, CodeForbidden, "forbidden")
+	ErrNotFound           = New(http.StatusNotFound, CodeNotFound, "not found")
+	ErrConflict           = New(http.StatusConflict, CodeConflict, "conflict")
+	ErrTooManyRequests    = New(http.StatusTooManyRequests, CodeTooManyRequests, "too many requests")
+	ErrInternal           = New(http.StatusInternalServerError, CodeInternal, "internal server error")
+	ErrServiceUnavailable = New(http.StatusServiceUnavailable, CodeServiceUnavailable, "service unavailable")
+	ErrTimeout            = New(http.StatusGatewayTimeout, CodeTimeout, "timeout")
+)
+
+// CodeError is the error with the http status and the business code, rendered by ErrorHandler.
+type CodeError struct {
+	Status  int         `json:"-"`
+	Code    int         `json:"code"`
+	Message string      `json:"message"`
+	Details interface{} `json:"details,omitempty"`
+	cause   error
+}
+
+func New(status, code int, message string) *CodeError {
+	return &CodeError{
+		Status:  status,
+		Code:    code,
+		Message: message,
+	}
+}
+
+func Newf(status, code int, format string, args ...interface{}) *CodeError {
+	return New(status, code, fmt.Sprintf(format, args...))
+}
+
+// Wrap keeps err as the cause, which is logged but not exposed to the client.
+func Wrap(err error, status, code int, message string) *CodeError {
+	ce := New(status, code, message)
+	ce.cause = err
+	return ce
+}
+
+func (e *CodeError) Error() string {
+	if e.cause != nil {
+		return fmt.Sprintf("code: %d, message: %s, cause: %s", e.Code, e.Message, e.cause.Error())
+	}
+
+	return fmt.Sprintf("code: %d, message: %s", e.Code, e.Message)
+}
+
+func (e *CodeError) Unwrap() error {
+	return e.cause
+}
+
+// WithDetails returns a copy with the details, the predefined errors are shared.
+func (e *CodeError) WithDetails(details interface{}) *CodeError {
+	ce := *e
+	ce.Details = details
+	return &ce
+}
+
+// WithCause returns a copy with the cause.
+func (e *CodeError) WithCause(err error) *CodeError {
+	ce := *e
+	ce.cause = err
+	return &ce
+}
+
+// From converts err to CodeError, the unknown errors are treated as internal errors.
+func From(err error) *CodeError {
+	if err == nil {
+		return nil
+	}
+
+	var ce *CodeError
+	if errors.As(err, &ce) {
+		return ce
+	}
+
+	var verr *binding.ValidationError
+	if errors.As(err, &verr) {
+		return Wrap(err, http.StatusBadRequest, CodeValidation, verr.Message).WithDetails(verr.Errors)
+	}
+
+	var he *echo.HTTPError
+	if errors.As(err, &he) {
+		return fromHTTPError(he)
+	}
+
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound), errors.Is(err, red.Nil):
+		return ErrNotFound.WithCause(err)
+	case errors.Is(err, breaker.ErrServiceUnavailable):
+		return ErrServiceUnavailable.WithCause(err)
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, http.ErrHandlerTimeout):
+		return ErrTimeout.WithCause(err)
+	case errors.Is(err, context.Canceled):
+		return Wrap(err, StatusClientClosedRequest, CodeClientClosed, "client closed request")
+	default:
+		return ErrInternal.WithCause(err)
+	}
+}
+
+func fromHTTPError(he *echo.HTTPError) *CodeError {
+	if he.Internal != nil {
+		var verr *binding.ValidationError
+		if errors.As(he.Internal, &verr) {
+			return From(verr)
+		}
+	}
+
+	var message string
+	switch msg := he.Message.(type) {
+	case string:
+		message = msg
+	case error:
+		message = msg.Error()
+	default:
+		message = http.StatusText(he.Code)
+	}
+
+	ce := New(he.Code, he.Code*100, message)
+	ce.cause = he.Internal
+	return ce
+}
@@ -0,0 +1,54 @@
+package errorx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	red "github.com/go-redis/redis/v8"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/zeromicro/go-zero/core/breaker"
+	"gorm.io/gorm"
+)
+
+func TestFrom(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		status int
+		code   int
+	}{
+		{"code error", fmt.Errorf("wrapped: %w", ErrForbidden), http.StatusForbidden, CodeForbidden},
+		{"record not found", fmt.Errorf("query: %w", gorm.ErrRecordNotFound), http.StatusNotFound, CodeNotFound},
+		{"redis nil", red.Nil, http.StatusNotFound, CodeNotFound},
+		{"breaker", breaker.ErrServiceUnavailable, http.StatusServiceUnavailable, CodeServiceUnavailable},
+		{"deadline", context.DeadlineExceeded, http.StatusGatewayTimeout, CodeTimeout},
+		{"canceled", context.Canceled, StatusClientClosedRequest, CodeClientClosed},
+		{"echo", echo.ErrMethodNotAllowed, http.StatusMethodNotAllowed, 40500},
+		{"unknown", errors.New("boom"), http.StatusInternalServerError, CodeInternal},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ce := From(test.err)
+			assert.Equal(t, test.status, ce.Status)
+			assert.Equal(t, test.code, ce.Code)
+		})
+	}
+
+	assert.Nil(t, From(nil))
+}
+
+func TestCodeError(t *testing.T) {
+	cause := errors.New("db down")
+	ce := Wrap(cause, http.StatusServiceUnavailable, 50301, "try later")
+	assert.True(t, errors.Is(ce, cause))
+	assert.Equal(t, "code: 50301, message: try later, cause: db down", ce.Error())
+
+	detailed := ErrBadRequest.WithDetails("name")
+	assert.Equal(t, "name", detailed.Details)
+	assert.Nil(t, ErrBadRequest.Details)
+}
@@ -0,0 +1,56 @@
+package errorx
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/valeamoris/go-ezio/core/requestid"
+	"github.com/valeamoris/go-ezio/rest/internal"
+)
+
+// Envelope is the json body of the error responses.
+type Envelope struct {
+	Code      int         `json:"code"`
+	Message   string      `json:"message"`
+	Details   interface{} `json:"details,omitempty"`
+	RequestId string      `json:"requestId,omitempty"`
+}
+
+// ErrorHandler renders the errors as Envelope, the messages and details of the server errors
+// are hidden if hideInternal, and the causes are logged with the request id.
+func ErrorHandler(hideInternal bool) echo.HTTPErrorHandler {
+	return func(err error, ctx echo.Context) {
+		ce := From(err)
+		if ce.Status >= http.StatusInternalServerError {
+			internal.Errorf(ctx, "request failed with code %d: %s", ce.Code, err.Error())
+		} else {
+			internal.Infof(ctx, "request failed with code %d: %s", ce.Code, err.Error())
+		}
+
+		if ctx.Response().Committed {
+			return
+		}
+
+		env := Envelope{
+			Code:      ce.Code,
+			Message:   ce.Message,
+			Details:   ce.Details,
+			RequestId: requestid.FromContext(ctx.Request().Context()),
+		}
+		// 未经过From转换的内部错误可能包含敏感信息
+		if hideInternal && ce.Status >= http.StatusInternalServerError {
+			env.Message = http.StatusText(ce.Status)
+			env.Details = nil
+		}
+
+		var werr error
+		if ctx.Request().Method == http.MethodHead {
+			werr = ctx.NoContent(ce.Status)
+		} else {
+			werr = ctx.JSON(ce.Status, env)
+		}
+		if werr != nil {
+			internal.Error(ctx, werr)
+		}
+	}
+}
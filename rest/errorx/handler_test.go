@@ -0,0 +1,79 @@
+package errorx
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/valeamoris/go-ezio/core/requestid"
+	"github.com/valeamoris/go-ezio/rest/binding"
+)
+
+func TestErrorHandler(t *testing.T) {
+	tests := []struct {
+		name         string
+		err          error
+		hideInternal bool
+		status       int
+		expect       Envelope
+	}{
+		{
+			name:   "code error",
+			err:    ErrNotFound.WithDetails("user"),
+			status: http.StatusNotFound,
+			expect: Envelope{Code: CodeNotFound, Message: "not found", Details: "user", RequestId: "abc"},
+		},
+		{
+			name:   "internal",
+			err:    errors.New("dial tcp 10.0.0.1:3306"),
+			status: http.StatusInternalServerError,
+			expect: Envelope{Code: CodeInternal, Message: "internal server error", RequestId: "abc"},
+		},
+		{
+			name:         "hidden",
+			err:          ErrServiceUnavailable.WithDetails("breaker of mysql"),
+			hideInternal: true,
+			status:       http.StatusServiceUnavailable,
+			expect:       Envelope{Code: CodeServiceUnavailable, Message: "Service Unavailable", RequestId: "abc"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req = req.WithContext(requestid.NewContext(req.Context(), "abc"))
+			resp := httptest.NewRecorder()
+			ErrorHandler(test.hideInternal)(test.err, echo.New().NewContext(req, resp))
+
+			assert.Equal(t, test.status, resp.Code)
+			var env Envelope
+			assert.Nil(t, json.Unmarshal(resp.Body.Bytes(), &env))
+			assert.Equal(t, test.expect, env)
+		})
+	}
+}
+
+func TestErrorHandler_Validation(t *testing.T) {
+	var v struct {
+		Name string `json:"name"`
+	}
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	resp := httptest.NewRecorder()
+	ctx := echo.New().NewContext(req, resp)
+	ErrorHandler(true)(binding.Bind(ctx, &v), ctx)
+
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+	var env struct {
+		Code    int                  `json:"code"`
+		Details []binding.FieldError `json:"details"`
+	}
+	assert.Nil(t, json.Unmarshal(resp.Body.Bytes(), &env))
+	assert.Equal(t, CodeValidation, env.Code)
+	assert.Equal(t, "name", env.Details[0].Field)
+}
@@ -0,0 +1,53 @@
+package health
+
+import (
+	"context"
+	"errors"
+
+	"github.com/valeamoris/go-ezio/broker"
+	"github.com/valeamoris/go-ezio/core/stores/redis"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrBrokerDisconnected = errors.New("broker is disconnected")
+	ErrBrokerUnsupported  = errors.New("broker doesn't report connection state")
+)
+
+type connectionState interface {
+	Connected() bool
+}
+
+func RedisChecker(node redis.Node) Checker {
+	return CheckerFunc(func(ctx context.Context) error {
+		return node.Ping(ctx).Err()
+	})
+}
+
+func GormChecker(db *gorm.DB) Checker {
+	return CheckerFunc(func(ctx context.Context) error {
+		conn, err := db.DB()
+		if err != nil {
+			return err
+		}
+
+		return conn.PingContext(ctx)
+	})
+}
+
+// BrokerChecker reports the connection state of brokers like rabbitmq,
+// which reconnect in background.
+func BrokerChecker(b broker.Broker) Checker {
+	return CheckerFunc(func(ctx context.Context) error {
+		state, ok := b.(connectionState)
+		if !ok {
+			return ErrBrokerUnsupported
+		}
+
+		if !state.Connected() {
+			return ErrBrokerDisconnected
+		}
+
+		return nil
+	})
+}
@@ -0,0 +1,270 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/zeromicro/go-zero/core/syncx"
+	"github.com/zeromicro/go-zero/core/timex"
+)
+
+const (
+	StatusUp   = "up"
+	StatusDown = "down"
+
+	defaultTimeout       = time.Second
+	defaultCacheDuration = time.Second
+)
+
+var ErrShuttingDown = errors.New("server is shutting down")
+
+type (
+	Checker interface {
+		Check(ctx context.Context) error
+	}
+
+	CheckerFunc func(ctx context.Context) error
+
+	CheckOption func(c *check)
+
+	Result struct {
+		Status    string    `json:"status"`
+		Duration  string    `json:"duration"`
+		Error     string    `json:"error,omitempty"`
+		CheckedAt time.Time `json:"checkedAt"`
+	}
+
+	Report struct {
+		Status string            `json:"status"`
+		Error  string            `json:"error,omitempty"`
+		Checks map[string]Result `json:"checks,omitempty"`
+	}
+
+	// thread-safe
+	Registry struct {
+		lock          sync.RWMutex
+		checks        []*check
+		timeout       time.Duration
+		cacheDuration time.Duration
+		shuttingDown  *syncx.AtomicBool
+	}
+
+	check struct {
+		name          string
+		checker       Checker
+		timeout       time.Duration
+		cacheDuration time.Duration
+		// 参与liveness探测，默认只参与readiness
+		liveness bool
+
+		lock   sync.Mutex
+		result *Result
+	}
+)
+
+func (f CheckerFunc) Check(ctx context.Context) error {
+	return f(ctx)
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		timeout:       defaultTimeout,
+		cacheDuration: defaultCacheDuration,
+		shuttingDown:  syncx.NewAtomicBool(),
+	}
+}
+
+// 单个检查的超时时间
+func WithTimeout(timeout time.Duration) CheckOption {
+	return func(c *check) {
+		c.timeout = timeout
+	}
+}
+
+// 检查结果的缓存时间，避免探针频繁打到下游
+func WithCacheDuration(duration time.Duration) CheckOption {
+	return func(c *check) {
+		c.cacheDuration = duration
+	}
+}
+
+// 检查同时参与liveness探测
+func WithLiveness() CheckOption {
+	return func(c *check) {
+		c.liveness = true
+	}
+}
+
+// SetDefaults sets the timeout and cache duration used by checks without their own,
+// the zero values keep the defaults, and a negative cacheDuration disables the cache.
+func (r *Registry) SetDefaults(timeout, cacheDuration time.Duration) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if timeout > 0 {
+		r.timeout = timeout
+	}
+	if cacheDuration != 0 {
+		r.cacheDuration = cacheDuration
+	}
+}
+
+func (r *Registry) Register(name string, checker Checker, opts ...CheckOption) {
+	c := &check{
+		name:          name,
+		checker:       checker,
+		cacheDuration: -1,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	for i, each := range r.checks {
+		if each.name == name {
+			r.checks[i] = c
+			return
+		}
+	}
+	r.checks = append(r.checks, c)
+}
+
+func (r *Registry) Names() []string {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	names := make([]string, 0, len(r.checks))
+	for _, c := range r.checks {
+		names = append(names, c.name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// MarkShuttingDown makes readiness fail, so that load balancers stop sending traffic.
+func (r *Registry) MarkShuttingDown() {
+	r.shuttingDown.Set(true)
+}
+
+func (r *Registry) ShuttingDown() bool {
+	return r.shuttingDown.True()
+}
+
+func (r *Registry) Liveness(ctx context.Context) Report {
+	return r.run(ctx, func(c *check) bool {
+		return c.liveness
+	})
+}
+
+func (r *Registry) Readiness(ctx context.Context) Report {
+	if r.ShuttingDown() {
+		return Report{
+			Status: StatusDown,
+			Error:  ErrShuttingDown.Error(),
+		}
+	}
+
+	return r.run(ctx, func(c *check) bool {
+		return true
+	})
+}
+
+func (r *Registry) run(ctx context.Context, filter func(c *check) bool) Report {
+	r.lock.RLock()
+	var checks []*check
+	for _, c := range r.checks {
+		if filter(c) {
+			checks = append(checks, c)
+		}
+	}
+	timeout := r.timeout
+	cacheDuration := r.cacheDuration
+	r.lock.RUnlock()
+
+	report := Report{
+		Status: StatusUp,
+	}
+	if len(checks) == 0 {
+		return report
+	}
+
+	results := make([]Result, len(checks))
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Add(1)
+		go func(i int, c *check) {
+			defer wg.Done()
+			results[i] = c.run(ctx, timeout, cacheDuration)
+		}(i, c)
+	}
+	wg.Wait()
+
+	report.Checks = make(map[string]Result, len(checks))
+	for i, c := range checks {
+		if results[i].Status != StatusUp {
+			report.Status = StatusDown
+		}
+		report.Checks[c.name] = results[i]
+	}
+
+	return report
+}
+
+func (c *check) run(ctx context.Context, timeout, cacheDuration time.Duration) Result {
+	if c.timeout > 0 {
+		timeout = c.timeout
+	}
+	if c.cacheDuration >= 0 {
+		cacheDuration = c.cacheDuration
+	}
+
+	// 并发的探测请求只执行一次，其余等待结果
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.result != nil && time.Since(c.result.CheckedAt) < cacheDuration {
+		return *c.result
+	}
+
+	start := timex.Now()
+	err := runWithTimeout(ctx, c.checker, timeout)
+	result := Result{
+		Status:    StatusUp,
+		Duration:  timex.ReprOfDuration(timex.Since(start)),
+		CheckedAt: time.Now(),
+	}
+	if err != nil {
+		result.Status = StatusDown
+		result.Error = err.Error()
+	}
+	c.result = &result
+
+	return result
+}
+
+func runWithTimeout(ctx context.Context, checker Checker, timeout time.Duration) (err error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if p := recover(); p != nil {
+				done <- errors.New("health check panicked")
+			}
+		}()
+		done <- checker.Check(ctx)
+	}()
+
+	select {
+	case err = <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
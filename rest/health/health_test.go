@@ -0,0 +1,150 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	red "github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+	"github.com/valeamoris/go-ezio/broker"
+)
+
+func TestRegistry_Empty(t *testing.T) {
+	r := NewRegistry()
+	assert.Equal(t, StatusUp, r.Liveness(context.Background()).Status)
+	assert.Equal(t, StatusUp, r.Readiness(context.Background()).Status)
+}
+
+func TestRegistry_Readiness(t *testing.T) {
+	r := NewRegistry()
+	r.Register("ok", CheckerFunc(func(ctx context.Context) error {
+		return nil
+	}))
+	r.Register("fail", CheckerFunc(func(ctx context.Context) error {
+		return errors.New("boom")
+	}))
+
+	report := r.Readiness(context.Background())
+	assert.Equal(t, StatusDown, report.Status)
+	assert.Equal(t, StatusUp, report.Checks["ok"].Status)
+	assert.Equal(t, StatusDown, report.Checks["fail"].Status)
+	assert.Equal(t, "boom", report.Checks["fail"].Error)
+
+	// readiness only checks don't take part in liveness
+	assert.Equal(t, StatusUp, r.Liveness(context.Background()).Status)
+	assert.Equal(t, []string{"fail", "ok"}, r.Names())
+}
+
+func TestRegistry_Liveness(t *testing.T) {
+	r := NewRegistry()
+	r.Register("fail", CheckerFunc(func(ctx context.Context) error {
+		return errors.New("boom")
+	}), WithLiveness())
+
+	report := r.Liveness(context.Background())
+	assert.Equal(t, StatusDown, report.Status)
+	assert.Len(t, report.Checks, 1)
+}
+
+func TestRegistry_Timeout(t *testing.T) {
+	r := NewRegistry()
+	r.Register("slow", CheckerFunc(func(ctx context.Context) error {
+		time.Sleep(time.Second)
+		return nil
+	}), WithTimeout(time.Millisecond*10))
+
+	report := r.Readiness(context.Background())
+	assert.Equal(t, StatusDown, report.Status)
+	assert.Equal(t, context.DeadlineExceeded.Error(), report.Checks["slow"].Error)
+}
+
+func TestRegistry_Panic(t *testing.T) {
+	r := NewRegistry()
+	r.Register("panic", CheckerFunc(func(ctx context.Context) error {
+		panic("boom")
+	}))
+
+	assert.Equal(t, StatusDown, r.Readiness(context.Background()).Status)
+}
+
+func TestRegistry_Cache(t *testing.T) {
+	var count int32
+	r := NewRegistry()
+	r.Register("cached", CheckerFunc(func(ctx context.Context) error {
+		atomic.AddInt32(&count, 1)
+		return nil
+	}), WithCacheDuration(time.Minute))
+	r.Register("uncached", CheckerFunc(func(ctx context.Context) error {
+		atomic.AddInt32(&count, 10)
+		return nil
+	}), WithCacheDuration(0))
+
+	for i := 0; i < 3; i++ {
+		r.Readiness(context.Background())
+	}
+	assert.Equal(t, int32(31), atomic.LoadInt32(&count))
+}
+
+func TestRegistry_SetDefaults(t *testing.T) {
+	r := NewRegistry()
+	r.SetDefaults(0, 0)
+	assert.Equal(t, defaultTimeout, r.timeout)
+	assert.Equal(t, defaultCacheDuration, r.cacheDuration)
+
+	var count int32
+	r.Register("uncached", CheckerFunc(func(ctx context.Context) error {
+		atomic.AddInt32(&count, 1)
+		return nil
+	}))
+	r.SetDefaults(time.Minute, -1)
+	assert.Equal(t, time.Minute, r.timeout)
+	for i := 0; i < 3; i++ {
+		r.Readiness(context.Background())
+	}
+	assert.Equal(t, int32(3), atomic.LoadInt32(&count))
+}
+
+func TestRegistry_ShuttingDown(t *testing.T) {
+	r := NewRegistry()
+	assert.Equal(t, StatusUp, r.Readiness(context.Background()).Status)
+	r.MarkShuttingDown()
+	report := r.Readiness(context.Background())
+	assert.Equal(t, StatusDown, report.Status)
+	assert.Equal(t, ErrShuttingDown.Error(), report.Error)
+	assert.Equal(t, StatusUp, r.Liveness(context.Background()).Status)
+}
+
+func TestRedisChecker(t *testing.T) {
+	mr, err := miniredis.Run()
+	assert.Nil(t, err)
+	defer mr.Close()
+
+	client := red.NewClient(&red.Options{Addr: mr.Addr()})
+	defer client.Close()
+	checker := RedisChecker(client)
+	assert.Nil(t, checker.Check(context.Background()))
+
+	mr.Close()
+	assert.NotNil(t, checker.Check(context.Background()))
+}
+
+type mockBroker struct {
+	broker.Broker
+	connected bool
+}
+
+func (b mockBroker) Connected() bool {
+	return b.connected
+}
+
+func TestBrokerChecker(t *testing.T) {
+	assert.Nil(t, BrokerChecker(mockBroker{connected: true}).Check(context.Background()))
+	assert.Equal(t, ErrBrokerDisconnected, BrokerChecker(mockBroker{}).Check(context.Background()))
+	assert.Equal(t, ErrBrokerUnsupported, BrokerChecker(struct {
+		broker.Broker
+	}{}).Check(context.Background()))
+}
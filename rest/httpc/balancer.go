@@ -0,0 +1,135 @@
+package httpc
+
+import (
+	"math"
+	"math/rand"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// ewma的衰减时间
+	decayTime = int64(10 * time.Second)
+	// 超过该时间未被选中的节点会被强制选中一次，以更新其延迟
+	forcePick = int64(time.Second)
+	// 初始的延迟，避免新节点一上来就承接所有请求
+	initLag     = int64(100 * time.Millisecond)
+	initSuccess = 1000
+	penalty     = int64(math.MaxInt32)
+)
+
+type (
+	endpoint struct {
+		url      *url.URL
+		inflight int64
+		// 纳秒的ewma延迟
+		lag int64
+		// 千分比的ewma成功率
+		success  uint64
+		last     int64
+		pickTime int64
+	}
+
+	// p2cBalancer picks the less loaded one from two random endpoints,
+	// the load is the ewma latency multiplied by the inflight requests and divided by the success rate.
+	p2cBalancer struct {
+		endpoints []*endpoint
+		r         *rand.Rand
+		lock      sync.Mutex
+	}
+)
+
+func newP2cBalancer(endpoints []*url.URL) *p2cBalancer {
+	b := &p2cBalancer{
+		r: rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+	now := time.Now().UnixNano()
+	for _, u := range endpoints {
+		b.endpoints = append(b.endpoints, &endpoint{
+			url:     u,
+			lag:     initLag,
+			success: initSuccess,
+			last:    now,
+		})
+	}
+
+	return b
+}
+
+func (b *p2cBalancer) pick() *endpoint {
+	var chosen *endpoint
+	switch len(b.endpoints) {
+	case 0:
+		return nil
+	case 1:
+		chosen = b.endpoints[0]
+	default:
+		b.lock.Lock()
+		a := b.r.Intn(len(b.endpoints))
+		c := b.r.Intn(len(b.endpoints) - 1)
+		b.lock.Unlock()
+		if c >= a {
+			c++
+		}
+		chosen = b.choose(b.endpoints[a], b.endpoints[c])
+	}
+
+	atomic.AddInt64(&chosen.inflight, 1)
+	return chosen
+}
+
+func (b *p2cBalancer) choose(e1, e2 *endpoint) *endpoint {
+	if e1.load() > e2.load() {
+		e1, e2 = e2, e1
+	}
+
+	now := time.Now().UnixNano()
+	pick := atomic.LoadInt64(&e2.pickTime)
+	if now-pick > forcePick && atomic.CompareAndSwapInt64(&e2.pickTime, pick, now) {
+		return e2
+	}
+
+	atomic.StoreInt64(&e1.pickTime, now)
+	return e1
+}
+
+// done updates the ewma latency and success rate of the endpoint.
+func (e *endpoint) done(start time.Time, success bool) {
+	atomic.AddInt64(&e.inflight, -1)
+
+	now := time.Now().UnixNano()
+	last := atomic.SwapInt64(&e.last, now)
+	td := now - last
+	if td < 0 {
+		td = 0
+	}
+	w := math.Exp(float64(-td) / float64(decayTime))
+
+	lag := now - start.UnixNano()
+	if lag < 0 {
+		lag = 0
+	}
+	olag := atomic.LoadInt64(&e.lag)
+	atomic.StoreInt64(&e.lag, int64(float64(olag)*w+float64(lag)*(1-w)))
+
+	var ok uint64
+	if success {
+		ok = initSuccess
+	}
+	osucc := atomic.LoadUint64(&e.success)
+	atomic.StoreUint64(&e.success, uint64(float64(osucc)*w+float64(ok)*(1-w)))
+}
+
+func (e *endpoint) load() int64 {
+	// +1避免inflight为0时负载为0
+	lag := int64(math.Sqrt(float64(atomic.LoadInt64(&e.lag) + 1)))
+	load := lag * (atomic.LoadInt64(&e.inflight) + 1)
+	success := atomic.LoadUint64(&e.success)
+	if success == 0 {
+		return penalty
+	}
+
+	return load * initSuccess / int64(success)
+}
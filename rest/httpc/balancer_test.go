@@ -0,0 +1,38 @@
+package httpc
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestP2cBalancer(t *testing.T) {
+	assert.Nil(t, newP2cBalancer(nil).pick())
+
+	var endpoints []*url.URL
+	for _, host := range []string{"a", "b"} {
+		endpoints = append(endpoints, &url.URL{Scheme: "http", Host: host})
+	}
+	b := newP2cBalancer(endpoints)
+	slow, fast := b.endpoints[0], b.endpoints[1]
+
+	// a一直失败且很慢，之后应该大部分请求落到b
+	for i := 0; i < 100; i++ {
+		slow.inflight++
+		slow.done(time.Now().Add(-time.Second), false)
+		fast.inflight++
+		fast.done(time.Now(), true)
+	}
+	assert.True(t, slow.load() > fast.load())
+
+	counts := make(map[string]int)
+	for i := 0; i < 1000; i++ {
+		e := b.pick()
+		counts[e.url.Host]++
+		e.done(time.Now(), true)
+	}
+	assert.True(t, counts["b"] > counts["a"], counts)
+	assert.Equal(t, int64(0), slow.inflight+fast.inflight)
+}
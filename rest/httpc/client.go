@@ -0,0 +1,342 @@
+// Package httpc is the http client to call the other services, with the breaker per host,
+// retries of the idempotent methods, tracing, request id propagation, metrics and p2c load balancing.
+package httpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/opentracing/opentracing-go/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/valeamoris/go-ezio/core/requestid"
+	"github.com/zeromicro/go-zero/core/breaker"
+)
+
+const (
+	headerRetryAfter = "Retry-After"
+	// 重试前读取并丢弃的响应body，以复用连接
+	maxDrainBytes = 4096
+	codeError     = "error"
+)
+
+var errNoEndpoint = errors.New("httpc: no endpoint configured for the relative url")
+
+type (
+	Option func(c *Client)
+
+	Client struct {
+		conf     Conf
+		name     string
+		client   *http.Client
+		tracer   opentracing.Tracer
+		metrics  metricsOptions
+		stat     *metrics
+		balancer *p2cBalancer
+		breakers map[string]breaker.Breaker
+		lock     sync.Mutex
+	}
+
+	// cancelBody cancels the timeout context of the call after the body is closed.
+	cancelBody struct {
+		io.ReadCloser
+		cancel context.CancelFunc
+	}
+)
+
+// 默认使用http.DefaultClient
+func WithHTTPClient(client *http.Client) Option {
+	return func(c *Client) {
+		c.client = client
+	}
+}
+
+// 默认使用opentracing.GlobalTracer()，B3等header由tracer的propagator决定
+func WithTracer(tracer opentracing.Tracer) Option {
+	return func(c *Client) {
+		c.tracer = tracer
+	}
+}
+
+// 默认注册到prometheus.DefaultRegisterer
+func WithPrometheus(registerer prometheus.Registerer, namespace string, buckets ...float64) Option {
+	return func(c *Client) {
+		c.metrics.registerer = registerer
+		c.metrics.namespace = namespace
+		if len(buckets) > 0 {
+			c.metrics.buckets = buckets
+		}
+	}
+}
+
+func MustNewClient(c Conf, opts ...Option) *Client {
+	client, err := NewClient(c, opts...)
+	if err != nil {
+		panic(err)
+	}
+
+	return client
+}
+
+func NewClient(c Conf, opts ...Option) (*Client, error) {
+	var endpoints []*url.URL
+	for _, endpoint := range c.Endpoints {
+		u, err := url.Parse(endpoint)
+		if err != nil {
+			return nil, err
+		}
+		if len(u.Scheme) == 0 || len(u.Host) == 0 {
+			return nil, fmt.Errorf("httpc: bad endpoint %q, scheme and host are required", endpoint)
+		}
+		endpoints = append(endpoints, u)
+	}
+
+	client := &Client{
+		conf:   c,
+		name:   c.Name,
+		client: http.DefaultClient,
+		tracer: opentracing.GlobalTracer(),
+		metrics: metricsOptions{
+			registerer: prometheus.DefaultRegisterer,
+			buckets:    defaultBuckets,
+		},
+		balancer: newP2cBalancer(endpoints),
+		breakers: make(map[string]breaker.Breaker),
+	}
+	for _, opt := range opts {
+		opt(client)
+	}
+	client.stat = newMetrics(client.metrics)
+
+	return client, nil
+}
+
+// Do sends the request, the relative url is resolved against the endpoints.
+// The idempotent methods are retried on the network errors, 429, 502, 503 and 504,
+// the request body must be rewindable by GetBody, like the ones created by http.NewRequest.
+// The returned response must be closed, and the non-2xx responses are not treated as errors.
+func (c *Client) Do(ctx context.Context, req *http.Request) (*http.Response, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	req = req.WithContext(ctx)
+
+	attempts := 1
+	if retryable(req) {
+		attempts += c.conf.MaxRetries
+	}
+
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				cancel()
+				return nil, err
+			}
+			req.Body = body
+		}
+
+		resp, err := c.attempt(ctx, req)
+		if attempt+1 >= attempts || !shouldRetry(ctx, resp, err) {
+			if err != nil {
+				cancel()
+				return nil, err
+			}
+
+			resp.Body = cancelBody{ReadCloser: resp.Body, cancel: cancel}
+			return resp, nil
+		}
+
+		wait := c.backoff(attempt, resp)
+		if resp != nil {
+			_, _ = io.CopyN(ioutil.Discard, resp.Body, maxDrainBytes)
+			_ = resp.Body.Close()
+		}
+		c.stat.retries.WithLabelValues(c.clientName(req), req.Method).Inc()
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			cancel()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+func (c *Client) attempt(ctx context.Context, req *http.Request) (resp *http.Response, err error) {
+	r := req.Clone(ctx)
+	var ep *endpoint
+	if len(r.URL.Host) == 0 {
+		if ep = c.balancer.pick(); ep == nil {
+			return nil, errNoEndpoint
+		}
+		resolve(r, ep.url)
+	}
+
+	if id := requestid.FromContext(ctx); len(id) > 0 && len(r.Header.Get(requestid.Header)) == 0 {
+		r.Header.Set(requestid.Header, id)
+	}
+
+	sp := c.startSpan(ctx, r)
+	start := time.Now()
+	defer func() {
+		code := codeError
+		success := err == nil
+		if resp != nil {
+			code = strconv.Itoa(resp.StatusCode)
+			success = resp.StatusCode < http.StatusInternalServerError
+			ext.HTTPStatusCode.Set(sp, uint16(resp.StatusCode))
+		}
+		if !success {
+			ext.Error.Set(sp, true)
+		}
+		if err != nil {
+			sp.LogFields(log.Error(err))
+		}
+		sp.Finish()
+
+		if ep != nil {
+			ep.done(start, success)
+		}
+		name := c.clientName(r)
+		c.stat.reqDur.WithLabelValues(name, r.Method).Observe(time.Since(start).Seconds())
+		c.stat.reqCnt.WithLabelValues(name, r.Method, code).Inc()
+	}()
+
+	promise, err := c.breaker(r.URL.Host).Allow()
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err = c.client.Do(r)
+	if err != nil {
+		promise.Reject(err.Error())
+	} else if resp.StatusCode >= http.StatusInternalServerError {
+		promise.Reject(fmt.Sprintf("%d %s", resp.StatusCode, http.StatusText(resp.StatusCode)))
+	} else {
+		promise.Accept()
+	}
+
+	return resp, err
+}
+
+func (c *Client) startSpan(ctx context.Context, r *http.Request) opentracing.Span {
+	opts := []opentracing.StartSpanOption{ext.SpanKindRPCClient}
+	if parent := opentracing.SpanFromContext(ctx); parent != nil {
+		opts = append(opts, opentracing.ChildOf(parent.Context()))
+	}
+
+	sp := c.tracer.StartSpan("HTTP "+r.Method, opts...)
+	ext.HTTPMethod.Set(sp, r.Method)
+	ext.HTTPUrl.Set(sp, r.URL.String())
+	ext.PeerHostname.Set(sp, r.URL.Hostname())
+	_ = c.tracer.Inject(sp.Context(), opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(r.Header))
+
+	return sp
+}
+
+func (c *Client) breaker(host string) breaker.Breaker {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	brk, ok := c.breakers[host]
+	if !ok {
+		brk = breaker.NewBreaker(breaker.WithName(host))
+		c.breakers[host] = brk
+	}
+
+	return brk
+}
+
+// backoff is the exponential backoff with jitter, Retry-After of the response is used if not too long.
+func (c *Client) backoff(attempt int, resp *http.Response) time.Duration {
+	max := time.Duration(c.conf.MaxRetryWait) * time.Millisecond
+	if resp != nil {
+		if seconds, err := strconv.Atoi(resp.Header.Get(headerRetryAfter)); err == nil && seconds >= 0 {
+			if wait := time.Duration(seconds) * time.Second; wait <= max {
+				return wait
+			}
+		}
+	}
+
+	wait := time.Duration(c.conf.RetryWait) * time.Millisecond << uint(attempt)
+	if wait > max || wait <= 0 {
+		wait = max
+	}
+	if wait <= 0 {
+		return 0
+	}
+
+	// 在[wait/2, wait)之间随机，避免重试集中
+	half := wait / 2
+	return half + time.Duration(rand.Int63n(int64(wait-half)+1))
+}
+
+func (c *Client) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if c.conf.Timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+
+	return context.WithTimeout(ctx, time.Duration(c.conf.Timeout)*time.Millisecond)
+}
+
+func (c *Client) clientName(r *http.Request) string {
+	if len(c.name) > 0 {
+		return c.name
+	}
+
+	return r.URL.Host
+}
+
+func (b cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+func resolve(r *http.Request, base *url.URL) {
+	r.URL.Scheme = base.Scheme
+	r.URL.Host = base.Host
+	if len(base.Path) > 0 && base.Path != "/" {
+		r.URL.Path = path.Join(base.Path, r.URL.Path)
+		r.URL.RawPath = ""
+	}
+	r.Host = ""
+}
+
+func retryable(r *http.Request) bool {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete, http.MethodTrace:
+	default:
+		return false
+	}
+
+	return r.Body == nil || r.Body == http.NoBody || r.GetBody != nil
+}
+
+func shouldRetry(ctx context.Context, resp *http.Response, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	if err != nil {
+		return true
+	}
+
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,203 @@
+package httpc
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/mocktracer"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/valeamoris/go-ezio/core/requestid"
+	"github.com/zeromicro/go-zero/core/breaker"
+)
+
+func newTestClient(t *testing.T, c Conf, opts ...Option) *Client {
+	if c.Timeout == 0 {
+		c.Timeout = 3000
+	}
+	if c.RetryWait == 0 {
+		c.RetryWait = 1
+		c.MaxRetryWait = 10
+	}
+	opts = append([]Option{WithPrometheus(prometheus.NewRegistry(), "")}, opts...)
+	client, err := NewClient(c, opts...)
+	assert.Nil(t, err)
+	return client
+}
+
+func TestClientRetry(t *testing.T) {
+	var calls int32
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		if atomic.AddInt32(&calls, 1)%3 != 0 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write(body)
+	}))
+	defer svr.Close()
+
+	registry := prometheus.NewRegistry()
+	c := newTestClient(t, Conf{Name: "test", MaxRetries: 2}, WithPrometheus(registry, "ns"))
+	req, err := http.NewRequest(http.MethodPut, svr.URL, strings.NewReader("hello"))
+	assert.Nil(t, err)
+	resp, err := c.Do(context.Background(), req)
+	assert.Nil(t, err)
+	body, err := ioutil.ReadAll(resp.Body)
+	assert.Nil(t, err)
+	assert.Nil(t, resp.Body.Close())
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "hello", string(body))
+	assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+	assert.Equal(t, float64(2), testutil.ToFloat64(c.stat.reqCnt.WithLabelValues("test", http.MethodPut, "503")))
+	families, err := registry.Gather()
+	assert.Nil(t, err)
+	assert.Len(t, families, 3)
+	assert.Equal(t, float64(2), testutil.ToFloat64(c.stat.retries.WithLabelValues("test", http.MethodPut)))
+
+	// POST不是幂等的，不重试
+	atomic.StoreInt32(&calls, 0)
+	req, err = http.NewRequest(http.MethodPost, svr.URL, nil)
+	assert.Nil(t, err)
+	resp, err = c.Do(context.Background(), req)
+	assert.Nil(t, err)
+	assert.Nil(t, resp.Body.Close())
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
+
+func TestClientNoRetryOnClientError(t *testing.T) {
+	var calls int32
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer svr.Close()
+
+	c := newTestClient(t, Conf{MaxRetries: 2})
+	err := c.Get(context.Background(), svr.URL, nil)
+	var re *ResponseError
+	assert.True(t, errors.As(err, &re))
+	assert.Equal(t, http.StatusBadRequest, re.StatusCode)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
+
+func TestClientTimeout(t *testing.T) {
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(time.Second):
+		case <-r.Context().Done():
+		}
+	}))
+	defer svr.Close()
+
+	c := newTestClient(t, Conf{Timeout: 50, MaxRetries: 2})
+	start := time.Now()
+	err := c.Get(context.Background(), svr.URL, nil)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	assert.True(t, time.Since(start) < time.Second)
+
+	// context的deadline更早
+	c = newTestClient(t, Conf{MaxRetries: 2})
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err = c.Get(ctx, svr.URL, nil)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+}
+
+func TestClientPropagation(t *testing.T) {
+	var header http.Header
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header.Clone()
+	}))
+	defer svr.Close()
+
+	tracer := mocktracer.New()
+	c := newTestClient(t, Conf{}, WithTracer(tracer))
+	parent := tracer.StartSpan("parent")
+	ctx := opentracing.ContextWithSpan(context.Background(), parent)
+	ctx = requestid.NewContext(ctx, "abc")
+	assert.Nil(t, c.Get(ctx, svr.URL+"/users", nil))
+	parent.Finish()
+
+	assert.Equal(t, "abc", header.Get(requestid.Header))
+	spans := tracer.FinishedSpans()
+	assert.Len(t, spans, 2)
+	assert.Equal(t, "HTTP GET", spans[0].OperationName)
+	assert.Equal(t, parent.Context().(mocktracer.MockSpanContext).SpanID, spans[0].ParentID)
+	assert.Equal(t, uint16(http.StatusOK), spans[0].Tag("http.status_code"))
+	assert.NotEmpty(t, header.Get("Mockpfx-Ids-Spanid"))
+}
+
+func TestClientBreaker(t *testing.T) {
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer svr.Close()
+
+	c := newTestClient(t, Conf{})
+	var rejected bool
+	for i := 0; i < 1000 && !rejected; i++ {
+		err := c.Get(context.Background(), svr.URL, nil)
+		rejected = errors.Is(err, breaker.ErrServiceUnavailable)
+	}
+	assert.True(t, rejected)
+}
+
+func TestClientEndpoints(t *testing.T) {
+	var hits [2]int32
+	newServer := func(i int) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/v1/users", r.URL.Path)
+			atomic.AddInt32(&hits[i], 1)
+			_, _ = w.Write([]byte(`{"name":"kevin"}`))
+		}))
+	}
+	svr1, svr2 := newServer(0), newServer(1)
+	defer svr1.Close()
+	defer svr2.Close()
+
+	c := newTestClient(t, Conf{Endpoints: []string{svr1.URL + "/v1", svr2.URL + "/v1"}})
+	for i := 0; i < 100; i++ {
+		var user struct {
+			Name string `json:"name"`
+		}
+		assert.Nil(t, c.Get(context.Background(), "/users", &user))
+		assert.Equal(t, "kevin", user.Name)
+	}
+	assert.True(t, atomic.LoadInt32(&hits[0]) > 0)
+	assert.True(t, atomic.LoadInt32(&hits[1]) > 0)
+
+	c = newTestClient(t, Conf{})
+	assert.Equal(t, errNoEndpoint, c.Get(context.Background(), "/users", nil))
+
+	_, err := NewClient(Conf{Endpoints: []string{"localhost:8080"}})
+	assert.NotNil(t, err)
+}
+
+func TestBackoff(t *testing.T) {
+	c := newTestClient(t, Conf{RetryWait: 100, MaxRetryWait: 1000})
+	for attempt := 0; attempt < 5; attempt++ {
+		wait := c.backoff(attempt, nil)
+		expect := 100 * time.Millisecond << uint(attempt)
+		if expect > time.Second {
+			expect = time.Second
+		}
+		assert.True(t, wait >= expect/2 && wait <= expect, wait)
+	}
+
+	resp := &http.Response{Header: http.Header{}}
+	resp.Header.Set(headerRetryAfter, "1")
+	assert.Equal(t, time.Second, c.backoff(0, resp))
+	resp.Header.Set(headerRetryAfter, "5")
+	assert.True(t, c.backoff(0, resp) <= 100*time.Millisecond)
+}
@@ -0,0 +1,15 @@
+package httpc
+
+type Conf struct {
+	// 用于metrics的服务名
+	Name string `json:",optional"`
+	// 如http://10.0.0.1:8080，多个时使用p2c负载均衡，为空时请求需要完整的url
+	Endpoints []string `json:",optional"`
+	// milliseconds, 整个调用包括重试的超时时间，context的deadline更早时以context为准
+	Timeout int64 `json:",default=3000"`
+	// 只有幂等的方法才会重试
+	MaxRetries int `json:",default=2,range=[0:10]"`
+	// milliseconds, 指数退避的初始和最大等待时间
+	RetryWait    int64 `json:",default=100"`
+	MaxRetryWait int64 `json:",default=2000"`
+}
@@ -0,0 +1,134 @@
+package httpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// 错误响应保留的最大body
+const maxErrorBody = 64 << 10
+
+type (
+	// ResponseError is returned for the non-2xx responses, Code, Message, Details and RequestId
+	// are decoded from the errorx envelope if the body is one.
+	ResponseError struct {
+		StatusCode int
+		Code       int
+		Message    string
+		Details    json.RawMessage
+		RequestId  string
+		// 原始的body，最多64KB
+		Body []byte
+	}
+
+	envelope struct {
+		Code      int             `json:"code"`
+		Message   string          `json:"message"`
+		Details   json.RawMessage `json:"details"`
+		RequestId string          `json:"requestId"`
+	}
+)
+
+func (e *ResponseError) Error() string {
+	if len(e.Message) > 0 {
+		return fmt.Sprintf("httpc: status %d, code %d: %s", e.StatusCode, e.Code, e.Message)
+	}
+
+	return fmt.Sprintf("httpc: status %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+}
+
+func (c *Client) Get(ctx context.Context, url string, out interface{}) error {
+	return c.DoJSON(ctx, http.MethodGet, url, nil, out)
+}
+
+func (c *Client) Post(ctx context.Context, url string, in, out interface{}) error {
+	return c.DoJSON(ctx, http.MethodPost, url, in, out)
+}
+
+func (c *Client) Put(ctx context.Context, url string, in, out interface{}) error {
+	return c.DoJSON(ctx, http.MethodPut, url, in, out)
+}
+
+func (c *Client) Patch(ctx context.Context, url string, in, out interface{}) error {
+	return c.DoJSON(ctx, http.MethodPatch, url, in, out)
+}
+
+func (c *Client) Delete(ctx context.Context, url string, out interface{}) error {
+	return c.DoJSON(ctx, http.MethodDelete, url, nil, out)
+}
+
+// DoJSON sends in as the json body if not nil, and decodes the 2xx response into out if not nil,
+// the non-2xx responses are returned as *ResponseError.
+func (c *Client) DoJSON(ctx context.Context, method, url string, in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		content, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(content)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set(echo.HeaderAccept, echo.MIMEApplicationJSON)
+	if in != nil {
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSONCharsetUTF8)
+	}
+
+	resp, err := c.Do(ctx, req)
+	if err != nil {
+		return err
+	}
+
+	return Parse(resp, out)
+}
+
+// Parse decodes the 2xx json response into out if not nil and closes the body,
+// the non-2xx responses are returned as *ResponseError.
+func Parse(resp *http.Response, out interface{}) error {
+	defer resp.Body.Close()
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return parseError(resp)
+	}
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		_, _ = io.Copy(ioutil.Discard, resp.Body)
+		return nil
+	}
+
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+func parseError(resp *http.Response) error {
+	content, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+	if err != nil {
+		return err
+	}
+
+	re := &ResponseError{
+		StatusCode: resp.StatusCode,
+		RequestId:  resp.Header.Get(echo.HeaderXRequestID),
+		Body:       content,
+	}
+	var env envelope
+	if json.Unmarshal(content, &env) == nil && env.Code != 0 {
+		re.Code = env.Code
+		re.Message = env.Message
+		re.Details = env.Details
+		if len(env.RequestId) > 0 {
+			re.RequestId = env.RequestId
+		}
+	}
+
+	return re
+}
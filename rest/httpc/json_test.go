@@ -0,0 +1,58 @@
+package httpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDoJSON(t *testing.T) {
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, echo.MIMEApplicationJSON, r.Header.Get(echo.HeaderAccept))
+		switch r.URL.Path {
+		case "/echo":
+			assert.Equal(t, echo.MIMEApplicationJSONCharsetUTF8, r.Header.Get(echo.HeaderContentType))
+			var in map[string]string
+			assert.Nil(t, json.NewDecoder(r.Body).Decode(&in))
+			_ = json.NewEncoder(w).Encode(in)
+		case "/empty":
+			w.WriteHeader(http.StatusNoContent)
+		case "/missing":
+			w.Header().Set(echo.HeaderXRequestID, "from-header")
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"code":40400,"message":"user not found","details":{"id":1},"requestId":"abc"}`))
+		default:
+			w.WriteHeader(http.StatusBadGateway)
+			_, _ = w.Write([]byte("bad gateway"))
+		}
+	}))
+	defer svr.Close()
+
+	c := newTestClient(t, Conf{})
+	var out map[string]string
+	assert.Nil(t, c.Post(context.Background(), svr.URL+"/echo", map[string]string{"a": "b"}, &out))
+	assert.Equal(t, map[string]string{"a": "b"}, out)
+	assert.Nil(t, c.Delete(context.Background(), svr.URL+"/empty", &out))
+
+	err := c.Get(context.Background(), svr.URL+"/missing", &out)
+	var re *ResponseError
+	assert.True(t, errors.As(err, &re))
+	assert.Equal(t, http.StatusNotFound, re.StatusCode)
+	assert.Equal(t, 40400, re.Code)
+	assert.Equal(t, "user not found", re.Message)
+	assert.JSONEq(t, `{"id":1}`, string(re.Details))
+	assert.Equal(t, "abc", re.RequestId)
+	assert.Equal(t, "httpc: status 404, code 40400: user not found", err.Error())
+
+	err = c.Post(context.Background(), svr.URL+"/other", nil, nil)
+	assert.True(t, errors.As(err, &re))
+	assert.Equal(t, 0, re.Code)
+	assert.Equal(t, "bad gateway", string(re.Body))
+	assert.Equal(t, "httpc: status 502 Bad Gateway", err.Error())
+}
@@ -0,0 +1,49 @@
+package httpc
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/valeamoris/go-ezio/rest/internal"
+)
+
+const defaultSubsystem = "http_client"
+
+var defaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+type (
+	metricsOptions struct {
+		registerer prometheus.Registerer
+		namespace  string
+		buckets    []float64
+	}
+
+	// metrics are labelled by the client name instead of the url to keep the cardinality low.
+	metrics struct {
+		reqCnt  *prometheus.CounterVec
+		reqDur  *prometheus.HistogramVec
+		retries *prometheus.CounterVec
+	}
+)
+
+func newMetrics(o metricsOptions) *metrics {
+	return &metrics{
+		reqCnt: internal.RegisterCollector(o.registerer, prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: o.namespace,
+			Subsystem: defaultSubsystem,
+			Name:      "requests_total",
+			Help:      "How many HTTP requests sent, partitioned by client, method and status code.",
+		}, []string{"client", "method", "code"})).(*prometheus.CounterVec),
+		reqDur: internal.RegisterCollector(o.registerer, prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: o.namespace,
+			Subsystem: defaultSubsystem,
+			Name:      "request_duration_seconds",
+			Help:      "The HTTP request latencies in seconds.",
+			Buckets:   o.buckets,
+		}, []string{"client", "method"})).(*prometheus.HistogramVec),
+		retries: internal.RegisterCollector(o.registerer, prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: o.namespace,
+			Subsystem: defaultSubsystem,
+			Name:      "retries_total",
+			Help:      "How many HTTP requests retried, partitioned by client and method.",
+		}, []string{"client", "method"})).(*prometheus.CounterVec),
+	}
+}
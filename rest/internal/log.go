@@ -4,11 +4,17 @@ import (
 	"bytes"
 	"fmt"
 	"github.com/labstack/echo/v4"
+	"github.com/valeamoris/go-ezio/core/requestid"
 	"github.com/zeromicro/go-zero/core/logx"
+	"net/http"
 	"sync"
 )
 
-var LogContext = contextKey("request_logs")
+var (
+	LogContext = contextKey("request_logs")
+	// 访问日志中间件放入的脱敏后的uri
+	UriContext = contextKey("request_uri")
+)
 
 type LogCollector struct {
 	Messages []string
@@ -77,8 +83,21 @@ func formatf(ctx echo.Context, format string, v ...interface{}) string {
 	return formatWithCtx(ctx, fmt.Sprintf(format, v...))
 }
 
+// Uri returns the request uri to log, with the secrets in the query redacted by the access log middleware.
+func Uri(r *http.Request) string {
+	if uri, ok := r.Context().Value(UriContext).(string); ok {
+		return uri
+	}
+
+	return r.RequestURI
+}
+
 func formatWithCtx(ctx echo.Context, v string) string {
-	return fmt.Sprintf("(%s - %s) %s", ctx.Request().RequestURI, ctx.RealIP(), v)
+	if id := requestid.FromContext(ctx.Request().Context()); len(id) > 0 {
+		return fmt.Sprintf("(%s - %s - %s) %s", Uri(ctx.Request()), ctx.RealIP(), id, v)
+	}
+
+	return fmt.Sprintf("(%s - %s) %s", Uri(ctx.Request()), ctx.RealIP(), v)
 }
 
 type contextKey string
@@ -0,0 +1,20 @@
+package internal
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+// RegisterCollector registers the collector, or returns the one already registered,
+// so that the metrics can be created more than once with the same registerer.
+func RegisterCollector(registerer prometheus.Registerer, collector prometheus.Collector) prometheus.Collector {
+	if err := registerer.Register(collector); err != nil {
+		if are, ok := err.(prometheus.AlreadyRegisteredError); ok {
+			return are.ExistingCollector
+		}
+
+		logx.Errorf("prometheus collector could not be registered: %s", err.Error())
+	}
+
+	return collector
+}
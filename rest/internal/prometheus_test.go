@@ -0,0 +1,18 @@
+package internal
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegisterCollector(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	newCounter := func() prometheus.Collector {
+		return prometheus.NewCounter(prometheus.CounterOpts{Name: "requests_total", Help: "requests"})
+	}
+
+	first := RegisterCollector(registry, newCounter())
+	assert.Same(t, first, RegisterCollector(registry, newCounter()))
+}
@@ -0,0 +1,89 @@
+package internal
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/uber/jaeger-client-go"
+)
+
+const (
+	traceparentHeader = "traceparent"
+	traceparentFormat = "00-%016x%016x-%016x-%02x"
+	traceparentLength = 55
+	sampledFlag       = 0x01
+)
+
+// W3CPropagator injects and extracts jaeger span contexts with the W3C traceparent header.
+type W3CPropagator struct{}
+
+func (p W3CPropagator) Inject(ctx jaeger.SpanContext, abstractCarrier interface{}) error {
+	carrier, ok := abstractCarrier.(opentracing.TextMapWriter)
+	if !ok {
+		return opentracing.ErrInvalidCarrier
+	}
+
+	var flags byte
+	if ctx.IsSampled() {
+		flags |= sampledFlag
+	}
+	carrier.Set(traceparentHeader, fmt.Sprintf(traceparentFormat,
+		ctx.TraceID().High, ctx.TraceID().Low, uint64(ctx.SpanID()), flags))
+	return nil
+}
+
+func (p W3CPropagator) Extract(abstractCarrier interface{}) (jaeger.SpanContext, error) {
+	carrier, ok := abstractCarrier.(opentracing.TextMapReader)
+	if !ok {
+		return jaeger.SpanContext{}, opentracing.ErrInvalidCarrier
+	}
+
+	var traceparent string
+	err := carrier.ForeachKey(func(key, val string) error {
+		if strings.EqualFold(key, traceparentHeader) {
+			traceparent = val
+		}
+		return nil
+	})
+	if err != nil {
+		return jaeger.SpanContext{}, err
+	}
+	if len(traceparent) == 0 {
+		return jaeger.SpanContext{}, opentracing.ErrSpanContextNotFound
+	}
+
+	return parseTraceparent(traceparent)
+}
+
+func parseTraceparent(traceparent string) (jaeger.SpanContext, error) {
+	parts := strings.Split(strings.TrimSpace(traceparent), "-")
+	if len(traceparent) < traceparentLength || len(parts) < 4 || len(parts[1]) != 32 || len(parts[2]) != 16 {
+		return jaeger.SpanContext{}, opentracing.ErrSpanContextCorrupted
+	}
+
+	high, err := strconv.ParseUint(parts[1][:16], 16, 64)
+	if err != nil {
+		return jaeger.SpanContext{}, opentracing.ErrSpanContextCorrupted
+	}
+	low, err := strconv.ParseUint(parts[1][16:], 16, 64)
+	if err != nil {
+		return jaeger.SpanContext{}, opentracing.ErrSpanContextCorrupted
+	}
+	spanID, err := strconv.ParseUint(parts[2], 16, 64)
+	if err != nil {
+		return jaeger.SpanContext{}, opentracing.ErrSpanContextCorrupted
+	}
+	flags, err := strconv.ParseUint(parts[3], 16, 8)
+	if err != nil {
+		return jaeger.SpanContext{}, opentracing.ErrSpanContextCorrupted
+	}
+
+	traceID := jaeger.TraceID{High: high, Low: low}
+	if !traceID.IsValid() || spanID == 0 {
+		return jaeger.SpanContext{}, opentracing.ErrSpanContextCorrupted
+	}
+
+	return jaeger.NewSpanContext(traceID, jaeger.SpanID(spanID), 0, flags&sampledFlag == sampledFlag, nil), nil
+}
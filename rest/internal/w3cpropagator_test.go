@@ -0,0 +1,43 @@
+package internal
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/uber/jaeger-client-go"
+)
+
+func TestW3CPropagator(t *testing.T) {
+	var p W3CPropagator
+	ctx := jaeger.NewSpanContext(jaeger.TraceID{High: 1, Low: 2}, 3, 0, true, nil)
+	header := http.Header{}
+	assert.Nil(t, p.Inject(ctx, opentracing.HTTPHeadersCarrier(header)))
+	assert.Equal(t, "00-00000000000000010000000000000002-0000000000000003-01", header.Get("traceparent"))
+
+	extracted, err := p.Extract(opentracing.HTTPHeadersCarrier(header))
+	assert.Nil(t, err)
+	assert.Equal(t, ctx.TraceID(), extracted.TraceID())
+	assert.Equal(t, ctx.SpanID(), extracted.SpanID())
+	assert.True(t, extracted.IsSampled())
+}
+
+func TestW3CPropagator_Invalid(t *testing.T) {
+	var p W3CPropagator
+	_, err := p.Extract(opentracing.HTTPHeadersCarrier(http.Header{}))
+	assert.Equal(t, opentracing.ErrSpanContextNotFound, err)
+
+	header := http.Header{}
+	header.Set("traceparent", "00-xyz-0000000000000003-01")
+	_, err = p.Extract(opentracing.HTTPHeadersCarrier(header))
+	assert.Equal(t, opentracing.ErrSpanContextCorrupted, err)
+
+	header.Set("traceparent", "00-00000000000000000000000000000000-0000000000000003-01")
+	_, err = p.Extract(opentracing.HTTPHeadersCarrier(header))
+	assert.Equal(t, opentracing.ErrSpanContextCorrupted, err)
+
+	_, err = p.Extract("invalid")
+	assert.Equal(t, opentracing.ErrInvalidCarrier, err)
+	assert.Equal(t, opentracing.ErrInvalidCarrier, p.Inject(jaeger.SpanContext{}, "invalid"))
+}
@@ -0,0 +1,205 @@
+package middleware
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	red "github.com/go-redis/redis/v8"
+	"github.com/labstack/echo/v4"
+	"github.com/valeamoris/go-ezio/core/stores/redis"
+	"github.com/zeromicro/go-zero/core/collection"
+)
+
+const (
+	HeaderApiKey          = "X-Api-Key"
+	defaultApiKeyPrefix   = "apikey:"
+	defaultApiKeyCacheTTL = time.Minute
+	defaultApiKeyCacheMax = 10000
+)
+
+type (
+	// Principal is the client authenticated by the api key or the hmac signature, it's set in the context
+	// as the claims of a *jwt.Token with the key "user", the same as the JWT middleware.
+	Principal struct {
+		Subject  string            `json:"sub"`
+		Roles    []string          `json:"roles,omitempty"`
+		Scopes   []string          `json:"scopes,omitempty"`
+		Metadata map[string]string `json:"metadata,omitempty"`
+	}
+
+	// ApiKeyStore finds the principal of the api key, the principal is nil if the key doesn't exist.
+	ApiKeyStore interface {
+		FindApiKey(ctx context.Context, key string) (*Principal, error)
+	}
+
+	// StaticApiKeys is the ApiKeyStore of the fixed keys, like the keys in the config.
+	StaticApiKeys map[string]Principal
+
+	// RedisApiKeyStore saves the principals in redis, keyed by the sha256 of the api keys.
+	RedisApiKeyStore struct {
+		store  redis.Node
+		prefix string
+	}
+
+	cachedApiKeyStore struct {
+		store ApiKeyStore
+		cache *collection.Cache
+	}
+
+	ApiKeyOptions struct {
+		// 存放api key的header，默认X-Api-Key
+		Header string
+		// 存放api key的query参数，为空时不从query获取，通过rest.Server使用时访问日志和审计记录中会脱敏
+		Query string
+	}
+)
+
+// ApiKeyMiddleware authenticates the requests by the api keys in the header or the query.
+func ApiKeyMiddleware(store ApiKeyStore, opts ApiKeyOptions) echo.MiddlewareFunc {
+	if len(opts.Header) == 0 {
+		opts.Header = HeaderApiKey
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			key := ctx.Request().Header.Get(opts.Header)
+			if len(key) == 0 && len(opts.Query) > 0 {
+				key = ctx.QueryParam(opts.Query)
+			}
+			if len(key) == 0 {
+				return echo.NewHTTPError(http.StatusUnauthorized, "missing api key")
+			}
+
+			principal, err := store.FindApiKey(ctx.Request().Context(), key)
+			if err != nil {
+				return err
+			}
+			if principal == nil {
+				return echo.NewHTTPError(http.StatusUnauthorized, "invalid api key")
+			}
+
+			setPrincipal(ctx, principal)
+			return next(ctx)
+		}
+	}
+}
+
+// PrincipalFrom returns the principal set by ApiKeyMiddleware or HmacMiddleware.
+func PrincipalFrom(ctx echo.Context) (*Principal, bool) {
+	token, ok := ctx.Get(jwtContextKey).(*jwt.Token)
+	if !ok || token == nil {
+		return nil, false
+	}
+
+	principal, ok := token.Claims.(*Principal)
+	return principal, ok
+}
+
+func setPrincipal(ctx echo.Context, principal *Principal) {
+	// 和JWT中间件一样，RateLimitByJwtSubject等按sub取值的逻辑可以复用
+	ctx.Set(jwtContextKey, &jwt.Token{
+		Claims: principal,
+		Valid:  true,
+	})
+}
+
+// Valid implements jwt.Claims, the principal is always valid once authenticated.
+func (p *Principal) Valid() error {
+	return nil
+}
+
+func (s StaticApiKeys) FindApiKey(_ context.Context, key string) (*Principal, error) {
+	principal, ok := s[key]
+	if !ok {
+		return nil, nil
+	}
+
+	return &principal, nil
+}
+
+// NewRedisApiKeyStore returns the ApiKeyStore in redis, prefix defaults to apikey:.
+func NewRedisApiKeyStore(store redis.Node, prefix string) *RedisApiKeyStore {
+	if len(prefix) == 0 {
+		prefix = defaultApiKeyPrefix
+	}
+
+	return &RedisApiKeyStore{
+		store:  store,
+		prefix: prefix,
+	}
+}
+
+func (s *RedisApiKeyStore) FindApiKey(ctx context.Context, key string) (*Principal, error) {
+	val, err := s.store.Get(ctx, s.redisKey(key)).Bytes()
+	if err == red.Nil {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var principal Principal
+	if err := json.Unmarshal(val, &principal); err != nil {
+		return nil, err
+	}
+
+	return &principal, nil
+}
+
+// SaveApiKey saves the api key, ttl 0 means never expire.
+func (s *RedisApiKeyStore) SaveApiKey(ctx context.Context, key string, principal Principal, ttl time.Duration) error {
+	val, err := json.Marshal(principal)
+	if err != nil {
+		return err
+	}
+
+	return s.store.Set(ctx, s.redisKey(key), val, ttl).Err()
+}
+
+func (s *RedisApiKeyStore) DeleteApiKey(ctx context.Context, key string) error {
+	return s.store.Del(ctx, s.redisKey(key)).Err()
+}
+
+func (s *RedisApiKeyStore) redisKey(key string) string {
+	return s.prefix + hashApiKey(key)
+}
+
+// NewCachedApiKeyStore caches the results of store in process for ttl, including the missing keys,
+// so the deleted keys are still valid for at most ttl. ttl defaults to one minute.
+func NewCachedApiKeyStore(store ApiKeyStore, ttl time.Duration) (ApiKeyStore, error) {
+	if ttl <= 0 {
+		ttl = defaultApiKeyCacheTTL
+	}
+
+	cache, err := collection.NewCache(ttl, collection.WithLimit(defaultApiKeyCacheMax),
+		collection.WithName("apikey"))
+	if err != nil {
+		return nil, err
+	}
+
+	return &cachedApiKeyStore{
+		store: store,
+		cache: cache,
+	}, nil
+}
+
+func (s *cachedApiKeyStore) FindApiKey(ctx context.Context, key string) (*Principal, error) {
+	val, err := s.cache.Take(hashApiKey(key), func() (interface{}, error) {
+		return s.store.FindApiKey(ctx, key)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return val.(*Principal), nil
+}
+
+// hashApiKey is used wherever the api key is saved or logged, the plain key is never kept.
+func hashApiKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,87 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/valeamoris/go-ezio/core/stores/redis"
+)
+
+type countingApiKeyStore struct {
+	ApiKeyStore
+	count int32
+}
+
+func (s *countingApiKeyStore) FindApiKey(ctx context.Context, key string) (*Principal, error) {
+	atomic.AddInt32(&s.count, 1)
+	return s.ApiKeyStore.FindApiKey(ctx, key)
+}
+
+func TestApiKeyMiddleware(t *testing.T) {
+	e := echo.New()
+	e.Use(ApiKeyMiddleware(StaticApiKeys{
+		"secret-key": {Subject: "partner", Scopes: []string{"orders"}},
+	}, ApiKeyOptions{Query: "api_key"}))
+	e.GET("/", func(ctx echo.Context) error {
+		principal, ok := PrincipalFrom(ctx)
+		assert.True(t, ok)
+		// 和JWT一样可以按subject限流
+		assert.Equal(t, "sub:partner", RateLimitByJwtSubject(ctx))
+		return ctx.String(http.StatusOK, principal.Subject)
+	})
+
+	get := func(target, key string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		if len(key) > 0 {
+			req.Header.Set(HeaderApiKey, key)
+		}
+		resp := httptest.NewRecorder()
+		e.ServeHTTP(resp, req)
+		return resp
+	}
+
+	resp := get("/", "secret-key")
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, "partner", resp.Body.String())
+	assert.Equal(t, http.StatusOK, get("/?api_key=secret-key", "").Code)
+	assert.Equal(t, http.StatusUnauthorized, get("/", "").Code)
+	assert.Equal(t, http.StatusUnauthorized, get("/", "wrong").Code)
+}
+
+func TestRedisApiKeyStore(t *testing.T) {
+	s, err := miniredis.Run()
+	assert.Nil(t, err)
+	defer s.Close()
+	node, err := redis.NewRedis(s.Addr(), redis.NodeType)
+	assert.Nil(t, err)
+
+	store := NewRedisApiKeyStore(node, "")
+	ctx := context.Background()
+	assert.Nil(t, store.SaveApiKey(ctx, "secret-key", Principal{Subject: "partner"}, 0))
+	// 不保存明文的key
+	assert.False(t, s.Exists("apikey:secret-key"))
+
+	counting := &countingApiKeyStore{ApiKeyStore: store}
+	cached, err := NewCachedApiKeyStore(counting, 0)
+	assert.Nil(t, err)
+	for i := 0; i < 3; i++ {
+		principal, err := cached.FindApiKey(ctx, "secret-key")
+		assert.Nil(t, err)
+		assert.Equal(t, "partner", principal.Subject)
+		principal, err = cached.FindApiKey(ctx, "wrong")
+		assert.Nil(t, err)
+		assert.Nil(t, principal)
+	}
+	assert.Equal(t, int32(2), atomic.LoadInt32(&counting.count))
+
+	assert.Nil(t, store.DeleteApiKey(ctx, "secret-key"))
+	principal, err := store.FindApiKey(ctx, "secret-key")
+	assert.Nil(t, err)
+	assert.Nil(t, principal)
+}
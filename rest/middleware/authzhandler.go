@@ -0,0 +1,185 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/labstack/echo/v4"
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+const (
+	defaultRolesClaim  = "roles"
+	defaultScopesClaim = "scope"
+	auditAuthzDenied   = "authz.denied"
+)
+
+type (
+	// Requirement is satisfied if the subject has any of the Roles and all of the Permissions,
+	// the empty fields are not checked.
+	Requirement struct {
+		Roles       []string `json:"roles,omitempty"`
+		Permissions []string `json:"permissions,omitempty"`
+	}
+
+	// AuthzRequest is the request to authorize, Path is the registered route path, like /users/:id.
+	AuthzRequest struct {
+		Subject string
+		// token中的角色
+		Roles []string
+		// token中的scope，作为直接授予的权限
+		Scopes []string
+		Method string
+		Path   string
+		// 分组和路由的要求，需要全部满足
+		Requirements []Requirement
+	}
+
+	// Policy decides whether the authenticated request is allowed.
+	Policy interface {
+		Authorize(ctx context.Context, req AuthzRequest) (bool, error)
+	}
+
+	AuditEntry struct {
+		Type         string        `json:"type"`
+		Subject      string        `json:"subject"`
+		Roles        []string      `json:"roles,omitempty"`
+		Scopes       []string      `json:"scopes,omitempty"`
+		Method       string        `json:"method"`
+		Route        string        `json:"route"`
+		Uri          string        `json:"uri"`
+		Ip           string        `json:"ip"`
+		TraceId      string        `json:"traceId,omitempty"`
+		RequestId    string        `json:"requestId,omitempty"`
+		Requirements []Requirement `json:"requirements,omitempty"`
+	}
+
+	AuthzOptions struct {
+		// 分组内所有路由都需要满足的要求
+		Requirement
+		// token中角色的claim，默认roles，值为字符串数组
+		RolesClaim string
+		// token中scope的claim，默认scope，值为空格分隔的字符串或字符串数组
+		ScopesClaim string
+		// 记录拒绝的请求，默认写入日志
+		Audit func(ctx echo.Context, entry AuditEntry)
+		// 审计记录的uri中需要脱敏的query参数，为nil时使用DefaultRedactQuery
+		RedactQuery []string
+	}
+)
+
+// AuthzMiddleware authorizes the requests authenticated by the JWT, api key or hmac middleware,
+// the routes add their own requirements by RouteKey(method, path). The unauthenticated requests
+// are rejected with 401, and the denied ones with 403, which are audited.
+func AuthzMiddleware(policy Policy, opts AuthzOptions, routes map[string]Requirement) echo.MiddlewareFunc {
+	if len(opts.RolesClaim) == 0 {
+		opts.RolesClaim = defaultRolesClaim
+	}
+	if len(opts.ScopesClaim) == 0 {
+		opts.ScopesClaim = defaultScopesClaim
+	}
+	if opts.Audit == nil {
+		opts.Audit = logAudit
+	}
+	if opts.RedactQuery == nil {
+		opts.RedactQuery = DefaultRedactQuery
+	}
+	redact := newRedactor(nil, nil, opts.RedactQuery)
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			token, ok := ctx.Get(jwtContextKey).(*jwt.Token)
+			if !ok || token == nil {
+				return echo.NewHTTPError(http.StatusUnauthorized, "unauthenticated")
+			}
+
+			req := ctx.Request()
+			authzReq := AuthzRequest{
+				Subject: jwtSubject(token.Claims),
+				Method:  req.Method,
+				Path:    ctx.Path(),
+			}
+			authzReq.Roles, authzReq.Scopes = rolesAndScopes(token.Claims, opts.RolesClaim, opts.ScopesClaim)
+			if !opts.Requirement.empty() {
+				authzReq.Requirements = append(authzReq.Requirements, opts.Requirement)
+			}
+			if r, ok := routes[RouteKey(req.Method, ctx.Path())]; ok && !r.empty() {
+				authzReq.Requirements = append(authzReq.Requirements, r)
+			}
+
+			allowed, err := policy.Authorize(req.Context(), authzReq)
+			if err != nil {
+				return err
+			}
+			if !allowed {
+				opts.Audit(ctx, AuditEntry{
+					Type:         auditAuthzDenied,
+					Subject:      authzReq.Subject,
+					Roles:        authzReq.Roles,
+					Scopes:       authzReq.Scopes,
+					Method:       req.Method,
+					Route:        ctx.Path(),
+					Uri:          redact.redactUri(req.RequestURI),
+					Ip:           ctx.RealIP(),
+					TraceId:      traceId(req.Context()),
+					RequestId:    requestIdFromRequest(req),
+					Requirements: authzReq.Requirements,
+				})
+				return echo.NewHTTPError(http.StatusForbidden, "permission denied")
+			}
+
+			return next(ctx)
+		}
+	}
+}
+
+func (r Requirement) empty() bool {
+	return len(r.Roles) == 0 && len(r.Permissions) == 0
+}
+
+func logAudit(_ echo.Context, entry AuditEntry) {
+	logx.Infov(entry)
+}
+
+func rolesAndScopes(claims jwt.Claims, rolesClaim, scopesClaim string) ([]string, []string) {
+	if principal, ok := claims.(*Principal); ok {
+		return principal.Roles, principal.Scopes
+	}
+
+	values, ok := claims.(jwt.MapClaims)
+	if !ok {
+		// 自定义claims按json字段取值
+		content, err := json.Marshal(claims)
+		if err != nil {
+			return nil, nil
+		}
+		if err := json.Unmarshal(content, &values); err != nil {
+			return nil, nil
+		}
+	}
+
+	return claimStrings(values[rolesClaim]), claimStrings(values[scopesClaim])
+}
+
+func claimStrings(value interface{}) []string {
+	switch v := value.(type) {
+	case string:
+		// OAuth2的scope是空格分隔的字符串
+		return strings.Fields(v)
+	case []string:
+		return v
+	case []interface{}:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,98 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+type authzClaims struct {
+	jwt.StandardClaims
+	Roles []string `json:"groups"`
+}
+
+func TestAuthzMiddleware(t *testing.T) {
+	var audits []AuditEntry
+	e := echo.New()
+	claims := make(map[string]jwt.Claims)
+	e.Use(func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			// 模拟JWT中间件
+			if c, ok := claims[ctx.Request().Header.Get("X-User")]; ok {
+				ctx.Set(jwtContextKey, &jwt.Token{Claims: c, Valid: true})
+			}
+			return next(ctx)
+		}
+	})
+	e.Use(AuthzMiddleware(NewRbacPolicy(RbacRules{
+		Permissions: map[string][]string{"editor": {"articles:*"}},
+	}), AuthzOptions{
+		Requirement: Requirement{Roles: []string{"staff", "editor"}},
+		Audit: func(ctx echo.Context, entry AuditEntry) {
+			audits = append(audits, entry)
+		},
+	}, map[string]Requirement{
+		RouteKey(http.MethodDelete, "/articles/:id"): {Permissions: []string{"articles:delete"}},
+	}))
+	ok := func(ctx echo.Context) error {
+		return ctx.NoContent(http.StatusOK)
+	}
+	e.GET("/articles/:id", ok)
+	e.DELETE("/articles/:id", ok)
+
+	claims["staff"] = jwt.MapClaims{"sub": "1", "roles": []interface{}{"staff"}, "scope": "articles:read"}
+	claims["editor"] = jwt.MapClaims{"sub": "2", "roles": []interface{}{"editor"}}
+	claims["custom"] = &authzClaims{StandardClaims: jwt.StandardClaims{Subject: "3"}, Roles: []string{"staff"}}
+	claims["guest"] = jwt.MapClaims{"sub": "4"}
+	claims["principal"] = &Principal{Subject: "5", Roles: []string{"staff"}, Scopes: []string{"articles:delete"}}
+
+	serve := func(method, user string) int {
+		req := httptest.NewRequest(method, "/articles/1", nil)
+		req.Header.Set("X-User", user)
+		resp := httptest.NewRecorder()
+		e.ServeHTTP(resp, req)
+		return resp.Code
+	}
+
+	assert.Equal(t, http.StatusOK, serve(http.MethodGet, "staff"))
+	assert.Equal(t, http.StatusOK, serve(http.MethodGet, "editor"))
+	assert.Equal(t, http.StatusForbidden, serve(http.MethodGet, "guest"))
+	assert.Equal(t, http.StatusUnauthorized, serve(http.MethodGet, "anonymous"))
+	// 路由的要求和分组的一起生效
+	assert.Equal(t, http.StatusForbidden, serve(http.MethodDelete, "staff"))
+	assert.Equal(t, http.StatusOK, serve(http.MethodDelete, "editor"))
+	assert.Equal(t, http.StatusOK, serve(http.MethodDelete, "principal"))
+
+	assert.Len(t, audits, 2)
+	assert.Equal(t, auditAuthzDenied, audits[1].Type)
+	assert.Equal(t, "1", audits[1].Subject)
+	assert.Equal(t, "/articles/:id", audits[1].Route)
+	assert.Equal(t, []string{"articles:read"}, audits[1].Scopes)
+	assert.Len(t, audits[1].Requirements, 2)
+
+	// 审计记录的uri中的api key脱敏
+	req := httptest.NewRequest(http.MethodGet, "/articles/1?api_key=secret", nil)
+	req.Header.Set("X-User", "guest")
+	e.ServeHTTP(httptest.NewRecorder(), req)
+	assert.Len(t, audits, 3)
+	assert.Equal(t, "/articles/1?api_key=***", audits[2].Uri)
+
+	// 自定义claims按json字段取值
+	e = echo.New()
+	e.Use(func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			ctx.Set(jwtContextKey, &jwt.Token{Claims: claims["custom"], Valid: true})
+			return next(ctx)
+		}
+	})
+	e.Use(AuthzMiddleware(NewRbacPolicy(RbacRules{}), AuthzOptions{
+		Requirement: Requirement{Roles: []string{"staff"}},
+		RolesClaim:  "groups",
+	}, nil))
+	e.GET("/articles/:id", ok)
+	assert.Equal(t, http.StatusOK, serve(http.MethodGet, ""))
+}
@@ -0,0 +1,65 @@
+package middleware
+
+import (
+	"io"
+
+	"github.com/labstack/echo/v4"
+)
+
+const bodyLimitKey = "body.limit"
+
+type limitedBody struct {
+	io.ReadCloser
+	limit int64
+	read  int64
+}
+
+// BodyLimitMiddleware rejects the requests with the body larger than limit with 413, the routes override
+// the limit by RouteKey(method, path), the path is the one registered, like /users/:id.
+// It runs after routing, so that the routes can have bigger limits than the global one.
+// The limit not greater than 0 means no limit.
+func BodyLimitMiddleware(limit int64, routes map[string]int64) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			max := limit
+			req := ctx.Request()
+			if l, ok := routes[RouteKey(req.Method, ctx.Path())]; ok {
+				max = l
+			}
+			if max <= 0 {
+				return next(ctx)
+			}
+
+			ctx.Set(bodyLimitKey, max)
+			if req.ContentLength > max {
+				return echo.ErrStatusRequestEntityTooLarge
+			}
+			// Content-Length可能缺失或不可信
+			req.Body = &limitedBody{ReadCloser: req.Body, limit: max}
+			return next(ctx)
+		}
+	}
+}
+
+func RouteKey(method, path string) string {
+	return method + " " + path
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.read > b.limit {
+		return 0, echo.ErrStatusRequestEntityTooLarge
+	}
+
+	n, err := b.ReadCloser.Read(p)
+	b.read += int64(n)
+	if b.read > b.limit {
+		return n - int(b.read-b.limit), echo.ErrStatusRequestEntityTooLarge
+	}
+
+	return n, err
+}
+
+func bodyLimit(ctx echo.Context) (int64, bool) {
+	limit, ok := ctx.Get(bodyLimitKey).(int64)
+	return limit, ok
+}
@@ -0,0 +1,66 @@
+package middleware
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBodyLimitMiddleware(t *testing.T) {
+	e := echo.New()
+	e.Use(BodyLimitMiddleware(4, map[string]int64{
+		RouteKey(http.MethodPost, "/uploads/:id"): 8,
+	}))
+	handler := func(ctx echo.Context) error {
+		body, err := ioutil.ReadAll(ctx.Request().Body)
+		if err != nil {
+			return err
+		}
+		return ctx.String(http.StatusOK, string(body))
+	}
+	e.POST("/users", handler)
+	e.POST("/uploads/:id", handler)
+
+	post := func(target, body string, chunked bool) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, target, strings.NewReader(body))
+		if chunked {
+			// 没有Content-Length时按读取的长度限制
+			req.Body = ioutil.NopCloser(bytes.NewReader([]byte(body)))
+			req.ContentLength = -1
+		}
+		resp := httptest.NewRecorder()
+		e.ServeHTTP(resp, req)
+		return resp
+	}
+
+	assert.Equal(t, http.StatusOK, post("/users", "1234", false).Code)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, post("/users", "12345", false).Code)
+	assert.Equal(t, http.StatusOK, post("/users", "1234", true).Code)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, post("/users", "12345", true).Code)
+
+	// 路由覆盖全局的限制
+	resp := post("/uploads/1", "12345678", false)
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, "12345678", resp.Body.String())
+	assert.Equal(t, http.StatusRequestEntityTooLarge, post("/uploads/1", "123456789", true).Code)
+}
+
+func TestBodyLimitMiddlewareUnlimited(t *testing.T) {
+	e := echo.New()
+	e.Use(BodyLimitMiddleware(0, nil))
+	e.POST("/", func(ctx echo.Context) error {
+		_, ok := bodyLimit(ctx)
+		assert.False(t, ok)
+		return ctx.NoContent(http.StatusOK)
+	})
+
+	resp := httptest.NewRecorder()
+	e.ServeHTTP(resp, httptest.NewRequest(http.MethodPost, "/", strings.NewReader("body")))
+	assert.Equal(t, http.StatusOK, resp.Code)
+}
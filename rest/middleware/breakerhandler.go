@@ -3,6 +3,7 @@ package middleware
 import (
 	"fmt"
 	"github.com/labstack/echo/v4"
+	"github.com/valeamoris/go-ezio/rest/internal"
 	"github.com/zeromicro/go-zero/core/breaker"
 	"github.com/zeromicro/go-zero/core/logx"
 	"github.com/zeromicro/go-zero/core/stat"
@@ -21,8 +22,9 @@ func BreakerMiddleware(method, path string, metrics *stat.Metrics, rejectHandler
 			promise, err := brk.Allow()
 			if err != nil {
 				metrics.AddDrop()
+				MarkDropped(ctx, DroppedByBreaker)
 				logx.Errorf("[http] dropped, %s - %s - %s",
-					ctx.Request().RequestURI, ctx.RealIP(), ctx.Request().UserAgent())
+					internal.Uri(ctx.Request()), ctx.RealIP(), ctx.Request().UserAgent())
 				ctx.Response().WriteHeader(http.StatusServiceUnavailable)
 				return nil
 			}
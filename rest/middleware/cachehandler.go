@@ -0,0 +1,394 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	red "github.com/go-redis/redis/v8"
+	"github.com/labstack/echo/v4"
+	"github.com/valeamoris/go-ezio/core/requestid"
+	"github.com/valeamoris/go-ezio/core/stores/redis"
+	"github.com/zeromicro/go-zero/core/logx"
+	"github.com/zeromicro/go-zero/core/syncx"
+	"github.com/zeromicro/go-zero/core/threading"
+)
+
+const (
+	HeaderXCache = "X-Cache"
+
+	headerAge          = "Age"
+	headerCacheControl = "Cache-Control"
+	headerETag         = "ETag"
+	headerIfNoneMatch  = "If-None-Match"
+
+	cacheHit   = "HIT"
+	cacheMiss  = "MISS"
+	cacheStale = "STALE"
+
+	cachePrefix          = "cache:"
+	cacheTagPrefix       = cachePrefix + "tag:"
+	defaultCacheTTL      = time.Minute
+	defaultCacheMaxBytes = 1 << 20
+)
+
+// 后台刷新时保留的echo store，认证中间件设置的token和csrf token，其他的值在后台执行时为空
+var revalidateKeys = []string{jwtContextKey, csrfContextKey}
+
+type (
+	CacheOptions struct {
+		// 缓存的有效期，默认1分钟
+		TTL time.Duration
+		// 过期后仍可返回旧响应并在后台刷新的时间，0代表不返回过期响应
+		StaleWhileRevalidate time.Duration
+		// 参与缓存key的请求header
+		Vary []string
+		// 响应的标签，用于InvalidateCacheTags按标签失效
+		Tags func(ctx echo.Context) []string
+		// 可缓存的最大响应body，默认1M
+		MaxBodyBytes int
+	}
+
+	cachedResponse struct {
+		Status   int         `json:"status"`
+		Header   http.Header `json:"header"`
+		Body     []byte      `json:"body"`
+		ETag     string      `json:"etag"`
+		StoredAt int64       `json:"storedAt"`
+	}
+
+	// bufferedWriter holds the whole response, so that the ETag can be set before writing.
+	bufferedWriter struct {
+		header http.Header
+		status int
+		body   bytes.Buffer
+	}
+
+	responseCache struct {
+		store   redis.Node
+		opts    CacheOptions
+		flights syncx.SingleFlight
+	}
+)
+
+// CacheMiddleware caches the successful GET responses in redis,
+// and answers If-None-Match with 304 if the ETag matches.
+// The responses of the authenticated requests are cached per subject.
+func CacheMiddleware(store redis.Node, opts CacheOptions) echo.MiddlewareFunc {
+	if opts.TTL <= 0 {
+		opts.TTL = defaultCacheTTL
+	}
+	if opts.MaxBodyBytes <= 0 {
+		opts.MaxBodyBytes = defaultCacheMaxBytes
+	}
+	cache := &responseCache{
+		store:   store,
+		opts:    opts,
+		flights: syncx.NewSingleFlight(),
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			if ctx.Request().Method != http.MethodGet {
+				return next(ctx)
+			}
+
+			return cache.serve(ctx, next)
+		}
+	}
+}
+
+// InvalidateCacheTags removes the cached responses with any of the given tags.
+func InvalidateCacheTags(ctx context.Context, store redis.Node, tags ...string) error {
+	for _, tag := range tags {
+		tagKey := cacheTagPrefix + tag
+		keys, err := store.SMembers(ctx, tagKey).Result()
+		if err != nil {
+			return err
+		}
+
+		// 集群模式下key可能不在同一个slot，逐个删除
+		for _, key := range keys {
+			if err := store.Del(ctx, key).Err(); err != nil {
+				return err
+			}
+		}
+		if err := store.Del(ctx, tagKey).Err(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (c *responseCache) serve(ctx echo.Context, next echo.HandlerFunc) error {
+	key := c.key(ctx)
+	cached, err := c.load(ctx.Request().Context(), key)
+	if err != nil {
+		logx.Errorf("response cache disabled for %s, redis error: %s", key, err.Error())
+		return next(ctx)
+	}
+
+	if cached != nil {
+		age := time.Since(time.Unix(0, cached.StoredAt*int64(time.Millisecond)))
+		if age < c.opts.TTL {
+			return writeCached(ctx, cached, cacheHit, age)
+		}
+		if age < c.opts.TTL+c.opts.StaleWhileRevalidate {
+			c.revalidate(ctx, key, next)
+			return writeCached(ctx, cached, cacheStale, age)
+		}
+	}
+
+	var leaderErr error
+	var leaderWriter *bufferedWriter
+	val, fresh, _ := c.flights.DoEx(key, func() (interface{}, error) {
+		leaderWriter, leaderErr = c.fetch(ctx, next)
+		return c.save(ctx, key, leaderWriter, leaderErr), nil
+	})
+
+	if fresh {
+		if resp, ok := val.(*cachedResponse); ok && resp != nil {
+			return writeCached(ctx, resp, cacheMiss, 0)
+		}
+		return writeBuffered(ctx, leaderWriter, leaderErr)
+	}
+
+	// 合并的请求结果不可缓存时，单独执行
+	if resp, ok := val.(*cachedResponse); ok && resp != nil {
+		return writeCached(ctx, resp, cacheMiss, 0)
+	}
+	return next(ctx)
+}
+
+func (c *responseCache) key(ctx echo.Context) string {
+	req := ctx.Request()
+	var b strings.Builder
+	b.WriteString(req.URL.Path)
+	b.WriteByte('?')
+	b.WriteString(normalizeQuery(req.URL.RawQuery))
+	// 认证的请求按主体区分，避免把一个用户的响应返回给其他用户
+	b.WriteString("\nsub:")
+	b.WriteString(authSubject(ctx))
+	for _, name := range c.opts.Vary {
+		b.WriteByte('\n')
+		b.WriteString(http.CanonicalHeaderKey(name))
+		b.WriteByte(':')
+		b.WriteString(req.Header.Get(name))
+	}
+
+	sum := sha1.Sum([]byte(b.String()))
+	return cachePrefix + ctx.Path() + ":" + hex.EncodeToString(sum[:])
+}
+
+func (c *responseCache) load(ctx context.Context, key string) (*cachedResponse, error) {
+	val, err := c.store.Get(ctx, key).Bytes()
+	if err == red.Nil {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var cached cachedResponse
+	if err := json.Unmarshal(val, &cached); err != nil {
+		return nil, err
+	}
+
+	return &cached, nil
+}
+
+// fetch runs the handler with a buffered response.
+func (c *responseCache) fetch(ctx echo.Context, next echo.HandlerFunc) (*bufferedWriter, error) {
+	resp := ctx.Response()
+	writer := newBufferedWriter()
+	ctx.SetResponse(echo.NewResponse(writer, ctx.Echo()))
+	defer ctx.SetResponse(resp)
+
+	err := next(ctx)
+	return writer, err
+}
+
+func (c *responseCache) save(ctx echo.Context, key string, writer *bufferedWriter, err error) *cachedResponse {
+	if err != nil || !cacheable(writer, c.opts.MaxBodyBytes) {
+		return nil
+	}
+
+	cached := &cachedResponse{
+		Status:   writer.status,
+		Header:   writer.header,
+		Body:     writer.body.Bytes(),
+		ETag:     etag(writer.body.Bytes()),
+		StoredAt: time.Now().UnixNano() / int64(time.Millisecond),
+	}
+	if len(c.opts.Vary) > 0 {
+		cached.Header.Set(echo.HeaderVary, strings.Join(c.opts.Vary, ", "))
+	}
+
+	val, err := json.Marshal(cached)
+	if err != nil {
+		logx.Error(err)
+		return cached
+	}
+
+	var tags []string
+	if c.opts.Tags != nil {
+		tags = c.opts.Tags(ctx)
+	}
+	expire := c.opts.TTL + c.opts.StaleWhileRevalidate
+	if _, err := c.store.Pipelined(context.Background(), func(p red.Pipeliner) error {
+		p.Set(context.Background(), key, val, expire)
+		for _, tag := range tags {
+			p.SAdd(context.Background(), cacheTagPrefix+tag, key)
+			p.Expire(context.Background(), cacheTagPrefix+tag, expire)
+		}
+		return nil
+	}); err != nil {
+		logx.Errorf("failed to cache response of %s: %s", key, err.Error())
+	}
+
+	return cached
+}
+
+// revalidate refreshes the stale response in background, the request's context is reused by echo,
+// so the handler runs with a copy of it, which keeps the request id and the values in revalidateKeys.
+func (c *responseCache) revalidate(ctx echo.Context, key string, next echo.HandlerFunc) {
+	bgCtx := context.Background()
+	if id := requestIdFromRequest(ctx.Request()); len(id) > 0 {
+		bgCtx = requestid.NewContext(bgCtx, id)
+	}
+	bg := ctx.Echo().NewContext(ctx.Request().Clone(bgCtx), newBufferedWriter())
+	bg.SetPath(ctx.Path())
+	bg.SetParamNames(ctx.ParamNames()...)
+	bg.SetParamValues(ctx.ParamValues()...)
+	for _, k := range revalidateKeys {
+		if val := ctx.Get(k); val != nil {
+			bg.Set(k, val)
+		}
+	}
+
+	threading.GoSafe(func() {
+		_, _, _ = c.flights.DoEx(key, func() (interface{}, error) {
+			writer, err := c.fetch(bg, next)
+			return c.save(bg, key, writer, err), nil
+		})
+	})
+}
+
+func cacheable(writer *bufferedWriter, maxBytes int) bool {
+	if writer.status != http.StatusOK || writer.body.Len() > maxBytes {
+		return false
+	}
+	if len(writer.header.Get(echo.HeaderSetCookie)) > 0 {
+		return false
+	}
+
+	control := strings.ToLower(writer.header.Get(headerCacheControl))
+	return !strings.Contains(control, "no-store") && !strings.Contains(control, "private")
+}
+
+func writeCached(ctx echo.Context, cached *cachedResponse, state string, age time.Duration) error {
+	header := ctx.Response().Header()
+	for k, v := range cached.Header {
+		header[k] = v
+	}
+	header.Set(headerETag, cached.ETag)
+	header.Set(HeaderXCache, state)
+	if age > 0 {
+		header.Set(headerAge, strconv.Itoa(int(age/time.Second)))
+	}
+
+	if etagMatch(ctx.Request().Header.Get(headerIfNoneMatch), cached.ETag) {
+		return ctx.NoContent(http.StatusNotModified)
+	}
+
+	return ctx.Blob(cached.Status, header.Get(echo.HeaderContentType), cached.Body)
+}
+
+func writeBuffered(ctx echo.Context, writer *bufferedWriter, err error) error {
+	if writer == nil || writer.status == 0 {
+		return err
+	}
+
+	header := ctx.Response().Header()
+	for k, v := range writer.header {
+		header[k] = v
+	}
+	if writer.status == http.StatusOK {
+		tag := etag(writer.body.Bytes())
+		header.Set(headerETag, tag)
+		if etagMatch(ctx.Request().Header.Get(headerIfNoneMatch), tag) {
+			ctx.Response().WriteHeader(http.StatusNotModified)
+			return err
+		}
+	}
+
+	ctx.Response().WriteHeader(writer.status)
+	if _, werr := ctx.Response().Write(writer.body.Bytes()); werr != nil && err == nil {
+		err = werr
+	}
+	return err
+}
+
+func etag(body []byte) string {
+	sum := sha1.Sum(body)
+	return `"` + hex.EncodeToString(sum[:]) + `"`
+}
+
+func etagMatch(ifNoneMatch, tag string) bool {
+	if len(ifNoneMatch) == 0 {
+		return false
+	}
+
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == tag {
+			return true
+		}
+	}
+
+	return false
+}
+
+// 参数按key排序，使得参数顺序不同的请求命中同一缓存
+func normalizeQuery(rawQuery string) string {
+	if len(rawQuery) == 0 {
+		return ""
+	}
+
+	query, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return rawQuery
+	}
+
+	return query.Encode()
+}
+
+func newBufferedWriter() *bufferedWriter {
+	return &bufferedWriter{
+		header: make(http.Header),
+	}
+}
+
+func (w *bufferedWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *bufferedWriter) Write(p []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.body.Write(p)
+}
+
+func (w *bufferedWriter) WriteHeader(code int) {
+	if w.status == 0 {
+		w.status = code
+	}
+}
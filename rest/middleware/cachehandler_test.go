@@ -0,0 +1,247 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/dgrijalva/jwt-go"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/valeamoris/go-ezio/core/requestid"
+	"github.com/valeamoris/go-ezio/core/stores/redis"
+)
+
+func TestCacheMiddleware(t *testing.T) {
+	s, err := miniredis.Run()
+	assert.Nil(t, err)
+	defer s.Close()
+	store, err := redis.NewRedis(s.Addr(), redis.NodeType)
+	assert.Nil(t, err)
+
+	var count int32
+	e := echo.New()
+	e.Use(CacheMiddleware(store, CacheOptions{
+		TTL:  time.Minute,
+		Vary: []string{"Accept-Language"},
+		Tags: func(ctx echo.Context) []string {
+			return []string{"user:" + ctx.Param("id")}
+		},
+	}))
+	e.GET("/users/:id", func(ctx echo.Context) error {
+		atomic.AddInt32(&count, 1)
+		return ctx.String(http.StatusOK, "user "+ctx.Param("id"))
+	})
+
+	get := func(target, lang, etag string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		req.Header.Set("Accept-Language", lang)
+		if len(etag) > 0 {
+			req.Header.Set("If-None-Match", etag)
+		}
+		resp := httptest.NewRecorder()
+		e.ServeHTTP(resp, req)
+		return resp
+	}
+
+	resp := get("/users/1?b=2&a=1", "en", "")
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, "user 1", resp.Body.String())
+	assert.Equal(t, cacheMiss, resp.Header().Get(HeaderXCache))
+	tag := resp.Header().Get("ETag")
+	assert.NotEmpty(t, tag)
+
+	resp = get("/users/1?a=1&b=2", "en", "")
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, "user 1", resp.Body.String())
+	assert.Equal(t, cacheHit, resp.Header().Get(HeaderXCache))
+	assert.Equal(t, tag, resp.Header().Get("ETag"))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&count))
+
+	resp = get("/users/1?a=1&b=2", "en", tag)
+	assert.Equal(t, http.StatusNotModified, resp.Code)
+	assert.Empty(t, resp.Body.String())
+
+	get("/users/1?a=1&b=2", "zh", "")
+	assert.Equal(t, int32(2), atomic.LoadInt32(&count))
+
+	assert.Nil(t, InvalidateCacheTags(context.Background(), store, "user:1"))
+	resp = get("/users/1?a=1&b=2", "en", "")
+	assert.Equal(t, cacheMiss, resp.Header().Get(HeaderXCache))
+	assert.Equal(t, int32(3), atomic.LoadInt32(&count))
+}
+
+func TestCacheMiddleware_Subject(t *testing.T) {
+	s, err := miniredis.Run()
+	assert.Nil(t, err)
+	defer s.Close()
+	store, err := redis.NewRedis(s.Addr(), redis.NodeType)
+	assert.Nil(t, err)
+
+	var count int32
+	e := echo.New()
+	e.Use(func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			// 模拟JWT中间件
+			if sub := ctx.Request().Header.Get("X-User"); len(sub) > 0 {
+				ctx.Set(jwtContextKey, &jwt.Token{Claims: jwt.MapClaims{"sub": sub}, Valid: true})
+			}
+			return next(ctx)
+		}
+	})
+	e.Use(CacheMiddleware(store, CacheOptions{TTL: time.Minute}))
+	e.GET("/me", func(ctx echo.Context) error {
+		atomic.AddInt32(&count, 1)
+		return ctx.String(http.StatusOK, "hello "+ctx.Request().Header.Get("X-User"))
+	})
+
+	get := func(user string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/me", nil)
+		req.Header.Set("X-User", user)
+		resp := httptest.NewRecorder()
+		e.ServeHTTP(resp, req)
+		return resp
+	}
+
+	assert.Equal(t, "hello alice", get("alice").Body.String())
+	resp := get("bob")
+	assert.Equal(t, "hello bob", resp.Body.String())
+	assert.Equal(t, cacheMiss, resp.Header().Get(HeaderXCache))
+	resp = get("alice")
+	assert.Equal(t, "hello alice", resp.Body.String())
+	assert.Equal(t, cacheHit, resp.Header().Get(HeaderXCache))
+	assert.Equal(t, int32(2), atomic.LoadInt32(&count))
+}
+
+func TestCacheMiddleware_Stale(t *testing.T) {
+	s, err := miniredis.Run()
+	assert.Nil(t, err)
+	defer s.Close()
+	store, err := redis.NewRedis(s.Addr(), redis.NodeType)
+	assert.Nil(t, err)
+
+	var count int32
+	e := echo.New()
+	e.Use(CacheMiddleware(store, CacheOptions{
+		TTL:                  time.Millisecond * 50,
+		StaleWhileRevalidate: time.Minute,
+	}))
+	e.GET("/", func(ctx echo.Context) error {
+		atomic.AddInt32(&count, 1)
+		return ctx.String(http.StatusOK, "ok")
+	})
+
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	time.Sleep(time.Millisecond * 100)
+	resp := httptest.NewRecorder()
+	e.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, cacheStale, resp.Header().Get(HeaderXCache))
+	assert.Equal(t, "ok", resp.Body.String())
+
+	assert.Eventually(t, func() bool {
+		return atomic.LoadInt32(&count) == 2
+	}, time.Second, time.Millisecond*10)
+}
+
+func TestCacheMiddleware_RevalidateContext(t *testing.T) {
+	s, err := miniredis.Run()
+	assert.Nil(t, err)
+	defer s.Close()
+	store, err := redis.NewRedis(s.Addr(), redis.NodeType)
+	assert.Nil(t, err)
+
+	token := &jwt.Token{Claims: jwt.MapClaims{"sub": "alice"}, Valid: true}
+	users := make(chan interface{}, 2)
+	ids := make(chan string, 2)
+	e := echo.New()
+	e.Use(RequestIdMiddleware)
+	e.Use(func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			// 模拟JWT中间件
+			ctx.Set(jwtContextKey, token)
+			return next(ctx)
+		}
+	})
+	e.Use(CacheMiddleware(store, CacheOptions{
+		TTL:                  time.Millisecond * 50,
+		StaleWhileRevalidate: time.Minute,
+	}))
+	e.GET("/", func(ctx echo.Context) error {
+		users <- ctx.Get(jwtContextKey)
+		ids <- requestid.FromContext(ctx.Request().Context())
+		return ctx.String(http.StatusOK, "ok")
+	})
+
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	<-users
+	<-ids
+	time.Sleep(time.Millisecond * 100)
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(echo.HeaderXRequestID, "stale-request")
+	resp := httptest.NewRecorder()
+	e.ServeHTTP(resp, req)
+	assert.Equal(t, cacheStale, resp.Header().Get(HeaderXCache))
+
+	// 后台刷新时仍然可以拿到认证的token和request id
+	select {
+	case user := <-users:
+		assert.Equal(t, token, user)
+		assert.Equal(t, "stale-request", <-ids)
+	case <-time.After(time.Second):
+		t.Fatal("stale response isn't revalidated")
+	}
+}
+
+func TestCacheMiddleware_SingleFlight(t *testing.T) {
+	s, err := miniredis.Run()
+	assert.Nil(t, err)
+	defer s.Close()
+	store, err := redis.NewRedis(s.Addr(), redis.NodeType)
+	assert.Nil(t, err)
+
+	var count int32
+	e := echo.New()
+	e.Use(CacheMiddleware(store, CacheOptions{}))
+	e.GET("/", func(ctx echo.Context) error {
+		atomic.AddInt32(&count, 1)
+		time.Sleep(time.Millisecond * 100)
+		return ctx.String(http.StatusOK, "ok")
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp := httptest.NewRecorder()
+			e.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/", nil))
+			assert.Equal(t, "ok", resp.Body.String())
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), atomic.LoadInt32(&count))
+}
+
+func TestCacheMiddleware_Uncacheable(t *testing.T) {
+	s, err := miniredis.Run()
+	assert.Nil(t, err)
+	defer s.Close()
+	store, err := redis.NewRedis(s.Addr(), redis.NodeType)
+	assert.Nil(t, err)
+
+	e := echo.New()
+	e.Use(CacheMiddleware(store, CacheOptions{}))
+	e.GET("/", func(ctx echo.Context) error {
+		return echo.NewHTTPError(http.StatusNotFound)
+	})
+
+	resp := httptest.NewRecorder()
+	e.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusNotFound, resp.Code)
+	assert.Empty(t, s.Keys())
+}
@@ -0,0 +1,308 @@
+package middleware
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/flate"
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zstd"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	EncodingBrotli  = "br"
+	EncodingZstd    = "zstd"
+	EncodingGzip    = "gzip"
+	EncodingDeflate = "deflate"
+
+	defaultCompressMinLength = 1024
+	identityEncoding         = "identity"
+)
+
+var (
+	// 按服务端优先级排列
+	DefaultCompressEncodings    = []string{EncodingBrotli, EncodingZstd, EncodingGzip, EncodingDeflate}
+	DefaultCompressContentTypes = []string{
+		"text/html",
+		"text/css",
+		"text/plain",
+		"text/xml",
+		"text/javascript",
+		"application/json",
+		"application/javascript",
+		"application/xml",
+		"application/problem+json",
+		"image/svg+xml",
+	}
+
+	errNotHijacker = errors.New("response writer is not a http.Hijacker")
+
+	encoderPools = map[string]*sync.Pool{
+		EncodingBrotli: {New: func() interface{} {
+			return brotli.NewWriterLevel(nil, brotli.DefaultCompression)
+		}},
+		EncodingZstd: {New: func() interface{} {
+			encoder, _ := zstd.NewWriter(nil, zstd.WithEncoderConcurrency(1))
+			return encoder
+		}},
+		EncodingGzip: {New: func() interface{} {
+			writer, _ := gzip.NewWriterLevel(nil, gzip.DefaultCompression)
+			return writer
+		}},
+		EncodingDeflate: {New: func() interface{} {
+			writer, _ := flate.NewWriter(nil, flate.DefaultCompression)
+			return writer
+		}},
+	}
+)
+
+type (
+	CompressOptions struct {
+		// 小于该长度的响应不压缩，默认1024
+		MinLength int
+		// 允许压缩的Content-Type，为空时使用DefaultCompressContentTypes
+		ContentTypes []string
+		// 支持的编码，按优先级排列，为空时使用DefaultCompressEncodings
+		Encodings []string
+	}
+
+	encoder interface {
+		io.WriteCloser
+		Reset(w io.Writer)
+		Flush() error
+	}
+
+	// compressWriter holds the output until MinLength bytes are written or flushed,
+	// then decides whether to compress by the status and the Content-Type.
+	compressWriter struct {
+		http.ResponseWriter
+		encoding     string
+		minLength    int
+		contentTypes []string
+		status       int
+		buf          bytes.Buffer
+		decided      bool
+		encoder      encoder
+	}
+)
+
+// CompressMiddleware compresses the responses with the encoding negotiated by Accept-Encoding.
+func CompressMiddleware(opts CompressOptions) echo.MiddlewareFunc {
+	if opts.MinLength <= 0 {
+		opts.MinLength = defaultCompressMinLength
+	}
+	if len(opts.ContentTypes) == 0 {
+		opts.ContentTypes = DefaultCompressContentTypes
+	}
+	if len(opts.Encodings) == 0 {
+		opts.Encodings = DefaultCompressEncodings
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			req := ctx.Request()
+			resp := ctx.Response()
+			resp.Header().Add(echo.HeaderVary, echo.HeaderAcceptEncoding)
+			// websocket等升级的连接不压缩
+			if req.Method == http.MethodHead || len(req.Header.Get("Upgrade")) > 0 {
+				return next(ctx)
+			}
+
+			encoding := negotiateEncoding(req.Header.Get(echo.HeaderAcceptEncoding), opts.Encodings)
+			if len(encoding) == 0 {
+				return next(ctx)
+			}
+
+			writer := resp.Writer
+			cw := &compressWriter{
+				ResponseWriter: writer,
+				encoding:       encoding,
+				minLength:      opts.MinLength,
+				contentTypes:   opts.ContentTypes,
+			}
+			resp.Writer = cw
+			defer func() {
+				cw.Close()
+				resp.Writer = writer
+			}()
+
+			return next(ctx)
+		}
+	}
+}
+
+// negotiateEncoding returns the first supported encoding accepted with q > 0, empty for identity.
+func negotiateEncoding(accept string, supported []string) string {
+	if len(accept) == 0 {
+		return ""
+	}
+
+	accepted := make(map[string]float64)
+	for _, part := range strings.Split(accept, ",") {
+		name, q := parseCoding(part)
+		if len(name) > 0 {
+			accepted[name] = q
+		}
+	}
+
+	for _, encoding := range supported {
+		if q, ok := accepted[encoding]; ok {
+			if q > 0 {
+				return encoding
+			}
+			continue
+		}
+		if q, ok := accepted["*"]; ok && q > 0 {
+			return encoding
+		}
+	}
+
+	return ""
+}
+
+func parseCoding(part string) (string, float64) {
+	fields := strings.Split(part, ";")
+	name := strings.ToLower(strings.TrimSpace(fields[0]))
+	if name == identityEncoding {
+		return "", 0
+	}
+
+	q := 1.0
+	for _, param := range fields[1:] {
+		param = strings.TrimSpace(param)
+		if strings.HasPrefix(param, "q=") {
+			if v, err := strconv.ParseFloat(param[2:], 64); err == nil {
+				q = v
+			}
+		}
+	}
+
+	return name, q
+}
+
+func (w *compressWriter) WriteHeader(code int) {
+	if w.status == 0 {
+		w.status = code
+	}
+}
+
+func (w *compressWriter) Write(p []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	if w.decided {
+		return w.write(p)
+	}
+
+	w.buf.Write(p)
+	if w.buf.Len() >= w.minLength {
+		if err := w.decide(true); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(p), nil
+}
+
+func (w *compressWriter) Flush() {
+	// 流式响应在首次flush时决定是否压缩
+	if !w.decided {
+		if w.status == 0 {
+			w.status = http.StatusOK
+		}
+		_ = w.decide(true)
+	}
+	if w.encoder != nil {
+		_ = w.encoder.Flush()
+	}
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (w *compressWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if hijacker, ok := w.ResponseWriter.(http.Hijacker); ok {
+		return hijacker.Hijack()
+	}
+
+	return nil, nil, errNotHijacker
+}
+
+// Close writes the pending output and returns the encoder to the pool.
+func (w *compressWriter) Close() {
+	if !w.decided {
+		if w.status == 0 {
+			return
+		}
+		_ = w.decide(false)
+	}
+
+	if w.encoder != nil {
+		_ = w.encoder.Close()
+		w.encoder.Reset(nil)
+		encoderPools[w.encoding].Put(w.encoder)
+		w.encoder = nil
+	}
+}
+
+func (w *compressWriter) decide(large bool) error {
+	w.decided = true
+	header := w.ResponseWriter.Header()
+	if large && w.shouldCompress(header) {
+		header.Set(echo.HeaderContentEncoding, w.encoding)
+		header.Del(echo.HeaderContentLength)
+		w.encoder = encoderPools[w.encoding].Get().(encoder)
+		w.encoder.Reset(w.ResponseWriter)
+	}
+
+	w.ResponseWriter.WriteHeader(w.status)
+	if w.buf.Len() == 0 {
+		return nil
+	}
+
+	_, err := w.write(w.buf.Bytes())
+	w.buf.Reset()
+	return err
+}
+
+func (w *compressWriter) shouldCompress(header http.Header) bool {
+	if w.status < http.StatusOK || w.status == http.StatusNoContent || w.status == http.StatusNotModified {
+		return false
+	}
+	if len(header.Get(echo.HeaderContentEncoding)) > 0 {
+		return false
+	}
+
+	contentType := header.Get(echo.HeaderContentType)
+	if len(contentType) == 0 {
+		contentType = http.DetectContentType(w.buf.Bytes())
+	}
+	if i := strings.IndexByte(contentType, ';'); i >= 0 {
+		contentType = contentType[:i]
+	}
+	contentType = strings.TrimSpace(strings.ToLower(contentType))
+	for _, allowed := range w.contentTypes {
+		if contentType == allowed {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (w *compressWriter) write(p []byte) (int, error) {
+	if w.encoder != nil {
+		return w.encoder.Write(p)
+	}
+
+	return w.ResponseWriter.Write(p)
+}
@@ -0,0 +1,107 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNegotiateEncoding(t *testing.T) {
+	tests := []struct {
+		accept string
+		expect string
+	}{
+		{"", ""},
+		{"gzip", EncodingGzip},
+		{"gzip, br", EncodingBrotli},
+		{"br;q=0, gzip;q=0.5", EncodingGzip},
+		{"identity", ""},
+		{"*", EncodingBrotli},
+		{"*, br;q=0", EncodingZstd},
+		{"compress", ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.accept, func(t *testing.T) {
+			assert.Equal(t, test.expect, negotiateEncoding(test.accept, DefaultCompressEncodings))
+		})
+	}
+}
+
+func TestCompressMiddleware(t *testing.T) {
+	body := strings.Repeat("hello world ", 200)
+	e := echo.New()
+	e.Use(CompressMiddleware(CompressOptions{}))
+	e.GET("/large", func(ctx echo.Context) error {
+		return ctx.String(http.StatusOK, body)
+	})
+	e.GET("/small", func(ctx echo.Context) error {
+		return ctx.String(http.StatusOK, "hello")
+	})
+	e.GET("/image", func(ctx echo.Context) error {
+		return ctx.Blob(http.StatusOK, "image/png", []byte(body))
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/large", nil)
+	req.Header.Set(echo.HeaderAcceptEncoding, "gzip")
+	resp := httptest.NewRecorder()
+	e.ServeHTTP(resp, req)
+	assert.Equal(t, EncodingGzip, resp.Header().Get(echo.HeaderContentEncoding))
+	assert.Equal(t, echo.HeaderAcceptEncoding, resp.Header().Get(echo.HeaderVary))
+	reader, err := gzip.NewReader(resp.Body)
+	assert.Nil(t, err)
+	content, err := ioutil.ReadAll(reader)
+	assert.Nil(t, err)
+	assert.Equal(t, body, string(content))
+
+	req = httptest.NewRequest(http.MethodGet, "/large", nil)
+	req.Header.Set(echo.HeaderAcceptEncoding, "br")
+	resp = httptest.NewRecorder()
+	e.ServeHTTP(resp, req)
+	assert.Equal(t, EncodingBrotli, resp.Header().Get(echo.HeaderContentEncoding))
+	content, err = ioutil.ReadAll(brotli.NewReader(resp.Body))
+	assert.Nil(t, err)
+	assert.Equal(t, body, string(content))
+
+	for _, path := range []string{"/small", "/image"} {
+		req = httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set(echo.HeaderAcceptEncoding, "gzip")
+		resp = httptest.NewRecorder()
+		e.ServeHTTP(resp, req)
+		assert.Empty(t, resp.Header().Get(echo.HeaderContentEncoding))
+		assert.Equal(t, http.StatusOK, resp.Code)
+	}
+}
+
+func TestCompressMiddleware_Stream(t *testing.T) {
+	e := echo.New()
+	e.Use(CompressMiddleware(CompressOptions{}))
+	e.GET("/stream", func(ctx echo.Context) error {
+		ctx.Response().Header().Set(echo.HeaderContentType, echo.MIMETextPlain)
+		ctx.Response().WriteHeader(http.StatusOK)
+		for i := 0; i < 3; i++ {
+			_, _ = ctx.Response().Write([]byte("chunk\n"))
+			ctx.Response().Flush()
+		}
+		return nil
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/stream", nil)
+	req.Header.Set(echo.HeaderAcceptEncoding, "gzip")
+	resp := httptest.NewRecorder()
+	e.ServeHTTP(resp, req)
+	assert.True(t, resp.Flushed)
+	assert.Equal(t, EncodingGzip, resp.Header().Get(echo.HeaderContentEncoding))
+	reader, err := gzip.NewReader(resp.Body)
+	assert.Nil(t, err)
+	content, err := ioutil.ReadAll(reader)
+	assert.Nil(t, err)
+	assert.Equal(t, "chunk\nchunk\nchunk\n", string(content))
+}
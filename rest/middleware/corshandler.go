@@ -0,0 +1,228 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	anyOrigin = "*"
+	// https://*.example.com匹配example.com的所有子域名
+	wildcardSubdomain = "*."
+)
+
+var (
+	ErrCorsCredentialsWithAnyOrigin = errors.New("cors: AllowCredentials can't be used with the * origin")
+
+	DefaultCorsMethods = []string{
+		http.MethodGet,
+		http.MethodHead,
+		http.MethodPost,
+		http.MethodPut,
+		http.MethodPatch,
+		http.MethodDelete,
+	}
+)
+
+type (
+	CorsOptions struct {
+		// 允许的Origin，*代表全部，https://*.example.com代表example.com的子域名，为空时不允许跨域
+		AllowOrigins []string
+		// 为空时使用DefaultCorsMethods
+		AllowMethods []string
+		// 预检请求允许的header，为空时允许预检请求中的全部header
+		AllowHeaders     []string
+		ExposeHeaders    []string
+		AllowCredentials bool
+		// seconds, 预检结果的缓存时间，0代表不缓存
+		MaxAge int
+	}
+
+	cors struct {
+		origins          []originMatcher
+		anyOrigin        bool
+		allowMethods     string
+		allowHeaders     string
+		exposeHeaders    string
+		allowCredentials bool
+		maxAge           string
+	}
+
+	corsPrefix struct {
+		prefix string
+		cors   *cors
+	}
+
+	originMatcher struct {
+		scheme string
+		host   string
+		// host为父域名，只匹配子域名
+		subdomain bool
+	}
+)
+
+// CorsMiddleware answers the preflight requests and adds the cors headers to the responses
+// of the allowed origins. The options in groups override opts for the paths under the prefixes,
+// it's applied before routing, since the preflight requests don't match the routes of the groups.
+// A nil opts means the paths not in groups are not allowed for cross origin requests.
+func CorsMiddleware(opts *CorsOptions, groups map[string]CorsOptions) (echo.MiddlewareFunc, error) {
+	var global *cors
+	if opts != nil {
+		var err error
+		if global, err = newCors(*opts); err != nil {
+			return nil, err
+		}
+	}
+
+	var prefixes []corsPrefix
+	for prefix, o := range groups {
+		c, err := newCors(o)
+		if err != nil {
+			return nil, err
+		}
+		prefixes = append(prefixes, corsPrefix{prefix: strings.TrimSuffix(prefix, "/"), cors: c})
+	}
+	// 最长的前缀优先
+	sort.Slice(prefixes, func(i, j int) bool {
+		return len(prefixes[i].prefix) > len(prefixes[j].prefix)
+	})
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			c := global
+			path := ctx.Request().URL.Path
+			for _, p := range prefixes {
+				if path == p.prefix || strings.HasPrefix(path, p.prefix+"/") || len(p.prefix) == 0 {
+					c = p.cors
+					break
+				}
+			}
+			if c == nil {
+				return next(ctx)
+			}
+
+			return c.handle(ctx, next)
+		}
+	}, nil
+}
+
+func newCors(opts CorsOptions) (*cors, error) {
+	c := &cors{
+		allowHeaders:     strings.Join(opts.AllowHeaders, ","),
+		exposeHeaders:    strings.Join(opts.ExposeHeaders, ","),
+		allowCredentials: opts.AllowCredentials,
+	}
+	if opts.MaxAge > 0 {
+		c.maxAge = strconv.Itoa(opts.MaxAge)
+	}
+	if len(opts.AllowMethods) > 0 {
+		c.allowMethods = strings.Join(opts.AllowMethods, ",")
+	} else {
+		c.allowMethods = strings.Join(DefaultCorsMethods, ",")
+	}
+
+	for _, origin := range opts.AllowOrigins {
+		if origin == anyOrigin {
+			c.anyOrigin = true
+			continue
+		}
+
+		u, err := url.Parse(strings.ToLower(origin))
+		if err != nil || len(u.Scheme) == 0 || len(u.Host) == 0 {
+			return nil, errors.New("cors: bad origin " + origin)
+		}
+		matcher := originMatcher{scheme: u.Scheme, host: u.Host}
+		if strings.HasPrefix(u.Host, wildcardSubdomain) {
+			matcher.host = strings.TrimPrefix(u.Host, wildcardSubdomain)
+			matcher.subdomain = true
+		}
+		c.origins = append(c.origins, matcher)
+	}
+	// 浏览器不接受带凭证的*，而回显任意Origin等于关闭了跨域保护
+	if c.anyOrigin && c.allowCredentials {
+		return nil, ErrCorsCredentialsWithAnyOrigin
+	}
+
+	return c, nil
+}
+
+func (c *cors) handle(ctx echo.Context, next echo.HandlerFunc) error {
+	req := ctx.Request()
+	header := ctx.Response().Header()
+	origin := req.Header.Get(echo.HeaderOrigin)
+	preflight := req.Method == http.MethodOptions && len(req.Header.Get(echo.HeaderAccessControlRequestMethod)) > 0
+
+	// 响应随Origin变化，缓存需要区分
+	header.Add(echo.HeaderVary, echo.HeaderOrigin)
+	if preflight {
+		header.Add(echo.HeaderVary, echo.HeaderAccessControlRequestMethod)
+		header.Add(echo.HeaderVary, echo.HeaderAccessControlRequestHeaders)
+	}
+
+	if len(origin) == 0 || !c.allowed(origin) {
+		if preflight {
+			// 不带cors header，由浏览器拒绝
+			return ctx.NoContent(http.StatusNoContent)
+		}
+		return next(ctx)
+	}
+
+	if c.anyOrigin {
+		header.Set(echo.HeaderAccessControlAllowOrigin, anyOrigin)
+	} else {
+		header.Set(echo.HeaderAccessControlAllowOrigin, origin)
+	}
+	if c.allowCredentials {
+		header.Set(echo.HeaderAccessControlAllowCredentials, "true")
+	}
+
+	if !preflight {
+		if len(c.exposeHeaders) > 0 {
+			header.Set(echo.HeaderAccessControlExposeHeaders, c.exposeHeaders)
+		}
+		return next(ctx)
+	}
+
+	header.Set(echo.HeaderAccessControlAllowMethods, c.allowMethods)
+	if len(c.allowHeaders) > 0 {
+		header.Set(echo.HeaderAccessControlAllowHeaders, c.allowHeaders)
+	} else if h := req.Header.Get(echo.HeaderAccessControlRequestHeaders); len(h) > 0 {
+		header.Set(echo.HeaderAccessControlAllowHeaders, h)
+	}
+	if len(c.maxAge) > 0 {
+		header.Set(echo.HeaderAccessControlMaxAge, c.maxAge)
+	}
+	return ctx.NoContent(http.StatusNoContent)
+}
+
+func (c *cors) allowed(origin string) bool {
+	if c.anyOrigin {
+		return true
+	}
+
+	u, err := url.Parse(strings.ToLower(origin))
+	if err != nil {
+		return false
+	}
+
+	for _, m := range c.origins {
+		if m.scheme != u.Scheme {
+			continue
+		}
+		if m.subdomain {
+			if strings.HasSuffix(u.Host, "."+m.host) {
+				return true
+			}
+		} else if m.host == u.Host {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,129 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func newCorsEcho(t *testing.T, opts *CorsOptions, groups map[string]CorsOptions) *echo.Echo {
+	cors, err := CorsMiddleware(opts, groups)
+	assert.Nil(t, err)
+
+	e := echo.New()
+	e.Use(cors)
+	handler := func(ctx echo.Context) error {
+		return ctx.String(http.StatusOK, "ok")
+	}
+	e.GET("/users", handler)
+	e.POST("/admin/users", handler)
+	return e
+}
+
+func TestCorsMiddleware(t *testing.T) {
+	e := newCorsEcho(t, &CorsOptions{
+		AllowOrigins:     []string{"https://example.com", "https://*.example.org"},
+		ExposeHeaders:    []string{"X-Request-Id"},
+		AllowCredentials: true,
+		MaxAge:           600,
+	}, nil)
+
+	tests := []struct {
+		origin  string
+		allowed bool
+	}{
+		{"https://example.com", true},
+		{"http://example.com", false},
+		{"https://evil.example.com", false},
+		{"https://api.example.org", true},
+		{"https://a.b.example.org", true},
+		{"https://example.org", false},
+		{"https://evilexample.org", false},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/users", nil)
+		req.Header.Set(echo.HeaderOrigin, test.origin)
+		resp := httptest.NewRecorder()
+		e.ServeHTTP(resp, req)
+		assert.Equal(t, http.StatusOK, resp.Code)
+		if test.allowed {
+			assert.Equal(t, test.origin, resp.Header().Get(echo.HeaderAccessControlAllowOrigin), test.origin)
+			assert.Equal(t, "true", resp.Header().Get(echo.HeaderAccessControlAllowCredentials))
+			assert.Equal(t, "X-Request-Id", resp.Header().Get(echo.HeaderAccessControlExposeHeaders))
+		} else {
+			assert.Empty(t, resp.Header().Get(echo.HeaderAccessControlAllowOrigin), test.origin)
+		}
+		assert.Equal(t, echo.HeaderOrigin, resp.Header().Get(echo.HeaderVary))
+	}
+
+	// 预检请求不需要路由
+	req := httptest.NewRequest(http.MethodOptions, "/users", nil)
+	req.Header.Set(echo.HeaderOrigin, "https://example.com")
+	req.Header.Set(echo.HeaderAccessControlRequestMethod, http.MethodPut)
+	req.Header.Set(echo.HeaderAccessControlRequestHeaders, "Content-Type,X-CSRF-Token")
+	resp := httptest.NewRecorder()
+	e.ServeHTTP(resp, req)
+	assert.Equal(t, http.StatusNoContent, resp.Code)
+	assert.Equal(t, "https://example.com", resp.Header().Get(echo.HeaderAccessControlAllowOrigin))
+	assert.Equal(t, "GET,HEAD,POST,PUT,PATCH,DELETE", resp.Header().Get(echo.HeaderAccessControlAllowMethods))
+	assert.Equal(t, "Content-Type,X-CSRF-Token", resp.Header().Get(echo.HeaderAccessControlAllowHeaders))
+	assert.Equal(t, "600", resp.Header().Get(echo.HeaderAccessControlMaxAge))
+
+	req = httptest.NewRequest(http.MethodOptions, "/users", nil)
+	req.Header.Set(echo.HeaderOrigin, "https://evil.com")
+	req.Header.Set(echo.HeaderAccessControlRequestMethod, http.MethodPut)
+	resp = httptest.NewRecorder()
+	e.ServeHTTP(resp, req)
+	assert.Equal(t, http.StatusNoContent, resp.Code)
+	assert.Empty(t, resp.Header().Get(echo.HeaderAccessControlAllowOrigin))
+	assert.Empty(t, resp.Header().Get(echo.HeaderAccessControlAllowMethods))
+}
+
+func TestCorsMiddlewareGroups(t *testing.T) {
+	e := newCorsEcho(t, nil, map[string]CorsOptions{
+		"/admin/": {
+			AllowOrigins: []string{"*"},
+			AllowMethods: []string{http.MethodPost},
+		},
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/users", nil)
+	req.Header.Set(echo.HeaderOrigin, "https://example.com")
+	resp := httptest.NewRecorder()
+	e.ServeHTTP(resp, req)
+	assert.Empty(t, resp.Header().Get(echo.HeaderAccessControlAllowOrigin))
+
+	req = httptest.NewRequest(http.MethodOptions, "/admin/users", nil)
+	req.Header.Set(echo.HeaderOrigin, "https://example.com")
+	req.Header.Set(echo.HeaderAccessControlRequestMethod, http.MethodPost)
+	resp = httptest.NewRecorder()
+	e.ServeHTTP(resp, req)
+	assert.Equal(t, http.StatusNoContent, resp.Code)
+	assert.Equal(t, "*", resp.Header().Get(echo.HeaderAccessControlAllowOrigin))
+	assert.Equal(t, http.MethodPost, resp.Header().Get(echo.HeaderAccessControlAllowMethods))
+	assert.Empty(t, resp.Header().Get(echo.HeaderAccessControlMaxAge))
+
+	// 前缀按路径段匹配
+	req = httptest.NewRequest(http.MethodOptions, "/administrators", nil)
+	req.Header.Set(echo.HeaderOrigin, "https://example.com")
+	req.Header.Set(echo.HeaderAccessControlRequestMethod, http.MethodPost)
+	resp = httptest.NewRecorder()
+	e.ServeHTTP(resp, req)
+	assert.Empty(t, resp.Header().Get(echo.HeaderAccessControlAllowOrigin))
+}
+
+func TestCorsMiddlewareInvalid(t *testing.T) {
+	_, err := CorsMiddleware(&CorsOptions{
+		AllowOrigins:     []string{"*"},
+		AllowCredentials: true,
+	}, nil)
+	assert.Equal(t, ErrCorsCredentialsWithAnyOrigin, err)
+
+	_, err = CorsMiddleware(nil, map[string]CorsOptions{
+		"/api": {AllowOrigins: []string{"example.com"}},
+	})
+	assert.NotNil(t, err)
+}
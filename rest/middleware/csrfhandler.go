@@ -0,0 +1,111 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	defaultCsrfCookie = "_csrf"
+	defaultCsrfMaxAge = 86400
+	csrfTokenBytes    = 32
+	csrfContextKey    = "csrf"
+)
+
+type CsrfOptions struct {
+	// 存放token的cookie，默认_csrf，不能是HttpOnly的，前端需要读取它放到header中
+	CookieName   string
+	CookieDomain string
+	// 默认/
+	CookiePath   string
+	CookieSecure bool
+	// 默认Lax
+	CookieSameSite http.SameSite
+	// seconds, 默认一天
+	MaxAge int
+	// 非安全方法需要带上的header，默认X-CSRF-Token
+	HeaderName string
+}
+
+// CsrfMiddleware protects the cookie authenticated routes by the double submit cookie,
+// the token is set in the cookie, and the unsafe requests must carry the same token in the header,
+// which the cross-site pages can't read.
+func CsrfMiddleware(opts CsrfOptions) echo.MiddlewareFunc {
+	if len(opts.CookieName) == 0 {
+		opts.CookieName = defaultCsrfCookie
+	}
+	if len(opts.CookiePath) == 0 {
+		opts.CookiePath = "/"
+	}
+	if opts.CookieSameSite == 0 {
+		opts.CookieSameSite = http.SameSiteLaxMode
+	}
+	if opts.MaxAge <= 0 {
+		opts.MaxAge = defaultCsrfMaxAge
+	}
+	if len(opts.HeaderName) == 0 {
+		opts.HeaderName = echo.HeaderXCSRFToken
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			var token string
+			if cookie, err := ctx.Cookie(opts.CookieName); err == nil && validCsrfToken(cookie.Value) {
+				token = cookie.Value
+			}
+
+			if !isSafeMethod(ctx.Request().Method) {
+				sent := ctx.Request().Header.Get(opts.HeaderName)
+				if len(token) == 0 || subtle.ConstantTimeCompare([]byte(token), []byte(sent)) != 1 {
+					return echo.NewHTTPError(http.StatusForbidden, "invalid csrf token")
+				}
+			}
+
+			if len(token) == 0 {
+				token = newCsrfToken()
+				ctx.SetCookie(&http.Cookie{
+					Name:     opts.CookieName,
+					Value:    token,
+					Domain:   opts.CookieDomain,
+					Path:     opts.CookiePath,
+					Expires:  time.Now().Add(time.Duration(opts.MaxAge) * time.Second),
+					MaxAge:   opts.MaxAge,
+					Secure:   opts.CookieSecure,
+					SameSite: opts.CookieSameSite,
+				})
+			}
+			ctx.Set(csrfContextKey, token)
+
+			return next(ctx)
+		}
+	}
+}
+
+// CsrfToken returns the csrf token of the request, to render it in the pages.
+func CsrfToken(ctx echo.Context) string {
+	token, _ := ctx.Get(csrfContextKey).(string)
+	return token
+}
+
+func newCsrfToken() string {
+	b := make([]byte, csrfTokenBytes)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+
+	return hex.EncodeToString(b)
+}
+
+func validCsrfToken(token string) bool {
+	if len(token) != csrfTokenBytes*2 {
+		return false
+	}
+
+	_, err := hex.DecodeString(token)
+	return err == nil
+}
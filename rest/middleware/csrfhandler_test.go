@@ -0,0 +1,61 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCsrfMiddleware(t *testing.T) {
+	e := echo.New()
+	e.Use(CsrfMiddleware(CsrfOptions{CookieSecure: true}))
+	handler := func(ctx echo.Context) error {
+		return ctx.String(http.StatusOK, CsrfToken(ctx))
+	}
+	e.GET("/form", handler)
+	e.POST("/form", handler)
+
+	// 安全方法下发token
+	resp := httptest.NewRecorder()
+	e.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/form", nil))
+	assert.Equal(t, http.StatusOK, resp.Code)
+	cookies := resp.Result().Cookies()
+	assert.Len(t, cookies, 1)
+	cookie := cookies[0]
+	assert.Equal(t, defaultCsrfCookie, cookie.Name)
+	assert.Equal(t, "/", cookie.Path)
+	assert.True(t, cookie.Secure)
+	assert.False(t, cookie.HttpOnly)
+	assert.Equal(t, http.SameSiteLaxMode, cookie.SameSite)
+	assert.Equal(t, cookie.Value, resp.Body.String())
+	assert.True(t, validCsrfToken(cookie.Value))
+
+	post := func(cookie *http.Cookie, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/form", nil)
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+		if len(token) > 0 {
+			req.Header.Set(echo.HeaderXCSRFToken, token)
+		}
+		resp := httptest.NewRecorder()
+		e.ServeHTTP(resp, req)
+		return resp
+	}
+
+	assert.Equal(t, http.StatusForbidden, post(nil, cookie.Value).Code)
+	assert.Equal(t, http.StatusForbidden, post(cookie, "").Code)
+	assert.Equal(t, http.StatusForbidden, post(cookie, newCsrfToken()).Code)
+	resp = post(cookie, cookie.Value)
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, cookie.Value, resp.Body.String())
+	// 已有token时不重新下发
+	assert.Empty(t, resp.Result().Cookies())
+
+	// 格式不对的cookie视为没有
+	invalid := &http.Cookie{Name: defaultCsrfCookie, Value: "abc"}
+	assert.Equal(t, http.StatusForbidden, post(invalid, "abc").Code)
+}
@@ -0,0 +1,23 @@
+package middleware
+
+import "github.com/labstack/echo/v4"
+
+const droppedReasonKey = "ezio:dropped_reason"
+
+const (
+	DroppedByShedding  = "shedding"
+	DroppedByTimeout   = "timeout"
+	DroppedByMaxConns  = "maxconns"
+	DroppedByBreaker   = "breaker"
+	DroppedByRateLimit = "ratelimit"
+)
+
+// MarkDropped records why the request is rejected, PrometheusMiddleware counts it by reason.
+func MarkDropped(ctx echo.Context, reason string) {
+	ctx.Set(droppedReasonKey, reason)
+}
+
+func droppedReason(ctx echo.Context) string {
+	reason, _ := ctx.Get(droppedReasonKey).(string)
+	return reason
+}
@@ -7,8 +7,14 @@ import (
 	"strings"
 )
 
-const gzipEncoding = "gzip"
+const (
+	gzipEncoding = "gzip"
+	// 没有BodyLimitMiddleware时解压后的最大body，和Conf.MaxBytes的上限一致
+	defaultGunzipMaxBytes = 8 << 20
+)
 
+// GunzipMiddleware decompresses the gzip request body, the decompressed body is limited
+// by the limit of BodyLimitMiddleware as well, to prevent the decompression bombs.
 func GunzipMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
 	return func(ctx echo.Context) error {
 		if strings.Contains(ctx.Request().Header.Get(echo.HeaderContentEncoding), gzipEncoding) {
@@ -17,8 +23,17 @@ func GunzipMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
 				ctx.Response().WriteHeader(http.StatusBadRequest)
 				return nil
 			}
+
+			limit, ok := bodyLimit(ctx)
+			if !ok {
+				limit = defaultGunzipMaxBytes
+			}
 			r := ctx.Request()
-			r.Body = reader
+			r.Body = &limitedBody{ReadCloser: reader, limit: limit}
+			// 解压后的长度未知
+			r.ContentLength = -1
+			r.Header.Del(echo.HeaderContentEncoding)
+			r.Header.Del(echo.HeaderContentLength)
 			ctx.SetRequest(r)
 		}
 		return next(ctx)
@@ -6,6 +6,7 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"strings"
 	"sync"
 	"testing"
@@ -76,3 +77,30 @@ func TestGunzipHandler_NoGzipButTelling(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, http.StatusBadRequest, resp.Code)
 }
+
+func TestGunzipHandlerBomb(t *testing.T) {
+	e := echo.New()
+	e.Use(BodyLimitMiddleware(1024, nil), GunzipMiddleware)
+	e.POST("/", func(ctx echo.Context) error {
+		body, err := ioutil.ReadAll(ctx.Request().Body)
+		if err != nil {
+			return err
+		}
+		return ctx.String(http.StatusOK, strconv.Itoa(len(body)))
+	})
+
+	post := func(size int) *httptest.ResponseRecorder {
+		compressed := codec.Gzip(bytes.Repeat([]byte{'a'}, size))
+		req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(compressed))
+		req.Header.Set(echo.HeaderContentEncoding, gzipEncoding)
+		resp := httptest.NewRecorder()
+		e.ServeHTTP(resp, req)
+		return resp
+	}
+
+	resp := post(1024)
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, "1024", resp.Body.String())
+	// 压缩后很小，解压后超过限制
+	assert.Equal(t, http.StatusRequestEntityTooLarge, post(1<<20).Code)
+}
@@ -0,0 +1,168 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/valeamoris/go-ezio/core/stores/redis"
+)
+
+const (
+	HeaderHmacKeyId     = "X-Key-Id"
+	HeaderHmacTimestamp = "X-Timestamp"
+	HeaderHmacNonce     = "X-Nonce"
+	HeaderHmacSignature = "X-Signature"
+
+	defaultHmacWindow = 5 * time.Minute
+	defaultHmacPrefix = "hmac:nonce:"
+	maxHmacNonceLen   = 128
+	hmacNonceBytes    = 16
+)
+
+type (
+	HmacKey struct {
+		Secret    string
+		Principal Principal
+	}
+
+	// HmacKeyStore finds the key by the key id, the key is nil if it doesn't exist.
+	HmacKeyStore interface {
+		FindHmacKey(ctx context.Context, keyId string) (*HmacKey, error)
+	}
+
+	// StaticHmacKeys is the HmacKeyStore of the fixed keys, keyed by the key ids.
+	StaticHmacKeys map[string]HmacKey
+
+	HmacOptions struct {
+		// 请求时间和服务器时间允许的偏差，默认5分钟，nonce保存两倍的时间
+		Window time.Duration
+		// nonce的redis key前缀，默认hmac:nonce:
+		Prefix string
+	}
+)
+
+// HmacMiddleware authenticates the requests signed by SignRequest, the signature covers the method,
+// the uri, the timestamp, the nonce and the body. The nonces are saved in redis by SETNX,
+// so the same signed request can't be replayed.
+func HmacMiddleware(keys HmacKeyStore, nonces redis.Node, opts HmacOptions) echo.MiddlewareFunc {
+	if opts.Window <= 0 {
+		opts.Window = defaultHmacWindow
+	}
+	if len(opts.Prefix) == 0 {
+		opts.Prefix = defaultHmacPrefix
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			req := ctx.Request()
+			keyId := req.Header.Get(HeaderHmacKeyId)
+			timestamp := req.Header.Get(HeaderHmacTimestamp)
+			nonce := req.Header.Get(HeaderHmacNonce)
+			signature := req.Header.Get(HeaderHmacSignature)
+			if len(keyId) == 0 || len(timestamp) == 0 || len(nonce) == 0 || len(signature) == 0 {
+				return echo.NewHTTPError(http.StatusUnauthorized, "missing signature")
+			}
+			if len(nonce) > maxHmacNonceLen {
+				return echo.NewHTTPError(http.StatusUnauthorized, "invalid nonce")
+			}
+
+			ts, err := strconv.ParseInt(timestamp, 10, 64)
+			if err != nil {
+				return echo.NewHTTPError(http.StatusUnauthorized, "invalid timestamp")
+			}
+			if skew := time.Since(time.Unix(ts, 0)); skew > opts.Window || skew < -opts.Window {
+				return echo.NewHTTPError(http.StatusUnauthorized, "request expired")
+			}
+
+			key, err := keys.FindHmacKey(req.Context(), keyId)
+			if err != nil {
+				return err
+			}
+			if key == nil {
+				return echo.NewHTTPError(http.StatusUnauthorized, "invalid key id")
+			}
+
+			expected, err := hmacSignature(req, key.Secret, timestamp, nonce)
+			if err != nil {
+				return err
+			}
+			if !hmac.Equal([]byte(expected), []byte(strings.ToLower(signature))) {
+				return echo.NewHTTPError(http.StatusUnauthorized, "invalid signature")
+			}
+
+			// 签名通过后再记录nonce，未认证的请求不能写入redis
+			ok, err := nonces.SetNX(req.Context(), opts.Prefix+keyId+":"+nonce, 1, 2*opts.Window).Result()
+			if err != nil {
+				return err
+			}
+			if !ok {
+				return echo.NewHTTPError(http.StatusUnauthorized, "replayed request")
+			}
+
+			principal := key.Principal
+			setPrincipal(ctx, &principal)
+			return next(ctx)
+		}
+	}
+}
+
+// SignRequest signs the request with the key for HmacMiddleware, it should be called after the body is set.
+func SignRequest(req *http.Request, keyId, secret string) error {
+	nonce := make([]byte, hmacNonceBytes)
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	signature, err := hmacSignature(req, secret, timestamp, hex.EncodeToString(nonce))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set(HeaderHmacKeyId, keyId)
+	req.Header.Set(HeaderHmacTimestamp, timestamp)
+	req.Header.Set(HeaderHmacNonce, hex.EncodeToString(nonce))
+	req.Header.Set(HeaderHmacSignature, signature)
+	return nil
+}
+
+func (s StaticHmacKeys) FindHmacKey(_ context.Context, keyId string) (*HmacKey, error) {
+	key, ok := s[keyId]
+	if !ok {
+		return nil, nil
+	}
+
+	return &key, nil
+}
+
+func hmacSignature(req *http.Request, secret, timestamp, nonce string) (string, error) {
+	bodyHash := sha256.New()
+	if req.Body != nil && req.Body != http.NoBody {
+		body, err := ioutil.ReadAll(req.Body)
+		if err != nil {
+			return "", err
+		}
+		req.Body = ioutil.NopCloser(bytes.NewReader(body))
+		bodyHash.Write(body)
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strings.Join([]string{
+		req.Method,
+		req.URL.RequestURI(),
+		timestamp,
+		nonce,
+		hex.EncodeToString(bodyHash.Sum(nil)),
+	}, "\n")))
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
@@ -0,0 +1,73 @@
+package middleware
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/valeamoris/go-ezio/core/stores/redis"
+)
+
+func TestHmacMiddleware(t *testing.T) {
+	s, err := miniredis.Run()
+	assert.Nil(t, err)
+	defer s.Close()
+	node, err := redis.NewRedis(s.Addr(), redis.NodeType)
+	assert.Nil(t, err)
+
+	e := echo.New()
+	e.Use(HmacMiddleware(StaticHmacKeys{
+		"partner": {Secret: "hmac-secret", Principal: Principal{Subject: "partner"}},
+	}, node, HmacOptions{}))
+	e.POST("/orders", func(ctx echo.Context) error {
+		principal, ok := PrincipalFrom(ctx)
+		assert.True(t, ok)
+		// 签名时读取的body需要还原
+		body, err := ioutil.ReadAll(ctx.Request().Body)
+		if err != nil {
+			return err
+		}
+		return ctx.String(http.StatusOK, principal.Subject+":"+string(body))
+	})
+
+	newRequest := func(keyId, secret string) *http.Request {
+		req := httptest.NewRequest(http.MethodPost, "/orders?id=1", strings.NewReader(`{"amount":1}`))
+		assert.Nil(t, SignRequest(req, keyId, secret))
+		return req
+	}
+	serve := func(req *http.Request) *httptest.ResponseRecorder {
+		resp := httptest.NewRecorder()
+		e.ServeHTTP(resp, req)
+		return resp
+	}
+
+	req := newRequest("partner", "hmac-secret")
+	replay := req.Clone(req.Context())
+	replay.Body = ioutil.NopCloser(strings.NewReader(`{"amount":1}`))
+	resp := serve(req)
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, `partner:{"amount":1}`, resp.Body.String())
+	// 相同的nonce不能重放
+	assert.Equal(t, http.StatusUnauthorized, serve(replay).Code)
+
+	assert.Equal(t, http.StatusUnauthorized, serve(newRequest("partner", "wrong")).Code)
+	assert.Equal(t, http.StatusUnauthorized, serve(newRequest("unknown", "hmac-secret")).Code)
+
+	tampered := newRequest("partner", "hmac-secret")
+	tampered.Body = ioutil.NopCloser(strings.NewReader(`{"amount":100}`))
+	assert.Equal(t, http.StatusUnauthorized, serve(tampered).Code)
+
+	expired := newRequest("partner", "hmac-secret")
+	expired.Header.Set(HeaderHmacTimestamp, strconv.FormatInt(time.Now().Add(-time.Hour).Unix(), 10))
+	assert.Equal(t, http.StatusUnauthorized, serve(expired).Code)
+
+	assert.Equal(t, http.StatusUnauthorized,
+		serve(httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader("{}"))).Code)
+}
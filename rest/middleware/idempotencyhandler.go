@@ -0,0 +1,307 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	red "github.com/go-redis/redis/v8"
+	"github.com/labstack/echo/v4"
+	"github.com/valeamoris/go-ezio/core/stores/redis"
+	"github.com/zeromicro/go-zero/core/logx"
+	"github.com/zeromicro/go-zero/core/threading"
+)
+
+const (
+	HeaderIdempotencyKey      = "Idempotency-Key"
+	HeaderIdempotentReplayed  = "Idempotent-Replayed"
+	defaultIdempotencyPrefix  = "idempotency:"
+	defaultIdempotencyTTL     = time.Hour * 24
+	defaultIdempotencyLock    = time.Second * 30
+	defaultIdempotencyMaxBody = 1 << 20
+	maxIdempotencyKeyLen      = 255
+)
+
+// 每个请求各自的header，不保存到幂等响应中，重放时由中间件重新生成
+var (
+	perRequestHeaders = map[string]bool{
+		http.CanonicalHeaderKey(echo.HeaderXRequestID):    true,
+		http.CanonicalHeaderKey(HeaderXCache):             true,
+		http.CanonicalHeaderKey(HeaderIdempotentReplayed): true,
+		http.CanonicalHeaderKey(echo.HeaderVary):          true,
+		http.CanonicalHeaderKey(echo.HeaderContentLength): true,
+		http.CanonicalHeaderKey("Retry-After"):            true,
+		http.CanonicalHeaderKey("Date"):                   true,
+	}
+	perRequestHeaderPrefixes = []string{
+		http.CanonicalHeaderKey("X-RateLimit-"),
+		http.CanonicalHeaderKey("Access-Control-"),
+	}
+)
+
+type (
+	IdempotencyOptions struct {
+		// 幂等key的header，默认Idempotency-Key
+		Header string
+		// redis key的前缀，默认idempotency:
+		Prefix string
+		// 响应的保存时间，默认24小时
+		TTL time.Duration
+		// 首个请求执行期间锁的过期时间，执行期间每半个过期时间续期一次，默认30秒
+		LockExpire time.Duration
+		// 可保存的最大响应body，超过时不保存，默认1M
+		MaxBodyBytes int
+	}
+
+	idempotentResponse struct {
+		Fingerprint string      `json:"fingerprint"`
+		Status      int         `json:"status"`
+		Header      http.Header `json:"header"`
+		Body        []byte      `json:"body"`
+	}
+)
+
+// IdempotencyMiddleware replays the stored response for the retried unsafe requests
+// with the same Idempotency-Key, the requests without the header are not affected.
+// The keys are scoped by the authenticated subject, the method and the route, so it should
+// be used after the authentication middleware.
+func IdempotencyMiddleware(store redis.Node, opts IdempotencyOptions) echo.MiddlewareFunc {
+	if len(opts.Header) == 0 {
+		opts.Header = HeaderIdempotencyKey
+	}
+	if len(opts.Prefix) == 0 {
+		opts.Prefix = defaultIdempotencyPrefix
+	}
+	if opts.TTL <= 0 {
+		opts.TTL = defaultIdempotencyTTL
+	}
+	if opts.LockExpire <= 0 {
+		opts.LockExpire = defaultIdempotencyLock
+	}
+	if opts.MaxBodyBytes <= 0 {
+		opts.MaxBodyBytes = defaultIdempotencyMaxBody
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			req := ctx.Request()
+			key := req.Header.Get(opts.Header)
+			if len(key) == 0 || isSafeMethod(req.Method) {
+				return next(ctx)
+			}
+			if len(key) > maxIdempotencyKeyLen {
+				return echo.NewHTTPError(http.StatusBadRequest, "idempotency key is too long")
+			}
+
+			fingerprint, err := requestFingerprint(req)
+			if err != nil {
+				return err
+			}
+
+			key = opts.Prefix + idempotencyKey(authSubject(ctx), req.Method, ctx.Path(), key)
+			stored, err := loadResponse(req.Context(), store, key)
+			if err != nil {
+				logx.Errorf("idempotency disabled for %s, redis error: %s", key, err.Error())
+				return next(ctx)
+			} else if stored != nil {
+				return replayResponse(ctx, stored, fingerprint)
+			}
+
+			lock := redis.NewRedisLock(store, key+":lock")
+			lock.SetExpire(int(opts.LockExpire / time.Second))
+			acquired, err := lock.Acquire(req.Context())
+			if err != nil {
+				logx.Errorf("idempotency disabled for %s, redis error: %s", key, err.Error())
+				return next(ctx)
+			} else if !acquired {
+				return echo.NewHTTPError(http.StatusConflict, "a request with the same idempotency key is in progress")
+			}
+			defer func() {
+				// 请求的context可能已经超时取消
+				if _, err := lock.Release(context.Background()); err != nil {
+					logx.Errorf("failed to release idempotency lock of %s: %s", key, err.Error())
+				}
+			}()
+
+			// 关闭超时的请求可能执行得比锁更久
+			stopRenew := renewLock(lock, opts.LockExpire, key)
+			defer stopRenew()
+
+			// 拿到锁之前，前一个请求可能刚好完成
+			if stored, err := loadResponse(req.Context(), store, key); err == nil && stored != nil {
+				return replayResponse(ctx, stored, fingerprint)
+			}
+
+			resp := ctx.Response()
+			writer := resp.Writer
+			body := &limitedBuffer{limit: opts.MaxBodyBytes}
+			resp.Writer = &responseCapturer{ResponseWriter: writer, buf: body}
+			err = next(ctx)
+			resp.Writer = writer
+
+			// 出错和5xx的请求允许客户端重试
+			if err != nil || !resp.Committed || resp.Status >= http.StatusInternalServerError || body.truncated {
+				return err
+			}
+
+			saveResponse(store, key, opts.TTL, idempotentResponse{
+				Fingerprint: fingerprint,
+				Status:      resp.Status,
+				Header:      storedHeader(resp.Header()),
+				Body:        body.Bytes(),
+			})
+			return nil
+		}
+	}
+}
+
+// renewLock extends the lock every half of the expire until the returned func is called,
+// which waits for the renewal to stop, so that the lock isn't acquired again after released.
+func renewLock(lock *redis.Lock, expire time.Duration, key string) func() {
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	threading.GoSafe(func() {
+		defer close(stopped)
+
+		ticker := time.NewTicker(expire / 2)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				// 同一个锁再次Acquire会重置过期时间
+				if _, err := lock.Acquire(context.Background()); err != nil {
+					logx.Errorf("failed to renew idempotency lock of %s: %s", key, err.Error())
+				}
+			}
+		}
+	})
+
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
+
+// idempotencyKey hashes the client's key with its scope, the same key of different subjects
+// or routes doesn't replay or block each other.
+func idempotencyKey(subject, method, path, key string) string {
+	hash := sha256.New()
+	for _, part := range []string{subject, method, path, key} {
+		hash.Write([]byte(part))
+		// 分隔各部分，避免拼接后相同
+		hash.Write([]byte{0})
+	}
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// authSubject is the subject of the token set by the JWT, api key or hmac middleware,
+// empty for the anonymous requests.
+func authSubject(ctx echo.Context) string {
+	if token, ok := ctx.Get(jwtContextKey).(*jwt.Token); ok && token != nil {
+		return jwtSubject(token.Claims)
+	}
+
+	return ""
+}
+
+func isSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	default:
+		return false
+	}
+}
+
+func requestFingerprint(req *http.Request) (string, error) {
+	hash := sha256.New()
+	hash.Write([]byte(req.Method))
+	hash.Write([]byte(req.URL.RequestURI()))
+	if req.Body != nil && req.Body != http.NoBody {
+		body, err := ioutil.ReadAll(req.Body)
+		if err != nil {
+			return "", err
+		}
+		req.Body = ioutil.NopCloser(bytes.NewReader(body))
+		hash.Write(body)
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+func loadResponse(ctx context.Context, store redis.Node, key string) (*idempotentResponse, error) {
+	val, err := store.Get(ctx, key).Bytes()
+	if err == red.Nil {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var stored idempotentResponse
+	if err := json.Unmarshal(val, &stored); err != nil {
+		return nil, err
+	}
+
+	return &stored, nil
+}
+
+func replayResponse(ctx echo.Context, stored *idempotentResponse, fingerprint string) error {
+	if stored.Fingerprint != fingerprint {
+		return echo.NewHTTPError(http.StatusUnprocessableEntity,
+			"idempotency key is reused with a different request")
+	}
+
+	header := ctx.Response().Header()
+	for k, v := range stored.Header {
+		header[k] = v
+	}
+	header.Set(HeaderIdempotentReplayed, "true")
+	return ctx.Blob(stored.Status, header.Get(echo.HeaderContentType), stored.Body)
+}
+
+// storedHeader returns the header without the per-request ones, such as the request id,
+// the rate limit and the cors headers, which would be wrong for the replayed request.
+func storedHeader(header http.Header) http.Header {
+	stored := make(http.Header, len(header))
+	for k, v := range header {
+		key := http.CanonicalHeaderKey(k)
+		if perRequestHeaders[key] || hasAnyPrefix(key, perRequestHeaderPrefixes) {
+			continue
+		}
+		stored[key] = v
+	}
+
+	return stored
+}
+
+func hasAnyPrefix(s string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(s, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func saveResponse(store redis.Node, key string, ttl time.Duration, resp idempotentResponse) {
+	val, err := json.Marshal(resp)
+	if err != nil {
+		logx.Error(err)
+		return
+	}
+
+	if err := store.Set(context.Background(), key, val, ttl).Err(); err != nil {
+		logx.Errorf("failed to save idempotent response of %s: %s", key, err.Error())
+	}
+}
@@ -0,0 +1,204 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/dgrijalva/jwt-go"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/valeamoris/go-ezio/core/stores/redis"
+)
+
+// 匿名请求POST /orders的幂等key a
+var orderKey = "idempotency:" + idempotencyKey("", http.MethodPost, "/orders", "a")
+
+func TestIdempotencyMiddleware(t *testing.T) {
+	s, err := miniredis.Run()
+	assert.Nil(t, err)
+	defer s.Close()
+	store, err := redis.NewRedis(s.Addr(), redis.NodeType)
+	assert.Nil(t, err)
+
+	var count int32
+	e := echo.New()
+	e.Use(IdempotencyMiddleware(store, IdempotencyOptions{}))
+	e.POST("/orders", func(ctx echo.Context) error {
+		atomic.AddInt32(&count, 1)
+		ctx.Response().Header().Set("X-Order", "1")
+		ctx.Response().Header().Set(echo.HeaderXRequestID, "first")
+		return ctx.String(http.StatusCreated, "created")
+	})
+
+	post := func(key, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader(body))
+		if len(key) > 0 {
+			req.Header.Set(HeaderIdempotencyKey, key)
+		}
+		resp := httptest.NewRecorder()
+		e.ServeHTTP(resp, req)
+		return resp
+	}
+
+	resp := post("a", "foo")
+	assert.Equal(t, http.StatusCreated, resp.Code)
+	assert.Empty(t, resp.Header().Get(HeaderIdempotentReplayed))
+
+	resp = post("a", "foo")
+	assert.Equal(t, http.StatusCreated, resp.Code)
+	assert.Equal(t, "created", resp.Body.String())
+	assert.Equal(t, "1", resp.Header().Get("X-Order"))
+	assert.Empty(t, resp.Header().Get(echo.HeaderXRequestID))
+	assert.Equal(t, "true", resp.Header().Get(HeaderIdempotentReplayed))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&count))
+
+	resp = post("a", "bar")
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.Code)
+
+	post("", "foo")
+	post("", "foo")
+	assert.Equal(t, int32(3), atomic.LoadInt32(&count))
+}
+
+func TestStoredHeader(t *testing.T) {
+	header := http.Header{}
+	header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	header.Set("X-Order", "1")
+	header.Set(echo.HeaderXRequestID, "id")
+	header.Set(HeaderRateLimitRemaining, "9")
+	header.Set(echo.HeaderAccessControlAllowOrigin, "*")
+	header.Set(echo.HeaderVary, echo.HeaderOrigin)
+	header.Set("Retry-After", "1")
+	header.Set(HeaderXCache, "MISS")
+
+	assert.Equal(t, http.Header{
+		echo.HeaderContentType: {echo.MIMEApplicationJSON},
+		"X-Order":              {"1"},
+	}, storedHeader(header))
+}
+
+func TestIdempotencyMiddleware_Conflict(t *testing.T) {
+	s, err := miniredis.Run()
+	assert.Nil(t, err)
+	defer s.Close()
+	store, err := redis.NewRedis(s.Addr(), redis.NodeType)
+	assert.Nil(t, err)
+	assert.Nil(t, s.Set(orderKey+":lock", "other"))
+
+	e := echo.New()
+	e.Use(IdempotencyMiddleware(store, IdempotencyOptions{}))
+	e.POST("/orders", func(ctx echo.Context) error {
+		return ctx.NoContent(http.StatusCreated)
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/orders", nil)
+	req.Header.Set(HeaderIdempotencyKey, "a")
+	resp := httptest.NewRecorder()
+	e.ServeHTTP(resp, req)
+	assert.Equal(t, http.StatusConflict, resp.Code)
+}
+
+func TestIdempotencyMiddleware_ServerError(t *testing.T) {
+	s, err := miniredis.Run()
+	assert.Nil(t, err)
+	defer s.Close()
+	store, err := redis.NewRedis(s.Addr(), redis.NodeType)
+	assert.Nil(t, err)
+
+	e := echo.New()
+	e.Use(IdempotencyMiddleware(store, IdempotencyOptions{}))
+	e.POST("/orders", func(ctx echo.Context) error {
+		return ctx.NoContent(http.StatusInternalServerError)
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/orders", nil)
+	req.Header.Set(HeaderIdempotencyKey, "a")
+	e.ServeHTTP(httptest.NewRecorder(), req)
+	assert.False(t, s.Exists(orderKey))
+	assert.False(t, s.Exists(orderKey+":lock"))
+}
+
+func TestIdempotencyMiddleware_RenewLock(t *testing.T) {
+	s, err := miniredis.Run()
+	assert.Nil(t, err)
+	defer s.Close()
+	store, err := redis.NewRedis(s.Addr(), redis.NodeType)
+	assert.Nil(t, err)
+
+	var locked []bool
+	e := echo.New()
+	// 不足1秒的锁在redis中的过期时间是500ms
+	e.Use(IdempotencyMiddleware(store, IdempotencyOptions{LockExpire: 100 * time.Millisecond}))
+	e.POST("/orders", func(ctx echo.Context) error {
+		for i := 0; i < 3; i++ {
+			time.Sleep(100 * time.Millisecond)
+			s.FastForward(400 * time.Millisecond)
+			locked = append(locked, s.Exists(orderKey+":lock"))
+		}
+		return ctx.NoContent(http.StatusCreated)
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/orders", nil)
+	req.Header.Set(HeaderIdempotencyKey, "a")
+	e.ServeHTTP(httptest.NewRecorder(), req)
+	assert.Equal(t, []bool{true, true, true}, locked)
+	// 释放之后不再续期
+	time.Sleep(100 * time.Millisecond)
+	assert.False(t, s.Exists(orderKey+":lock"))
+}
+
+func TestIdempotencyMiddleware_Scope(t *testing.T) {
+	s, err := miniredis.Run()
+	assert.Nil(t, err)
+	defer s.Close()
+	store, err := redis.NewRedis(s.Addr(), redis.NodeType)
+	assert.Nil(t, err)
+
+	var count int32
+	e := echo.New()
+	e.Use(func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			// 模拟JWT中间件
+			if sub := ctx.Request().Header.Get("X-User"); len(sub) > 0 {
+				ctx.Set(jwtContextKey, &jwt.Token{Claims: jwt.MapClaims{"sub": sub}, Valid: true})
+			}
+			return next(ctx)
+		}
+	})
+	e.Use(IdempotencyMiddleware(store, IdempotencyOptions{}))
+	handler := func(ctx echo.Context) error {
+		atomic.AddInt32(&count, 1)
+		return ctx.String(http.StatusCreated, ctx.Request().Header.Get("X-User"))
+	}
+	e.POST("/orders", handler)
+	e.PUT("/orders", handler)
+	e.POST("/payments", handler)
+
+	serve := func(method, path, user string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader("foo"))
+		req.Header.Set(HeaderIdempotencyKey, "a")
+		req.Header.Set("X-User", user)
+		resp := httptest.NewRecorder()
+		e.ServeHTTP(resp, req)
+		return resp
+	}
+
+	assert.Equal(t, "alice", serve(http.MethodPost, "/orders", "alice").Body.String())
+	// 其他主体的相同key不会拿到alice的响应
+	resp := serve(http.MethodPost, "/orders", "bob")
+	assert.Equal(t, "bob", resp.Body.String())
+	assert.Empty(t, resp.Header().Get(HeaderIdempotentReplayed))
+	serve(http.MethodPut, "/orders", "alice")
+	serve(http.MethodPost, "/payments", "alice")
+	assert.Equal(t, int32(4), atomic.LoadInt32(&count))
+
+	resp = serve(http.MethodPost, "/orders", "alice")
+	assert.Equal(t, "alice", resp.Body.String())
+	assert.Equal(t, "true", resp.Header().Get(HeaderIdempotentReplayed))
+	assert.Equal(t, int32(4), atomic.LoadInt32(&count))
+}
@@ -0,0 +1,245 @@
+package middleware
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/zeromicro/go-zero/core/conf"
+	"github.com/zeromicro/go-zero/core/logx"
+	"github.com/zeromicro/go-zero/core/threading"
+)
+
+// Internal代表InternalNetworks
+const Internal = "internal"
+
+// 回环地址和私有网络
+var InternalNetworks = []string{
+	"127.0.0.0/8",
+	"10.0.0.0/8",
+	"172.16.0.0/12",
+	"192.168.0.0/16",
+	"::1/128",
+	"fc00::/7",
+}
+
+type (
+	// IPRules is the allow and deny lists of CIDRs or IPs, internal is short for InternalNetworks.
+	IPRules struct {
+		// 为空时允许Deny之外的全部地址
+		Allow []string `json:",optional"`
+		// 优先于Allow
+		Deny []string `json:",optional"`
+	}
+
+	// IPFilter checks the client ips against the rules, the rules can be updated on the fly.
+	IPFilter struct {
+		allow []*net.IPNet
+		deny  []*net.IPNet
+		lock  sync.RWMutex
+		done  chan struct{}
+		once  sync.Once
+	}
+)
+
+func NewIPFilter(rules IPRules) (*IPFilter, error) {
+	f := &IPFilter{done: make(chan struct{})}
+	if err := f.Update(rules); err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
+
+// WatchIPFilter loads the rules from the json or yaml file, and reloads them if the file is modified,
+// the file is checked every interval. The rules are kept if the modified file is invalid.
+func WatchIPFilter(file string, interval time.Duration) (*IPFilter, error) {
+	info, err := os.Stat(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var rules IPRules
+	if err := conf.LoadConfig(file, &rules); err != nil {
+		return nil, err
+	}
+	f, err := NewIPFilter(rules)
+	if err != nil {
+		return nil, err
+	}
+
+	threading.GoSafe(func() {
+		f.watch(file, info.ModTime(), interval)
+	})
+	return f, nil
+}
+
+// Update replaces the rules, the rules are kept if any of the new ones is invalid.
+func (f *IPFilter) Update(rules IPRules) error {
+	allow, err := ParseCIDRs(rules.Allow)
+	if err != nil {
+		return err
+	}
+	deny, err := ParseCIDRs(rules.Deny)
+	if err != nil {
+		return err
+	}
+
+	f.lock.Lock()
+	f.allow = allow
+	f.deny = deny
+	f.lock.Unlock()
+	return nil
+}
+
+// Allowed reports whether the ip is allowed, the invalid ips are not allowed.
+func (f *IPFilter) Allowed(ip string) bool {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+
+	f.lock.RLock()
+	defer f.lock.RUnlock()
+
+	if containsIP(f.deny, parsed) {
+		return false
+	}
+
+	return len(f.allow) == 0 || containsIP(f.allow, parsed)
+}
+
+// Close stops watching the file.
+func (f *IPFilter) Close() {
+	f.once.Do(func() {
+		close(f.done)
+	})
+}
+
+func (f *IPFilter) watch(file string, modTime time.Time, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-f.done:
+			return
+		case <-ticker.C:
+		}
+
+		info, err := os.Stat(file)
+		if err != nil {
+			logx.Errorf("ip filter: stat %s failed: %s", file, err.Error())
+			continue
+		}
+		if info.ModTime().Equal(modTime) {
+			continue
+		}
+		modTime = info.ModTime()
+
+		var rules IPRules
+		if err := conf.LoadConfig(file, &rules); err != nil {
+			logx.Errorf("ip filter: load %s failed: %s", file, err.Error())
+			continue
+		}
+		if err := f.Update(rules); err != nil {
+			logx.Errorf("ip filter: bad rules in %s: %s", file, err.Error())
+			continue
+		}
+		logx.Infof("ip filter: rules reloaded from %s", file)
+	}
+}
+
+// IPFilterMiddleware rejects the requests from the ips not allowed by filter with 403,
+// the client ip is ctx.RealIP(), which trusts the forwarded headers only from the trusted proxies.
+func IPFilterMiddleware(filter *IPFilter) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			if !filter.Allowed(ctx.RealIP()) {
+				return echo.NewHTTPError(http.StatusForbidden, "ip not allowed")
+			}
+
+			return next(ctx)
+		}
+	}
+}
+
+// RealIPExtractor returns the extractor of the client ip, the header X-Forwarded-For or X-Real-IP
+// is trusted only if the request comes from the trusted proxies, the ip of the connection is
+// used if no proxies are trusted.
+func RealIPExtractor(header string, trustedProxies []string) (echo.IPExtractor, error) {
+	if len(trustedProxies) == 0 {
+		return echo.ExtractIPDirect(), nil
+	}
+
+	nets, err := ParseCIDRs(trustedProxies)
+	if err != nil {
+		return nil, err
+	}
+
+	// 只信任配置的地址，不使用echo默认信任的私有网络
+	opts := []echo.TrustOption{
+		echo.TrustLoopback(false),
+		echo.TrustLinkLocal(false),
+		echo.TrustPrivateNet(false),
+	}
+	for _, n := range nets {
+		opts = append(opts, echo.TrustIPRange(n))
+	}
+
+	if strings.EqualFold(header, echo.HeaderXRealIP) {
+		return echo.ExtractIPFromRealIPHeader(opts...), nil
+	}
+	return echo.ExtractIPFromXFFHeader(opts...), nil
+}
+
+// ParseCIDRs parses the CIDRs or IPs, internal is expanded to InternalNetworks.
+func ParseCIDRs(cidrs []string) ([]*net.IPNet, error) {
+	var nets []*net.IPNet
+	for _, cidr := range cidrs {
+		cidr = strings.TrimSpace(cidr)
+		if cidr == Internal {
+			internal, err := ParseCIDRs(InternalNetworks)
+			if err != nil {
+				return nil, err
+			}
+			nets = append(nets, internal...)
+			continue
+		}
+
+		if !strings.Contains(cidr, "/") {
+			ip := net.ParseIP(cidr)
+			if ip == nil {
+				return nil, errors.New("invalid ip: " + cidr)
+			}
+			if ip.To4() != nil {
+				cidr += "/32"
+			} else {
+				cidr += "/128"
+			}
+		}
+
+		_, n, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, err
+		}
+		nets = append(nets, n)
+	}
+
+	return nets, nil
+}
+
+func containsIP(nets []*net.IPNet, ip net.IP) bool {
+	for _, n := range nets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
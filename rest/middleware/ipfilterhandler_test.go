@@ -0,0 +1,129 @@
+package middleware
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseCIDRs(t *testing.T) {
+	nets, err := ParseCIDRs([]string{"10.0.0.1", " 192.168.0.0/16", "::1"})
+	assert.Nil(t, err)
+	assert.Len(t, nets, 3)
+	assert.Equal(t, "10.0.0.1/32", nets[0].String())
+	assert.Equal(t, "::1/128", nets[2].String())
+
+	nets, err = ParseCIDRs([]string{Internal})
+	assert.Nil(t, err)
+	assert.Len(t, nets, len(InternalNetworks))
+
+	_, err = ParseCIDRs([]string{"10.0.0"})
+	assert.NotNil(t, err)
+	_, err = ParseCIDRs([]string{"10.0.0.0/33"})
+	assert.NotNil(t, err)
+}
+
+func TestIPFilter(t *testing.T) {
+	filter, err := NewIPFilter(IPRules{
+		Allow: []string{Internal},
+		Deny:  []string{"10.0.0.13"},
+	})
+	assert.Nil(t, err)
+	assert.True(t, filter.Allowed("10.1.2.3"))
+	assert.True(t, filter.Allowed("::1"))
+	assert.False(t, filter.Allowed("10.0.0.13"))
+	assert.False(t, filter.Allowed("8.8.8.8"))
+	assert.False(t, filter.Allowed("unknown"))
+
+	// 无效的规则不生效
+	assert.NotNil(t, filter.Update(IPRules{Deny: []string{"bad"}}))
+	assert.False(t, filter.Allowed("8.8.8.8"))
+
+	assert.Nil(t, filter.Update(IPRules{Deny: []string{"10.0.0.0/8"}}))
+	assert.True(t, filter.Allowed("8.8.8.8"))
+	assert.False(t, filter.Allowed("10.1.2.3"))
+}
+
+func TestIPFilterMiddleware(t *testing.T) {
+	filter, err := NewIPFilter(IPRules{Allow: []string{"10.0.0.0/8"}})
+	assert.Nil(t, err)
+	extractor, err := RealIPExtractor(echo.HeaderXForwardedFor, []string{"192.0.2.1"})
+	assert.Nil(t, err)
+
+	e := echo.New()
+	e.IPExtractor = extractor
+	e.Use(IPFilterMiddleware(filter))
+	e.GET("/", func(ctx echo.Context) error {
+		return ctx.String(http.StatusOK, ctx.RealIP())
+	})
+
+	serve := func(remoteAddr, forwardedFor string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = remoteAddr
+		if len(forwardedFor) > 0 {
+			req.Header.Set(echo.HeaderXForwardedFor, forwardedFor)
+		}
+		resp := httptest.NewRecorder()
+		e.ServeHTTP(resp, req)
+		return resp
+	}
+
+	// 来自可信代理
+	resp := serve("192.0.2.1:1234", "8.8.8.8, 10.0.0.1")
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, "10.0.0.1", resp.Body.String())
+	assert.Equal(t, http.StatusForbidden, serve("192.0.2.1:1234", "10.0.0.1, 8.8.8.8").Code)
+	// 不可信的来源伪造的header
+	assert.Equal(t, http.StatusForbidden, serve("192.0.2.2:1234", "10.0.0.1").Code)
+	assert.Equal(t, http.StatusOK, serve("10.0.0.2:1234", "").Code)
+}
+
+func TestRealIPExtractor(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "10.0.0.1:1234"
+	req.Header.Set(echo.HeaderXForwardedFor, "8.8.8.8")
+	req.Header.Set(echo.HeaderXRealIP, "8.8.4.4")
+
+	extractor, err := RealIPExtractor(echo.HeaderXForwardedFor, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "10.0.0.1", extractor(req))
+
+	extractor, err = RealIPExtractor(echo.HeaderXRealIP, []string{Internal})
+	assert.Nil(t, err)
+	assert.Equal(t, "8.8.4.4", extractor(req))
+
+	_, err = RealIPExtractor(echo.HeaderXForwardedFor, []string{"bad"})
+	assert.NotNil(t, err)
+}
+
+func TestWatchIPFilter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ipfilter")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "ipfilter.json")
+	assert.Nil(t, ioutil.WriteFile(file, []byte(`{"Deny": ["8.8.8.8"]}`), 0644))
+
+	filter, err := WatchIPFilter(file, 10*time.Millisecond)
+	assert.Nil(t, err)
+	defer filter.Close()
+	assert.False(t, filter.Allowed("8.8.8.8"))
+	assert.True(t, filter.Allowed("8.8.4.4"))
+
+	assert.Nil(t, ioutil.WriteFile(file, []byte(`{"Deny": ["8.8.4.4"]}`), 0644))
+	// 避免修改时间相同
+	modTime := time.Now().Add(time.Second)
+	assert.Nil(t, os.Chtimes(file, modTime, modTime))
+	assert.Eventually(t, func() bool {
+		return filter.Allowed("8.8.8.8") && !filter.Allowed("8.8.4.4")
+	}, time.Second, 10*time.Millisecond)
+
+	_, err = WatchIPFilter(filepath.Join(dir, "missing.json"), time.Second)
+	assert.NotNil(t, err)
+}
@@ -1,122 +1,261 @@
 package middleware
 
 import (
+	"bufio"
 	"bytes"
 	"context"
-	"fmt"
 	"github.com/labstack/echo/v4"
+	"github.com/opentracing/opentracing-go"
+	"github.com/uber/jaeger-client-go"
+	"github.com/valeamoris/go-ezio/core/trace"
 	"github.com/valeamoris/go-ezio/rest/internal"
 	"github.com/zeromicro/go-zero/core/logx"
 	"github.com/zeromicro/go-zero/core/timex"
 	"github.com/zeromicro/go-zero/core/utils"
+	"io"
+	"math/rand"
+	"net"
 	"net/http"
-	"net/http/httputil"
-	"sync"
+	"strings"
 	"time"
 )
 
-const slowThreshold = time.Millisecond * 500
+const (
+	slowThreshold       = time.Millisecond * 500
+	defaultMaxBodyBytes = 4096
+)
 
-func LogMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
-	pool := &sync.Pool{
-		New: func() interface{} {
-			return bytes.NewBuffer(make([]byte, 256))
-		},
+// 测试时替换，检查写入的日志
+var accessLogger = logx.WithDuration
+
+type (
+	AccessLogOptions struct {
+		// 需要脱敏的header，为nil时使用DefaultRedactHeaders
+		RedactHeaders []string
+		// 需要脱敏的json字段，为nil时使用DefaultRedactFields
+		RedactFields []string
+		// uri中需要脱敏的query参数，为nil时使用DefaultRedactQuery
+		RedactQuery []string
+		// 记录请求和响应body的最大字节数，0代表不记录
+		MaxBodyBytes int
+		// 成功请求的采样率，(0, 1]，0按1处理，失败和慢请求总是记录
+		SampleRate float64
+		// 记录脱敏后的请求header
+		Headers bool
 	}
 
-	return func(ctx echo.Context) error {
-		timer := utils.NewElapsedTimer()
-		logs := new(internal.LogCollector)
-		ctx.SetRequest(ctx.Request().WithContext(context.WithValue(ctx.Request().Context(), internal.LogContext, logs)))
-		err := next(ctx)
-		logBrief(ctx, pool, timer, logs)
-		return err
+	accessLog struct {
+		Status       int         `json:"status"`
+		Method       string      `json:"method"`
+		Route        string      `json:"route"`
+		Uri          string      `json:"uri"`
+		Latency      string      `json:"latency"`
+		Bytes        int64       `json:"bytes"`
+		Ip           string      `json:"ip"`
+		UserAgent    string      `json:"userAgent"`
+		TraceId      string      `json:"traceId,omitempty"`
+		RequestId    string      `json:"requestId,omitempty"`
+		Error        string      `json:"error,omitempty"`
+		Header       http.Header `json:"header,omitempty"`
+		RequestBody  string      `json:"requestBody,omitempty"`
+		ResponseBody string      `json:"responseBody,omitempty"`
+		Logs         string      `json:"logs,omitempty"`
 	}
+
+	// limitedBuffer keeps at most limit bytes, the rest is dropped and marked as truncated.
+	limitedBuffer struct {
+		bytes.Buffer
+		limit     int
+		truncated bool
+	}
+
+	bodyCapturer struct {
+		io.ReadCloser
+		buf *limitedBuffer
+	}
+
+	responseCapturer struct {
+		http.ResponseWriter
+		buf *limitedBuffer
+	}
+)
+
+func LogMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return AccessLogMiddleware(AccessLogOptions{})(next)
 }
 
 func DetailedLogMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
-	pool := &sync.Pool{
-		New: func() interface{} {
-			return bytes.NewBuffer(make([]byte, 256))
-		},
-	}
-	return func(ctx echo.Context) error {
-		timer := utils.NewElapsedTimer()
-		logs := new(internal.LogCollector)
-		ctx.SetRequest(ctx.Request().WithContext(context.WithValue(ctx.Request().Context(), internal.LogContext, logs)))
-		err := next(ctx)
-		logDetails(ctx, pool, timer, logs)
-		return err
+	return AccessLogMiddleware(AccessLogOptions{
+		MaxBodyBytes: defaultMaxBodyBytes,
+		Headers:      true,
+	})(next)
+}
+
+// AccessLogMiddleware writes one structured entry per request,
+// with the headers and json fields redacted.
+func AccessLogMiddleware(opts AccessLogOptions) echo.MiddlewareFunc {
+	if opts.RedactHeaders == nil {
+		opts.RedactHeaders = DefaultRedactHeaders
+	}
+	if opts.RedactFields == nil {
+		opts.RedactFields = DefaultRedactFields
+	}
+	if opts.RedactQuery == nil {
+		opts.RedactQuery = DefaultRedactQuery
+	}
+	if opts.SampleRate <= 0 || opts.SampleRate > 1 {
+		opts.SampleRate = 1
+	}
+	redact := newRedactor(opts.RedactHeaders, opts.RedactFields, opts.RedactQuery)
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			timer := utils.NewElapsedTimer()
+			logs := new(internal.LogCollector)
+			req := ctx.Request()
+			reqCtx := context.WithValue(req.Context(), internal.LogContext, logs)
+			reqCtx = context.WithValue(reqCtx, internal.UriContext, redact.redactUri(req.RequestURI))
+			req = req.WithContext(reqCtx)
+			ctx.SetRequest(req)
+
+			var reqBody, respBody *limitedBuffer
+			// 长连接的body不记录
+			if opts.MaxBodyBytes > 0 && !isStream(req) {
+				reqBody = &limitedBuffer{limit: opts.MaxBodyBytes}
+				respBody = &limitedBuffer{limit: opts.MaxBodyBytes}
+				if req.Body != nil && req.Body != http.NoBody {
+					req.Body = &bodyCapturer{ReadCloser: req.Body, buf: reqBody}
+				}
+				resp := ctx.Response()
+				writer := resp.Writer
+				resp.Writer = &responseCapturer{ResponseWriter: writer, buf: respBody}
+				defer func() {
+					resp.Writer = writer
+				}()
+			}
+
+			err := next(ctx)
+			logAccess(ctx, err, timer.Duration(), opts, redact, logs, reqBody, respBody)
+			return err
+		}
 	}
 }
 
-func logBrief(ctx echo.Context, pool *sync.Pool, timer *utils.ElapsedTimer, logs *internal.LogCollector) {
-	buf := pool.Get().(*bytes.Buffer)
-	buf.Reset()
-	defer pool.Put(buf)
+func logAccess(ctx echo.Context, err error, duration time.Duration, opts AccessLogOptions, redact *redactor,
+	logs *internal.LogCollector, reqBody, respBody *limitedBuffer) {
+	req := ctx.Request()
+	resp := ctx.Response()
+	status := resp.Status
+	if err != nil && !resp.Committed {
+		status = errorStatus(err)
+	}
 
-	// 需要注意的是trace中间件需要放到log前
-	duration := timer.Duration()
-	buf.WriteString(fmt.Sprintf("%d - %s - %s - %s - %s - %s",
-		ctx.Response().Status, ctx.Request().Method, ctx.Request().RequestURI, ctx.RealIP(), ctx.Request().UserAgent(),
-		timex.ReprOfDuration(duration)))
-	if duration > slowThreshold {
-		logx.Slowf("[HTTP] %d - %s - %s - %s - %s - slowcall(%s)",
-			ctx.Response().Status, ctx.Request().Method, ctx.Request().RequestURI, ctx.RealIP(), ctx.Request().UserAgent(),
-			timex.ReprOfDuration(duration))
+	ok := isOkResponse(status)
+	// 长连接的耗时是连接的时长，不算慢请求
+	slow := duration > slowThreshold && !isStream(req)
+	if ok && !slow && status < http.StatusBadRequest && opts.SampleRate < 1 && rand.Float64() >= opts.SampleRate {
+		return
 	}
 
-	ok := isOkResponse(ctx.Response().Status)
-	if !ok {
-		buf.WriteString(fmt.Sprintf("\n%s", dumpRequest(ctx.Request())))
+	entry := accessLog{
+		Status:    status,
+		Method:    req.Method,
+		Route:     ctx.Path(),
+		Uri:       internal.Uri(req),
+		Latency:   timex.ReprOfDuration(duration),
+		Bytes:     resp.Size,
+		Ip:        ctx.RealIP(),
+		UserAgent: req.UserAgent(),
+		TraceId:   traceId(req.Context()),
+		RequestId: requestIdFromRequest(req),
+		Logs:      logs.Flush(),
 	}
-	body := logs.Flush()
-	if len(body) > 0 {
-		buf.WriteString(fmt.Sprintf("\n%s", body))
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	if opts.Headers || !ok {
+		entry.Header = redact.redactHeader(req.Header)
+	}
+	if reqBody != nil {
+		entry.RequestBody = redact.redactBody(reqBody.Content(), req.Header.Get(echo.HeaderContentType))
+		entry.ResponseBody = redact.redactBody(respBody.Content(), resp.Header().Get(echo.HeaderContentType))
 	}
 
-	if ok {
-		logx.Info(buf.String())
-	} else {
-		logx.Error(buf.String())
+	logger := accessLogger(duration)
+	switch {
+	case slow:
+		logger.Slowv(entry)
+	case ok:
+		logger.Infov(entry)
+	default:
+		logger.Errorv(entry)
 	}
 }
 
-func logDetails(ctx echo.Context, pool *sync.Pool, timer *utils.ElapsedTimer, logs *internal.LogCollector) {
-	buf := pool.Get().(*bytes.Buffer)
-	buf.Reset()
-	defer pool.Put(buf)
+func traceId(ctx context.Context) string {
+	if span := opentracing.SpanFromContext(ctx); span != nil {
+		if spanCtx, ok := span.Context().(jaeger.SpanContext); ok {
+			return spanCtx.TraceID().String()
+		}
+	}
+
+	return trace.TraceIDFromContext(ctx)
+}
 
-	duration := timer.Duration()
-	buf.WriteString(fmt.Sprintf("%d - %s - %s - %s\n=> %s\n",
-		ctx.Response().Status, ctx.Request().Method, ctx.RealIP(), timex.ReprOfDuration(duration), dumpRequest(ctx.Request())))
-	if duration > slowThreshold {
-		logx.Slowf("[HTTP] %d - %s - %s - slowcall(%s)\n=> %s\n",
-			ctx.Response().Status, ctx.Request().Method, ctx.RealIP(), timex.ReprOfDuration(duration), dumpRequest(ctx.Request()))
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if remain := b.limit - b.Len(); remain < len(p) {
+		b.truncated = true
+		if remain > 0 {
+			b.Buffer.Write(p[:remain])
+		}
+		return len(p), nil
 	}
 
-	ok := isOkResponse(ctx.Response().Status)
-	if !ok {
-		buf.WriteString(fmt.Sprintf("\n%s", dumpRequest(ctx.Request())))
+	return b.Buffer.Write(p)
+}
+
+func (b *limitedBuffer) Content() []byte {
+	if b.truncated {
+		return append(b.Bytes(), "...(truncated)"...)
 	}
-	body := logs.Flush()
-	if len(body) > 0 {
-		buf.WriteString(fmt.Sprintf("\n%s", body))
+
+	return b.Bytes()
+}
+
+func (c *bodyCapturer) Read(p []byte) (int, error) {
+	n, err := c.ReadCloser.Read(p)
+	if n > 0 {
+		_, _ = c.buf.Write(p[:n])
 	}
-	if ok {
-		logx.Info(buf.String())
-	} else {
-		logx.Error(buf.String())
+	return n, err
+}
+
+func (w *responseCapturer) Write(p []byte) (int, error) {
+	_, _ = w.buf.Write(p)
+	return w.ResponseWriter.Write(p)
+}
+
+func (w *responseCapturer) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
 	}
 }
 
-func dumpRequest(r *http.Request) string {
-	reqContent, err := httputil.DumpRequest(r, true)
-	if err != nil {
-		return err.Error()
-	} else {
-		return string(reqContent)
+func (w *responseCapturer) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if hijacker, ok := w.ResponseWriter.(http.Hijacker); ok {
+		return hijacker.Hijack()
 	}
+
+	return nil, nil, http.ErrNotSupported
+}
+
+// isStream reports whether the request is a WebSocket upgrade or a Server-Sent Events subscription.
+func isStream(r *http.Request) bool {
+	if strings.EqualFold(r.Header.Get(echo.HeaderUpgrade), "websocket") {
+		return true
+	}
+
+	return strings.Contains(r.Header.Get(echo.HeaderAccept), "text/event-stream")
 }
 
 func isOkResponse(code int) bool {
@@ -7,10 +7,12 @@ import (
 	"log"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/zeromicro/go-zero/core/logx"
 )
 
 func init() {
@@ -80,3 +82,137 @@ func BenchmarkLogHandler(b *testing.B) {
 		_ = handler(ctx)
 	}
 }
+
+func TestAccessLogHandler_CaptureBody(t *testing.T) {
+	handler := AccessLogMiddleware(AccessLogOptions{
+		MaxBodyBytes: 4,
+		SampleRate:   0.5,
+	})(func(ctx echo.Context) error {
+		body, err := ioutil.ReadAll(ctx.Request().Body)
+		assert.Nil(t, err)
+		assert.Equal(t, `{"password":"123"}`, string(body))
+		return ctx.String(http.StatusOK, "content")
+	})
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "http://localhost", strings.NewReader(`{"password":"123"}`))
+	resp := httptest.NewRecorder()
+	ctx := e.NewContext(req, resp)
+	err := handler(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, "content", resp.Body.String())
+	assert.Equal(t, resp, ctx.Response().Writer)
+}
+
+func TestAccessLogHandler_RedactFormBody(t *testing.T) {
+	logs := captureAccessLogs(t)
+	handler := AccessLogMiddleware(AccessLogOptions{MaxBodyBytes: 1024})(func(ctx echo.Context) error {
+		assert.Equal(t, "123", ctx.FormValue("password"))
+		return ctx.String(http.StatusOK, "ok")
+	})
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/login", strings.NewReader("user=kevin&password=123"))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+	assert.Nil(t, handler(e.NewContext(req, httptest.NewRecorder())))
+
+	assert.Len(t, *logs, 1)
+	assert.Equal(t, "user=kevin&password=***", (*logs)[0].entry.(accessLog).RequestBody)
+}
+
+func TestAccessLogHandler_Error(t *testing.T) {
+	handler := LogMiddleware(func(ctx echo.Context) error {
+		return echo.NewHTTPError(http.StatusBadGateway)
+	})
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "http://localhost", nil)
+	req.Header.Set(echo.HeaderAuthorization, "Bearer abc")
+	ctx := e.NewContext(req, httptest.NewRecorder())
+	assert.NotNil(t, handler(ctx))
+}
+
+func TestAccessLogHandler_RedactApiKey(t *testing.T) {
+	logs := captureAccessLogs(t)
+	handler := AccessLogMiddleware(AccessLogOptions{
+		RedactHeaders: []string{"X-Key"},
+		RedactQuery:   []string{"key"},
+		Headers:       true,
+	})(func(ctx echo.Context) error {
+		internal.Info(ctx, "handled")
+		return ctx.NoContent(http.StatusOK)
+	})
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/orders?id=1&key=secret-key&Key=other", nil)
+	req.Header.Set("X-Key", "secret-header")
+	assert.Nil(t, handler(e.NewContext(req, httptest.NewRecorder())))
+
+	assert.Len(t, *logs, 1)
+	entry := (*logs)[0].entry.(accessLog)
+	assert.Equal(t, "/orders?id=1&key=***&Key=***", entry.Uri)
+	assert.Equal(t, redactedValue, entry.Header.Get("X-Key"))
+	// 中间件和handler的日志也使用脱敏后的uri
+	assert.Contains(t, entry.Logs, "(/orders?id=1&key=***&Key=***")
+	assert.NotContains(t, entry.Logs, "secret")
+}
+
+func TestAccessLogHandler_Stream(t *testing.T) {
+	handler := AccessLogMiddleware(AccessLogOptions{MaxBodyBytes: 1024})(func(ctx echo.Context) error {
+		// 长连接不包装writer
+		_, ok := ctx.Response().Writer.(*responseCapturer)
+		assert.False(t, ok)
+		return ctx.String(http.StatusOK, "data: a\n\n")
+	})
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "http://localhost", nil)
+	req.Header.Set(echo.HeaderAccept, "text/event-stream")
+	assert.True(t, isStream(req))
+	assert.Nil(t, handler(e.NewContext(req, httptest.NewRecorder())))
+
+	req = httptest.NewRequest(http.MethodGet, "http://localhost", nil)
+	req.Header.Set(echo.HeaderUpgrade, "WebSocket")
+	assert.True(t, isStream(req))
+	assert.False(t, isStream(httptest.NewRequest(http.MethodGet, "http://localhost", nil)))
+}
+
+type recordedLog struct {
+	level string
+	entry interface{}
+}
+
+// recordingLogger records the structured entries written by the access log.
+type recordingLogger struct {
+	logs *[]recordedLog
+}
+
+func (l recordingLogger) Error(...interface{})          {}
+func (l recordingLogger) Errorf(string, ...interface{}) {}
+func (l recordingLogger) Errorv(v interface{})          { l.record("error", v) }
+func (l recordingLogger) Info(...interface{})           {}
+func (l recordingLogger) Infof(string, ...interface{})  {}
+func (l recordingLogger) Infov(v interface{})           { l.record("info", v) }
+func (l recordingLogger) Slow(...interface{})           {}
+func (l recordingLogger) Slowf(string, ...interface{})  {}
+func (l recordingLogger) Slowv(v interface{})           { l.record("slow", v) }
+
+func (l recordingLogger) WithDuration(time.Duration) logx.Logger {
+	return l
+}
+
+func (l recordingLogger) record(level string, v interface{}) {
+	*l.logs = append(*l.logs, recordedLog{level: level, entry: v})
+}
+
+func captureAccessLogs(t *testing.T) *[]recordedLog {
+	logs := new([]recordedLog)
+	accessLogger = func(time.Duration) logx.Logger {
+		return recordingLogger{logs: logs}
+	}
+	t.Cleanup(func() {
+		accessLogger = logx.WithDuration
+	})
+	return logs
+}
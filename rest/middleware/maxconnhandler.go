@@ -16,8 +16,9 @@ func MaxConnMiddleware(n int) echo.MiddlewareFunc {
 			}
 		}
 	}
+	// echo每个请求都会重新组装Use的中间件，latch需要在外面创建
+	latch := syncx.NewLimit(n)
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
-		latch := syncx.NewLimit(n)
 		return func(ctx echo.Context) error {
 			if latch.TryBorrow() {
 				defer func() {
@@ -28,6 +29,7 @@ func MaxConnMiddleware(n int) echo.MiddlewareFunc {
 
 				return next(ctx)
 			} else {
+				MarkDropped(ctx, DroppedByMaxConns)
 				internal.Errorf(ctx, "concurrent connections over %d, reject with code %d",
 					n, http.StatusServiceUnavailable)
 				ctx.Response().WriteHeader(http.StatusServiceUnavailable)
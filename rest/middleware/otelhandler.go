@@ -0,0 +1,55 @@
+package middleware
+
+import (
+	"fmt"
+	"github.com/labstack/echo/v4"
+	"github.com/valeamoris/go-ezio/core/trace"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.7.0"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+// OtelMiddleware starts an opentelemetry server span for each request,
+// the parent is extracted with the global propagator, W3C traceparent by default.
+func OtelMiddleware(serviceName string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) (err error) {
+			req := c.Request()
+			route := c.Path()
+			ctx := otel.GetTextMapPropagator().Extract(req.Context(), propagation.HeaderCarrier(req.Header))
+			ctx, span := trace.Tracer().Start(ctx, "HTTP "+req.Method+" "+route,
+				oteltrace.WithSpanKind(oteltrace.SpanKindServer),
+				oteltrace.WithAttributes(semconv.HTTPServerAttributesFromHTTPRequest(serviceName, route, req)...),
+				oteltrace.WithAttributes(attribute.String(tagClientIP, c.RealIP())))
+			if requestID := requestIdFromRequest(req); len(requestID) > 0 {
+				span.SetAttributes(attribute.String(tagRequestID, requestID))
+			}
+			c.SetRequest(req.WithContext(ctx))
+
+			defer func() {
+				if p := recover(); p != nil {
+					span.RecordError(fmt.Errorf("%v", p), oteltrace.WithAttributes(attribute.String("event", "panic")))
+					span.SetStatus(codes.Error, "panic")
+					span.End()
+					panic(p)
+				}
+
+				status := c.Response().Status
+				if err != nil {
+					span.RecordError(err)
+					if !c.Response().Committed {
+						status = errorStatus(err)
+					}
+				}
+				span.SetAttributes(semconv.HTTPAttributesFromHTTPStatusCode(status)...)
+				span.SetStatus(semconv.SpanStatusFromHTTPStatusCodeAndSpanKind(status, oteltrace.SpanKindServer))
+				span.End()
+			}()
+
+			return next(c)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/valeamoris/go-ezio/core/trace"
+	"go.opentelemetry.io/otel/codes"
+	oteltrace "go.opentelemetry.io/otel/trace"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+const traceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+func TestOtelHandler(t *testing.T) {
+	assert.Nil(t, trace.StartAgent(trace.Conf{Name: "test", Sampler: 1, Batcher: trace.BatcherMemory}))
+	defer trace.StopAgent(context.Background())
+
+	e := echo.New()
+	e.Use(OtelMiddleware("test"))
+	e.GET("/users/:id", func(ctx echo.Context) error {
+		spanCtx := oteltrace.SpanContextFromContext(ctx.Request().Context())
+		assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", spanCtx.TraceID().String())
+		return errors.New("boom")
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/users/1", nil)
+	req.Header.Set("traceparent", traceparent)
+	e.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := trace.MemoryExporter().GetSpans()
+	assert.Len(t, spans, 1)
+	assert.Equal(t, "HTTP GET /users/:id", spans[0].Name)
+	assert.Equal(t, oteltrace.SpanKindServer, spans[0].SpanKind)
+	assert.Equal(t, "00f067aa0ba902b7", spans[0].Parent.SpanID().String())
+	assert.Equal(t, codes.Error, spans[0].Status.Code)
+}
+
+func TestOtelHandler_Panic(t *testing.T) {
+	assert.Nil(t, trace.StartAgent(trace.Conf{Name: "test", Sampler: 1, Batcher: trace.BatcherMemory}))
+	defer trace.StopAgent(context.Background())
+
+	handler := OtelMiddleware("test")(func(ctx echo.Context) error {
+		panic("whatever")
+	})
+	req := httptest.NewRequest(http.MethodGet, "http://localhost", nil)
+	ctx := echo.New().NewContext(req, httptest.NewRecorder())
+	assert.Panics(t, func() {
+		_ = handler(ctx)
+	})
+
+	spans := trace.MemoryExporter().GetSpans()
+	assert.Len(t, spans, 1)
+	assert.Equal(t, codes.Error, spans[0].Status.Code)
+}
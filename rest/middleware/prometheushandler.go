@@ -6,245 +6,175 @@ import (
 	"time"
 
 	"github.com/labstack/echo/v4"
-	"github.com/labstack/gommon/log"
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/valeamoris/go-ezio/rest/errorx"
+	"github.com/valeamoris/go-ezio/rest/internal"
 )
 
-var defaultSubsystem = "echo"
-
-// Standard default metrics
-//	counter, counter_vec, gauge, gauge_vec,
-//	histogram, histogram_vec, summary, summary_vec
-var reqCnt = &Metric{
-	ID:          "reqCnt",
-	Name:        "requests_total",
-	Description: "How many HTTP requests processed, partitioned by status code and HTTP method.",
-	Type:        "counter_vec",
-	Args:        []string{"code", "method", "host", "url"}}
-
-var reqDur = &Metric{
-	ID:          "reqDur",
-	Name:        "request_duration_seconds",
-	Description: "The HTTP request latencies in seconds.",
-	Type:        "summary"}
-
-var resSz = &Metric{
-	ID:          "resSz",
-	Name:        "response_size_bytes",
-	Description: "The HTTP response sizes in bytes.",
-	Type:        "summary"}
-
-var reqSz = &Metric{
-	ID:          "reqSz",
-	Name:        "request_size_bytes",
-	Description: "The HTTP request sizes in bytes.",
-	Type:        "summary"}
+const (
+	defaultSubsystem = "http"
+	unmatchedRoute   = "unmatched"
+)
 
-var standardMetrics = []*Metric{
-	reqCnt,
-	reqDur,
-	resSz,
-	reqSz,
-}
+var (
+	DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+	sizeBuckets    = prometheus.ExponentialBuckets(128, 4, 8)
+)
 
-/*
-RequestCounterURLLabelMappingFunc is a function which can be supplied to the middleware to control
-the cardinality of the request counter's "url" label, which might be required in some contexts.
-For instance, if for a "/customer/:name" route you don't want to generate a time series for every
-possible customer name, you could use this function:
+type (
+	PrometheusOption func(o *prometheusOptions)
 
-func(c echo.Context) string {
-	url := c.Request.URL.Path
-	for _, p := range c.Params {
-		if p.Key == "name" {
-			url = strings.Replace(url, p.Value, ":name", 1)
-			break
-		}
+	prometheusOptions struct {
+		registerer prometheus.Registerer
+		namespace  string
+		buckets    []float64
 	}
-	return url
-}
-
-which would map "/customer/alice" and "/customer/bob" to their template "/customer/:name".
-*/
-type RequestCounterURLLabelMappingFunc func(c echo.Context) string
 
-// Metric is a definition for the name, description, type, ID, and
-// prometheus.Collector type (i.e. CounterVec, Summary, etc) of each metric
-type Metric struct {
-	MetricCollector prometheus.Collector
-	ID              string
-	Name            string
-	Description     string
-	Type            string
-	Args            []string
-}
-
-// Prometheus contains the metrics gathered by the instance and its path
-type Prometheus struct {
-	reqCnt               *prometheus.CounterVec
-	reqDur, reqSz, resSz prometheus.Summary
-
-	MetricsList []*Metric
-	Subsystem   string
-
-	RequestCounterURLLabelMappingFunc RequestCounterURLLabelMappingFunc
+	// Prometheus contains the metrics gathered by the instance,
+	// all of them are labelled by route template instead of raw url to keep the cardinality low.
+	Prometheus struct {
+		reqCnt   *prometheus.CounterVec
+		reqDur   *prometheus.HistogramVec
+		reqSz    prometheus.Histogram
+		resSz    prometheus.Histogram
+		inFlight *prometheus.GaugeVec
+		dropped  *prometheus.CounterVec
+	}
+)
 
-	// Context string to use as a prometheus URL label
-	URLLabelFromContext string
+// 注册到指定的registry，默认为prometheus.DefaultRegisterer
+func WithRegisterer(registerer prometheus.Registerer) PrometheusOption {
+	return func(o *prometheusOptions) {
+		o.registerer = registerer
+	}
 }
 
-// NewPrometheus generates a new set of metrics with a certain subsystem name
-func NewPrometheus() *Prometheus {
-	var metricsList []*Metric
-
-	for _, metric := range standardMetrics {
-		metricsList = append(metricsList, metric)
+func WithNamespace(namespace string) PrometheusOption {
+	return func(o *prometheusOptions) {
+		o.namespace = namespace
 	}
+}
 
-	p := &Prometheus{
-		MetricsList: metricsList,
-		Subsystem:   defaultSubsystem,
-		RequestCounterURLLabelMappingFunc: func(c echo.Context) string {
-			return c.Path() // i.e. by default do nothing, i.e. return URL as is
-		},
+// 请求延迟的分桶，单位秒
+func WithBuckets(buckets ...float64) PrometheusOption {
+	return func(o *prometheusOptions) {
+		o.buckets = buckets
 	}
-
-	p.registerMetrics("requests")
-
-	return p
 }
 
-// NewMetric associates prometheus.Collector based on Metric.Type
-func NewMetric(m *Metric, subsystem string) prometheus.Collector {
-	var metric prometheus.Collector
-	switch m.Type {
-	case "counter_vec":
-		metric = prometheus.NewCounterVec(
-			prometheus.CounterOpts{
-				Subsystem: subsystem,
-				Name:      m.Name,
-				Help:      m.Description,
-			},
-			m.Args,
-		)
-	case "counter":
-		metric = prometheus.NewCounter(
-			prometheus.CounterOpts{
-				Subsystem: subsystem,
-				Name:      m.Name,
-				Help:      m.Description,
-			},
-		)
-	case "gauge_vec":
-		metric = prometheus.NewGaugeVec(
-			prometheus.GaugeOpts{
-				Subsystem: subsystem,
-				Name:      m.Name,
-				Help:      m.Description,
-			},
-			m.Args,
-		)
-	case "gauge":
-		metric = prometheus.NewGauge(
-			prometheus.GaugeOpts{
-				Subsystem: subsystem,
-				Name:      m.Name,
-				Help:      m.Description,
-			},
-		)
-	case "histogram_vec":
-		metric = prometheus.NewHistogramVec(
-			prometheus.HistogramOpts{
-				Subsystem: subsystem,
-				Name:      m.Name,
-				Help:      m.Description,
-			},
-			m.Args,
-		)
-	case "histogram":
-		metric = prometheus.NewHistogram(
-			prometheus.HistogramOpts{
-				Subsystem: subsystem,
-				Name:      m.Name,
-				Help:      m.Description,
-			},
-		)
-	case "summary_vec":
-		metric = prometheus.NewSummaryVec(
-			prometheus.SummaryOpts{
-				Subsystem: subsystem,
-				Name:      m.Name,
-				Help:      m.Description,
-			},
-			m.Args,
-		)
-	case "summary":
-		metric = prometheus.NewSummary(
-			prometheus.SummaryOpts{
-				Subsystem: subsystem,
-				Name:      m.Name,
-				Help:      m.Description,
-			},
-		)
+// NewPrometheus registers the metrics, the collectors already registered are reused,
+// so that it's safe to create it more than once on the same registry.
+func NewPrometheus(opts ...PrometheusOption) *Prometheus {
+	o := prometheusOptions{
+		registerer: prometheus.DefaultRegisterer,
+		buckets:    DefaultBuckets,
+	}
+	for _, opt := range opts {
+		opt(&o)
 	}
-	return metric
-}
 
-func (p *Prometheus) registerMetrics(subsystem string) {
-	for _, metricDef := range p.MetricsList {
-		metric := NewMetric(metricDef, subsystem)
-		if err := prometheus.Register(metric); err != nil {
-			log.Errorf("%s could not be registered in Prometheus: %v", metricDef.Name, err)
-		}
-		switch metricDef {
-		case reqCnt:
-			p.reqCnt = metric.(*prometheus.CounterVec)
-		case reqDur:
-			p.reqDur = metric.(prometheus.Summary)
-		case resSz:
-			p.resSz = metric.(prometheus.Summary)
-		case reqSz:
-			p.reqSz = metric.(prometheus.Summary)
-		}
-		metricDef.MetricCollector = metric
+	return &Prometheus{
+		reqCnt: internal.RegisterCollector(o.registerer, prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: o.namespace,
+			Subsystem: defaultSubsystem,
+			Name:      "requests_total",
+			Help:      "How many HTTP requests processed, partitioned by route, method and status code.",
+		}, []string{"route", "method", "code"})).(*prometheus.CounterVec),
+		reqDur: internal.RegisterCollector(o.registerer, prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: o.namespace,
+			Subsystem: defaultSubsystem,
+			Name:      "request_duration_seconds",
+			Help:      "The HTTP request latencies in seconds.",
+			Buckets:   o.buckets,
+		}, []string{"route", "method", "status"})).(*prometheus.HistogramVec),
+		reqSz: internal.RegisterCollector(o.registerer, prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: o.namespace,
+			Subsystem: defaultSubsystem,
+			Name:      "request_size_bytes",
+			Help:      "The HTTP request sizes in bytes.",
+			Buckets:   sizeBuckets,
+		})).(prometheus.Histogram),
+		resSz: internal.RegisterCollector(o.registerer, prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: o.namespace,
+			Subsystem: defaultSubsystem,
+			Name:      "response_size_bytes",
+			Help:      "The HTTP response sizes in bytes.",
+			Buckets:   sizeBuckets,
+		})).(prometheus.Histogram),
+		inFlight: internal.RegisterCollector(o.registerer, prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: o.namespace,
+			Subsystem: defaultSubsystem,
+			Name:      "requests_in_flight",
+			Help:      "How many HTTP requests are being processed.",
+		}, []string{"route", "method"})).(*prometheus.GaugeVec),
+		dropped: internal.RegisterCollector(o.registerer, prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: o.namespace,
+			Subsystem: defaultSubsystem,
+			Name:      "requests_dropped_total",
+			Help:      "How many HTTP requests are dropped by shedding, timeout and so on, partitioned by reason.",
+		}, []string{"route", "reason"})).(*prometheus.CounterVec),
 	}
 }
 
-// HandlerFunc defines handler function for middleware
-func PrometheusMiddleware() echo.MiddlewareFunc {
-	p := NewPrometheus()
+func PrometheusMiddleware(opts ...PrometheusOption) echo.MiddlewareFunc {
+	p := NewPrometheus(opts...)
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) (err error) {
 			start := time.Now()
-			reqSz := computeApproximateRequestSize(c.Request())
-
-			err = next(c)
+			route := c.Path()
+			if len(route) == 0 {
+				route = unmatchedRoute
+			}
+			method := c.Request().Method
 
-			status := strconv.Itoa(c.Response().Status)
+			inFlight := p.inFlight.WithLabelValues(route, method)
+			inFlight.Inc()
+			defer inFlight.Dec()
 
-			elapsed := float64(time.Since(start)) / float64(time.Second)
-			resSz := float64(c.Response().Size)
+			reqSz := computeApproximateRequestSize(c.Request())
 
-			p.reqDur.Observe(elapsed)
-			url := p.RequestCounterURLLabelMappingFunc(c)
+			err = next(c)
 
-			if len(p.URLLabelFromContext) > 0 {
-				u := c.Get(p.URLLabelFromContext)
-				if u == nil {
-					u = "unknown"
-				}
-				url = u.(string)
+			status := c.Response().Status
+			// 未提交的error会由echo的HTTPErrorHandler渲染
+			if err != nil && !c.Response().Committed {
+				status = errorStatus(err)
 			}
 
-			p.reqCnt.WithLabelValues(status, c.Request().Method, c.Request().Host, url).Inc()
+			p.reqDur.WithLabelValues(route, method, statusClass(status)).Observe(time.Since(start).Seconds())
+			p.reqCnt.WithLabelValues(route, method, strconv.Itoa(status)).Inc()
 			p.reqSz.Observe(float64(reqSz))
-			p.resSz.Observe(resSz)
+			p.resSz.Observe(float64(c.Response().Size))
+			if reason := droppedReason(c); len(reason) > 0 {
+				p.dropped.WithLabelValues(route, reason).Inc()
+			}
 
 			return
 		}
 	}
 }
 
+// errorStatus is the status of the error rendered by errorx.ErrorHandler, like *errorx.CodeError
+// and *echo.HTTPError, the unknown errors are 500.
+func errorStatus(err error) int {
+	return errorx.From(err).Status
+}
+
+func statusClass(status int) string {
+	switch {
+	case status >= 500:
+		return "5xx"
+	case status >= 400:
+		return "4xx"
+	case status >= 300:
+		return "3xx"
+	case status >= 200:
+		return "2xx"
+	default:
+		return "1xx"
+	}
+}
+
 func computeApproximateRequestSize(r *http.Request) int {
 	s := 0
 	if r.URL != nil {
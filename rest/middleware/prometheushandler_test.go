@@ -2,11 +2,16 @@ package middleware
 
 import (
 	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
+	"github.com/opentracing/opentracing-go/mocktracer"
 	"github.com/stretchr/testify/assert"
+	"github.com/valeamoris/go-ezio/rest/errorx"
 )
 
 func TestPromMetricHandler(t *testing.T) {
@@ -23,3 +28,90 @@ func TestPromMetricHandler(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, http.StatusOK, resp.Code)
 }
+
+func TestPromMetricHandler_RegisterTwice(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	first := NewPrometheus(WithRegisterer(registry))
+	second := NewPrometheus(WithRegisterer(registry))
+	assert.True(t, first.reqDur == second.reqDur)
+}
+
+func TestPromMetricHandler_RouteLabels(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	e := echo.New()
+	e.Use(PrometheusMiddleware(WithRegisterer(registry), WithNamespace("test"), WithBuckets(0.1, 1)))
+	e.GET("/users/:id", func(ctx echo.Context) error {
+		return ctx.String(http.StatusOK, "ok")
+	})
+	e.GET("/timeout", func(ctx echo.Context) error {
+		MarkDropped(ctx, DroppedByTimeout)
+		return ctx.NoContent(http.StatusGatewayTimeout)
+	})
+
+	for _, path := range []string{"/users/1", "/users/2", "/timeout"} {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		e.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	families, err := registry.Gather()
+	assert.Nil(t, err)
+	for _, family := range families {
+		if family.GetName() == "test_http_request_duration_seconds" {
+			assert.Len(t, family.GetMetric(), 2)
+			assert.Len(t, family.GetMetric()[0].GetHistogram().GetBucket(), 2)
+		}
+	}
+	expected := `
+# HELP test_http_requests_total How many HTTP requests processed, partitioned by route, method and status code.
+# TYPE test_http_requests_total counter
+test_http_requests_total{code="200",method="GET",route="/users/:id"} 2
+test_http_requests_total{code="504",method="GET",route="/timeout"} 1
+`
+	assert.Nil(t, testutil.GatherAndCompare(registry, strings.NewReader(expected), "test_http_requests_total"))
+	expected = `
+# HELP test_http_requests_dropped_total How many HTTP requests are dropped by shedding, timeout and so on, partitioned by reason.
+# TYPE test_http_requests_dropped_total counter
+test_http_requests_dropped_total{reason="timeout",route="/timeout"} 1
+`
+	assert.Nil(t, testutil.GatherAndCompare(registry, strings.NewReader(expected), "test_http_requests_dropped_total"))
+}
+
+func TestStatusClass(t *testing.T) {
+	assert.Equal(t, "1xx", statusClass(http.StatusContinue))
+	assert.Equal(t, "2xx", statusClass(http.StatusOK))
+	assert.Equal(t, "3xx", statusClass(http.StatusFound))
+	assert.Equal(t, "4xx", statusClass(http.StatusNotFound))
+	assert.Equal(t, "5xx", statusClass(http.StatusServiceUnavailable))
+}
+
+func TestErrorStatusOfCodeError(t *testing.T) {
+	logs := captureAccessLogs(t)
+	registry := prometheus.NewRegistry()
+	tracer := mocktracer.New()
+	e := echo.New()
+	e.HTTPErrorHandler = errorx.ErrorHandler(false)
+	e.Use(TracingMiddleware(tracer))
+	e.Use(LogMiddleware)
+	e.Use(PrometheusMiddleware(WithRegisterer(registry), WithNamespace("test")))
+	e.GET("/users/:id", func(ctx echo.Context) error {
+		return errorx.ErrNotFound
+	})
+
+	resp := httptest.NewRecorder()
+	e.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/users/1", nil))
+	assert.Equal(t, http.StatusNotFound, resp.Code)
+
+	assert.Len(t, *logs, 1)
+	assert.Equal(t, "info", (*logs)[0].level)
+	assert.Equal(t, http.StatusNotFound, (*logs)[0].entry.(accessLog).Status)
+	expected := `
+# HELP test_http_requests_total How many HTTP requests processed, partitioned by route, method and status code.
+# TYPE test_http_requests_total counter
+test_http_requests_total{code="404",method="GET",route="/users/:id"} 1
+`
+	assert.Nil(t, testutil.GatherAndCompare(registry, strings.NewReader(expected), "test_http_requests_total"))
+	spans := tracer.FinishedSpans()
+	assert.Len(t, spans, 1)
+	assert.Equal(t, uint16(http.StatusNotFound), spans[0].Tag("http.status_code"))
+	assert.Nil(t, spans[0].Tag("error"))
+}
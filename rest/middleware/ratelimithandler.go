@@ -0,0 +1,120 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/labstack/echo/v4"
+	"github.com/valeamoris/go-ezio/core/limit"
+	"github.com/valeamoris/go-ezio/rest/internal"
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+const (
+	HeaderRateLimitLimit     = "X-RateLimit-Limit"
+	HeaderRateLimitRemaining = "X-RateLimit-Remaining"
+	HeaderRateLimitReset     = "X-RateLimit-Reset"
+
+	// echo的JWT中间件默认的ContextKey
+	jwtContextKey = "user"
+
+	headerRetryAfter = "Retry-After"
+)
+
+// RateLimitKeyFunc returns the key to limit on, empty key means not limited.
+type RateLimitKeyFunc func(ctx echo.Context) string
+
+// RateLimitMiddleware rejects the requests over the limit with 429,
+// the requests are let through if the limiter fails.
+func RateLimitMiddleware(limiter limit.Limiter, keyFunc RateLimitKeyFunc) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			key := keyFunc(ctx)
+			if len(key) == 0 {
+				return next(ctx)
+			}
+
+			result, err := limiter.Allow(ctx.Request().Context(), key)
+			if err != nil {
+				logx.Errorf("rate limiter failed, request let through: %s", err.Error())
+				return next(ctx)
+			}
+
+			header := ctx.Response().Header()
+			header.Set(HeaderRateLimitLimit, strconv.Itoa(result.Limit))
+			header.Set(HeaderRateLimitRemaining, strconv.Itoa(result.Remaining))
+			header.Set(HeaderRateLimitReset, strconv.Itoa(ceilSeconds(result.ResetAfter)))
+			if result.Allowed {
+				return next(ctx)
+			}
+
+			MarkDropped(ctx, DroppedByRateLimit)
+			internal.Errorf(ctx, "rate limit exceeded for %s, reject with code %d", key, http.StatusTooManyRequests)
+			header.Set(headerRetryAfter, strconv.Itoa(ceilSeconds(result.RetryAfter)))
+			ctx.Response().WriteHeader(http.StatusTooManyRequests)
+			return nil
+		}
+	}
+}
+
+// 按客户端ip限流
+func RateLimitByIP(ctx echo.Context) string {
+	return "ip:" + ctx.RealIP()
+}
+
+// 按JWT的subject限流，需要在JWT中间件之后，未认证的请求不限流
+func RateLimitByJwtSubject(ctx echo.Context) string {
+	if subject := authSubject(ctx); len(subject) > 0 {
+		return "sub:" + subject
+	}
+
+	return ""
+}
+
+// 按请求header中的api key限流，没有api key的请求不限流，key会写入日志和redis，使用它的sha256
+func RateLimitByApiKey(header string) RateLimitKeyFunc {
+	return func(ctx echo.Context) string {
+		if key := ctx.Request().Header.Get(header); len(key) > 0 {
+			return "key:" + hashApiKey(key)
+		}
+
+		return ""
+	}
+}
+
+func jwtSubject(claims jwt.Claims) string {
+	switch c := claims.(type) {
+	case jwt.MapClaims:
+		subject, _ := c["sub"].(string)
+		return subject
+	case *jwt.StandardClaims:
+		return c.Subject
+	}
+
+	// 自定义claims，一般内嵌了jwt.StandardClaims
+	val := reflect.ValueOf(claims)
+	for val.Kind() == reflect.Ptr {
+		if val.IsNil() {
+			return ""
+		}
+		val = val.Elem()
+	}
+	if val.Kind() != reflect.Struct {
+		return ""
+	}
+
+	field := val.FieldByName("Subject")
+	if field.IsValid() && field.Kind() == reflect.String {
+		return field.String()
+	}
+
+	return ""
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,65 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/valeamoris/go-ezio/core/limit"
+)
+
+func TestRateLimitMiddleware(t *testing.T) {
+	e := echo.New()
+	limiter, err := limit.NewPeriodLimiter(nil, time.Minute, 2)
+	assert.Nil(t, err)
+	handler := RateLimitMiddleware(limiter, RateLimitByIP)(
+		func(ctx echo.Context) error {
+			return ctx.NoContent(http.StatusOK)
+		})
+
+	for i := 0; i < 2; i++ {
+		req := httptest.NewRequest(http.MethodGet, "http://localhost", nil)
+		resp := httptest.NewRecorder()
+		assert.Nil(t, handler(e.NewContext(req, resp)))
+		assert.Equal(t, http.StatusOK, resp.Code)
+		assert.Equal(t, "2", resp.Header().Get(HeaderRateLimitLimit))
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "http://localhost", nil)
+	resp := httptest.NewRecorder()
+	ctx := e.NewContext(req, resp)
+	assert.Nil(t, handler(ctx))
+	assert.Equal(t, http.StatusTooManyRequests, resp.Code)
+	assert.Equal(t, "0", resp.Header().Get(HeaderRateLimitRemaining))
+	assert.NotEmpty(t, resp.Header().Get("Retry-After"))
+	assert.Equal(t, DroppedByRateLimit, droppedReason(ctx))
+}
+
+func TestRateLimitByJwtSubject(t *testing.T) {
+	e := echo.New()
+	ctx := e.NewContext(httptest.NewRequest(http.MethodGet, "http://localhost", nil), httptest.NewRecorder())
+	assert.Empty(t, RateLimitByJwtSubject(ctx))
+
+	ctx.Set("user", &jwt.Token{Claims: jwt.MapClaims{"sub": "kevin"}})
+	assert.Equal(t, "sub:kevin", RateLimitByJwtSubject(ctx))
+
+	type claims struct {
+		jwt.StandardClaims
+		Name string
+	}
+	ctx.Set("user", &jwt.Token{Claims: &claims{StandardClaims: jwt.StandardClaims{Subject: "anna"}}})
+	assert.Equal(t, "sub:anna", RateLimitByJwtSubject(ctx))
+}
+
+func TestRateLimitByApiKey(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "http://localhost", nil)
+	ctx := echo.New().NewContext(req, httptest.NewRecorder())
+	keyFunc := RateLimitByApiKey("X-Api-Key")
+	assert.Empty(t, keyFunc(ctx))
+	req.Header.Set("X-Api-Key", "foo")
+	assert.Equal(t, "key:2c26b46b68ffc68ff99b453c1d30413413422d706483bfa0f98a5e886266e7ae", keyFunc(ctx))
+}
@@ -0,0 +1,292 @@
+package middleware
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zeromicro/go-zero/core/logx"
+	"github.com/zeromicro/go-zero/core/threading"
+	"gorm.io/gorm"
+)
+
+const (
+	// 授予全部权限
+	wildcardPermission  = "*"
+	defaultRbacInterval = time.Minute
+)
+
+type (
+	// RbacRules grants the permissions to the roles, and the roles to the subjects besides the ones in the token.
+	RbacRules struct {
+		// 角色拥有的权限，支持*和orders:*这样的前缀通配
+		Permissions map[string][]string `json:",optional"`
+		// 主体额外拥有的角色，key为token的sub
+		Roles map[string][]string `json:",optional"`
+	}
+
+	// RbacPolicy is the Policy of the role based access control, the rules can be updated on the fly.
+	RbacPolicy struct {
+		rules RbacRules
+		lock  sync.RWMutex
+		done  chan struct{}
+		once  sync.Once
+	}
+
+	// RolePermission is the row of the table role_permissions used by NewGormRbacPolicy.
+	RolePermission struct {
+		Role       string `gorm:"size:64;index"`
+		Permission string `gorm:"size:128"`
+	}
+
+	// SubjectRole is the row of the table subject_roles used by NewGormRbacPolicy.
+	SubjectRole struct {
+		Subject string `gorm:"size:128;index"`
+		Role    string `gorm:"size:64"`
+	}
+)
+
+func NewRbacPolicy(rules RbacRules) *RbacPolicy {
+	return &RbacPolicy{
+		rules: rules,
+		done:  make(chan struct{}),
+	}
+}
+
+// WatchRbacPolicy loads the casbin like rules from the file, and reloads them if the file is modified,
+// the file is checked every interval. The lines are:
+//
+//	# 角色admin拥有全部权限
+//	p, admin, *
+//	# 等同于p, editor, articles:write
+//	p, editor, articles, write
+//	g, alice, admin
+//
+// interval defaults to one minute.
+func WatchRbacPolicy(file string, interval time.Duration) (*RbacPolicy, error) {
+	if interval <= 0 {
+		interval = defaultRbacInterval
+	}
+
+	info, err := os.Stat(file)
+	if err != nil {
+		return nil, err
+	}
+
+	rules, err := LoadRbacRules(file)
+	if err != nil {
+		return nil, err
+	}
+
+	p := NewRbacPolicy(rules)
+	modTime := info.ModTime()
+	threading.GoSafe(func() {
+		p.refresh(interval, func() error {
+			info, err := os.Stat(file)
+			if err != nil {
+				return err
+			}
+			if info.ModTime().Equal(modTime) {
+				return nil
+			}
+			modTime = info.ModTime()
+
+			rules, err := LoadRbacRules(file)
+			if err != nil {
+				return err
+			}
+			p.Update(rules)
+			logx.Infof("rbac: rules reloaded from %s", file)
+			return nil
+		})
+	})
+	return p, nil
+}
+
+// NewGormRbacPolicy loads the rules from the tables role_permissions and subject_roles,
+// and reloads them every interval, which defaults to one minute.
+func NewGormRbacPolicy(db *gorm.DB, interval time.Duration) (*RbacPolicy, error) {
+	if interval <= 0 {
+		interval = defaultRbacInterval
+	}
+
+	rules, err := loadGormRbacRules(db)
+	if err != nil {
+		return nil, err
+	}
+
+	p := NewRbacPolicy(rules)
+	threading.GoSafe(func() {
+		p.refresh(interval, func() error {
+			rules, err := loadGormRbacRules(db)
+			if err != nil {
+				return err
+			}
+			p.Update(rules)
+			return nil
+		})
+	})
+	return p, nil
+}
+
+// LoadRbacRules parses the casbin like rules file, see WatchRbacPolicy.
+func LoadRbacRules(file string) (RbacRules, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return RbacRules{}, err
+	}
+	defer f.Close()
+
+	rules := RbacRules{
+		Permissions: make(map[string][]string),
+		Roles:       make(map[string][]string),
+	}
+	scanner := bufio.NewScanner(f)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Split(line, ",")
+		for i := range fields {
+			fields[i] = strings.TrimSpace(fields[i])
+		}
+		switch {
+		case fields[0] == "p" && len(fields) == 3:
+			rules.Permissions[fields[1]] = append(rules.Permissions[fields[1]], fields[2])
+		case fields[0] == "p" && len(fields) == 4:
+			rules.Permissions[fields[1]] = append(rules.Permissions[fields[1]], fields[2]+":"+fields[3])
+		case fields[0] == "g" && len(fields) == 3:
+			rules.Roles[fields[1]] = append(rules.Roles[fields[1]], fields[2])
+		default:
+			return RbacRules{}, fmt.Errorf("%s:%d: invalid rule %q", file, lineNo, line)
+		}
+	}
+
+	return rules, scanner.Err()
+}
+
+// Update replaces the rules.
+func (p *RbacPolicy) Update(rules RbacRules) {
+	p.lock.Lock()
+	p.rules = rules
+	p.lock.Unlock()
+}
+
+// Authorize allows the request if all the requirements are satisfied, the permissions are granted
+// by the scopes in the token, or by the roles in the token and the rules.
+func (p *RbacPolicy) Authorize(_ context.Context, req AuthzRequest) (bool, error) {
+	if len(req.Requirements) == 0 {
+		return true, nil
+	}
+
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	roles := append(append([]string(nil), req.Roles...), p.rules.Roles[req.Subject]...)
+	grants := append([]string(nil), req.Scopes...)
+	for _, role := range roles {
+		grants = append(grants, p.rules.Permissions[role]...)
+	}
+
+	for _, r := range req.Requirements {
+		if len(r.Roles) > 0 && !containsAny(roles, r.Roles) {
+			return false, nil
+		}
+		for _, permission := range r.Permissions {
+			if !granted(grants, permission) {
+				return false, nil
+			}
+		}
+	}
+
+	return true, nil
+}
+
+// Close stops reloading the rules.
+func (p *RbacPolicy) Close() {
+	p.once.Do(func() {
+		close(p.done)
+	})
+}
+
+func (p *RbacPolicy) refresh(interval time.Duration, reload func() error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.done:
+			return
+		case <-ticker.C:
+		}
+
+		// 加载失败时保留原来的规则
+		if err := reload(); err != nil {
+			logx.Errorf("rbac: reload rules failed: %s", err.Error())
+		}
+	}
+}
+
+func (RolePermission) TableName() string {
+	return "role_permissions"
+}
+
+func (SubjectRole) TableName() string {
+	return "subject_roles"
+}
+
+func loadGormRbacRules(db *gorm.DB) (RbacRules, error) {
+	var permissions []RolePermission
+	if err := db.Find(&permissions).Error; err != nil {
+		return RbacRules{}, err
+	}
+	var roles []SubjectRole
+	if err := db.Find(&roles).Error; err != nil {
+		return RbacRules{}, err
+	}
+
+	rules := RbacRules{
+		Permissions: make(map[string][]string),
+		Roles:       make(map[string][]string),
+	}
+	for _, p := range permissions {
+		rules.Permissions[p.Role] = append(rules.Permissions[p.Role], p.Permission)
+	}
+	for _, r := range roles {
+		rules.Roles[r.Subject] = append(rules.Roles[r.Subject], r.Role)
+	}
+
+	return rules, nil
+}
+
+func containsAny(values, targets []string) bool {
+	for _, v := range values {
+		for _, t := range targets {
+			if v == t {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func granted(grants []string, permission string) bool {
+	for _, g := range grants {
+		if g == permission || g == wildcardPermission {
+			return true
+		}
+		// orders:*授予orders:read
+		if strings.HasSuffix(g, wildcardPermission) && strings.HasPrefix(permission, strings.TrimSuffix(g, wildcardPermission)) {
+			return true
+		}
+	}
+
+	return false
+}
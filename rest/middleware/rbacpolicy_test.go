@@ -0,0 +1,91 @@
+package middleware
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRbacPolicy(t *testing.T) {
+	p := NewRbacPolicy(RbacRules{
+		Permissions: map[string][]string{
+			"admin":  {"*"},
+			"editor": {"articles:*", "comments:read"},
+		},
+		Roles: map[string][]string{"alice": {"admin"}},
+	})
+	authorize := func(req AuthzRequest) bool {
+		allowed, err := p.Authorize(context.Background(), req)
+		assert.Nil(t, err)
+		return allowed
+	}
+	need := func(permissions ...string) []Requirement {
+		return []Requirement{{Permissions: permissions}}
+	}
+
+	assert.True(t, authorize(AuthzRequest{Subject: "bob"}))
+	assert.True(t, authorize(AuthzRequest{Roles: []string{"editor"}, Requirements: need("articles:write", "comments:read")}))
+	assert.False(t, authorize(AuthzRequest{Roles: []string{"editor"}, Requirements: need("comments:write")}))
+	// 规则中分配的角色
+	assert.True(t, authorize(AuthzRequest{Subject: "alice", Requirements: need("users:delete")}))
+	assert.True(t, authorize(AuthzRequest{Subject: "alice", Requirements: []Requirement{{Roles: []string{"admin"}}}}))
+	// scope直接授予的权限
+	assert.True(t, authorize(AuthzRequest{Scopes: []string{"users:read"}, Requirements: need("users:read")}))
+	assert.False(t, authorize(AuthzRequest{Scopes: []string{"users:read"}, Requirements: []Requirement{
+		{Permissions: []string{"users:read"}},
+		{Roles: []string{"editor"}},
+	}}))
+
+	p.Update(RbacRules{})
+	assert.False(t, authorize(AuthzRequest{Subject: "alice", Requirements: need("users:delete")}))
+}
+
+func TestWatchRbacPolicy(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rbac")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "rbac.csv")
+	assert.Nil(t, ioutil.WriteFile(file, []byte(`
+# 编辑
+p, editor, articles, write
+p, editor, comments:*
+g, alice, editor
+`), 0644))
+	p, err := WatchRbacPolicy(file, 10*time.Millisecond)
+	assert.Nil(t, err)
+	defer p.Close()
+
+	authorize := func(permission string) bool {
+		allowed, err := p.Authorize(context.Background(), AuthzRequest{
+			Subject:      "alice",
+			Requirements: []Requirement{{Permissions: []string{permission}}},
+		})
+		assert.Nil(t, err)
+		return allowed
+	}
+	assert.True(t, authorize("articles:write"))
+	assert.True(t, authorize("comments:delete"))
+	assert.False(t, authorize("users:read"))
+
+	// 非法的文件不影响已有的规则
+	assert.Nil(t, ioutil.WriteFile(file, []byte("p, editor\n"), 0644))
+	assert.Nil(t, os.Chtimes(file, time.Now(), time.Now().Add(time.Second)))
+	time.Sleep(50 * time.Millisecond)
+	assert.True(t, authorize("articles:write"))
+
+	assert.Nil(t, ioutil.WriteFile(file, []byte("p, editor, users:read\ng, alice, editor\n"), 0644))
+	assert.Nil(t, os.Chtimes(file, time.Now(), time.Now().Add(2*time.Second)))
+	assert.Eventually(t, func() bool {
+		return authorize("users:read")
+	}, time.Second, 10*time.Millisecond)
+	assert.False(t, authorize("articles:write"))
+
+	_, err = LoadRbacRules(file + ".missing")
+	assert.NotNil(t, err)
+}
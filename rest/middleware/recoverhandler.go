@@ -3,6 +3,9 @@ package middleware
 import (
 	"fmt"
 	"github.com/labstack/echo/v4"
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/opentracing/opentracing-go/log"
 	"github.com/valeamoris/go-ezio/rest/internal"
 	"net/http"
 	"runtime/debug"
@@ -13,6 +16,10 @@ func RecoverMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
 		defer func() {
 			if r := recover(); r != nil {
 				internal.Error(ctx, fmt.Sprintf("%v\n%s", r, debug.Stack()))
+				if span := opentracing.SpanFromContext(ctx.Request().Context()); span != nil {
+					ext.Error.Set(span, true)
+					span.LogFields(log.String("event", "panic"), log.String("message", fmt.Sprint(r)))
+				}
 				ctx.Response().WriteHeader(http.StatusInternalServerError)
 			}
 		}()
@@ -0,0 +1,137 @@
+package middleware
+
+import (
+	"encoding/json"
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+const redactedValue = "***"
+
+var (
+	DefaultRedactHeaders = []string{
+		echo.HeaderAuthorization,
+		"Proxy-Authorization",
+		"Cookie",
+		"Set-Cookie",
+		"X-Api-Key",
+	}
+	DefaultRedactFields = []string{"password", "passwd", "secret", "token"}
+	DefaultRedactQuery  = []string{"access_token", "api_key"}
+)
+
+type redactor struct {
+	headers map[string]bool
+	fields  map[string]bool
+	queries map[string]bool
+	pattern *regexp.Regexp
+}
+
+func newRedactor(headers, fields, queries []string) *redactor {
+	r := &redactor{
+		headers: make(map[string]bool),
+		fields:  make(map[string]bool),
+		queries: make(map[string]bool),
+	}
+	for _, header := range headers {
+		r.headers[http.CanonicalHeaderKey(header)] = true
+	}
+	for _, query := range queries {
+		r.queries[strings.ToLower(query)] = true
+	}
+
+	quoted := make([]string, 0, len(fields))
+	for _, field := range fields {
+		r.fields[strings.ToLower(field)] = true
+		quoted = append(quoted, regexp.QuoteMeta(field))
+	}
+	if len(quoted) > 0 {
+		// 截断的json无法解析，退化为按正则替换
+		r.pattern = regexp.MustCompile(`(?i)("(?:` + strings.Join(quoted, "|") +
+			`)"\s*:\s*)("(?:[^"\\]|\\.)*"?|[^,}\]\s]*)`)
+	}
+
+	return r
+}
+
+func (r *redactor) redactHeader(header http.Header) http.Header {
+	redacted := make(http.Header, len(header))
+	for k, v := range header {
+		if r.headers[http.CanonicalHeaderKey(k)] {
+			redacted[k] = []string{redactedValue}
+		} else {
+			redacted[k] = v
+		}
+	}
+
+	return redacted
+}
+
+// redactUri replaces the values of the redacted query params, the others are kept as they are.
+func (r *redactor) redactUri(uri string) string {
+	i := strings.IndexByte(uri, '?')
+	if i < 0 || len(r.queries) == 0 {
+		return uri
+	}
+
+	return uri[:i+1] + redactParams(uri[i+1:], r.queries)
+}
+
+// redactBody redacts the fields of the json or the form body, contentType is the Content-Type header.
+func (r *redactor) redactBody(body []byte, contentType string) string {
+	if len(body) == 0 || len(r.fields) == 0 {
+		return string(body)
+	}
+	if strings.HasPrefix(strings.ToLower(contentType), echo.MIMEApplicationForm) {
+		return redactParams(string(body), r.fields)
+	}
+
+	var v interface{}
+	if err := json.Unmarshal(body, &v); err == nil {
+		if redacted, err := json.Marshal(r.redactValue(v)); err == nil {
+			return string(redacted)
+		}
+	}
+
+	return r.pattern.ReplaceAllString(string(body), `${1}"`+redactedValue+`"`)
+}
+
+func (r *redactor) redactValue(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		for k, item := range val {
+			if r.fields[strings.ToLower(k)] {
+				val[k] = redactedValue
+			} else {
+				val[k] = r.redactValue(item)
+			}
+		}
+		return val
+	case []interface{}:
+		for i, item := range val {
+			val[i] = r.redactValue(item)
+		}
+		return val
+	default:
+		return v
+	}
+}
+
+// redactParams replaces the values of the urlencoded params in names, which are lower cased.
+func redactParams(params string, names map[string]bool) string {
+	pairs := strings.Split(params, "&")
+	for i, pair := range pairs {
+		name := pair
+		if j := strings.IndexByte(pair, '='); j >= 0 {
+			name = pair[:j]
+		}
+		if unescaped, err := url.QueryUnescape(name); err == nil && names[strings.ToLower(unescaped)] {
+			pairs[i] = name + "=" + redactedValue
+		}
+	}
+
+	return strings.Join(pairs, "&")
+}
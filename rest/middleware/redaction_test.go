@@ -0,0 +1,65 @@
+package middleware
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedactor_Header(t *testing.T) {
+	r := newRedactor(DefaultRedactHeaders, DefaultRedactFields, nil)
+	header := http.Header{}
+	header.Set("Authorization", "Bearer abc")
+	header.Set("X-Test", "test")
+	redacted := r.redactHeader(header)
+	assert.Equal(t, redactedValue, redacted.Get("Authorization"))
+	assert.Equal(t, "test", redacted.Get("X-Test"))
+	assert.Equal(t, "Bearer abc", header.Get("Authorization"))
+}
+
+func TestRedactor_Body(t *testing.T) {
+	r := newRedactor(nil, []string{"password", "token"}, nil)
+	assert.JSONEq(t, `{"name":"kevin","password":"***","nested":[{"Token":"***"}]}`,
+		r.redactBody([]byte(`{"name":"kevin","password":"123456","nested":[{"Token":"abc"}]}`), echo.MIMEApplicationJSON))
+	assert.Equal(t, `{"name":"kevin","password":"***","nested":...(truncated)`,
+		r.redactBody([]byte(`{"name":"kevin","password":"12\"3456","nested":...(truncated)`), echo.MIMEApplicationJSON))
+	assert.Equal(t, `{"password": "***", "count":1`, r.redactBody([]byte(`{"password": 123, "count":1`), ""))
+	assert.Equal(t, "plain text", r.redactBody([]byte("plain text"), echo.MIMETextPlain))
+}
+
+func TestRedactor_FormBody(t *testing.T) {
+	r := newRedactor(nil, DefaultRedactFields, nil)
+	assert.Equal(t, "name=kevin&password=***&Token=***&secret=***",
+		r.redactBody([]byte("name=kevin&password=123456&Token=abc&secret"), echo.MIMEApplicationForm))
+	assert.Equal(t, "name=kevin&pass%77ord=***&token=***",
+		r.redactBody([]byte("name=kevin&pass%77ord=123&token=ab...(truncated)"), "application/x-www-form-urlencoded; charset=UTF-8"))
+	// 非表单的body不按参数处理
+	assert.Equal(t, "password=123", r.redactBody([]byte("password=123"), echo.MIMETextPlain))
+}
+
+func TestRedactor_NoFields(t *testing.T) {
+	r := newRedactor(nil, nil, nil)
+	assert.Equal(t, `{"password":"123"}`, r.redactBody([]byte(`{"password":"123"}`), echo.MIMEApplicationJSON))
+}
+
+func TestRedactor_Uri(t *testing.T) {
+	r := newRedactor(nil, nil, []string{"api_key", "token"})
+	assert.Equal(t, "/orders", r.redactUri("/orders"))
+	assert.Equal(t, "/orders?id=1", r.redactUri("/orders?id=1"))
+	assert.Equal(t, "/orders?api_key=***&id=1&TOKEN=***&token=***&api%5Fkey=***",
+		r.redactUri("/orders?api_key=abc&id=1&TOKEN=def&token&api%5Fkey=ghi"))
+	assert.Equal(t, "/orders?api_key=abc", newRedactor(nil, nil, nil).redactUri("/orders?api_key=abc"))
+}
+
+func TestLimitedBuffer(t *testing.T) {
+	buf := &limitedBuffer{limit: 5}
+	n, err := buf.Write([]byte("abc"))
+	assert.Nil(t, err)
+	assert.Equal(t, 3, n)
+	n, err = buf.Write([]byte("defg"))
+	assert.Nil(t, err)
+	assert.Equal(t, 4, n)
+	assert.Equal(t, "abcde...(truncated)", string(buf.Content()))
+}
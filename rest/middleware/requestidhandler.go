@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/valeamoris/go-ezio/core/requestid"
+	"net/http"
+)
+
+const maxRequestIdLen = 128
+
+// RequestIdMiddleware takes the request id from X-Request-ID or generates one,
+// then puts it into the request context and the response header.
+func RequestIdMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(ctx echo.Context) error {
+		req := ctx.Request()
+		id := req.Header.Get(echo.HeaderXRequestID)
+		if !validRequestId(id) {
+			id = requestid.New()
+		}
+
+		ctx.SetRequest(req.WithContext(requestid.NewContext(req.Context(), id)))
+		ctx.Response().Header().Set(echo.HeaderXRequestID, id)
+		return next(ctx)
+	}
+}
+
+func requestIdFromRequest(r *http.Request) string {
+	if id := requestid.FromContext(r.Context()); len(id) > 0 {
+		return id
+	}
+
+	return r.Header.Get(echo.HeaderXRequestID)
+}
+
+// 避免外部传入超长或包含控制字符的id污染日志
+func validRequestId(id string) bool {
+	if len(id) == 0 || len(id) > maxRequestIdLen {
+		return false
+	}
+
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/valeamoris/go-ezio/core/requestid"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRequestIdHandler(t *testing.T) {
+	var id string
+	handler := RequestIdMiddleware(func(ctx echo.Context) error {
+		id = requestid.FromContext(ctx.Request().Context())
+		return nil
+	})
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "http://localhost", nil)
+	req.Header.Set(echo.HeaderXRequestID, "abc")
+	resp := httptest.NewRecorder()
+	err := handler(e.NewContext(req, resp))
+	assert.Nil(t, err)
+	assert.Equal(t, "abc", id)
+	assert.Equal(t, "abc", resp.Header().Get(echo.HeaderXRequestID))
+}
+
+func TestRequestIdHandler_Generate(t *testing.T) {
+	var id string
+	handler := RequestIdMiddleware(func(ctx echo.Context) error {
+		id = requestid.FromContext(ctx.Request().Context())
+		return nil
+	})
+
+	e := echo.New()
+	for _, value := range []string{"", strings.Repeat("a", maxRequestIdLen+1), "a b"} {
+		req := httptest.NewRequest(http.MethodGet, "http://localhost", nil)
+		req.Header.Set(echo.HeaderXRequestID, value)
+		resp := httptest.NewRecorder()
+		err := handler(e.NewContext(req, resp))
+		assert.Nil(t, err)
+		assert.Len(t, id, 36)
+		assert.Equal(t, id, resp.Header().Get(echo.HeaderXRequestID))
+	}
+}
@@ -0,0 +1,66 @@
+package middleware
+
+import (
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	headerReferrerPolicy = "Referrer-Policy"
+	schemeHttps          = "https"
+)
+
+type SecurityHeadersOptions struct {
+	// seconds, Strict-Transport-Security的max-age，只在https请求中返回，0代表不返回
+	HSTSMaxAge            int64
+	HSTSIncludeSubdomains bool
+	HSTSPreload           bool
+	// Content-Security-Policy，为空时不返回
+	ContentSecurityPolicy string
+	// X-Frame-Options，如DENY和SAMEORIGIN，为空时不返回
+	FrameOptions string
+	// Referrer-Policy，为空时不返回
+	ReferrerPolicy string
+	// X-Content-Type-Options: nosniff
+	ContentTypeNosniff bool
+}
+
+// SecurityHeadersMiddleware sets the security headers before the handler runs,
+// so that the error responses carry them as well.
+func SecurityHeadersMiddleware(opts SecurityHeadersOptions) echo.MiddlewareFunc {
+	var hsts string
+	if opts.HSTSMaxAge > 0 {
+		hsts = "max-age=" + strconv.FormatInt(opts.HSTSMaxAge, 10)
+		if opts.HSTSIncludeSubdomains {
+			hsts += "; includeSubDomains"
+		}
+		if opts.HSTSPreload {
+			hsts += "; preload"
+		}
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			header := ctx.Response().Header()
+			// 浏览器忽略http响应中的HSTS，Scheme按X-Forwarded-Proto判断代理后的请求
+			if len(hsts) > 0 && ctx.Scheme() == schemeHttps {
+				header.Set(echo.HeaderStrictTransportSecurity, hsts)
+			}
+			if len(opts.ContentSecurityPolicy) > 0 {
+				header.Set(echo.HeaderContentSecurityPolicy, opts.ContentSecurityPolicy)
+			}
+			if len(opts.FrameOptions) > 0 {
+				header.Set(echo.HeaderXFrameOptions, opts.FrameOptions)
+			}
+			if len(opts.ReferrerPolicy) > 0 {
+				header.Set(headerReferrerPolicy, opts.ReferrerPolicy)
+			}
+			if opts.ContentTypeNosniff {
+				header.Set(echo.HeaderXContentTypeOptions, "nosniff")
+			}
+
+			return next(ctx)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSecurityHeadersMiddleware(t *testing.T) {
+	e := echo.New()
+	e.Use(SecurityHeadersMiddleware(SecurityHeadersOptions{
+		HSTSMaxAge:            31536000,
+		HSTSIncludeSubdomains: true,
+		ContentSecurityPolicy: "default-src 'self'",
+		FrameOptions:          "DENY",
+		ReferrerPolicy:        "no-referrer",
+		ContentTypeNosniff:    true,
+	}))
+	e.GET("/", func(ctx echo.Context) error {
+		return echo.NewHTTPError(http.StatusBadRequest)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	resp := httptest.NewRecorder()
+	e.ServeHTTP(resp, req)
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+	assert.Empty(t, resp.Header().Get(echo.HeaderStrictTransportSecurity))
+	assert.Equal(t, "default-src 'self'", resp.Header().Get(echo.HeaderContentSecurityPolicy))
+	assert.Equal(t, "DENY", resp.Header().Get(echo.HeaderXFrameOptions))
+	assert.Equal(t, "no-referrer", resp.Header().Get(headerReferrerPolicy))
+	assert.Equal(t, "nosniff", resp.Header().Get(echo.HeaderXContentTypeOptions))
+
+	// 代理后的https请求
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(echo.HeaderXForwardedProto, "https")
+	resp = httptest.NewRecorder()
+	e.ServeHTTP(resp, req)
+	assert.Equal(t, "max-age=31536000; includeSubDomains", resp.Header().Get(echo.HeaderStrictTransportSecurity))
+
+	// 未配置的header不返回
+	e = echo.New()
+	e.Use(SecurityHeadersMiddleware(SecurityHeadersOptions{}))
+	resp = httptest.NewRecorder()
+	e.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Empty(t, resp.Header().Get(echo.HeaderXFrameOptions))
+	assert.Empty(t, resp.Header().Get(echo.HeaderXContentTypeOptions))
+}
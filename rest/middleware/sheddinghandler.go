@@ -2,6 +2,7 @@ package middleware
 
 import (
 	"github.com/labstack/echo/v4"
+	"github.com/valeamoris/go-ezio/rest/internal"
 	"github.com/zeromicro/go-zero/core/load"
 	"github.com/zeromicro/go-zero/core/logx"
 	"github.com/zeromicro/go-zero/core/stat"
@@ -34,8 +35,9 @@ func SheddingMiddleware(shedder load.Shedder, metrics *stat.Metrics) echo.Middle
 			if err != nil {
 				metrics.AddDrop()
 				sheddingStat.IncrementDrop()
+				MarkDropped(ctx, DroppedByShedding)
 				logx.Errorf("[http] dropped, %s - %s - %s",
-					ctx.Request().RequestURI, ctx.RealIP(), ctx.Request().UserAgent())
+					internal.Uri(ctx.Request()), ctx.RealIP(), ctx.Request().UserAgent())
 				ctx.Response().WriteHeader(http.StatusServiceUnavailable)
 				return nil
 			}
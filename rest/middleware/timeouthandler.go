@@ -1,37 +1,200 @@
 package middleware
 
 import (
+	"bytes"
 	"context"
-	"github.com/labstack/echo/v4"
+	"fmt"
 	"net/http"
+	"runtime/debug"
+	"strconv"
+	"sync"
 	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/valeamoris/go-ezio/rest/errorx"
 )
 
-const reason = "Request Timeout"
+// 客户端可以通过该header缩短超时时间，毫秒数或者time.Duration格式，如500ms
+const HeaderRequestTimeout = "X-Request-Timeout"
+
+type (
+	// timeoutWriter buffers the handler's output, only one of the handler and the timeout
+	// commits to the real response, like http.TimeoutHandler.
+	timeoutWriter struct {
+		lock     sync.Mutex
+		header   http.Header
+		buf      bytes.Buffer
+		code     int
+		timedOut bool
+	}
+
+	// timeoutContext runs the handler with its own response, the values are still
+	// shared with the parent until timed out, since the parent might be reused by echo after that.
+	timeoutContext struct {
+		echo.Context
+		parent echo.Context
+		// 检查timedOut和访问parent需要是原子的，超时后不能再访问parent
+		lock     sync.Mutex
+		timedOut bool
+	}
+)
 
 func TimeoutMiddleware(duration time.Duration) echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
-		if duration > 0 {
-			return func(ctx echo.Context) error {
-				var cancelCtx context.CancelFunc
-				newCtx, cancelCtx := context.WithTimeout(ctx.Request().Context(), duration)
-				defer cancelCtx()
-				done := make(chan error, 1)
-
-				go func() {
-					ctx.SetRequest(ctx.Request().WithContext(newCtx))
-					done <- next(ctx)
+		if duration <= 0 {
+			return next
+		}
+
+		return func(ctx echo.Context) error {
+			timeout := requestTimeout(ctx.Request(), duration)
+			newCtx, cancel := context.WithTimeout(ctx.Request().Context(), timeout)
+			defer cancel()
+
+			tw := &timeoutWriter{header: make(http.Header)}
+			tc := newTimeoutContext(ctx, ctx.Request().WithContext(newCtx), tw)
+			done := make(chan error, 1)
+			panicChan := make(chan interface{}, 1)
+			go func() {
+				defer func() {
+					if p := recover(); p != nil {
+						panicChan <- fmt.Sprintf("%v\n%s", p, debug.Stack())
+					}
 				}()
+				done <- next(tc)
+			}()
 
-				select {
-				case <-newCtx.Done():
-					return ctx.String(http.StatusGatewayTimeout, newCtx.Err().Error())
-				case err := <-done:
-					return err
-				}
+			select {
+			case p := <-panicChan:
+				panic(p)
+			case err := <-done:
+				return tw.commit(ctx.Response(), err)
+			case <-newCtx.Done():
+				tw.lock.Lock()
+				tw.timedOut = true
+				tw.lock.Unlock()
+				tc.timeout()
+
+				MarkDropped(ctx, DroppedByTimeout)
+				// 由错误处理统一输出，超时是504，客户端断开是499
+				return errorx.From(newCtx.Err())
 			}
-		} else {
-			return next
 		}
 	}
 }
+
+func requestTimeout(r *http.Request, duration time.Duration) time.Duration {
+	val := r.Header.Get(HeaderRequestTimeout)
+	if len(val) == 0 {
+		return duration
+	}
+
+	var timeout time.Duration
+	if millis, err := strconv.ParseInt(val, 10, 64); err == nil {
+		timeout = time.Duration(millis) * time.Millisecond
+	} else if d, err := time.ParseDuration(val); err == nil {
+		timeout = d
+	}
+
+	// 只允许缩短
+	if timeout > 0 && timeout < duration {
+		return timeout
+	}
+
+	return duration
+}
+
+func newTimeoutContext(parent echo.Context, r *http.Request, tw *timeoutWriter) *timeoutContext {
+	ctx := parent.Echo().NewContext(r, tw)
+	ctx.SetPath(parent.Path())
+	ctx.SetParamNames(parent.ParamNames()...)
+	ctx.SetParamValues(parent.ParamValues()...)
+	ctx.SetHandler(parent.Handler())
+
+	return &timeoutContext{
+		Context: ctx,
+		parent:  parent,
+	}
+}
+
+func (c *timeoutContext) Get(key string) interface{} {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if !c.timedOut {
+		return c.parent.Get(key)
+	}
+
+	return c.Context.Get(key)
+}
+
+func (c *timeoutContext) Set(key string, val interface{}) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if !c.timedOut {
+		c.parent.Set(key, val)
+		return
+	}
+
+	c.Context.Set(key, val)
+}
+
+// timeout detaches from the parent, it waits for the ongoing Get or Set on the parent.
+func (c *timeoutContext) timeout() {
+	c.lock.Lock()
+	c.timedOut = true
+	c.lock.Unlock()
+}
+
+func (tw *timeoutWriter) Header() http.Header {
+	return tw.header
+}
+
+func (tw *timeoutWriter) Write(p []byte) (int, error) {
+	tw.lock.Lock()
+	defer tw.lock.Unlock()
+
+	if tw.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	if tw.code == 0 {
+		tw.code = http.StatusOK
+	}
+
+	return tw.buf.Write(p)
+}
+
+func (tw *timeoutWriter) WriteHeader(code int) {
+	tw.lock.Lock()
+	defer tw.lock.Unlock()
+
+	if tw.timedOut || tw.code != 0 {
+		return
+	}
+	tw.code = code
+}
+
+// Flush is a no-op, the output is written after the handler returns,
+// streaming handlers should disable the timeout.
+func (tw *timeoutWriter) Flush() {
+}
+
+func (tw *timeoutWriter) commit(resp *echo.Response, err error) error {
+	tw.lock.Lock()
+	defer tw.lock.Unlock()
+
+	if tw.code == 0 {
+		return err
+	}
+
+	header := resp.Header()
+	for k, v := range tw.header {
+		header[k] = v
+	}
+	resp.WriteHeader(tw.code)
+	if _, werr := resp.Write(tw.buf.Bytes()); werr != nil && err == nil {
+		return werr
+	}
+
+	return err
+}
@@ -1,7 +1,9 @@
 package middleware
 
 import (
+	"context"
 	"github.com/labstack/echo/v4"
+	"github.com/valeamoris/go-ezio/rest/errorx"
 	"io/ioutil"
 	"log"
 	"net/http"
@@ -28,8 +30,11 @@ func TestTimeout(t *testing.T) {
 	resp := httptest.NewRecorder()
 	ctx := e.NewContext(req, resp)
 	err := handler(ctx)
-	assert.NoError(t, err)
+	assert.Equal(t, errorx.CodeTimeout, errorx.From(err).Code)
+	// 超时通过错误处理输出
+	errorx.ErrorHandler(false)(err, ctx)
 	assert.Equal(t, http.StatusGatewayTimeout, resp.Code)
+	assert.JSONEq(t, `{"code":50400,"message":"timeout"}`, resp.Body.String())
 }
 
 func TestWithinTimeout(t *testing.T) {
@@ -63,3 +68,124 @@ func TestWithoutTimeout(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, http.StatusOK, resp.Code)
 }
+
+func TestTimeout_DiscardLateWrites(t *testing.T) {
+	written := make(chan error, 1)
+	handler := TimeoutMiddleware(time.Millisecond * 10)(func(ctx echo.Context) error {
+		<-ctx.Request().Context().Done()
+		time.Sleep(time.Millisecond * 10)
+		written <- ctx.String(http.StatusOK, "late")
+		return nil
+	})
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "http://localhost", nil)
+	resp := httptest.NewRecorder()
+	ctx := e.NewContext(req, resp)
+	err := handler(ctx)
+	assert.Equal(t, http.StatusGatewayTimeout, errorx.From(err).Status)
+	assert.Equal(t, http.ErrHandlerTimeout, <-written)
+	assert.Equal(t, 0, resp.Body.Len())
+	assert.Equal(t, DroppedByTimeout, droppedReason(ctx))
+}
+
+func TestTimeout_ValuesAfterTimeout(t *testing.T) {
+	done := make(chan struct{})
+	handler := TimeoutMiddleware(time.Millisecond * 10)(func(ctx echo.Context) error {
+		defer close(done)
+		ctx.Set("foo", "bar")
+		for i := 0; i < 1000; i++ {
+			ctx.Set("count", i)
+			ctx.Get("count")
+		}
+		<-ctx.Request().Context().Done()
+		for i := 0; i < 1000; i++ {
+			ctx.Set("count", i)
+			ctx.Get("count")
+		}
+		return nil
+	})
+
+	e := echo.New()
+	ctx := e.NewContext(httptest.NewRequest(http.MethodGet, "http://localhost", nil), httptest.NewRecorder())
+	err := handler(ctx)
+	assert.Equal(t, http.StatusGatewayTimeout, errorx.From(err).Status)
+	// 超时后echo可能复用parent，handler不能再访问它
+	for i := 0; i < 1000; i++ {
+		ctx.Set("count", -1)
+	}
+	<-done
+	assert.Equal(t, "bar", ctx.Get("foo"))
+	assert.Equal(t, -1, ctx.Get("count"))
+}
+
+func TestTimeout_ClientClosed(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	handler := TimeoutMiddleware(time.Minute)(func(ctx echo.Context) error {
+		<-release
+		return nil
+	})
+
+	reqCtx, cancel := context.WithCancel(context.Background())
+	cancel()
+	req := httptest.NewRequest(http.MethodGet, "http://localhost", nil).WithContext(reqCtx)
+	err := handler(echo.New().NewContext(req, httptest.NewRecorder()))
+	assert.Equal(t, errorx.StatusClientClosedRequest, errorx.From(err).Status)
+}
+
+func TestTimeout_BufferedResponse(t *testing.T) {
+	handler := TimeoutMiddleware(time.Second)(func(ctx echo.Context) error {
+		ctx.Set("foo", "bar")
+		ctx.Response().Header().Set("X-Foo", "bar")
+		return ctx.String(http.StatusCreated, "ok "+ctx.Param("id"))
+	})
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "http://localhost", nil)
+	resp := httptest.NewRecorder()
+	ctx := e.NewContext(req, resp)
+	ctx.SetParamNames("id")
+	ctx.SetParamValues("1")
+	assert.NoError(t, handler(ctx))
+	assert.Equal(t, http.StatusCreated, resp.Code)
+	assert.Equal(t, "ok 1", resp.Body.String())
+	assert.Equal(t, "bar", resp.Header().Get("X-Foo"))
+	assert.Equal(t, "bar", ctx.Get("foo"))
+	assert.Equal(t, http.StatusCreated, ctx.Response().Status)
+}
+
+func TestTimeout_Panic(t *testing.T) {
+	handler := TimeoutMiddleware(time.Second)(func(ctx echo.Context) error {
+		panic("whatever")
+	})
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "http://localhost", nil)
+	ctx := e.NewContext(req, httptest.NewRecorder())
+	assert.Panics(t, func() {
+		_ = handler(ctx)
+	})
+}
+
+func TestRequestTimeout(t *testing.T) {
+	tests := []struct {
+		header string
+		expect time.Duration
+	}{
+		{"", time.Second},
+		{"100", time.Millisecond * 100},
+		{"200ms", time.Millisecond * 200},
+		{"5s", time.Second},
+		{"-1", time.Second},
+		{"bad", time.Second},
+	}
+
+	for _, test := range tests {
+		t.Run(test.header, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "http://localhost", nil)
+			req.Header.Set(HeaderRequestTimeout, test.header)
+			assert.Equal(t, test.expect, requestTimeout(req, time.Second))
+		})
+	}
+}
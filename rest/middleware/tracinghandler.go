@@ -1,66 +1,73 @@
 package middleware
 
 import (
+	"fmt"
 	"github.com/labstack/echo/v4"
 	"github.com/opentracing/opentracing-go"
 	"github.com/opentracing/opentracing-go/ext"
-	"github.com/uber/jaeger-client-go"
-	jaegercfg "github.com/uber/jaeger-client-go/config"
-	"github.com/uber/jaeger-client-go/zipkin"
-	"io"
-	"log"
+	"github.com/opentracing/opentracing-go/log"
 	"net/http"
 )
 
-const defaultComponentName = "echo/v4"
+const (
+	defaultComponentName = "echo/v4"
 
-func TracingMiddleware(serviceName string) (echo.MiddlewareFunc, io.Closer) {
-	// Recommended configuration for production.
-	cfg := jaegercfg.Configuration{}
-
-	// Zipkin shares span ID between client and server spans; it must be enabled via the following option.
-	zipkinPropagator := zipkin.NewZipkinB3HTTPHeaderPropagator()
-
-	// Create tracer and then initialize global tracer
-	closer, err := cfg.InitGlobalTracer(
-		serviceName,
-		jaegercfg.Logger(jaeger.StdLogger),
-		jaegercfg.Injector(opentracing.HTTPHeaders, zipkinPropagator),
-		jaegercfg.Extractor(opentracing.HTTPHeaders, zipkinPropagator),
-		jaegercfg.ZipkinSharedRPCSpan(true),
-	)
+	tagRoute     = "http.route"
+	tagClientIP  = "http.client_ip"
+	tagRequestID = "request.id"
+)
 
-	if err != nil {
-		log.Panicf("Could not initialize jaeger tracer: %s", err.Error())
-	}
+// TracingMiddleware starts a server span for each request with the given tracer,
+// the tracer is not registered as the global one, it's up to the caller.
+func TracingMiddleware(tracer opentracing.Tracer) echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
-		return func(c echo.Context) error {
+		return func(c echo.Context) (err error) {
 			req := c.Request()
 			opname := "HTTP " + req.Method + " URL: " + c.Path()
 			var sp opentracing.Span
-			if ctx, err := opentracing.GlobalTracer().Extract(opentracing.HTTPHeaders,
+			if ctx, err := tracer.Extract(opentracing.HTTPHeaders,
 				opentracing.HTTPHeadersCarrier(req.Header)); err != nil {
-				sp = opentracing.StartSpan(opname)
+				sp = tracer.StartSpan(opname)
 			} else {
-				sp = opentracing.StartSpan(opname, ext.RPCServerOption(ctx), opentracing.ChildOf(ctx))
+				sp = tracer.StartSpan(opname, ext.RPCServerOption(ctx))
 			}
 
 			ext.HTTPMethod.Set(sp, req.Method)
 			ext.HTTPUrl.Set(sp, req.URL.String())
 			ext.Component.Set(sp, defaultComponentName)
+			sp.SetTag(tagRoute, c.Path())
+			sp.SetTag(tagClientIP, c.RealIP())
+			if requestID := requestIdFromRequest(req); len(requestID) > 0 {
+				sp.SetTag(tagRequestID, requestID)
+			}
 			req = req.WithContext(opentracing.ContextWithSpan(req.Context(), sp))
 			c.SetRequest(req)
 
 			defer func() {
+				if p := recover(); p != nil {
+					ext.Error.Set(sp, true)
+					sp.LogFields(log.String("event", "panic"), log.String("message", fmt.Sprint(p)))
+					sp.Finish()
+					panic(p)
+				}
+
 				status := c.Response().Status
 				committed := c.Response().Committed
+				if err != nil {
+					sp.LogFields(log.Error(err))
+					if !committed {
+						status = errorStatus(err)
+					}
+				}
 				ext.HTTPStatusCode.Set(sp, uint16(status))
-				if status >= http.StatusInternalServerError || !committed {
+				// 4xx是客户端的错误，不标记span
+				if status >= http.StatusInternalServerError {
 					ext.Error.Set(sp, true)
 				}
 				sp.Finish()
 			}()
+
 			return next(c)
 		}
-	}, closer
+	}
 }
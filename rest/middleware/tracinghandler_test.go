@@ -1,10 +1,13 @@
 package middleware
 
 import (
+	"errors"
 	"github.com/labstack/echo/v4"
 	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/mocktracer"
 	"github.com/stretchr/testify/assert"
 	"github.com/uber/jaeger-client-go"
+	"github.com/uber/jaeger-client-go/zipkin"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -17,8 +20,12 @@ func TestTracingHandler(t *testing.T) {
 	req := httptest.NewRequest(http.MethodGet, "http://localhost", nil)
 	req.Header.Set("x-b3-traceid", tradeId)
 	req.Header.Set("x-b3-spanid", tradeId)
-	md, closer := TracingMiddleware("test")
+	propagator := zipkin.NewZipkinB3HTTPHeaderPropagator()
+	tracer, closer := jaeger.NewTracer("test", jaeger.NewConstSampler(true), jaeger.NewNullReporter(),
+		jaeger.TracerOptions.Injector(opentracing.HTTPHeaders, propagator),
+		jaeger.TracerOptions.Extractor(opentracing.HTTPHeaders, propagator))
 	defer closer.Close()
+	md := TracingMiddleware(tracer)
 
 	handler := md(func(ctx echo.Context) error {
 		span := opentracing.SpanFromContext(ctx.Request().Context())
@@ -34,3 +41,43 @@ func TestTracingHandler(t *testing.T) {
 	assert.Nil(t, err)
 	assert.Equal(t, http.StatusOK, resp.Code)
 }
+
+func TestTracingHandler_Tags(t *testing.T) {
+	tracer := mocktracer.New()
+	e := echo.New()
+	e.Use(TracingMiddleware(tracer))
+	e.GET("/users/:id", func(ctx echo.Context) error {
+		return errors.New("boom")
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/users/1", nil)
+	req.Header.Set(echo.HeaderXRequestID, "abc")
+	req.Header.Set(echo.HeaderXRealIP, "1.2.3.4")
+	e.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := tracer.FinishedSpans()
+	assert.Len(t, spans, 1)
+	assert.Equal(t, "/users/:id", spans[0].Tag(tagRoute))
+	assert.Equal(t, "1.2.3.4", spans[0].Tag(tagClientIP))
+	assert.Equal(t, "abc", spans[0].Tag(tagRequestID))
+	assert.Equal(t, true, spans[0].Tag("error"))
+	assert.Equal(t, uint16(http.StatusInternalServerError), spans[0].Tag("http.status_code"))
+	assert.Len(t, spans[0].Logs(), 1)
+}
+
+func TestTracingHandler_Panic(t *testing.T) {
+	tracer := mocktracer.New()
+	handler := TracingMiddleware(tracer)(func(ctx echo.Context) error {
+		panic("whatever")
+	})
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "http://localhost", nil)
+	ctx := e.NewContext(req, httptest.NewRecorder())
+	assert.Panics(t, func() {
+		_ = handler(ctx)
+	})
+	spans := tracer.FinishedSpans()
+	assert.Len(t, spans, 1)
+	assert.Equal(t, true, spans[0].Tag("error"))
+}
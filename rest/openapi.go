@@ -0,0 +1,113 @@
+package rest
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/valeamoris/go-ezio/rest/middleware"
+	"github.com/valeamoris/go-ezio/rest/openapi"
+)
+
+const bearerFormatJwt = "JWT"
+
+func docKey(method, path string) string {
+	return strings.ToUpper(method) + " " + path
+}
+
+func (s *engine) openApi() (*openapi.Document, error) {
+	title := s.conf.Admin.Docs.Title
+	if len(title) == 0 {
+		title = s.serviceName()
+	}
+	gen := openapi.NewGenerator(openapi.Info{
+		Title:       title,
+		Description: s.conf.Admin.Docs.Description,
+		Version:     s.conf.Admin.Docs.Version,
+	})
+
+	for _, g := range s.groups {
+		// 分组的认证中间件依次执行，需要同时满足
+		var security []string
+		if g.jwt.enabled {
+			gen.AddSecurityScheme(openapi.SchemeJwt, openapi.SecurityScheme{
+				Type:         "http",
+				Scheme:       "bearer",
+				BearerFormat: bearerFormatJwt,
+			})
+			security = append(security, openapi.SchemeJwt)
+		}
+		if g.apiKey.enabled {
+			header := g.apiKey.opts.Header
+			if len(header) == 0 {
+				header = middleware.HeaderApiKey
+			}
+			gen.AddSecurityScheme(openapi.SchemeApiKey, openapi.SecurityScheme{
+				Type: "apiKey",
+				In:   "header",
+				Name: header,
+			})
+			security = append(security, openapi.SchemeApiKey)
+		}
+		if g.hmac.enabled {
+			gen.AddSecurityScheme(openapi.SchemeHmac, openapi.SecurityScheme{
+				Type:        "apiKey",
+				In:          "header",
+				Name:        middleware.HeaderHmacSignature,
+				Description: "HMAC-SHA256 signature with X-Key-Id, X-Timestamp and X-Nonce, see rest.SignRequest",
+			})
+			security = append(security, openapi.SchemeHmac)
+		}
+
+		for _, route := range g.Routes {
+			doc := g.docs[docKey(route.Method, route.Path)]
+			if err := gen.Add(openapi.Route{
+				Method:      route.Method,
+				Path:        g.Prefix + route.Path,
+				Summary:     doc.Summary,
+				Description: doc.Description,
+				Tags:        append(append([]string(nil), g.tags...), doc.Tags...),
+				Deprecated:  doc.Deprecated,
+				Request:     doc.Request,
+				Response:    doc.Response,
+				Security:    security,
+			}); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return gen.Document(), nil
+}
+
+func (s *engine) bindDocs(admin *echo.Echo) {
+	if !s.conf.Admin.Docs.Enabled {
+		return
+	}
+
+	admin.GET("/openapi.json", func(ctx echo.Context) error {
+		doc, err := s.openApi()
+		if err != nil {
+			return err
+		}
+		content, err := doc.JSON()
+		if err != nil {
+			return err
+		}
+		return ctx.JSONBlob(http.StatusOK, content)
+	})
+	admin.GET("/openapi.yaml", func(ctx echo.Context) error {
+		doc, err := s.openApi()
+		if err != nil {
+			return err
+		}
+		content, err := doc.YAML()
+		if err != nil {
+			return err
+		}
+		return ctx.Blob(http.StatusOK, "application/yaml", content)
+	})
+	admin.GET("/docs", func(ctx echo.Context) error {
+		return ctx.HTML(http.StatusOK, openapi.SwaggerUI(s.serviceName(), "/openapi.json"))
+	})
+}
@@ -0,0 +1,127 @@
+package openapi
+
+import (
+	"encoding/json"
+
+	"gopkg.in/yaml.v2"
+)
+
+const Version = "3.0.3"
+
+type (
+	Document struct {
+		OpenAPI    string               `json:"openapi"`
+		Info       Info                 `json:"info"`
+		Servers    []Server             `json:"servers,omitempty"`
+		Paths      map[string]*PathItem `json:"paths"`
+		Components Components           `json:"components,omitempty"`
+		Tags       []Tag                `json:"tags,omitempty"`
+	}
+
+	Info struct {
+		Title       string `json:"title"`
+		Description string `json:"description,omitempty"`
+		Version     string `json:"version"`
+	}
+
+	Server struct {
+		URL         string `json:"url"`
+		Description string `json:"description,omitempty"`
+	}
+
+	Tag struct {
+		Name        string `json:"name"`
+		Description string `json:"description,omitempty"`
+	}
+
+	// PathItem holds the operations keyed by the lower case method.
+	PathItem map[string]*Operation
+
+	Operation struct {
+		Tags        []string              `json:"tags,omitempty"`
+		Summary     string                `json:"summary,omitempty"`
+		Description string                `json:"description,omitempty"`
+		OperationID string                `json:"operationId,omitempty"`
+		Parameters  []Parameter           `json:"parameters,omitempty"`
+		RequestBody *RequestBody          `json:"requestBody,omitempty"`
+		Responses   map[string]*Response  `json:"responses"`
+		Security    []SecurityRequirement `json:"security,omitempty"`
+		Deprecated  bool                  `json:"deprecated,omitempty"`
+	}
+
+	Parameter struct {
+		Name        string  `json:"name"`
+		In          string  `json:"in"`
+		Description string  `json:"description,omitempty"`
+		Required    bool    `json:"required,omitempty"`
+		Schema      *Schema `json:"schema"`
+	}
+
+	RequestBody struct {
+		Required bool                  `json:"required,omitempty"`
+		Content  map[string]*MediaType `json:"content"`
+	}
+
+	Response struct {
+		Description string                `json:"description"`
+		Content     map[string]*MediaType `json:"content,omitempty"`
+	}
+
+	MediaType struct {
+		Schema *Schema `json:"schema"`
+	}
+
+	Schema struct {
+		Ref                  string             `json:"$ref,omitempty"`
+		Type                 string             `json:"type,omitempty"`
+		Format               string             `json:"format,omitempty"`
+		Description          string             `json:"description,omitempty"`
+		Properties           map[string]*Schema `json:"properties,omitempty"`
+		Required             []string           `json:"required,omitempty"`
+		Items                *Schema            `json:"items,omitempty"`
+		AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+		Enum                 []interface{}      `json:"enum,omitempty"`
+		Default              interface{}        `json:"default,omitempty"`
+		Minimum              *float64           `json:"minimum,omitempty"`
+		Maximum              *float64           `json:"maximum,omitempty"`
+		ExclusiveMinimum     bool               `json:"exclusiveMinimum,omitempty"`
+		ExclusiveMaximum     bool               `json:"exclusiveMaximum,omitempty"`
+		Nullable             bool               `json:"nullable,omitempty"`
+	}
+
+	Components struct {
+		Schemas         map[string]*Schema         `json:"schemas,omitempty"`
+		SecuritySchemes map[string]*SecurityScheme `json:"securitySchemes,omitempty"`
+	}
+
+	SecurityScheme struct {
+		Type         string `json:"type"`
+		Description  string `json:"description,omitempty"`
+		Name         string `json:"name,omitempty"`
+		In           string `json:"in,omitempty"`
+		Scheme       string `json:"scheme,omitempty"`
+		BearerFormat string `json:"bearerFormat,omitempty"`
+	}
+
+	// SecurityRequirement maps the scheme names to the scopes.
+	SecurityRequirement map[string][]string
+)
+
+func (d *Document) JSON() ([]byte, error) {
+	return json.MarshalIndent(d, "", "  ")
+}
+
+// YAML keeps the field order of JSON, since YAML is a superset of JSON.
+func (d *Document) YAML() ([]byte, error) {
+	content, err := json.Marshal(d)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc yaml.MapSlice
+	if err := yaml.Unmarshal(content, &doc); err != nil {
+		return nil, err
+	}
+
+	return yaml.Marshal(doc)
+}
@@ -0,0 +1,414 @@
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/valeamoris/go-ezio/rest/binding"
+)
+
+const (
+	mimeJSON = "application/json"
+
+	SchemeJwt    = "jwt"
+	SchemeApiKey = "apiKey"
+	SchemeHmac   = "hmac"
+)
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	durationType = reflect.TypeOf(time.Duration(0))
+	rawJSONType  = reflect.TypeOf(json.RawMessage{})
+)
+
+type (
+	// Route describes an operation, Request and Response are the values or pointers of the Go types,
+	// the request's path, form and header fields are parameters, and json fields are the body.
+	Route struct {
+		Method      string
+		Path        string
+		Summary     string
+		Description string
+		Tags        []string
+		Deprecated  bool
+		Request     interface{}
+		Response    interface{}
+		// 安全方案的名字，如SchemeJwt，需要同时满足
+		Security []string
+	}
+
+	Generator struct {
+		doc   *Document
+		names map[reflect.Type]string
+		tags  map[string]bool
+	}
+)
+
+func NewGenerator(info Info) *Generator {
+	return &Generator{
+		doc: &Document{
+			OpenAPI: Version,
+			Info:    info,
+			Paths:   make(map[string]*PathItem),
+			Components: Components{
+				Schemas:         make(map[string]*Schema),
+				SecuritySchemes: make(map[string]*SecurityScheme),
+			},
+		},
+		names: make(map[reflect.Type]string),
+		tags:  make(map[string]bool),
+	}
+}
+
+func (g *Generator) AddSecurityScheme(name string, scheme SecurityScheme) {
+	g.doc.Components.SecuritySchemes[name] = &scheme
+}
+
+func (g *Generator) Add(route Route) error {
+	op := &Operation{
+		Tags:        route.Tags,
+		Summary:     route.Summary,
+		Description: route.Description,
+		OperationID: operationID(route.Method, route.Path),
+		Deprecated:  route.Deprecated,
+		Responses:   make(map[string]*Response),
+	}
+	for _, tag := range route.Tags {
+		if !g.tags[tag] {
+			g.tags[tag] = true
+			g.doc.Tags = append(g.doc.Tags, Tag{Name: tag})
+		}
+	}
+	// 同一个requirement中的方案需要同时满足，多个requirement之间是或的关系
+	if len(route.Security) > 0 {
+		requirement := make(SecurityRequirement, len(route.Security))
+		for _, name := range route.Security {
+			requirement[name] = []string{}
+		}
+		op.Security = []SecurityRequirement{requirement}
+	}
+
+	if route.Request != nil {
+		if err := g.addRequest(op, reflect.TypeOf(route.Request)); err != nil {
+			return err
+		}
+	}
+
+	resp := &Response{Description: http.StatusText(http.StatusOK)}
+	if route.Response != nil {
+		schema, err := g.schema(reflect.TypeOf(route.Response))
+		if err != nil {
+			return err
+		}
+		resp.Content = map[string]*MediaType{mimeJSON: {Schema: schema}}
+	}
+	op.Responses[strconv.Itoa(http.StatusOK)] = resp
+
+	path := convertPath(route.Path)
+	item, ok := g.doc.Paths[path]
+	if !ok {
+		item = &PathItem{}
+		g.doc.Paths[path] = item
+	}
+	(*item)[strings.ToLower(route.Method)] = op
+	return nil
+}
+
+func (g *Generator) Document() *Document {
+	return g.doc
+}
+
+func (g *Generator) addRequest(op *Operation, t reflect.Type) error {
+	t = indirect(t)
+	if t.Kind() != reflect.Struct {
+		schema, err := g.schema(t)
+		if err != nil {
+			return err
+		}
+		op.RequestBody = &RequestBody{
+			Required: true,
+			Content:  map[string]*MediaType{mimeJSON: {Schema: schema}},
+		}
+		return nil
+	}
+
+	body := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	if err := g.walkRequest(op, t, body); err != nil {
+		return err
+	}
+	if len(body.Properties) > 0 {
+		op.RequestBody = &RequestBody{
+			Required: len(body.Required) > 0,
+			Content:  map[string]*MediaType{mimeJSON: {Schema: body}},
+		}
+	}
+
+	return nil
+}
+
+func (g *Generator) walkRequest(op *Operation, t reflect.Type, body *Schema) error {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if len(field.PkgPath) > 0 && !field.Anonymous {
+			continue
+		}
+
+		tag, ok, err := binding.ParseField(field)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			if field.Anonymous && indirect(field.Type).Kind() == reflect.Struct {
+				if err := g.walkRequest(op, indirect(field.Type), body); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		schema, err := g.fieldSchema(field.Type, tag)
+		if err != nil {
+			return err
+		}
+
+		required := !tag.Optional && !tag.HasDefault
+		if tag.Source == "json" {
+			body.Properties[tag.Name] = schema
+			if required {
+				body.Required = append(body.Required, tag.Name)
+			}
+			continue
+		}
+
+		in := tag.Source
+		if in == "form" {
+			in = "query"
+		}
+		op.Parameters = append(op.Parameters, Parameter{
+			Name:     tag.Name,
+			In:       in,
+			Required: required || in == "path",
+			Schema:   schema,
+		})
+	}
+
+	return nil
+}
+
+func (g *Generator) schema(t reflect.Type) (*Schema, error) {
+	nullable := false
+	for t.Kind() == reflect.Ptr {
+		nullable = true
+		t = t.Elem()
+	}
+
+	switch {
+	case t == timeType:
+		return &Schema{Type: "string", Format: "date-time", Nullable: nullable}, nil
+	case t == durationType:
+		return &Schema{Type: "integer", Format: "int64", Description: "nanoseconds", Nullable: nullable}, nil
+	case t == rawJSONType:
+		return &Schema{}, nil
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean", Nullable: nullable}, nil
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16:
+		return &Schema{Type: "integer", Format: "int32", Nullable: nullable}, nil
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64", Nullable: nullable}, nil
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float", Nullable: nullable}, nil
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double", Nullable: nullable}, nil
+	case reflect.String:
+		return &Schema{Type: "string", Nullable: nullable}, nil
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte", Nullable: nullable}, nil
+		}
+		items, err := g.schema(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return &Schema{Type: "array", Items: items, Nullable: nullable}, nil
+	case reflect.Map:
+		values, err := g.schema(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return &Schema{Type: "object", AdditionalProperties: values, Nullable: nullable}, nil
+	case reflect.Interface:
+		return &Schema{}, nil
+	case reflect.Struct:
+		if len(t.Name()) == 0 {
+			return g.structSchema(t)
+		}
+		name, err := g.component(t)
+		if err != nil {
+			return nil, err
+		}
+		return &Schema{Ref: "#/components/schemas/" + name}, nil
+	default:
+		return nil, fmt.Errorf("openapi: unsupported type %s", t)
+	}
+}
+
+// component registers the named struct type in components, and returns its name.
+func (g *Generator) component(t reflect.Type) (string, error) {
+	if name, ok := g.names[t]; ok {
+		return name, nil
+	}
+
+	name := t.Name()
+	if _, ok := g.doc.Components.Schemas[name]; ok {
+		name = strings.ReplaceAll(t.PkgPath(), "/", ".") + "." + name
+	}
+	// 先占位，支持递归的类型
+	g.names[t] = name
+	g.doc.Components.Schemas[name] = &Schema{}
+
+	schema, err := g.structSchema(t)
+	if err != nil {
+		return "", err
+	}
+	g.doc.Components.Schemas[name] = schema
+	return name, nil
+}
+
+func (g *Generator) structSchema(t reflect.Type) (*Schema, error) {
+	schema := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	if err := g.walkStruct(t, schema); err != nil {
+		return nil, err
+	}
+
+	return schema, nil
+}
+
+func (g *Generator) walkStruct(t reflect.Type, schema *Schema) error {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if len(field.PkgPath) > 0 && !field.Anonymous {
+			continue
+		}
+
+		tag, ok, err := binding.ParseField(field)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			if field.Anonymous && indirect(field.Type).Kind() == reflect.Struct {
+				if err := g.walkStruct(indirect(field.Type), schema); err != nil {
+					return err
+				}
+				continue
+			}
+			if _, ignored := field.Tag.Lookup("json"); ignored || len(field.PkgPath) > 0 {
+				continue
+			}
+			// 与encoding/json一致，没有tag的字段使用字段名
+			tag = binding.Tag{Source: "json", Name: field.Name, Optional: true}
+		}
+		if tag.Source != "json" {
+			continue
+		}
+
+		prop, err := g.fieldSchema(field.Type, tag)
+		if err != nil {
+			return err
+		}
+		schema.Properties[tag.Name] = prop
+		if !tag.Optional && !tag.HasDefault {
+			schema.Required = append(schema.Required, tag.Name)
+		}
+	}
+
+	return nil
+}
+
+func (g *Generator) fieldSchema(t reflect.Type, tag binding.Tag) (*Schema, error) {
+	schema, err := g.schema(t)
+	if err != nil {
+		return nil, err
+	}
+	if len(schema.Ref) > 0 {
+		return schema, nil
+	}
+
+	target := schema
+	if schema.Type == "array" && schema.Items != nil && len(schema.Items.Ref) == 0 {
+		target = schema.Items
+	}
+	for _, option := range tag.Options {
+		target.Enum = append(target.Enum, typedValue(target.Type, option))
+	}
+	target.Minimum = tag.Min
+	target.Maximum = tag.Max
+	target.ExclusiveMinimum = tag.ExclusiveMin
+	target.ExclusiveMaximum = tag.ExclusiveMax
+	if tag.HasDefault {
+		schema.Default = typedValue(schema.Type, tag.Default)
+	}
+
+	return schema, nil
+}
+
+func typedValue(typ, val string) interface{} {
+	switch typ {
+	case "integer":
+		if n, err := strconv.ParseInt(val, 10, 64); err == nil {
+			return n
+		}
+	case "number":
+		if f, err := strconv.ParseFloat(val, 64); err == nil {
+			return f
+		}
+	case "boolean":
+		if b, err := strconv.ParseBool(val); err == nil {
+			return b
+		}
+	}
+
+	return val
+}
+
+// convertPath converts /users/:id to /users/{id}.
+func convertPath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		switch {
+		case strings.HasPrefix(segment, ":"):
+			segments[i] = "{" + segment[1:] + "}"
+		case segment == "*":
+			segments[i] = "{wildcard}"
+		}
+	}
+
+	return strings.Join(segments, "/")
+}
+
+func operationID(method, path string) string {
+	var b strings.Builder
+	b.WriteString(strings.ToLower(method))
+	for _, segment := range strings.FieldsFunc(path, func(r rune) bool {
+		return r == '/' || r == ':' || r == '-' || r == '_' || r == '.' || r == '*'
+	}) {
+		b.WriteString(strings.ToUpper(segment[:1]) + segment[1:])
+	}
+
+	return b.String()
+}
+
+func indirect(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	return t
+}
@@ -0,0 +1,104 @@
+package openapi
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type (
+	address struct {
+		City string `json:"city"`
+	}
+
+	user struct {
+		Id        int64     `json:"id"`
+		Name      string    `json:"name"`
+		Email     *string   `json:"email,optional"`
+		Addresses []address `json:"addresses,optional"`
+		CreatedAt time.Time `json:"createdAt"`
+		Parent    *user     `json:"parent,optional"`
+	}
+
+	updateUserRequest struct {
+		Id     int64    `path:"id"`
+		Force  bool     `form:"force,default=false"`
+		Token  string   `header:"X-Token,optional"`
+		Name   string   `json:"name"`
+		Status string   `json:"status,options=on|off"`
+		Age    int      `json:"age,optional,range=[0:150)"`
+		Roles  []string `json:"roles,optional,options=admin|user"`
+	}
+)
+
+func TestGenerator(t *testing.T) {
+	g := NewGenerator(Info{Title: "users", Version: "1.0.0"})
+	g.AddSecurityScheme(SchemeJwt, SecurityScheme{Type: "http", Scheme: "bearer", BearerFormat: "JWT"})
+	assert.Nil(t, g.Add(Route{
+		Method:   "PUT",
+		Path:     "/users/:id",
+		Summary:  "update user",
+		Tags:     []string{"user"},
+		Request:  updateUserRequest{},
+		Response: &user{},
+		Security: []string{SchemeJwt},
+	}))
+	doc := g.Document()
+
+	op := (*doc.Paths["/users/{id}"])["put"]
+	assert.Equal(t, "putUsersId", op.OperationID)
+	assert.Equal(t, []SecurityRequirement{{SchemeJwt: {}}}, op.Security)
+	assert.Equal(t, []Parameter{
+		{Name: "id", In: "path", Required: true, Schema: &Schema{Type: "integer", Format: "int64"}},
+		{Name: "force", In: "query", Schema: &Schema{Type: "boolean", Default: false}},
+		{Name: "X-Token", In: "header", Schema: &Schema{Type: "string"}},
+	}, op.Parameters)
+
+	body := op.RequestBody.Content[mimeJSON].Schema
+	assert.Equal(t, []string{"name", "status"}, body.Required)
+	assert.Equal(t, []interface{}{"on", "off"}, body.Properties["status"].Enum)
+	assert.Equal(t, 150.0, *body.Properties["age"].Maximum)
+	assert.True(t, body.Properties["age"].ExclusiveMaximum)
+	assert.Equal(t, []interface{}{"admin", "user"}, body.Properties["roles"].Items.Enum)
+
+	assert.Equal(t, "#/components/schemas/user", op.Responses["200"].Content[mimeJSON].Schema.Ref)
+	schema := doc.Components.Schemas["user"]
+	assert.Equal(t, []string{"id", "name", "createdAt"}, schema.Required)
+	assert.Equal(t, "date-time", schema.Properties["createdAt"].Format)
+	assert.True(t, schema.Properties["email"].Nullable)
+	assert.Equal(t, "#/components/schemas/user", schema.Properties["parent"].Ref)
+	assert.Equal(t, "#/components/schemas/address", schema.Properties["addresses"].Items.Ref)
+}
+
+func TestGenerator_SecurityRequirement(t *testing.T) {
+	g := NewGenerator(Info{Title: "users", Version: "1.0.0"})
+	assert.Nil(t, g.Add(Route{Method: "GET", Path: "/users", Security: []string{SchemeJwt, SchemeHmac}}))
+	assert.Nil(t, g.Add(Route{Method: "GET", Path: "/ping"}))
+	doc := g.Document()
+
+	assert.Equal(t, []SecurityRequirement{{SchemeJwt: {}, SchemeHmac: {}}}, (*doc.Paths["/users"])["get"].Security)
+	assert.Nil(t, (*doc.Paths["/ping"])["get"].Security)
+}
+
+func TestDocument_Marshal(t *testing.T) {
+	g := NewGenerator(Info{Title: "users", Version: "1.0.0"})
+	assert.Nil(t, g.Add(Route{Method: "GET", Path: "/ping"}))
+
+	content, err := g.Document().JSON()
+	assert.Nil(t, err)
+	var m map[string]interface{}
+	assert.Nil(t, json.Unmarshal(content, &m))
+	assert.Equal(t, Version, m["openapi"])
+
+	content, err = g.Document().YAML()
+	assert.Nil(t, err)
+	assert.Contains(t, string(content), "openapi: 3.0.3\ninfo:\n  title: users\n")
+	assert.Contains(t, string(content), "operationId: getPing")
+}
+
+func TestConvertPath(t *testing.T) {
+	assert.Equal(t, "/users/{id}/books/{bookId}", convertPath("/users/:id/books/:bookId"))
+	assert.Equal(t, "/static/{wildcard}", convertPath("/static/*"))
+}
@@ -0,0 +1,27 @@
+package openapi
+
+import (
+	"fmt"
+	"html"
+)
+
+const swaggerUITemplate = `<!DOCTYPE html>
+<html>
+<head>
+  <meta charset="utf-8">
+  <title>%s</title>
+  <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@4/swagger-ui.css">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="https://unpkg.com/swagger-ui-dist@4/swagger-ui-bundle.js"></script>
+  <script>
+    window.ui = SwaggerUIBundle({url: %q, dom_id: "#swagger-ui"});
+  </script>
+</body>
+</html>`
+
+// SwaggerUI returns the page rendering the spec at specURL, the assets are loaded from unpkg.
+func SwaggerUI(title, specURL string) string {
+	return fmt.Sprintf(swaggerUITemplate, html.EscapeString(title), specURL)
+}
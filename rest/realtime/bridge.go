@@ -0,0 +1,274 @@
+// Package realtime bridges the broker topics to the SSE and WebSocket clients, the clients
+// choose the topics by patterns, and only receive the messages of their own claim, like the tenant.
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	red "github.com/go-redis/redis/v8"
+	"github.com/valeamoris/go-ezio/broker"
+	"github.com/valeamoris/go-ezio/core/stores/redis"
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+const (
+	defaultName       = "realtime"
+	defaultReplaySize = 1000
+
+	fieldTopic  = "topic"
+	fieldHeader = "header"
+	fieldBody   = "body"
+
+	// 阻塞读取的时间，也是Stop最多等待的时间
+	readBlock = time.Second
+	readCount = 100
+	// 读取失败后的重试间隔
+	retryInterval = time.Second
+)
+
+type (
+	Option func(*Bridge)
+
+	message struct {
+		// redis stream的id，没有redis时为空
+		id     string
+		topic  string
+		header map[string]string
+		body   []byte
+	}
+
+	// Bridge subscribes the topics and delivers the messages to the clients connected to this instance.
+	Bridge struct {
+		conf    Conf
+		broker  broker.Broker
+		store   redis.Node
+		key     string
+		subs    []broker.Subscriber
+		clients map[*client]struct{}
+		lock    sync.RWMutex
+		done    chan struct{}
+		once    sync.Once
+		wg      sync.WaitGroup
+	}
+)
+
+func NewBridge(b broker.Broker, c Conf, opts ...Option) *Bridge {
+	if len(c.Name) == 0 {
+		c.Name = defaultName
+	}
+	if c.ReplaySize <= 0 {
+		c.ReplaySize = defaultReplaySize
+	}
+	if len(c.Header) == 0 {
+		c.Header = c.Claim
+	}
+
+	bridge := &Bridge{
+		conf:    c,
+		broker:  b,
+		key:     c.Name + ":events",
+		clients: make(map[*client]struct{}),
+		done:    make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(bridge)
+	}
+
+	return bridge
+}
+
+// WithRedis keeps the recent messages in a redis stream, for the clients to resume from
+// the last event id, and to share the subscriptions across the instances.
+func WithRedis(store redis.Node) Option {
+	return func(b *Bridge) {
+		b.store = store
+	}
+}
+
+// Start subscribes the topics. Without redis, every instance subscribes the topics itself.
+// With redis, the messages are consumed by the queue shared by the instances and appended
+// to the redis stream, which every instance reads to deliver the messages.
+func (b *Bridge) Start() error {
+	handler := b.deliver
+	var opts []broker.SubscribeOption
+	if b.store != nil {
+		lastId, err := b.lastId()
+		if err != nil {
+			return err
+		}
+
+		handler = b.append
+		opts = append(opts, broker.Queue(b.conf.Name), broker.DisableAutoAck())
+		b.wg.Add(1)
+		go b.read(lastId)
+	}
+
+	for _, topic := range b.conf.Topics {
+		sub, err := b.broker.Subscribe(topic, handler, opts...)
+		if err != nil {
+			b.Stop()
+			return err
+		}
+		b.subs = append(b.subs, sub)
+	}
+
+	return nil
+}
+
+// Stop unsubscribes the topics and closes the clients after their buffered messages are sent.
+func (b *Bridge) Stop() {
+	b.once.Do(func() {
+		for _, sub := range b.subs {
+			if err := sub.Unsubscribe(); err != nil {
+				logx.Errorf("realtime: unsubscribe %s failed: %s", sub.Topic(), err.Error())
+			}
+		}
+		close(b.done)
+		b.wg.Wait()
+
+		b.lock.RLock()
+		defer b.lock.RUnlock()
+		for c := range b.clients {
+			c.conn.Close()
+		}
+	})
+}
+
+// Len returns the number of the clients connected to this instance.
+func (b *Bridge) Len() int {
+	b.lock.RLock()
+	defer b.lock.RUnlock()
+	return len(b.clients)
+}
+
+func (b *Bridge) add(c *client) {
+	b.lock.Lock()
+	b.clients[c] = struct{}{}
+	b.lock.Unlock()
+}
+
+func (b *Bridge) remove(c *client) {
+	b.lock.Lock()
+	delete(b.clients, c)
+	b.lock.Unlock()
+}
+
+func (b *Bridge) deliver(e broker.Event) error {
+	msg := e.Message()
+	b.dispatch(&message{
+		topic:  e.Topic(),
+		header: msg.Header,
+		body:   msg.Body,
+	})
+	return nil
+}
+
+func (b *Bridge) append(e broker.Event) error {
+	msg := e.Message()
+	header, err := json.Marshal(msg.Header)
+	if err != nil {
+		return err
+	}
+
+	if err := b.store.XAdd(broker.EventContext(e), &red.XAddArgs{
+		Stream: b.key,
+		MaxLen: b.conf.ReplaySize,
+		Approx: true,
+		Values: map[string]interface{}{
+			fieldTopic:  e.Topic(),
+			fieldHeader: header,
+			fieldBody:   msg.Body,
+		},
+	}).Err(); err != nil {
+		return err
+	}
+
+	return e.Ack()
+}
+
+func (b *Bridge) dispatch(msg *message) {
+	b.lock.RLock()
+	defer b.lock.RUnlock()
+
+	for c := range b.clients {
+		if b.accept(c, msg) {
+			c.deliver(msg)
+		}
+	}
+}
+
+func (b *Bridge) accept(c *client, msg *message) bool {
+	if !matchAny(c.patterns, msg.topic) {
+		return false
+	}
+
+	// 没有claim header的消息不属于任何客户端
+	return len(b.conf.Claim) == 0 || (len(msg.header[b.conf.Header]) > 0 && msg.header[b.conf.Header] == c.claim)
+}
+
+func (b *Bridge) lastId() (string, error) {
+	msgs, err := b.store.XRevRangeN(context.Background(), b.key, "+", "-", 1).Result()
+	if err != nil {
+		return "", err
+	}
+	if len(msgs) == 0 {
+		return "0-0", nil
+	}
+
+	return msgs[0].ID, nil
+}
+
+func (b *Bridge) read(lastId string) {
+	defer b.wg.Done()
+
+	for {
+		select {
+		case <-b.done:
+			return
+		default:
+		}
+
+		streams, err := b.store.XRead(context.Background(), &red.XReadArgs{
+			Streams: []string{b.key, lastId},
+			Count:   readCount,
+			Block:   readBlock,
+		}).Result()
+		if err == red.Nil {
+			continue
+		}
+		if err != nil {
+			logx.Errorf("realtime: read %s failed: %s", b.key, err.Error())
+			select {
+			case <-b.done:
+				return
+			case <-time.After(retryInterval):
+			}
+			continue
+		}
+
+		for _, s := range streams {
+			for _, m := range s.Messages {
+				lastId = m.ID
+				b.dispatch(toMessage(m))
+			}
+		}
+	}
+}
+
+func toMessage(m red.XMessage) *message {
+	msg := &message{id: m.ID}
+	msg.topic, _ = m.Values[fieldTopic].(string)
+	if header, ok := m.Values[fieldHeader].(string); ok {
+		if err := json.Unmarshal([]byte(header), &msg.header); err != nil {
+			logx.Errorf("realtime: bad header of message %s: %s", m.ID, err.Error())
+		}
+	}
+	if body, ok := m.Values[fieldBody].(string); ok {
+		msg.body = []byte(body)
+	}
+
+	return msg
+}
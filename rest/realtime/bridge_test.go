@@ -0,0 +1,256 @@
+package realtime
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/dgrijalva/jwt-go"
+	"github.com/gorilla/websocket"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/valeamoris/go-ezio/broker"
+	"github.com/valeamoris/go-ezio/core/stores/redis"
+	"github.com/valeamoris/go-ezio/rest"
+	"github.com/valeamoris/go-ezio/rest/resttest"
+)
+
+const secret = "realtime-secret"
+
+type (
+	mockBroker struct {
+		handlers map[string][]broker.Handler
+		opts     []broker.SubscribeOptions
+		lock     sync.Mutex
+	}
+
+	mockSubscriber struct {
+		topic string
+		opts  broker.SubscribeOptions
+	}
+
+	mockEvent struct {
+		topic string
+		msg   *broker.Message
+		acked bool
+	}
+)
+
+func newMockBroker() *mockBroker {
+	return &mockBroker{handlers: make(map[string][]broker.Handler)}
+}
+
+func (b *mockBroker) Init(...broker.Option) error { return nil }
+func (b *mockBroker) Options() broker.Options     { return broker.Options{} }
+func (b *mockBroker) Address() string             { return "" }
+func (b *mockBroker) Connect() error              { return nil }
+func (b *mockBroker) Disconnect() error           { return nil }
+func (b *mockBroker) String() string              { return "mock" }
+
+func (b *mockBroker) Publish(topic string, m *broker.Message, _ ...broker.PublishOption) error {
+	b.lock.Lock()
+	handlers := b.handlers[topic]
+	b.lock.Unlock()
+
+	for _, h := range handlers {
+		e := &mockEvent{topic: topic, msg: m}
+		if err := h(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *mockBroker) Subscribe(topic string, h broker.Handler, opts ...broker.SubscribeOption) (broker.Subscriber, error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	opt := broker.NewSubscribeOptions(opts...)
+	b.handlers[topic] = append(b.handlers[topic], h)
+	b.opts = append(b.opts, opt)
+	return &mockSubscriber{topic: topic, opts: opt}, nil
+}
+
+func (s *mockSubscriber) Options() broker.SubscribeOptions { return s.opts }
+func (s *mockSubscriber) Topic() string                    { return s.topic }
+func (s *mockSubscriber) Unsubscribe() error               { return nil }
+
+func (e *mockEvent) Topic() string            { return e.topic }
+func (e *mockEvent) Message() *broker.Message { return e.msg }
+func (e *mockEvent) Error() error             { return nil }
+
+func (e *mockEvent) Ack() error {
+	e.acked = true
+	return nil
+}
+
+func newTestServer(t *testing.T, bridge *Bridge) *httptest.Server {
+	srv := resttest.NewServer(t, resttest.NewConf())
+	srv.Group(rest.Group{
+		Routes: []rest.Route{
+			{Method: http.MethodGet, Path: "/events", Handler: rest.HandlerFunc(bridge.SSE())},
+			{Method: http.MethodGet, Path: "/ws", Handler: rest.HandlerFunc(bridge.WebSocket())},
+		},
+	}, rest.WithStreaming(), rest.WithJwt(secret, jwt.MapClaims{}))
+
+	svr := httptest.NewServer(srv)
+	t.Cleanup(svr.Close)
+	return svr
+}
+
+func token(t *testing.T, claims jwt.MapClaims) string {
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(secret))
+	assert.Nil(t, err)
+	return "Bearer " + token
+}
+
+func publish(t *testing.T, b broker.Broker, topic, tenant, body string) {
+	assert.Nil(t, b.Publish(topic, &broker.Message{
+		Header: map[string]string{"tenant": tenant},
+		Body:   []byte(body),
+	}))
+}
+
+type sseReader struct {
+	reader *bufio.Reader
+}
+
+// next reads an event as the lines without the trailing blank line.
+func (r *sseReader) next(t *testing.T) []string {
+	var lines []string
+	for {
+		line, err := r.reader.ReadString('\n')
+		assert.Nil(t, err)
+		line = strings.TrimSuffix(line, "\n")
+		if len(line) == 0 {
+			return lines
+		}
+		lines = append(lines, line)
+	}
+}
+
+func connectSSE(t *testing.T, target, auth, lastEventId string) (*http.Response, *sseReader) {
+	req, err := http.NewRequest(http.MethodGet, target, nil)
+	assert.Nil(t, err)
+	req.Header.Set(echo.HeaderAccept, "text/event-stream")
+	if len(auth) > 0 {
+		req.Header.Set(echo.HeaderAuthorization, auth)
+	}
+	if len(lastEventId) > 0 {
+		req.Header.Set("Last-Event-ID", lastEventId)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	assert.Nil(t, err)
+	t.Cleanup(func() {
+		_ = resp.Body.Close()
+	})
+
+	return resp, &sseReader{reader: bufio.NewReader(resp.Body)}
+}
+
+func TestBridge(t *testing.T) {
+	b := newMockBroker()
+	bridge := NewBridge(b, Conf{
+		Topics: []string{"order.created", "order.paid", "user.created"},
+		Claim:  "tenant",
+	})
+	assert.Nil(t, bridge.Start())
+	defer bridge.Stop()
+	svr := newTestServer(t, bridge)
+
+	resp, _ := connectSSE(t, svr.URL+"/events", token(t, jwt.MapClaims{"sub": "kevin"}), "")
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	resp, _ = connectSSE(t, svr.URL+"/events?topics=order.cre*", token(t, jwt.MapClaims{"tenant": "a"}), "")
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	resp, reader := connectSSE(t, svr.URL+"/events?topics=order.*", token(t, jwt.MapClaims{"tenant": "a"}), "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Eventually(t, func() bool {
+		return bridge.Len() == 1
+	}, time.Second, 10*time.Millisecond)
+
+	publish(t, b, "order.created", "b", "other tenant")
+	publish(t, b, "user.created", "a", "other topic")
+	publish(t, b, "order.created", "a", "created")
+	publish(t, b, "order.paid", "a", "paid")
+	assert.Equal(t, []string{"event: order.created", "data: created"}, reader.next(t))
+	assert.Equal(t, []string{"event: order.paid", "data: paid"}, reader.next(t))
+
+	bridge.Stop()
+	assert.Eventually(t, func() bool {
+		return bridge.Len() == 0
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestBridgeReplay(t *testing.T) {
+	s, err := miniredis.Run()
+	assert.Nil(t, err)
+	defer s.Close()
+	store, err := redis.NewRedis(s.Addr(), redis.NodeType)
+	assert.Nil(t, err)
+
+	b := newMockBroker()
+	bridge := NewBridge(b, Conf{
+		Topics:     []string{"order.created"},
+		Claim:      "tenant",
+		ReplaySize: 10,
+	}, WithRedis(store))
+	assert.Nil(t, bridge.Start())
+	defer bridge.Stop()
+	assert.Equal(t, "realtime", b.opts[0].Queue)
+	assert.False(t, b.opts[0].AutoAck)
+	svr := newTestServer(t, bridge)
+
+	auth := token(t, jwt.MapClaims{"tenant": float64(1)})
+	resp, reader := connectSSE(t, svr.URL+"/events", auth, "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Eventually(t, func() bool {
+		return bridge.Len() == 1
+	}, time.Second, 10*time.Millisecond)
+
+	publish(t, b, "order.created", "1", "first")
+	first := reader.next(t)
+	assert.Len(t, first, 3)
+	assert.Equal(t, []string{"event: order.created", "data: first"}, first[1:])
+	lastEventId := strings.TrimPrefix(first[0], "id: ")
+	assert.True(t, validId(lastEventId))
+	_ = resp.Body.Close()
+	assert.Eventually(t, func() bool {
+		return bridge.Len() == 0
+	}, time.Second, 10*time.Millisecond)
+
+	// 断线期间的消息在重连后重放
+	publish(t, b, "order.created", "2", "other tenant")
+	publish(t, b, "order.created", "1", "second")
+	resp, reader = connectSSE(t, svr.URL+"/events", auth, lastEventId)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	second := reader.next(t)
+	assert.Equal(t, "data: second", second[2])
+	assert.True(t, after(strings.TrimPrefix(second[0], "id: "), lastEventId))
+	assert.Eventually(t, func() bool {
+		return bridge.Len() == 1
+	}, time.Second, 10*time.Millisecond)
+	publish(t, b, "order.created", "1", "third")
+	assert.Equal(t, "data: third", reader.next(t)[2])
+
+	// WebSocket的消息带上id和主题
+	header := http.Header{}
+	header.Set(echo.HeaderAuthorization, auth)
+	ws, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(svr.URL, "http")+
+		"/ws?topics="+url.QueryEscape("order.#")+"&lastEventId="+lastEventId, header)
+	assert.Nil(t, err)
+	defer ws.Close()
+	_, msg, err := ws.ReadMessage()
+	assert.Nil(t, err)
+	assert.Contains(t, string(msg), `"topic":"order.created","data":"second"`)
+	_, msg, err = ws.ReadMessage()
+	assert.Nil(t, err)
+	assert.Contains(t, string(msg), `"data":"third"`)
+}
@@ -0,0 +1,266 @@
+package realtime
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/labstack/echo/v4"
+	"github.com/valeamoris/go-ezio/rest/errorx"
+	"github.com/valeamoris/go-ezio/rest/stream"
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+const (
+	// jwt中间件存放token的key
+	jwtContextKey = "user"
+	// 逗号分隔的主题模式
+	queryTopics = "topics"
+)
+
+type (
+	client struct {
+		conn     *stream.Conn
+		patterns [][]string
+		claim    string
+		lock     sync.Mutex
+		// 重放期间实时消息先缓存，重放完成后去重发送
+		replaying bool
+		pending   []*message
+		limit     int64
+		overflow  bool
+	}
+
+	// WebSocket没有event字段，主题和id放在消息里
+	envelope struct {
+		Id    string      `json:"id,omitempty"`
+		Topic string      `json:"topic"`
+		Data  interface{} `json:"data"`
+	}
+)
+
+// SSE returns the handler of Server-Sent Events, the topic is the event name.
+// The route should be in a group with rest.WithStreaming, and rest.WithJwt if Claim is set.
+func (b *Bridge) SSE() echo.HandlerFunc {
+	return b.handler(stream.SSE)
+}
+
+// WebSocket returns the handler of WebSocket, the messages are sent as json with the id,
+// topic and data. The messages from the client are ignored.
+func (b *Bridge) WebSocket() echo.HandlerFunc {
+	return b.handler(stream.WebSocket)
+}
+
+func (b *Bridge) handler(serve func(fn stream.Handler) echo.HandlerFunc) echo.HandlerFunc {
+	return func(ctx echo.Context) error {
+		// 连接建立前校验，以便返回错误的状态码
+		claim, err := b.claimOf(ctx)
+		if err != nil {
+			return err
+		}
+
+		patterns, err := parsePatterns(ctx.QueryParam(queryTopics))
+		if err != nil {
+			return err
+		}
+
+		return serve(func(_ echo.Context, conn *stream.Conn) error {
+			return b.serve(&client{
+				conn:     conn,
+				patterns: patterns,
+				claim:    claim,
+				limit:    b.conf.ReplaySize,
+			})
+		})(ctx)
+	}
+}
+
+func (b *Bridge) serve(c *client) error {
+	c.replaying = b.store != nil && validId(c.conn.LastEventId)
+	b.add(c)
+	defer b.remove(c)
+
+	if c.replaying {
+		if err := b.replay(c); err != nil {
+			return err
+		}
+	}
+
+	// WebSocket客户端的消息需要读取，否则读不到pong
+	for {
+		select {
+		case _, ok := <-c.conn.Messages():
+			if !ok {
+				return nil
+			}
+		case <-c.conn.Done():
+			return nil
+		}
+	}
+}
+
+// replay sends the messages after the last event id, then the live messages received meanwhile.
+func (b *Bridge) replay(c *client) error {
+	last := c.conn.LastEventId
+	msgs, err := b.store.XRangeN(c.conn.Context().Request().Context(), b.key, nextId(last), "+",
+		b.conf.ReplaySize).Result()
+	if err != nil {
+		logx.Errorf("realtime: replay %s from %s failed: %s", b.key, last, err.Error())
+	}
+	for _, m := range msgs {
+		msg := toMessage(m)
+		last = msg.id
+		if !b.accept(c, msg) {
+			continue
+		}
+		if err := c.conn.SendWait(c.event(msg)); err != nil {
+			return nil
+		}
+	}
+
+	for {
+		c.lock.Lock()
+		pending := c.pending
+		c.pending = nil
+		if len(pending) == 0 {
+			c.replaying = false
+			overflow := c.overflow
+			c.lock.Unlock()
+			if overflow {
+				return stream.ErrSlowConsumer
+			}
+			return nil
+		}
+		c.lock.Unlock()
+
+		for _, msg := range pending {
+			// 已经在重放中发送过
+			if !after(msg.id, last) {
+				continue
+			}
+			last = msg.id
+			if err := c.conn.SendWait(c.event(msg)); err != nil {
+				return nil
+			}
+		}
+	}
+}
+
+func (b *Bridge) claimOf(ctx echo.Context) (string, error) {
+	if len(b.conf.Claim) == 0 {
+		return "", nil
+	}
+
+	token, ok := ctx.Get(jwtContextKey).(*jwt.Token)
+	if !ok {
+		return "", errorx.ErrUnauthorized
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		// 自定义的claims按json字段取值
+		content, err := json.Marshal(token.Claims)
+		if err != nil {
+			return "", err
+		}
+		if err := json.Unmarshal(content, &claims); err != nil {
+			return "", err
+		}
+	}
+
+	value := claimValue(claims[b.conf.Claim])
+	if len(value) == 0 {
+		return "", errorx.ErrForbidden
+	}
+
+	return value, nil
+}
+
+func (c *client) deliver(msg *message) {
+	c.lock.Lock()
+	if c.replaying {
+		if int64(len(c.pending)) < c.limit {
+			c.pending = append(c.pending, msg)
+		} else {
+			c.overflow = true
+		}
+		c.lock.Unlock()
+		return
+	}
+	c.lock.Unlock()
+
+	// 缓冲满时客户端被断开，由客户端重连后重放
+	_ = c.conn.Send(c.event(msg))
+}
+
+func (c *client) event(msg *message) stream.Event {
+	if c.conn.Kind() != stream.KindWebSocket {
+		return stream.Event{Id: msg.id, Event: msg.topic, Data: msg.body}
+	}
+
+	env := envelope{Id: msg.id, Topic: msg.topic}
+	if json.Valid(msg.body) {
+		env.Data = json.RawMessage(msg.body)
+	} else {
+		env.Data = string(msg.body)
+	}
+	data, _ := json.Marshal(env)
+	return stream.Event{Id: msg.id, Data: data}
+}
+
+func claimValue(v interface{}) string {
+	switch val := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return val
+	case float64:
+		// 避免大的数字被格式化为科学计数法
+		return strconv.FormatFloat(val, 'f', -1, 64)
+	default:
+		return fmt.Sprint(val)
+	}
+}
+
+// the ids of redis stream are like 1526919030474-55.
+func parseId(id string) (ms, seq uint64, ok bool) {
+	parts := strings.SplitN(id, "-", 2)
+	if len(parts) != 2 {
+		return 0, 0, false
+	}
+
+	ms, err := strconv.ParseUint(parts[0], 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	seq, err = strconv.ParseUint(parts[1], 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+
+	return ms, seq, true
+}
+
+func validId(id string) bool {
+	_, _, ok := parseId(id)
+	return ok
+}
+
+// nextId is the smallest id after id, since the exclusive range needs redis 6.2.
+func nextId(id string) string {
+	ms, seq, _ := parseId(id)
+	if seq == ^uint64(0) {
+		return strconv.FormatUint(ms+1, 10) + "-0"
+	}
+
+	return strconv.FormatUint(ms, 10) + "-" + strconv.FormatUint(seq+1, 10)
+}
+
+func after(id, than string) bool {
+	ms, seq, _ := parseId(id)
+	thanMs, thanSeq, _ := parseId(than)
+	return ms > thanMs || (ms == thanMs && seq > thanSeq)
+}
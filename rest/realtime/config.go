@@ -0,0 +1,14 @@
+package realtime
+
+type Conf struct {
+	// 订阅的broker主题
+	Topics []string
+	// redis stream的key和共享队列名的前缀，多个bridge共用redis时需要区分
+	Name string `json:",default=realtime"`
+	// 客户端只收到消息header中该claim的值和jwt中一致的消息，为空时不过滤
+	Claim string `json:",optional"`
+	// 消息中存放claim值的header，默认和Claim相同
+	Header string `json:",optional"`
+	// redis中保留的可重放消息数，也是一次重放的最大消息数
+	ReplaySize int64 `json:",default=1000"`
+}
@@ -0,0 +1,112 @@
+package realtime
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/valeamoris/go-ezio/rest/errorx"
+)
+
+const (
+	topicSeparator = "."
+	// 匹配一段
+	wildcardOne = "*"
+	// 匹配零段或多段
+	wildcardAny = "#"
+	// 客户端通过query指定模式，限制数量和长度
+	maxPatterns      = 32
+	maxPatternLength = 256
+)
+
+// parsePatterns parses the comma separated patterns from the client, all topics are matched if empty.
+func parsePatterns(topics string) ([][]string, error) {
+	if len(topics) == 0 {
+		return [][]string{{wildcardAny}}, nil
+	}
+
+	values := strings.Split(topics, ",")
+	if len(values) > maxPatterns {
+		return nil, errorx.Newf(http.StatusBadRequest, errorx.CodeBadRequest,
+			"at most %d topic patterns are allowed", maxPatterns)
+	}
+
+	patterns := make([][]string, 0, len(values))
+	for _, value := range values {
+		pattern, ok := compilePattern(value)
+		if !ok {
+			return nil, errorx.Newf(http.StatusBadRequest, errorx.CodeBadRequest, "invalid topic pattern %q", value)
+		}
+		patterns = append(patterns, pattern)
+	}
+
+	return patterns, nil
+}
+
+// compilePattern splits the pattern into segments, the consecutive # are merged into one.
+func compilePattern(pattern string) ([]string, bool) {
+	if len(pattern) == 0 || len(pattern) > maxPatternLength {
+		return nil, false
+	}
+
+	segments := strings.Split(pattern, topicSeparator)
+	compiled := make([]string, 0, len(segments))
+	for _, segment := range segments {
+		if len(segment) == 0 {
+			return nil, false
+		}
+		// 通配符只能单独作为一段
+		if segment != wildcardOne && segment != wildcardAny && strings.ContainsAny(segment, wildcardOne+wildcardAny) {
+			return nil, false
+		}
+		if segment == wildcardAny && len(compiled) > 0 && compiled[len(compiled)-1] == wildcardAny {
+			continue
+		}
+		compiled = append(compiled, segment)
+	}
+
+	return compiled, true
+}
+
+// match reports whether the topic matches the pattern, the segments are separated by dots,
+// * matches exactly one segment and # matches zero or more, like the amqp topic exchange.
+func match(pattern, topic string) bool {
+	segments, ok := compilePattern(pattern)
+	return ok && matchSegments(segments, strings.Split(topic, topicSeparator))
+}
+
+// matchSegments matches by dynamic programming in O(len(pattern)*len(topic)),
+// matched[j] reports whether the pattern so far matches the first j segments of the topic.
+func matchSegments(pattern, topic []string) bool {
+	matched := make([]bool, len(topic)+1)
+	next := make([]bool, len(topic)+1)
+	matched[0] = true
+	for _, segment := range pattern {
+		switch segment {
+		case wildcardAny:
+			reached := false
+			for j := range matched {
+				reached = reached || matched[j]
+				next[j] = reached
+			}
+		default:
+			next[0] = false
+			for j := 1; j <= len(topic); j++ {
+				next[j] = matched[j-1] && (segment == wildcardOne || segment == topic[j-1])
+			}
+		}
+		matched, next = next, matched
+	}
+
+	return matched[len(topic)]
+}
+
+func matchAny(patterns [][]string, topic string) bool {
+	segments := strings.Split(topic, topicSeparator)
+	for _, pattern := range patterns {
+		if matchSegments(pattern, segments) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,83 @@
+package realtime
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valeamoris/go-ezio/rest/errorx"
+)
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		pattern string
+		topic   string
+		match   bool
+	}{
+		{"order.created", "order.created", true},
+		{"order.created", "order.paid", false},
+		{"order.*", "order.created", true},
+		{"order.*", "order", false},
+		{"order.*", "order.item.created", false},
+		{"order.#", "order", true},
+		{"order.#", "order.item.created", true},
+		{"#.created", "order.item.created", true},
+		{"#.created", "order.paid", false},
+		{"*.item.#", "order.item", true},
+		{"#", "order.created", true},
+		{"user.*", "order.created", false},
+		{"#.#.created", "order.created", true},
+		{"order.#.#", "order", true},
+		{"*.#.*", "order", false},
+		{"order.cre*", "order.created", false},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.match, match(test.pattern, test.topic), test.pattern+" "+test.topic)
+	}
+}
+
+func TestMatchManyWildcards(t *testing.T) {
+	// 回溯匹配时是指数级的耗时
+	start := time.Now()
+	assert.False(t, match(strings.Repeat("#.", 80)+"x", "order.created.v1.eu.shop"))
+	assert.False(t, match(strings.Repeat("#.*.", 60)+"x", "order.created.v1.eu.shop"))
+	assert.True(t, time.Since(start) < time.Second)
+}
+
+func TestParsePatterns(t *testing.T) {
+	patterns, err := parsePatterns("")
+	assert.Nil(t, err)
+	assert.Equal(t, [][]string{{"#"}}, patterns)
+
+	patterns, err = parsePatterns("order.#.#.created,user.*")
+	assert.Nil(t, err)
+	assert.Equal(t, [][]string{{"order", "#", "created"}, {"user", "*"}}, patterns)
+	assert.True(t, matchAny(patterns, "user.created"))
+	assert.False(t, matchAny(patterns, "user.item.created"))
+
+	for _, topics := range []string{
+		"order..created",
+		"order.",
+		"order,",
+		"order.#created",
+		strings.Repeat("a", maxPatternLength+1),
+		strings.Repeat("a,", maxPatterns) + "a",
+	} {
+		_, err = parsePatterns(topics)
+		assert.Equal(t, http.StatusBadRequest, errorx.From(err).Status, topics)
+	}
+}
+
+func TestId(t *testing.T) {
+	assert.True(t, validId("1526919030474-55"))
+	assert.False(t, validId("55"))
+	assert.False(t, validId("a-b"))
+	assert.Equal(t, "1526919030474-56", nextId("1526919030474-55"))
+	assert.Equal(t, "2-0", nextId("1-18446744073709551615"))
+	assert.True(t, after("2-0", "1-9"))
+	assert.True(t, after("1-10", "1-9"))
+	assert.False(t, after("1-9", "1-9"))
+}
@@ -0,0 +1,133 @@
+package resttest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/labstack/echo/v4"
+	"github.com/valeamoris/go-ezio/rest"
+)
+
+// Request builds the request fluently, the errors fail the test immediately.
+type Request struct {
+	server *Server
+	ctx    context.Context
+	method string
+	path   string
+	query  url.Values
+	header http.Header
+	body   io.Reader
+	// hmac签名的key，在Do时签名
+	hmacKeyId  string
+	hmacSecret string
+}
+
+func (r *Request) WithContext(ctx context.Context) *Request {
+	r.ctx = ctx
+	return r
+}
+
+func (r *Request) Header(key, value string) *Request {
+	r.header.Set(key, value)
+	return r
+}
+
+func (r *Request) Query(key, value string) *Request {
+	if r.query == nil {
+		r.query = make(url.Values)
+	}
+	r.query.Add(key, value)
+	return r
+}
+
+// Jwt signs the claims with HS256 and sets the bearer token.
+func (r *Request) Jwt(secret string, claims jwt.Claims) *Request {
+	r.server.t.Helper()
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(secret))
+	if err != nil {
+		r.server.t.Fatalf("resttest: sign jwt: %s", err.Error())
+	}
+
+	return r.Header(echo.HeaderAuthorization, "Bearer "+token)
+}
+
+// Hmac signs the request with rest.SignRequest when it's sent, after the body and the query are set.
+func (r *Request) Hmac(keyId, secret string) *Request {
+	r.hmacKeyId = keyId
+	r.hmacSecret = secret
+	return r
+}
+
+// JSON encodes v as the body, v is sent as is if it's a string or []byte.
+func (r *Request) JSON(v interface{}) *Request {
+	r.server.t.Helper()
+
+	switch body := v.(type) {
+	case string:
+		r.body = strings.NewReader(body)
+	case []byte:
+		r.body = bytes.NewReader(body)
+	default:
+		content, err := json.Marshal(v)
+		if err != nil {
+			r.server.t.Fatalf("resttest: encode json: %s", err.Error())
+		}
+		r.body = bytes.NewReader(content)
+	}
+
+	return r.Header(echo.HeaderContentType, echo.MIMEApplicationJSONCharsetUTF8)
+}
+
+// Form sends the values as application/x-www-form-urlencoded.
+func (r *Request) Form(values url.Values) *Request {
+	r.body = strings.NewReader(values.Encode())
+	return r.Header(echo.HeaderContentType, echo.MIMEApplicationForm)
+}
+
+func (r *Request) Body(body io.Reader) *Request {
+	r.body = body
+	return r
+}
+
+// Do serves the request through the server and records the response.
+func (r *Request) Do() *Response {
+	r.server.t.Helper()
+
+	target := r.path
+	if len(r.query) > 0 {
+		separator := "?"
+		if strings.Contains(target, "?") {
+			separator = "&"
+		}
+		target += separator + r.query.Encode()
+	}
+
+	req := httptest.NewRequest(r.method, target, r.body)
+	if r.ctx != nil {
+		req = req.WithContext(r.ctx)
+	}
+	for key, values := range r.header {
+		req.Header[key] = values
+	}
+	if len(r.hmacKeyId) > 0 {
+		if err := rest.SignRequest(req, r.hmacKeyId, r.hmacSecret); err != nil {
+			r.server.t.Fatalf("resttest: sign request: %s", err.Error())
+		}
+	}
+
+	recorder := httptest.NewRecorder()
+	r.server.ServeHTTP(recorder, req)
+
+	return &Response{
+		t:        r.server.t,
+		Recorder: recorder,
+	}
+}
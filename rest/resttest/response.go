@@ -0,0 +1,93 @@
+package resttest
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Response asserts on the recorded response, the failed assertions are reported but not fatal,
+// so that all of them in a chain are reported.
+type Response struct {
+	t        testing.TB
+	Recorder *httptest.ResponseRecorder
+}
+
+func (r *Response) Status(code int) *Response {
+	r.t.Helper()
+	assert.Equal(r.t, code, r.Recorder.Code, "status of response: %s", r.Recorder.Body.String())
+	return r
+}
+
+func (r *Response) Header(key, value string) *Response {
+	r.t.Helper()
+	assert.Equal(r.t, value, r.Recorder.Header().Get(key), "header %s", key)
+	return r
+}
+
+func (r *Response) HeaderExists(key string) *Response {
+	r.t.Helper()
+	assert.NotEmpty(r.t, r.Recorder.Header().Get(key), "header %s", key)
+	return r
+}
+
+// JSON asserts the body equals to expected in json, expected is compared as is
+// if it's a string or []byte, otherwise it's encoded first.
+func (r *Response) JSON(expected interface{}) *Response {
+	r.t.Helper()
+
+	var content string
+	switch v := expected.(type) {
+	case string:
+		content = v
+	case []byte:
+		content = string(v)
+	default:
+		encoded, err := json.Marshal(expected)
+		if err != nil {
+			r.t.Fatalf("resttest: encode json: %s", err.Error())
+		}
+		content = string(encoded)
+	}
+
+	assert.JSONEq(r.t, content, r.Recorder.Body.String())
+	return r
+}
+
+// JSONPath asserts the value of the top level field in the json body.
+func (r *Response) JSONPath(field string, expected interface{}) *Response {
+	r.t.Helper()
+
+	var body map[string]interface{}
+	if err := json.Unmarshal(r.Recorder.Body.Bytes(), &body); err != nil {
+		r.t.Fatalf("resttest: decode json: %s", err.Error())
+	}
+
+	// 通过json转换，使数字等类型和解码后的一致
+	encoded, err := json.Marshal(expected)
+	if err != nil {
+		r.t.Fatalf("resttest: encode json: %s", err.Error())
+	}
+	var value interface{}
+	if err := json.Unmarshal(encoded, &value); err != nil {
+		r.t.Fatalf("resttest: decode json: %s", err.Error())
+	}
+
+	assert.Equal(r.t, value, body[field], "json field %s", field)
+	return r
+}
+
+func (r *Response) Decode(v interface{}) *Response {
+	r.t.Helper()
+
+	if err := json.Unmarshal(r.Recorder.Body.Bytes(), v); err != nil {
+		r.t.Fatalf("resttest: decode json: %s", err.Error())
+	}
+	return r
+}
+
+func (r *Response) Body() string {
+	return r.Recorder.Body.String()
+}
@@ -0,0 +1,117 @@
+// Package resttest runs a rest.Server in-process for the tests, the requests go through
+// the real middleware chain without binding a port.
+//
+//	srv := resttest.NewServer(t, resttest.NewConf())
+//	srv.Group(rest.Group{Routes: routes}, rest.WithJwt(secret, jwt.MapClaims{}))
+//	srv.Get("/users/1").Jwt(secret, jwt.MapClaims{"sub": "1"}).Do().
+//		Status(http.StatusOK).
+//		JSON(`{"id":1}`)
+package resttest
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/mocktracer"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/valeamoris/go-ezio/rest"
+	"github.com/zeromicro/go-zero/core/conf"
+)
+
+// 只包含必填项，其余使用默认值
+const defaultConf = `{"Name": "resttest", "Port": 0}`
+
+// Server is the rest.Server with a mock tracer and a prometheus registry of its own,
+// the global tracer set by the server is restored when the test finishes.
+type Server struct {
+	*rest.Server
+	t        testing.TB
+	handler  http.Handler
+	Tracer   *mocktracer.MockTracer
+	Registry *prometheus.Registry
+}
+
+// NewConf returns the rest.Conf with the default values, like the one loaded from an empty config file.
+// The Mode is pro by default, since the other modes disable the load shedding globally.
+func NewConf() rest.Conf {
+	var c rest.Conf
+	if err := conf.LoadConfigFromJsonBytes([]byte(defaultConf), &c); err != nil {
+		panic(err)
+	}
+
+	return c
+}
+
+// NewServer creates the server, the options are applied after the mock tracer and the registry,
+// so that they can be replaced.
+func NewServer(t testing.TB, c rest.Conf, opts ...rest.RunOption) *Server {
+	t.Helper()
+
+	tracer := mocktracer.New()
+	registry := prometheus.NewRegistry()
+	globalTracer := opentracing.GlobalTracer()
+	t.Cleanup(func() {
+		opentracing.SetGlobalTracer(globalTracer)
+	})
+
+	opts = append([]rest.RunOption{
+		rest.WithTracer(tracer),
+		rest.WithPrometheus(registry, ""),
+	}, opts...)
+	server, err := rest.NewServer(c, opts...)
+	if err != nil {
+		t.Fatalf("resttest: create server: %s", err.Error())
+	}
+
+	return &Server{
+		Server:   server,
+		t:        t,
+		Tracer:   tracer,
+		Registry: registry,
+	}
+}
+
+// ServeHTTP binds the routes on the first request, the groups must be added before.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.t.Helper()
+
+	if s.handler == nil {
+		handler, err := s.Server.Handler()
+		if err != nil {
+			s.t.Fatalf("resttest: bind routes: %s", err.Error())
+		}
+		s.handler = handler
+	}
+
+	s.handler.ServeHTTP(w, r)
+}
+
+func (s *Server) Get(path string) *Request {
+	return s.NewRequest(http.MethodGet, path)
+}
+
+func (s *Server) Post(path string) *Request {
+	return s.NewRequest(http.MethodPost, path)
+}
+
+func (s *Server) Put(path string) *Request {
+	return s.NewRequest(http.MethodPut, path)
+}
+
+func (s *Server) Patch(path string) *Request {
+	return s.NewRequest(http.MethodPatch, path)
+}
+
+func (s *Server) Delete(path string) *Request {
+	return s.NewRequest(http.MethodDelete, path)
+}
+
+func (s *Server) NewRequest(method, path string) *Request {
+	return &Request{
+		server: s,
+		method: method,
+		path:   path,
+		header: make(http.Header),
+	}
+}
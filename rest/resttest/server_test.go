@@ -0,0 +1,295 @@
+package resttest
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/dgrijalva/jwt-go"
+	"github.com/opentracing/opentracing-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/valeamoris/go-ezio/core/stores/redis"
+	"github.com/valeamoris/go-ezio/rest"
+)
+
+const secret = "resttest-secret"
+
+type user struct {
+	Id   int64  `path:"id"`
+	Name string `form:"name,optional"`
+}
+
+func newUserServer(t *testing.T) *Server {
+	srv := NewServer(t, NewConf())
+	srv.Group(rest.Group{
+		Routes: []rest.Route{
+			{
+				Method: http.MethodGet,
+				Path:   "/users/:id",
+				Handler: func(ctx rest.Context) error {
+					var u user
+					if err := rest.Bind(ctx, &u); err != nil {
+						return err
+					}
+					return ctx.JSON(http.StatusOK, u)
+				},
+			},
+			{
+				Method: http.MethodGet,
+				Path:   "/slow",
+				Handler: func(ctx rest.Context) error {
+					time.Sleep(100 * time.Millisecond)
+					return ctx.NoContent(http.StatusOK)
+				},
+			},
+		},
+	}, rest.WithShedding(), rest.WithTimeout(20*time.Millisecond))
+	srv.Group(rest.Group{
+		Prefix: "/me",
+		Routes: []rest.Route{
+			{
+				Method: http.MethodGet,
+				Path:   "",
+				Handler: func(ctx rest.Context) error {
+					claims := ctx.Get("user").(*jwt.Token).Claims.(jwt.MapClaims)
+					return ctx.JSON(http.StatusOK, map[string]interface{}{"sub": claims["sub"]})
+				},
+			},
+		},
+	}, rest.WithJwt(secret, jwt.MapClaims{}))
+
+	return srv
+}
+
+func TestServer(t *testing.T) {
+	srv := newUserServer(t)
+
+	srv.Get("/users/1").Query("name", "kevin").Header("X-Request-Id", "abc").Do().
+		Status(http.StatusOK).
+		Header("X-Request-Id", "abc").
+		JSON(`{"Id":1,"Name":"kevin"}`).
+		JSONPath("Id", 1)
+	srv.Get("/users/x").Do().
+		Status(http.StatusBadRequest).
+		JSONPath("code", 40001)
+	srv.Get("/slow").Do().
+		Status(http.StatusGatewayTimeout)
+
+	srv.Get("/me").Do().
+		Status(http.StatusBadRequest)
+	var me map[string]string
+	srv.Get("/me").Jwt(secret, jwt.MapClaims{"sub": "kevin"}).Do().
+		Status(http.StatusOK).
+		Decode(&me)
+	assert.Equal(t, "kevin", me["sub"])
+
+	spans := srv.Tracer.FinishedSpans()
+	assert.Len(t, spans, 5)
+	assert.Equal(t, "HTTP GET URL: /users/:id", spans[0].OperationName)
+
+	families, err := srv.Registry.Gather()
+	assert.Nil(t, err)
+	var names []string
+	for _, family := range families {
+		names = append(names, family.GetName())
+	}
+	assert.Contains(t, names, "http_requests_total")
+}
+
+func TestServerIsolation(t *testing.T) {
+	global := opentracing.GlobalTracer()
+	t.Run("server", func(t *testing.T) {
+		srv := newUserServer(t)
+		srv.Get("/users/1").Do().Status(http.StatusOK)
+		assert.Equal(t, srv.Tracer, opentracing.GlobalTracer())
+	})
+	assert.Equal(t, global, opentracing.GlobalTracer())
+}
+
+func TestServerSecurity(t *testing.T) {
+	c := NewConf()
+	c.Security.Cors.Enabled = true
+	c.Security.Cors.AllowOrigins = []string{"https://*.example.com"}
+	srv := NewServer(t, c)
+	handler := func(ctx rest.Context) error {
+		return ctx.NoContent(http.StatusOK)
+	}
+	srv.Group(rest.Group{
+		Routes: []rest.Route{{Method: http.MethodGet, Path: "/public", Handler: handler}},
+	})
+	srv.Group(rest.Group{
+		Prefix: "/account",
+		Routes: []rest.Route{
+			{Method: http.MethodGet, Path: "", Handler: handler},
+			{Method: http.MethodPost, Path: "", Handler: handler},
+		},
+	}, rest.WithCors(rest.CorsConf{
+		Enabled:          true,
+		AllowOrigins:     []string{"https://app.example.com"},
+		AllowCredentials: true,
+	}), rest.WithCsrf())
+
+	// 默认的安全响应头
+	srv.Get("/public").Header("Origin", "https://www.example.com").Do().
+		Status(http.StatusOK).
+		Header("Access-Control-Allow-Origin", "https://www.example.com").
+		Header("X-Frame-Options", "DENY").
+		Header("X-Content-Type-Options", "nosniff").
+		Header("Referrer-Policy", "strict-origin-when-cross-origin").
+		Header("Strict-Transport-Security", "")
+	srv.Get("/public").Header("X-Forwarded-Proto", "https").Do().
+		Header("Strict-Transport-Security", "max-age=31536000")
+
+	// 分组覆盖全局的跨域配置
+	srv.NewRequest(http.MethodOptions, "/account").
+		Header("Origin", "https://www.example.com").
+		Header("Access-Control-Request-Method", http.MethodPost).Do().
+		Status(http.StatusNoContent).
+		Header("Access-Control-Allow-Origin", "")
+	srv.NewRequest(http.MethodOptions, "/account").
+		Header("Origin", "https://app.example.com").
+		Header("Access-Control-Request-Method", http.MethodPost).Do().
+		Status(http.StatusNoContent).
+		Header("Access-Control-Allow-Origin", "https://app.example.com").
+		Header("Access-Control-Allow-Credentials", "true").
+		Header("Access-Control-Max-Age", "600")
+
+	cookies := srv.Get("/account").Do().Status(http.StatusOK).Recorder.Result().Cookies()
+	assert.Len(t, cookies, 1)
+	assert.Equal(t, "_csrf", cookies[0].Name)
+	srv.Post("/account").Header("Cookie", cookies[0].String()).Do().
+		Status(http.StatusForbidden)
+	srv.Post("/account").Header("Cookie", cookies[0].String()).Header("X-CSRF-Token", cookies[0].Value).Do().
+		Status(http.StatusOK)
+}
+
+func TestServerIPFilter(t *testing.T) {
+	c := NewConf()
+	c.TrustedProxies = []string{"192.0.2.1"}
+	srv := NewServer(t, c)
+	srv.Group(rest.Group{
+		Prefix: "/admin",
+		Routes: []rest.Route{{
+			Method: http.MethodGet,
+			Path:   "/ip",
+			Handler: func(ctx rest.Context) error {
+				return ctx.String(http.StatusOK, ctx.RealIP())
+			},
+		}},
+	}, rest.WithInternalOnly())
+
+	// httptest的请求来自192.0.2.1
+	srv.Get("/admin/ip").Do().Status(http.StatusForbidden)
+	assert.Equal(t, "10.0.0.1", srv.Get("/admin/ip").Header("X-Forwarded-For", "10.0.0.1").Do().
+		Status(http.StatusOK).Body())
+
+	c.TrustedProxies = nil
+	srv = NewServer(t, c)
+	srv.Group(rest.Group{
+		Prefix: "/admin",
+		Routes: []rest.Route{{
+			Method: http.MethodGet,
+			Path:   "/ip",
+			Handler: func(ctx rest.Context) error {
+				return ctx.NoContent(http.StatusOK)
+			},
+		}},
+	}, rest.WithInternalOnly())
+	// 没有可信代理时，转发的header不可信
+	srv.Get("/admin/ip").Header("X-Forwarded-For", "10.0.0.1").Do().Status(http.StatusForbidden)
+}
+
+func TestServerBodyLimit(t *testing.T) {
+	c := NewConf()
+	c.MaxBytes = 4
+	srv := NewServer(t, c)
+	echoBody := func(ctx rest.Context) error {
+		body, err := ioutil.ReadAll(ctx.Request().Body)
+		if err != nil {
+			return err
+		}
+		return ctx.String(http.StatusOK, string(body))
+	}
+	srv.Group(rest.Group{
+		Routes: []rest.Route{{Method: http.MethodPost, Path: "/users", Handler: echoBody}},
+	})
+	srv.Group(rest.Group{
+		Prefix: "/uploads",
+		Routes: []rest.Route{{Method: http.MethodPost, Path: "", Handler: echoBody}},
+	}, rest.WithMaxBytes(8))
+
+	srv.Post("/users").Body(strings.NewReader("12345")).Do().Status(http.StatusRequestEntityTooLarge)
+	assert.Equal(t, "12345678", srv.Post("/uploads").Body(strings.NewReader("12345678")).Do().
+		Status(http.StatusOK).Body())
+	srv.Post("/uploads").Body(strings.NewReader("123456789")).Do().Status(http.StatusRequestEntityTooLarge)
+}
+
+func TestServerClientAuth(t *testing.T) {
+	s, err := miniredis.Run()
+	assert.Nil(t, err)
+	defer s.Close()
+	store, err := redis.NewRedis(s.Addr(), redis.NodeType)
+	assert.Nil(t, err)
+
+	srv := NewServer(t, NewConf(), rest.WithRedis(store))
+	whoami := []rest.Route{{
+		Method: http.MethodPost,
+		Path:   "/whoami",
+		Handler: func(ctx rest.Context) error {
+			principal, _ := rest.PrincipalFrom(ctx)
+			return ctx.String(http.StatusOK, principal.Subject)
+		},
+	}}
+	srv.Group(rest.Group{Prefix: "/keys", Routes: whoami},
+		rest.WithApiKey(rest.StaticApiKeys{"secret-key": {Subject: "service"}}, rest.ApiKeyOptions{}))
+	srv.Group(rest.Group{Prefix: "/signed", Routes: whoami},
+		rest.WithHmac(rest.StaticHmacKeys{"partner": {Secret: "hmac-secret", Principal: rest.Principal{Subject: "partner"}}},
+			rest.HmacOptions{}))
+
+	srv.Post("/keys/whoami").Do().Status(http.StatusUnauthorized)
+	assert.Equal(t, "service", srv.Post("/keys/whoami").Header("X-Api-Key", "secret-key").Do().
+		Status(http.StatusOK).Body())
+
+	srv.Post("/signed/whoami").JSON(`{"amount":1}`).Do().Status(http.StatusUnauthorized)
+	assert.Equal(t, "partner", srv.Post("/signed/whoami").JSON(`{"amount":1}`).Hmac("partner", "hmac-secret").Do().
+		Status(http.StatusOK).Body())
+	srv.Post("/signed/whoami").Hmac("partner", "wrong-secret").Do().Status(http.StatusUnauthorized)
+}
+
+func TestServerAuthz(t *testing.T) {
+	srv := NewServer(t, NewConf())
+	ok := func(ctx rest.Context) error {
+		return ctx.NoContent(http.StatusOK)
+	}
+	srv.Group(rest.Group{
+		Prefix: "/articles",
+		Routes: []rest.Route{
+			{Method: http.MethodGet, Path: "/:id", Handler: ok},
+			{Method: http.MethodDelete, Path: "/:id", Handler: ok, Permissions: []string{"articles:delete"}},
+		},
+	}, rest.WithJwt(secret, jwt.MapClaims{}), rest.WithAuthz(rest.NewRbacPolicy(rest.RbacRules{
+		Permissions: map[string][]string{"admin": {"*"}},
+	}), rest.AuthzOptions{Requirement: rest.Requirement{Roles: []string{"admin", "staff"}}}))
+	srv.Group(rest.Group{
+		Prefix: "/reports",
+		Routes: []rest.Route{{Method: http.MethodGet, Path: "", Handler: ok, Roles: []string{"finance"}}},
+	}, rest.WithApiKey(rest.StaticApiKeys{
+		"finance-key": {Subject: "finance", Roles: []string{"finance"}},
+		"other-key":   {Subject: "other"},
+	}, rest.ApiKeyOptions{}))
+
+	staff := jwt.MapClaims{"sub": "1", "roles": []string{"staff"}}
+	srv.Get("/articles/1").Jwt(secret, staff).Do().Status(http.StatusOK)
+	srv.Delete("/articles/1").Jwt(secret, staff).Do().Status(http.StatusForbidden).
+		JSONPath("code", float64(40300))
+	srv.Delete("/articles/1").Jwt(secret, jwt.MapClaims{"sub": "2", "roles": []string{"admin"}}).Do().
+		Status(http.StatusOK)
+	srv.Get("/articles/1").Jwt(secret, jwt.MapClaims{"sub": "3"}).Do().Status(http.StatusForbidden)
+
+	// 没有WithAuthz时按路由的要求校验
+	srv.Get("/reports").Header("X-Api-Key", "finance-key").Do().Status(http.StatusOK)
+	srv.Get("/reports").Header("X-Api-Key", "other-key").Do().Status(http.StatusForbidden)
+}
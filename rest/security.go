@@ -0,0 +1,105 @@
+package rest
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/valeamoris/go-ezio/rest/middleware"
+)
+
+const (
+	defaultHSTSMaxAge     = 365 * 24 * 3600
+	defaultFrameOptions   = "DENY"
+	defaultReferrerPolicy = "strict-origin-when-cross-origin"
+	defaultCorsMaxAge     = 600
+)
+
+// bindSecurity applies the security headers and cors of Conf.Security, and the cors overrides of the groups.
+func (s *engine) bindSecurity() error {
+	headers := s.conf.Security.Headers
+	if !headers.Disabled {
+		// 没有配置Security时，嵌套的默认值不会生效
+		if headers.HSTSMaxAge == 0 {
+			headers.HSTSMaxAge = defaultHSTSMaxAge
+		}
+		if len(headers.FrameOptions) == 0 {
+			headers.FrameOptions = defaultFrameOptions
+		}
+		if len(headers.ReferrerPolicy) == 0 {
+			headers.ReferrerPolicy = defaultReferrerPolicy
+		}
+		s.Echo.Use(middleware.SecurityHeadersMiddleware(middleware.SecurityHeadersOptions{
+			HSTSMaxAge:            headers.HSTSMaxAge,
+			HSTSIncludeSubdomains: headers.HSTSIncludeSubdomains,
+			HSTSPreload:           headers.HSTSPreload,
+			ContentSecurityPolicy: headers.ContentSecurityPolicy,
+			FrameOptions:          headers.FrameOptions,
+			ReferrerPolicy:        headers.ReferrerPolicy,
+			ContentTypeNosniff:    true,
+		}))
+	}
+
+	var global *middleware.CorsOptions
+	if s.conf.Security.Cors.Enabled {
+		global = corsOptions(s.conf.Security.Cors)
+	}
+	groups := make(map[string]middleware.CorsOptions)
+	for _, g := range s.groups {
+		if g.cors == nil {
+			continue
+		}
+		if g.cors.Enabled {
+			groups[g.Prefix] = *corsOptions(*g.cors)
+		} else {
+			// 没有允许的Origin，即不允许跨域
+			groups[g.Prefix] = middleware.CorsOptions{}
+		}
+	}
+	if global == nil && len(groups) == 0 {
+		return nil
+	}
+
+	cors, err := middleware.CorsMiddleware(global, groups)
+	if err != nil {
+		return err
+	}
+	s.Echo.Use(cors)
+	return nil
+}
+
+func corsOptions(c CorsConf) *middleware.CorsOptions {
+	if c.MaxAge == 0 {
+		c.MaxAge = defaultCorsMaxAge
+	}
+
+	return &middleware.CorsOptions{
+		AllowOrigins:     c.AllowOrigins,
+		AllowMethods:     c.AllowMethods,
+		AllowHeaders:     c.AllowHeaders,
+		ExposeHeaders:    c.ExposeHeaders,
+		AllowCredentials: c.AllowCredentials,
+		MaxAge:           c.MaxAge,
+	}
+}
+
+func (s *engine) csrfOptions() middleware.CsrfOptions {
+	c := s.conf.Security.Csrf
+	opts := middleware.CsrfOptions{
+		CookieName:   c.CookieName,
+		CookieDomain: c.CookieDomain,
+		CookiePath:   c.CookiePath,
+		CookieSecure: c.CookieSecure,
+		MaxAge:       c.MaxAge,
+		HeaderName:   c.HeaderName,
+	}
+	switch strings.ToLower(c.SameSite) {
+	case "strict":
+		opts.CookieSameSite = http.SameSiteStrictMode
+	case "none":
+		opts.CookieSameSite = http.SameSiteNoneMode
+	default:
+		opts.CookieSameSite = http.SameSiteLaxMode
+	}
+
+	return opts
+}
@@ -2,14 +2,26 @@ package rest
 
 import (
 	"context"
+	"errors"
 	"github.com/dgrijalva/jwt-go"
 	"github.com/labstack/echo/v4"
+	"github.com/opentracing/opentracing-go"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/valeamoris/go-ezio/core/limit"
+	"github.com/valeamoris/go-ezio/core/stores/redis"
+	"github.com/valeamoris/go-ezio/rest/health"
+	"github.com/valeamoris/go-ezio/rest/middleware"
+	"github.com/valeamoris/go-ezio/rest/openapi"
 	"github.com/zeromicro/go-zero/core/breaker"
 	"github.com/zeromicro/go-zero/core/logx"
+	"gorm.io/gorm"
 	"log"
 	"net/http"
+	"time"
 )
 
+var errRedisRequired = errors.New("redis is required, set it with rest.WithRedis")
+
 type (
 	runOptions struct {
 		start func(*engine) error
@@ -53,6 +65,8 @@ func NewServer(c Conf, opts ...RunOption) (*Server, error) {
 	for _, opt := range opts {
 		opt(server)
 	}
+	// 依赖选项中的prometheus配置
+	server.engine.streams = server.engine.newStreamHub()
 
 	return server, nil
 }
@@ -76,6 +90,37 @@ func WithErrorHandler(errHandler func(err error, ctx Context)) RunOption {
 	}
 }
 
+// 自定义prometheus的registry和namespace，registry为nil时使用默认的registry，
+// 管理端口的/metrics同样使用该registry
+func WithPrometheus(registry *prometheus.Registry, namespace string, buckets ...float64) RunOption {
+	return func(srv *Server) {
+		opts := []middleware.PrometheusOption{middleware.WithNamespace(namespace)}
+		srv.engine.namespace = namespace
+		if registry != nil {
+			srv.engine.gatherer = registry
+			opts = append(opts, middleware.WithRegisterer(registry))
+		}
+		if len(buckets) > 0 {
+			opts = append(opts, middleware.WithBuckets(buckets...))
+		}
+		srv.engine.prometheusOpts = opts
+	}
+}
+
+// 注入tracer，替代根据TraceConf创建的jaeger tracer
+func WithTracer(tracer opentracing.Tracer) RunOption {
+	return func(srv *Server) {
+		srv.engine.tracer = tracer
+	}
+}
+
+// 设置redis，限流等需要共享状态的功能依赖它，未设置时退化为单机
+func WithRedis(store redis.Node) RunOption {
+	return func(srv *Server) {
+		srv.engine.redis = store
+	}
+}
+
 func WithBreakerRejectHandler(rejectHandler func(promise breaker.Promise, err error)) RunOption {
 	return func(srv *Server) {
 		srv.engine.rejectHandler = rejectHandler
@@ -90,6 +135,16 @@ func (e *Server) Start() {
 	handlerError(e.opts.start(e.engine))
 }
 
+// Handler binds the routes and returns the server as http.Handler without listening,
+// to serve it in-process, like resttest. The routes are bound only once, so add the groups before.
+func (e *Server) Handler() (http.Handler, error) {
+	if err := e.engine.bind(); err != nil {
+		return nil, err
+	}
+
+	return e.engine.Echo, nil
+}
+
 // graceful shutdown
 func (e *Server) Shutdown(ctx context.Context) error {
 	return e.engine.Shutdown(ctx)
@@ -107,6 +162,25 @@ func (e *Server) Group(g Group, opts ...RouteOption) {
 	e.engine.AddGroup(g)
 }
 
+// 注册健康检查，默认只参与readiness
+func (e *Server) AddHealthCheck(name string, checker health.Checker, opts ...health.CheckOption) {
+	e.engine.health.Register(name, checker, opts...)
+}
+
+// 按标签失效WithCache缓存的响应
+func (e *Server) InvalidateCache(ctx context.Context, tags ...string) error {
+	if e.engine.redis == nil {
+		return errRedisRequired
+	}
+
+	return middleware.InvalidateCacheTags(ctx, e.engine.redis, tags...)
+}
+
+// OpenApi generates the openapi document of the groups.
+func (e *Server) OpenApi() (*openapi.Document, error) {
+	return e.engine.openApi()
+}
+
 func (e *Server) Use(middlewares ...Middleware) {
 	for _, m := range middlewares {
 		e.engine.Use(func(next echo.HandlerFunc) echo.HandlerFunc {
@@ -134,6 +208,36 @@ func WithJwt(secret string, claims jwt.Claims) RouteOption {
 	}
 }
 
+// 校验header或query中的api key，作为JWT之外的认证方式，认证后的Principal和JWT的claims一样
+// 以*jwt.Token放在context的user中
+func WithApiKey(store ApiKeyStore, opts ApiKeyOptions) RouteOption {
+	return func(r *Group) {
+		r.apiKey.enabled = true
+		r.apiKey.store = store
+		r.apiKey.opts = opts
+	}
+}
+
+// 校验SignRequest签名的请求，nonce保存在redis中防止重放，需要WithRedis，
+// 认证后的Principal和WithApiKey一样放在context中
+func WithHmac(keys HmacKeyStore, opts HmacOptions) RouteOption {
+	return func(r *Group) {
+		r.hmac.enabled = true
+		r.hmac.keys = keys
+		r.hmac.opts = opts
+	}
+}
+
+// 在认证之后按policy授权，opts.Requirement作用于分组内所有路由，路由的Roles和Permissions额外生效，
+// 拒绝的请求返回403并记录审计日志，policy为nil时只使用token中的角色和scope
+func WithAuthz(policy Policy, opts AuthzOptions) RouteOption {
+	return func(r *Group) {
+		r.authz.enabled = true
+		r.authz.policy = policy
+		r.authz.opts = opts
+	}
+}
+
 func validateSecret(secret string) {
 	if len(secret) < 8 {
 		panic("secret's length can't be less than 8")
@@ -164,6 +268,77 @@ func WithTimeoutDisabled() RouteOption {
 	}
 }
 
+// 分组内是SSE或WebSocket的长连接，使用stream.SSE和stream.WebSocket作为handler，
+// 不使用超时中间件，不能和缓存、幂等同时使用
+func WithStreaming() RouteOption {
+	return func(r *Group) {
+		r.streaming = true
+	}
+}
+
+// 覆盖全局的跨域配置，Enabled为false时分组不允许跨域
+func WithCors(c CorsConf) RouteOption {
+	return func(r *Group) {
+		r.cors = &c
+	}
+}
+
+// 对使用cookie认证的分组开启CSRF保护，非安全方法需要在header中带上cookie中的token，
+// 配置在Conf.Security.Csrf中
+func WithCsrf() RouteOption {
+	return func(r *Group) {
+		r.csrf = true
+	}
+}
+
+// 只允许filter允许的客户端地址访问，客户端地址按Conf.TrustedProxies获取
+func WithIPFilter(filter *IPFilter) RouteOption {
+	return func(r *Group) {
+		r.ipFilter = filter
+	}
+}
+
+// 只允许回环地址和私有网络访问，如管理接口
+func WithInternalOnly() RouteOption {
+	filter, err := NewIPFilter(IPRules{Allow: []string{middleware.Internal}})
+	if err != nil {
+		panic(err)
+	}
+
+	return WithIPFilter(filter)
+}
+
+// 覆盖全局的Conf.MaxBytes，可以大于它，如上传文件的接口，gzip的请求解压后同样受此限制
+func WithMaxBytes(n int64) RouteOption {
+	return func(r *Group) {
+		r.maxBytes = n
+	}
+}
+
+// 覆盖全局的超时时间
+func WithTimeout(timeout time.Duration) RouteOption {
+	return func(r *Group) {
+		r.timeout = timeout
+	}
+}
+
+// 路由的openapi文档，path为分组内的路径
+func WithDoc(method, path string, doc RouteDoc) RouteOption {
+	return func(r *Group) {
+		if r.docs == nil {
+			r.docs = make(map[string]RouteDoc)
+		}
+		r.docs[docKey(method, path)] = doc
+	}
+}
+
+// 分组内所有路由在openapi文档中的标签
+func WithTags(tags ...string) RouteOption {
+	return func(r *Group) {
+		r.tags = append(r.tags, tags...)
+	}
+}
+
 func WithStatic(prefix, root string) RouteOption {
 	return func(r *Group) {
 		r.static.enabled = true
@@ -171,3 +346,91 @@ func WithStatic(prefix, root string) RouteOption {
 		r.static.root = root
 	}
 }
+
+// 令牌桶限流，每秒补充rate个令牌，最多允许burst个突发请求，rate必须大于0，否则启动时返回错误
+func WithRateLimit(keyFunc RateLimitKeyFunc, rate, burst int) RouteOption {
+	return func(r *Group) {
+		r.rateLimit.enabled = true
+		r.rateLimit.keyFunc = keyFunc
+		r.rateLimit.rate = rate
+		r.rateLimit.burst = burst
+	}
+}
+
+// 使用自定义的限流器，如固定窗口或滑动窗口
+func WithRateLimiter(keyFunc RateLimitKeyFunc, limiter limit.Limiter) RouteOption {
+	return func(r *Group) {
+		r.rateLimit.enabled = true
+		r.rateLimit.keyFunc = keyFunc
+		r.rateLimit.limiter = limiter
+	}
+}
+
+// 按Idempotency-Key重放非幂等请求的响应，key按认证的主体、方法和路由区分，ttl为响应保存时间，需要WithRedis
+func WithIdempotency(ttl time.Duration) RouteOption {
+	return func(r *Group) {
+		r.idempotency.enabled = true
+		r.idempotency.ttl = ttl
+	}
+}
+
+// 缓存GET请求的响应，需要WithRedis，认证的请求按主体分别缓存
+func WithCache(opts CacheOptions) RouteOption {
+	return func(r *Group) {
+		r.cache.enabled = true
+		r.cache.opts = opts
+	}
+}
+
+var (
+	RateLimitByIP         RateLimitKeyFunc = middleware.RateLimitByIP
+	RateLimitByJwtSubject RateLimitKeyFunc = middleware.RateLimitByJwtSubject
+)
+
+func NewIPFilter(rules IPRules) (*IPFilter, error) {
+	return middleware.NewIPFilter(rules)
+}
+
+// 从json或yaml文件加载规则，文件修改后按interval检查并重新加载
+func WatchIPFilter(file string, interval time.Duration) (*IPFilter, error) {
+	return middleware.WatchIPFilter(file, interval)
+}
+
+func RateLimitByApiKey(header string) RateLimitKeyFunc {
+	return middleware.RateLimitByApiKey(header)
+}
+
+// 保存在redis中的api key，prefix默认apikey:
+func NewRedisApiKeyStore(store redis.Node, prefix string) *middleware.RedisApiKeyStore {
+	return middleware.NewRedisApiKeyStore(store, prefix)
+}
+
+// 在进程内缓存store的结果ttl时间，删除的key最多ttl后失效
+func NewCachedApiKeyStore(store ApiKeyStore, ttl time.Duration) (ApiKeyStore, error) {
+	return middleware.NewCachedApiKeyStore(store, ttl)
+}
+
+// WithApiKey或WithHmac认证的Principal
+func PrincipalFrom(ctx Context) (*Principal, bool) {
+	return middleware.PrincipalFrom(ctx)
+}
+
+// 为WithHmac的分组签名请求，需要在设置body之后调用
+func SignRequest(req *http.Request, keyId, secret string) error {
+	return middleware.SignRequest(req, keyId, secret)
+}
+
+// 固定规则的RBAC策略
+func NewRbacPolicy(rules RbacRules) *RbacPolicy {
+	return middleware.NewRbacPolicy(rules)
+}
+
+// 从casbin风格的规则文件加载RBAC策略，文件修改后按interval检查并重新加载
+func WatchRbacPolicy(file string, interval time.Duration) (*RbacPolicy, error) {
+	return middleware.WatchRbacPolicy(file, interval)
+}
+
+// 从role_permissions和subject_roles表加载RBAC策略，按interval重新加载
+func NewGormRbacPolicy(db *gorm.DB, interval time.Duration) (*RbacPolicy, error) {
+	return middleware.NewGormRbacPolicy(db, interval)
+}
@@ -0,0 +1,227 @@
+package rest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/valeamoris/go-ezio/core/limit"
+	"github.com/zeromicro/go-zero/core/conf"
+)
+
+func newTestConf(t *testing.T, adminPort int) Conf {
+	var c Conf
+	content := fmt.Sprintf(`{"Name": "rest", "Host": "127.0.0.1", "Port": %d, "Admin": {"Port": %d}}`,
+		freePort(t), adminPort)
+	assert.Nil(t, conf.LoadConfigFromJsonBytes([]byte(content), &c))
+	return c
+}
+
+func freePort(t *testing.T) int {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer listener.Close()
+	return listener.Addr().(*net.TCPAddr).Port
+}
+
+func newTestServer(t *testing.T, c Conf) *Server {
+	srv, err := NewServer(c, WithPrometheus(prometheus.NewRegistry(), ""))
+	assert.Nil(t, err)
+	srv.Group(Group{Routes: []Route{{
+		Method: http.MethodGet,
+		Path:   "/ping",
+		Handler: func(ctx Context) error {
+			return ctx.String(http.StatusOK, "pong")
+		},
+	}}})
+	return srv
+}
+
+// 在-race下运行，Start和Shutdown在不同的goroutine
+func TestServerStartShutdown(t *testing.T) {
+	adminPort := freePort(t)
+	c := newTestConf(t, adminPort)
+	srv := newTestServer(t, c)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		srv.Start()
+	}()
+
+	assert.Eventually(t, func() bool {
+		resp, err := http.Get(fmt.Sprintf("http://127.0.0.1:%d/ping", c.Port))
+		if err != nil {
+			return false
+		}
+		resp.Body.Close()
+		return resp.StatusCode == http.StatusOK
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Eventually(t, func() bool {
+		resp, err := http.Get(fmt.Sprintf("http://127.0.0.1:%d/stats", adminPort))
+		if err != nil {
+			return false
+		}
+		resp.Body.Close()
+		return resp.StatusCode == http.StatusOK
+	}, 5*time.Second, 10*time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	assert.Nil(t, srv.Shutdown(ctx))
+	<-done
+	_, err := http.Get(fmt.Sprintf("http://127.0.0.1:%d/stats", adminPort))
+	assert.NotNil(t, err)
+}
+
+func TestServerShutdownWhileStarting(t *testing.T) {
+	srv := newTestServer(t, newTestConf(t, freePort(t)))
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		srv.Start()
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	assert.Nil(t, srv.Shutdown(ctx))
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("server isn't stopped")
+	}
+}
+
+func TestServerInvalidRateLimit(t *testing.T) {
+	srv := newTestServer(t, newTestConf(t, 0))
+	srv.Group(Group{Prefix: "/limited", Routes: []Route{{
+		Method: http.MethodGet,
+		Path:   "/",
+		Handler: func(ctx Context) error {
+			return nil
+		},
+	}}}, WithRateLimit(RateLimitByIP, 0, 0))
+	assert.True(t, errors.Is(srv.engine.bind(), limit.ErrInvalidTokenRate))
+}
+
+func TestServerCountMaxConnsDropped(t *testing.T) {
+	c := newTestConf(t, 0)
+	c.MaxConns = 1
+	registry := prometheus.NewRegistry()
+	srv, err := NewServer(c, WithPrometheus(registry, "test"))
+	assert.Nil(t, err)
+	started := make(chan struct{})
+	release := make(chan struct{})
+	srv.Group(Group{Routes: []Route{{
+		Method: http.MethodGet,
+		Path:   "/slow",
+		Handler: func(ctx Context) error {
+			close(started)
+			<-release
+			return ctx.NoContent(http.StatusOK)
+		},
+	}}})
+	assert.Nil(t, srv.engine.bind())
+
+	serve := func() int {
+		resp := httptest.NewRecorder()
+		srv.engine.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/slow", nil))
+		return resp.Code
+	}
+	done := make(chan int)
+	go func() {
+		done <- serve()
+	}()
+	<-started
+	assert.Equal(t, http.StatusServiceUnavailable, serve())
+	close(release)
+	assert.Equal(t, http.StatusOK, <-done)
+
+	expected := `
+# HELP test_http_requests_dropped_total How many HTTP requests are dropped by shedding, timeout and so on, partitioned by reason.
+# TYPE test_http_requests_dropped_total counter
+test_http_requests_dropped_total{reason="maxconns",route="/slow"} 1
+`
+	assert.Nil(t, testutil.GatherAndCompare(registry, strings.NewReader(expected), "test_http_requests_dropped_total"))
+}
+
+func TestServerShutdownDrainDelay(t *testing.T) {
+	c := newTestConf(t, 0)
+	c.Health.DrainDelay = 300
+	srv := newTestServer(t, c)
+	go srv.Start()
+
+	get := func(path string) int {
+		resp, err := http.Get(fmt.Sprintf("http://127.0.0.1:%d%s", c.Port, path))
+		if err != nil {
+			return 0
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	assert.Eventually(t, func() bool {
+		return get("/ping") == http.StatusOK
+	}, 5*time.Second, 10*time.Millisecond)
+
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		done <- srv.Shutdown(context.Background())
+	}()
+	// 等待摘除期间readiness失败，请求仍然正常处理
+	assert.Eventually(t, func() bool {
+		return get("/readyz") == http.StatusServiceUnavailable
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, http.StatusOK, get("/ping"))
+
+	assert.Nil(t, <-done)
+	assert.True(t, time.Since(start) >= 300*time.Millisecond)
+	assert.Equal(t, 0, get("/ping"))
+}
+
+func TestServerApiKeyParams(t *testing.T) {
+	srv := newTestServer(t, newTestConf(t, 0))
+	srv.Group(Group{Prefix: "/a"}, WithApiKey(StaticApiKeys{}, ApiKeyOptions{Header: "X-Key", Query: "key"}))
+	srv.Group(Group{Prefix: "/b"}, WithApiKey(StaticApiKeys{}, ApiKeyOptions{}))
+
+	// 访问日志中脱敏api key所在的header和query参数
+	headers, queries := srv.engine.apiKeyParams()
+	assert.Equal(t, []string{"X-Key", "X-Api-Key"}, headers)
+	assert.Equal(t, []string{"key"}, queries)
+}
+
+func TestServerDefaultTrace(t *testing.T) {
+	// 没有配置Trace时仍然启用追踪
+	srv := newTestServer(t, newTestConf(t, 0))
+	conf := srv.engine.traceConf()
+	assert.False(t, conf.Disabled)
+	assert.Equal(t, "jaeger", conf.Backend)
+	assert.Equal(t, "const", conf.Sampler)
+	assert.Equal(t, float64(1), conf.SamplerRate)
+	assert.NotNil(t, srv.engine.getTracer())
+	assert.Nil(t, srv.engine.Close())
+
+	c := newTestConf(t, 0)
+	c.Trace.Disabled = true
+	assert.Nil(t, newTestServer(t, c).engine.getTracer())
+}
+
+func TestServerStreamHeartbeat(t *testing.T) {
+	// 没有配置Stream时使用stream.Options的默认心跳
+	srv := newTestServer(t, newTestConf(t, 0))
+	assert.Equal(t, time.Duration(0), srv.engine.streamOptions().Heartbeat)
+
+	c := newTestConf(t, 0)
+	c.Stream.Heartbeat = -1
+	assert.True(t, newTestServer(t, c).engine.streamOptions().Heartbeat < 0)
+	c.Stream.Heartbeat = 5000
+	assert.Equal(t, 5*time.Second, newTestServer(t, c).engine.streamOptions().Heartbeat)
+}
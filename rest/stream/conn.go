@@ -0,0 +1,211 @@
+package stream
+
+import (
+	"encoding/json"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+type (
+	// Handler serves the connection until conn.Done() is closed, the connection is closed
+	// after it returns, the buffered events are still sent if it returns nil.
+	// The ctx must not be used to write the response, use conn.Send instead.
+	Handler func(ctx echo.Context, conn *Conn) error
+
+	Event struct {
+		Id string
+		// SSE的event字段，WebSocket忽略
+		Event string
+		Data  []byte
+		// SSE客户端的重连间隔，WebSocket忽略
+		Retry time.Duration
+	}
+
+	Conn struct {
+		kind string
+		hub  *Hub
+		ctx  echo.Context
+		send chan Event
+		done chan struct{}
+		once sync.Once
+		err  error
+		// WebSocket收到的消息
+		messages chan []byte
+		// 断线重连时客户端带上的最后一个事件id
+		LastEventId string
+	}
+)
+
+func (h *Hub) newConn(kind string, ctx echo.Context) *Conn {
+	return &Conn{
+		kind: kind,
+		hub:  h,
+		ctx:  ctx,
+		send: make(chan Event, h.opts.SendBuffer),
+		done: make(chan struct{}),
+	}
+}
+
+func (c *Conn) Kind() string {
+	return c.kind
+}
+
+// Context returns the echo context of the request, like the claims set by the jwt middleware.
+func (c *Conn) Context() echo.Context {
+	return c.ctx
+}
+
+// Send queues the event without blocking, the connection is closed with ErrSlowConsumer
+// if the send buffer is full.
+func (c *Conn) Send(e Event) error {
+	select {
+	case <-c.done:
+		return ErrClosed
+	default:
+	}
+
+	select {
+	case c.send <- e:
+		return nil
+	case <-c.done:
+		return ErrClosed
+	default:
+		c.hub.evicted.WithLabelValues(c.kind).Inc()
+		c.close(ErrSlowConsumer)
+		return ErrSlowConsumer
+	}
+}
+
+// SendWait queues the event, blocking while the send buffer is full, for the events paced
+// by the sender, like replaying the history.
+func (c *Conn) SendWait(e Event) error {
+	select {
+	case <-c.done:
+		return ErrClosed
+	default:
+	}
+
+	select {
+	case c.send <- e:
+		return nil
+	case <-c.done:
+		return ErrClosed
+	}
+}
+
+// SendJSON queues v encoded in json as the data of the event.
+func (c *Conn) SendJSON(id, event string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	return c.Send(Event{Id: id, Event: event, Data: data})
+}
+
+// Messages returns the messages received from the WebSocket client, it's closed when
+// the connection is closed, and nil for SSE. The reading blocks if they are not consumed.
+func (c *Conn) Messages() <-chan []byte {
+	return c.messages
+}
+
+func (c *Conn) Done() <-chan struct{} {
+	return c.done
+}
+
+// Err returns why the connection is closed, nil if it's closed normally or still open.
+func (c *Conn) Err() error {
+	select {
+	case <-c.done:
+		return c.err
+	default:
+		return nil
+	}
+}
+
+// Close closes the connection after the buffered events are sent.
+func (c *Conn) Close() {
+	c.close(nil)
+}
+
+func (c *Conn) close(err error) {
+	c.once.Do(func() {
+		c.err = err
+		close(c.done)
+	})
+}
+
+// graceful means the buffered events should be sent before closing.
+func (c *Conn) graceful() bool {
+	return c.err == nil || c.err == ErrShutdown
+}
+
+// run writes the events queued by fn until the connection is closed, then calls finish if not nil,
+// and waits for fn to return.
+func (h *Hub) run(conn *Conn, fn Handler, closed <-chan struct{}, write func(e Event) error, ping func() error,
+	finish func()) error {
+	result := make(chan error, 1)
+	go func() {
+		defer func() {
+			if p := recover(); p != nil {
+				result <- fmt.Errorf("stream: panic: %v\n%s", p, debug.Stack())
+			}
+		}()
+		result <- fn(conn.ctx, conn)
+	}()
+
+	var heartbeat <-chan time.Time
+	if h.opts.Heartbeat > 0 {
+		ticker := time.NewTicker(h.opts.Heartbeat)
+		defer ticker.Stop()
+		heartbeat = ticker.C
+	}
+
+	var err error
+	returned := false
+	for {
+		select {
+		case e := <-conn.send:
+			if werr := write(e); werr != nil {
+				conn.close(werr)
+			}
+		case <-heartbeat:
+			if werr := ping(); werr != nil {
+				conn.close(werr)
+			}
+		case <-closed:
+			conn.close(ErrClosed)
+		case err = <-result:
+			returned = true
+			conn.close(err)
+		case <-conn.done:
+			if conn.graceful() {
+				drain(conn, write)
+			}
+			if finish != nil {
+				finish()
+			}
+			if !returned {
+				err = <-result
+			}
+			return err
+		}
+	}
+}
+
+func drain(conn *Conn, write func(e Event) error) {
+	for {
+		select {
+		case e := <-conn.send:
+			if err := write(e); err != nil {
+				return
+			}
+		default:
+			return
+		}
+	}
+}
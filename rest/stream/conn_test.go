@@ -0,0 +1,108 @@
+package stream
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestHub(opts Options) *Hub {
+	opts.Registerer = prometheus.NewRegistry()
+	return NewHub(opts)
+}
+
+func TestConnSend(t *testing.T) {
+	hub := newTestHub(Options{SendBuffer: 1})
+	conn := hub.newConn(KindSSE, nil)
+	assert.True(t, hub.add(conn))
+	assert.Equal(t, 1, hub.Len())
+	assert.Equal(t, float64(1), testutil.ToFloat64(hub.gauge.WithLabelValues(KindSSE)))
+
+	assert.Nil(t, conn.Send(Event{Data: []byte("a")}))
+	assert.Nil(t, conn.Err())
+	assert.Equal(t, ErrSlowConsumer, conn.SendJSON("1", "", "b"))
+	assert.Equal(t, ErrSlowConsumer, conn.Err())
+	assert.Equal(t, ErrClosed, conn.Send(Event{}))
+	assert.Equal(t, float64(1), testutil.ToFloat64(hub.evicted.WithLabelValues(KindSSE)))
+	<-conn.Done()
+
+	hub.remove(conn)
+	hub.remove(conn)
+	assert.Equal(t, 0, hub.Len())
+	assert.Equal(t, float64(0), testutil.ToFloat64(hub.gauge.WithLabelValues(KindSSE)))
+}
+
+func TestConnSendWait(t *testing.T) {
+	hub := newTestHub(Options{SendBuffer: 1, Heartbeat: -1})
+	conn := hub.newConn(KindSSE, nil)
+
+	// 缓冲满时等待写出，而不是断开
+	var written []string
+	err := hub.run(conn, func(_ echo.Context, conn *Conn) error {
+		for _, data := range []string{"a", "b", "c"} {
+			if err := conn.SendWait(Event{Data: []byte(data)}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}, nil, func(e Event) error {
+		written = append(written, string(e.Data))
+		return nil
+	}, nil, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"a", "b", "c"}, written)
+	assert.Equal(t, ErrClosed, conn.SendWait(Event{}))
+}
+
+func TestHubClose(t *testing.T) {
+	hub := newTestHub(Options{})
+	conn := hub.newConn(KindWebSocket, nil)
+	assert.True(t, hub.add(conn))
+	assert.Len(t, hub.Conns(), 1)
+
+	hub.Close()
+	<-conn.Done()
+	assert.Equal(t, ErrShutdown, conn.Err())
+	assert.True(t, conn.graceful())
+	assert.False(t, hub.add(hub.newConn(KindSSE, nil)))
+}
+
+func TestRun(t *testing.T) {
+	hub := newTestHub(Options{Heartbeat: -1})
+	conn := hub.newConn(KindSSE, nil)
+
+	// handler返回nil时，缓冲的消息仍然会发送
+	var written []string
+	err := hub.run(conn, func(_ echo.Context, conn *Conn) error {
+		assert.Nil(t, conn.Send(Event{Data: []byte("a")}))
+		assert.Nil(t, conn.Send(Event{Data: []byte("b")}))
+		return nil
+	}, nil, func(e Event) error {
+		written = append(written, string(e.Data))
+		return nil
+	}, nil, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"a", "b"}, written)
+
+	conn = hub.newConn(KindSSE, nil)
+	err = hub.run(conn, func(_ echo.Context, conn *Conn) error {
+		panic("boom")
+	}, nil, nil, nil, nil)
+	assert.Contains(t, err.Error(), "stream: panic: boom")
+
+	conn = hub.newConn(KindSSE, nil)
+	failed := errors.New("failed")
+	err = hub.run(conn, func(_ echo.Context, conn *Conn) error {
+		_ = conn.Send(Event{})
+		<-conn.Done()
+		return nil
+	}, nil, func(e Event) error {
+		return failed
+	}, nil, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, failed, conn.Err())
+}
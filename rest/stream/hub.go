@@ -0,0 +1,177 @@
+// Package stream serves the long-lived connections, Server-Sent Events and WebSocket,
+// each connection has a bounded send buffer and is evicted if the client can't keep up.
+package stream
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/valeamoris/go-ezio/rest/internal"
+)
+
+const (
+	KindSSE       = "sse"
+	KindWebSocket = "websocket"
+
+	defaultSendBuffer   = 64
+	defaultHeartbeat    = 15 * time.Second
+	defaultWriteTimeout = 10 * time.Second
+	defaultReadLimit    = 64 << 10
+
+	hubKey = "stream.hub"
+)
+
+var (
+	ErrSlowConsumer = errors.New("stream: send buffer is full, slow consumer evicted")
+	ErrClosed       = errors.New("stream: connection closed")
+	ErrShutdown     = errors.New("stream: server shutting down")
+
+	defaultHub     *Hub
+	defaultHubOnce sync.Once
+)
+
+type (
+	Options struct {
+		// 每个连接缓冲的消息数，满了之后视为慢消费者并断开，默认64
+		SendBuffer int
+		// SSE的注释心跳和WebSocket的ping间隔，默认15s，负数代表关闭
+		Heartbeat time.Duration
+		// WebSocket单条消息的写超时，默认10s
+		WriteTimeout time.Duration
+		// WebSocket读取的最大消息，默认64KB
+		ReadLimit int64
+		// WebSocket的跨域检查，默认要求Origin和Host一致
+		CheckOrigin func(r *http.Request) bool
+		// 连接数的gauge注册到的registerer，默认为prometheus.DefaultRegisterer
+		Registerer prometheus.Registerer
+		Namespace  string
+	}
+
+	// Hub tracks the connections, to count them and to close them on shutdown.
+	Hub struct {
+		opts    Options
+		conns   map[*Conn]struct{}
+		closed  bool
+		lock    sync.Mutex
+		gauge   *prometheus.GaugeVec
+		evicted *prometheus.CounterVec
+	}
+)
+
+func NewHub(opts Options) *Hub {
+	if opts.SendBuffer <= 0 {
+		opts.SendBuffer = defaultSendBuffer
+	}
+	if opts.Heartbeat == 0 {
+		opts.Heartbeat = defaultHeartbeat
+	}
+	if opts.WriteTimeout <= 0 {
+		opts.WriteTimeout = defaultWriteTimeout
+	}
+	if opts.ReadLimit <= 0 {
+		opts.ReadLimit = defaultReadLimit
+	}
+	if opts.Registerer == nil {
+		opts.Registerer = prometheus.DefaultRegisterer
+	}
+
+	return &Hub{
+		opts:  opts,
+		conns: make(map[*Conn]struct{}),
+		gauge: internal.RegisterCollector(opts.Registerer, prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: opts.Namespace,
+			Subsystem: "http",
+			Name:      "stream_connections",
+			Help:      "How many streaming connections are open, partitioned by kind.",
+		}, []string{"kind"})).(*prometheus.GaugeVec),
+		evicted: internal.RegisterCollector(opts.Registerer, prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: opts.Namespace,
+			Subsystem: "http",
+			Name:      "stream_evicted_total",
+			Help:      "How many streaming connections are evicted as slow consumers, partitioned by kind.",
+		}, []string{"kind"})).(*prometheus.CounterVec),
+	}
+}
+
+// Middleware makes the handlers of SSE and WebSocket use the hub.
+func Middleware(hub *Hub) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			ctx.Set(hubKey, hub)
+			return next(ctx)
+		}
+	}
+}
+
+// Len returns the number of the open connections.
+func (h *Hub) Len() int {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	return len(h.conns)
+}
+
+// Conns returns a snapshot of the open connections.
+func (h *Hub) Conns() []*Conn {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	conns := make([]*Conn, 0, len(h.conns))
+	for conn := range h.conns {
+		conns = append(conns, conn)
+	}
+	return conns
+}
+
+// Close closes all the connections with ErrShutdown, the new ones are rejected after that.
+// The buffered events are still sent before the connections are closed.
+func (h *Hub) Close() {
+	h.lock.Lock()
+	h.closed = true
+	conns := make([]*Conn, 0, len(h.conns))
+	for conn := range h.conns {
+		conns = append(conns, conn)
+	}
+	h.lock.Unlock()
+
+	for _, conn := range conns {
+		conn.close(ErrShutdown)
+	}
+}
+
+func (h *Hub) add(conn *Conn) bool {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	if h.closed {
+		return false
+	}
+	h.conns[conn] = struct{}{}
+	h.gauge.WithLabelValues(conn.kind).Inc()
+	return true
+}
+
+func (h *Hub) remove(conn *Conn) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	if _, ok := h.conns[conn]; ok {
+		delete(h.conns, conn)
+		h.gauge.WithLabelValues(conn.kind).Dec()
+	}
+}
+
+func hubFromContext(ctx echo.Context) *Hub {
+	if hub, ok := ctx.Get(hubKey).(*Hub); ok {
+		return hub
+	}
+
+	// 不在rest.Server中使用时，使用默认的hub
+	defaultHubOnce.Do(func() {
+		defaultHub = NewHub(Options{})
+	})
+	return defaultHub
+}
@@ -0,0 +1,76 @@
+package stream_test
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/valeamoris/go-ezio/rest"
+	"github.com/valeamoris/go-ezio/rest/resttest"
+	"github.com/valeamoris/go-ezio/rest/stream"
+)
+
+func TestStreamingGroup(t *testing.T) {
+	c := resttest.NewConf()
+	c.Verbose = true
+	c.Compress.Enabled = true
+	srv := resttest.NewServer(t, c)
+	srv.Group(rest.Group{
+		Routes: []rest.Route{
+			{
+				Method: http.MethodGet,
+				Path:   "/events",
+				Handler: rest.HandlerFunc(stream.SSE(func(ctx echo.Context, conn *stream.Conn) error {
+					// 超过分组的超时时间
+					time.Sleep(50 * time.Millisecond)
+					return conn.Send(stream.Event{Data: []byte("late")})
+				})),
+			},
+			{
+				Method: http.MethodGet,
+				Path:   "/ws",
+				Handler: rest.HandlerFunc(stream.WebSocket(func(ctx echo.Context, conn *stream.Conn) error {
+					for msg := range conn.Messages() {
+						if err := conn.Send(stream.Event{Data: msg}); err != nil {
+							return err
+						}
+					}
+					return nil
+				})),
+			},
+		},
+	}, rest.WithStreaming(), rest.WithTimeout(10*time.Millisecond))
+
+	svr := httptest.NewServer(srv)
+	defer svr.Close()
+
+	req, err := http.NewRequest(http.MethodGet, svr.URL+"/events", nil)
+	assert.Nil(t, err)
+	req.Header.Set(echo.HeaderAccept, "text/event-stream")
+	resp, err := http.DefaultClient.Do(req)
+	assert.Nil(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	reader := bufio.NewReader(resp.Body)
+	line, err := reader.ReadString('\n')
+	assert.Nil(t, err)
+	assert.Equal(t, "data: late\n", line)
+
+	// 压缩和日志中间件不影响hijack
+	header := http.Header{}
+	header.Set(echo.HeaderAcceptEncoding, "gzip")
+	ws, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(svr.URL, "http")+"/ws", header)
+	assert.Nil(t, err)
+	defer ws.Close()
+	assert.Nil(t, ws.WriteMessage(websocket.TextMessage, []byte("hello")))
+	_, msg, err := ws.ReadMessage()
+	assert.Nil(t, err)
+	assert.Equal(t, "hello", string(msg))
+}
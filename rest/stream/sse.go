@@ -0,0 +1,96 @@
+package stream
+
+import (
+	"bytes"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	headerLastEventId = "Last-Event-ID"
+	// EventSource不能设置header时，通过参数传递
+	queryLastEventId = "lastEventId"
+	mimeEventStream  = "text/event-stream"
+)
+
+var pingComment = []byte(": ping\n\n")
+
+type sseWriter struct {
+	resp *echo.Response
+	buf  bytes.Buffer
+}
+
+// SSE serves the Server-Sent Events, the events sent by conn are written as they come,
+// and a comment is sent as the heartbeat.
+func SSE(fn Handler) echo.HandlerFunc {
+	return func(ctx echo.Context) error {
+		resp := ctx.Response()
+		if _, ok := resp.Writer.(http.Flusher); !ok {
+			return echo.NewHTTPError(http.StatusInternalServerError, "streaming is not supported")
+		}
+
+		hub := hubFromContext(ctx)
+		conn := hub.newConn(KindSSE, ctx)
+		req := ctx.Request()
+		conn.LastEventId = req.Header.Get(headerLastEventId)
+		if len(conn.LastEventId) == 0 {
+			conn.LastEventId = ctx.QueryParam(queryLastEventId)
+		}
+		if !hub.add(conn) {
+			return echo.NewHTTPError(http.StatusServiceUnavailable, ErrShutdown.Error())
+		}
+		defer hub.remove(conn)
+
+		header := resp.Header()
+		header.Set(echo.HeaderContentType, mimeEventStream)
+		header.Set("Cache-Control", "no-cache")
+		header.Set("Connection", "keep-alive")
+		// 关闭nginx的缓冲
+		header.Set("X-Accel-Buffering", "no")
+		resp.WriteHeader(http.StatusOK)
+		resp.Flush()
+
+		w := &sseWriter{resp: resp}
+		return hub.run(conn, fn, req.Context().Done(), w.write, w.ping, nil)
+	}
+}
+
+func (w *sseWriter) write(e Event) error {
+	w.buf.Reset()
+	if len(e.Id) > 0 {
+		w.field("id", []byte(e.Id))
+	}
+	if len(e.Event) > 0 {
+		w.field("event", []byte(e.Event))
+	}
+	if e.Retry > 0 {
+		w.field("retry", []byte(strconv.FormatInt(e.Retry.Milliseconds(), 10)))
+	}
+	for _, line := range bytes.Split(e.Data, []byte("\n")) {
+		w.field("data", bytes.TrimSuffix(line, []byte("\r")))
+	}
+	w.buf.WriteByte('\n')
+
+	if _, err := w.resp.Write(w.buf.Bytes()); err != nil {
+		return err
+	}
+	w.resp.Flush()
+	return nil
+}
+
+func (w *sseWriter) ping() error {
+	if _, err := w.resp.Write(pingComment); err != nil {
+		return err
+	}
+	w.resp.Flush()
+	return nil
+}
+
+func (w *sseWriter) field(name string, value []byte) {
+	w.buf.WriteString(name)
+	w.buf.WriteString(": ")
+	w.buf.Write(value)
+	w.buf.WriteByte('\n')
+}
@@ -0,0 +1,94 @@
+package stream
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSSE(t *testing.T) {
+	hub := newTestHub(Options{Heartbeat: 20 * time.Millisecond})
+	e := echo.New()
+	e.Use(Middleware(hub))
+	e.GET("/events", SSE(func(ctx echo.Context, conn *Conn) error {
+		assert.Equal(t, "7", conn.LastEventId)
+		assert.Nil(t, conn.Send(Event{Id: "8", Event: "greet", Data: []byte("a\nb"), Retry: time.Second}))
+		<-conn.Done()
+		return nil
+	}))
+	svr := httptest.NewServer(e)
+	defer svr.Close()
+
+	req, err := http.NewRequest(http.MethodGet, svr.URL+"/events?lastEventId=7", nil)
+	assert.Nil(t, err)
+	resp, err := http.DefaultClient.Do(req)
+	assert.Nil(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, mimeEventStream, resp.Header.Get(echo.HeaderContentType))
+
+	reader := bufio.NewReader(resp.Body)
+	assert.Equal(t, "id: 8\nevent: greet\nretry: 1000\ndata: a\ndata: b\n\n", readEvent(t, reader))
+	assert.Equal(t, ": ping\n\n", readEvent(t, reader))
+	assert.Equal(t, 1, hub.Len())
+
+	hub.Close()
+	for {
+		if _, err := reader.ReadString('\n'); err != nil {
+			break
+		}
+	}
+	assert.Eventually(t, func() bool {
+		return hub.Len() == 0
+	}, time.Second, 10*time.Millisecond)
+
+	resp, err = http.Get(svr.URL + "/events")
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	_ = resp.Body.Close()
+}
+
+func TestSSEClientGone(t *testing.T) {
+	hub := newTestHub(Options{})
+	e := echo.New()
+	e.Use(Middleware(hub))
+	done := make(chan error, 1)
+	e.GET("/events", SSE(func(ctx echo.Context, conn *Conn) error {
+		<-conn.Done()
+		done <- conn.Err()
+		return nil
+	}))
+	svr := httptest.NewServer(e)
+	defer svr.Close()
+
+	resp, err := http.Get(svr.URL + "/events")
+	assert.Nil(t, err)
+	assert.Eventually(t, func() bool {
+		return hub.Len() == 1
+	}, time.Second, 10*time.Millisecond)
+	_ = resp.Body.Close()
+
+	select {
+	case err := <-done:
+		assert.Equal(t, ErrClosed, err)
+	case <-time.After(time.Second):
+		t.Fatal("connection not closed")
+	}
+}
+
+func readEvent(t *testing.T, reader *bufio.Reader) string {
+	var b strings.Builder
+	for {
+		line, err := reader.ReadString('\n')
+		assert.Nil(t, err)
+		b.WriteString(line)
+		if line == "\n" {
+			return b.String()
+		}
+	}
+}
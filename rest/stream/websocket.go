@@ -0,0 +1,116 @@
+package stream
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/labstack/echo/v4"
+)
+
+// WebSocket upgrades the connection, the events sent by conn are written as text messages,
+// and the received messages are delivered by conn.Messages(). The client is pinged as the heartbeat,
+// and closed if no pong is received in two heartbeats.
+func WebSocket(fn Handler) echo.HandlerFunc {
+	return func(ctx echo.Context) error {
+		hub := hubFromContext(ctx)
+		conn := hub.newConn(KindWebSocket, ctx)
+		conn.messages = make(chan []byte)
+		// 浏览器的WebSocket不能设置header，通过参数传递
+		conn.LastEventId = ctx.QueryParam(queryLastEventId)
+		if !hub.add(conn) {
+			return echo.NewHTTPError(http.StatusServiceUnavailable, ErrShutdown.Error())
+		}
+		defer hub.remove(conn)
+
+		upgrader := websocket.Upgrader{CheckOrigin: hub.opts.CheckOrigin}
+		ws, err := upgrader.Upgrade(ctx.Response(), ctx.Request(), nil)
+		if err != nil {
+			// 错误响应已经由upgrader写入
+			return nil
+		}
+		defer ws.Close()
+
+		// 连接已被hijack，标记状态供日志和监控使用
+		resp := ctx.Response()
+		resp.Status = http.StatusSwitchingProtocols
+		resp.Committed = true
+
+		closed := make(chan struct{})
+		go hub.read(conn, ws, closed)
+
+		w := &wsWriter{ws: ws, timeout: hub.opts.WriteTimeout}
+		return hub.run(conn, fn, closed, w.write, w.ping, func() {
+			// 发送close帧后等待客户端回复，读取结束后Messages()随之关闭
+			w.close(conn.Err())
+			_ = ws.SetReadDeadline(time.Now().Add(w.timeout))
+		})
+	}
+}
+
+func (h *Hub) read(conn *Conn, ws *websocket.Conn, closed chan struct{}) {
+	defer close(closed)
+	defer close(conn.messages)
+
+	ws.SetReadLimit(h.opts.ReadLimit)
+	if h.opts.Heartbeat > 0 {
+		pongWait := 2 * h.opts.Heartbeat
+		_ = ws.SetReadDeadline(time.Now().Add(pongWait))
+		ws.SetPongHandler(func(string) error {
+			return ws.SetReadDeadline(time.Now().Add(pongWait))
+		})
+	}
+
+	for {
+		_, msg, err := ws.ReadMessage()
+		if err != nil {
+			if websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				err = ErrClosed
+			}
+			conn.close(err)
+			return
+		}
+
+		select {
+		case conn.messages <- msg:
+		case <-conn.done:
+			return
+		}
+	}
+}
+
+type wsWriter struct {
+	ws      *websocket.Conn
+	timeout time.Duration
+}
+
+func (w *wsWriter) write(e Event) error {
+	if err := w.ws.SetWriteDeadline(time.Now().Add(w.timeout)); err != nil {
+		return err
+	}
+
+	return w.ws.WriteMessage(websocket.TextMessage, e.Data)
+}
+
+func (w *wsWriter) ping() error {
+	return w.ws.WriteControl(websocket.PingMessage, nil, time.Now().Add(w.timeout))
+}
+
+func (w *wsWriter) close(reason error) {
+	code := websocket.CloseNormalClosure
+	text := ""
+	switch reason {
+	case nil:
+	case ErrShutdown:
+		code = websocket.CloseGoingAway
+		text = reason.Error()
+	case ErrSlowConsumer:
+		code = websocket.CloseTryAgainLater
+		text = reason.Error()
+	default:
+		code = websocket.CloseInternalServerErr
+	}
+
+	_ = w.ws.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, text),
+		time.Now().Add(w.timeout))
+}
@@ -0,0 +1,94 @@
+package stream
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWebSocket(t *testing.T) {
+	// 第一个客户端读取超时后不再回复close帧，服务端最多等待WriteTimeout
+	hub := newTestHub(Options{Heartbeat: 20 * time.Millisecond, WriteTimeout: 100 * time.Millisecond})
+	e := echo.New()
+	e.Use(Middleware(hub))
+	e.GET("/ws", WebSocket(func(ctx echo.Context, conn *Conn) error {
+		for msg := range conn.Messages() {
+			if err := conn.Send(Event{Data: append([]byte("echo: "), msg...)}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}))
+	svr := httptest.NewServer(e)
+	defer svr.Close()
+
+	url := "ws" + strings.TrimPrefix(svr.URL, "http") + "/ws"
+	client, _, err := websocket.DefaultDialer.Dial(url, nil)
+	assert.Nil(t, err)
+	defer client.Close()
+
+	var pings int
+	client.SetPingHandler(func(data string) error {
+		pings++
+		return client.WriteControl(websocket.PongMessage, []byte(data), time.Now().Add(time.Second))
+	})
+
+	assert.Nil(t, client.WriteMessage(websocket.TextMessage, []byte("hello")))
+	_, msg, err := client.ReadMessage()
+	assert.Nil(t, err)
+	assert.Equal(t, "echo: hello", string(msg))
+	assert.Equal(t, 1, hub.Len())
+
+	// 读取期间处理ping
+	_ = client.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+	_, _, err = client.ReadMessage()
+	assert.NotNil(t, err)
+	assert.True(t, pings > 0)
+
+	client2, _, err := websocket.DefaultDialer.Dial(url, nil)
+	assert.Nil(t, err)
+	defer client2.Close()
+	assert.Eventually(t, func() bool {
+		return hub.Len() == 2
+	}, time.Second, 10*time.Millisecond)
+
+	hub.Close()
+	_, _, err = client2.ReadMessage()
+	assert.True(t, websocket.IsCloseError(err, websocket.CloseGoingAway), err)
+	assert.Eventually(t, func() bool {
+		return hub.Len() == 0
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestWebSocketClientClose(t *testing.T) {
+	hub := newTestHub(Options{})
+	e := echo.New()
+	done := make(chan error, 1)
+	e.GET("/ws", WebSocket(func(ctx echo.Context, conn *Conn) error {
+		<-conn.Done()
+		done <- conn.Err()
+		return nil
+	}))
+	e.Use(Middleware(hub))
+	svr := httptest.NewServer(e)
+	defer svr.Close()
+
+	url := "ws" + strings.TrimPrefix(svr.URL, "http") + "/ws"
+	client, _, err := websocket.DefaultDialer.Dial(url, nil)
+	assert.Nil(t, err)
+	assert.Nil(t, client.WriteMessage(websocket.CloseMessage,
+		websocket.FormatCloseMessage(websocket.CloseNormalClosure, "")))
+	_ = client.Close()
+
+	select {
+	case err := <-done:
+		assert.Equal(t, ErrClosed, err)
+	case <-time.After(time.Second):
+		t.Fatal("connection not closed")
+	}
+}
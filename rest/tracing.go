@@ -0,0 +1,82 @@
+package rest
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/uber/jaeger-client-go"
+	jaegercfg "github.com/uber/jaeger-client-go/config"
+	"github.com/uber/jaeger-client-go/zipkin"
+	"github.com/valeamoris/go-ezio/core/trace"
+	"github.com/valeamoris/go-ezio/rest/internal"
+)
+
+const (
+	backendJaeger  = "jaeger"
+	backendOtel    = "otel"
+	defaultSampler = "const"
+	defaultBatcher = "otlp"
+
+	propagationB3     = "b3"
+	propagationW3C    = "w3c"
+	propagationJaeger = "jaeger"
+)
+
+func startOtelAgent(serviceName string, c TraceConf) error {
+	return trace.StartAgent(trace.Conf{
+		Name:     serviceName,
+		Endpoint: c.Endpoint,
+		Sampler:  c.SamplerRate,
+		Batcher:  c.Batcher,
+		Insecure: c.Insecure,
+	})
+}
+
+func stopOtelAgent() error {
+	return trace.StopAgent(context.Background())
+}
+
+func newTracer(serviceName string, c TraceConf) (opentracing.Tracer, io.Closer, error) {
+	cfg := jaegercfg.Configuration{
+		ServiceName: serviceName,
+		Sampler: &jaegercfg.SamplerConfig{
+			Type:  c.Sampler,
+			Param: c.SamplerRate,
+		},
+		Reporter: &jaegercfg.ReporterConfig{},
+	}
+	if strings.HasPrefix(c.Endpoint, "http://") || strings.HasPrefix(c.Endpoint, "https://") {
+		cfg.Reporter.CollectorEndpoint = c.Endpoint
+	} else {
+		cfg.Reporter.LocalAgentHostPort = c.Endpoint
+	}
+
+	opts := []jaegercfg.Option{
+		jaegercfg.Logger(jaeger.StdLogger),
+	}
+	switch c.Propagation {
+	case propagationB3, "":
+		// Zipkin shares span ID between client and server spans; it must be enabled via the following option.
+		propagator := zipkin.NewZipkinB3HTTPHeaderPropagator()
+		opts = append(opts,
+			jaegercfg.Injector(opentracing.HTTPHeaders, propagator),
+			jaegercfg.Extractor(opentracing.HTTPHeaders, propagator),
+			jaegercfg.ZipkinSharedRPCSpan(true),
+		)
+	case propagationW3C:
+		var propagator internal.W3CPropagator
+		opts = append(opts,
+			jaegercfg.Injector(opentracing.HTTPHeaders, propagator),
+			jaegercfg.Extractor(opentracing.HTTPHeaders, propagator),
+		)
+	case propagationJaeger:
+		// jaeger默认的uber-trace-id
+	default:
+		return nil, nil, fmt.Errorf("trace propagation '%s' is not supported", c.Propagation)
+	}
+
+	return cfg.NewTracer(opts...)
+}
@@ -3,6 +3,9 @@ package rest
 import (
 	"github.com/dgrijalva/jwt-go"
 	"github.com/labstack/echo/v4"
+	"github.com/valeamoris/go-ezio/core/limit"
+	"github.com/valeamoris/go-ezio/rest/middleware"
+	"time"
 )
 
 type (
@@ -13,6 +16,18 @@ type (
 		claims     jwt.Claims
 	}
 
+	apiKeySetting struct {
+		enabled bool
+		store   ApiKeyStore
+		opts    ApiKeyOptions
+	}
+
+	hmacSetting struct {
+		enabled bool
+		keys    HmacKeyStore
+		opts    HmacOptions
+	}
+
 	RouteOption func(r *Group)
 
 	HandlerFunc func(ctx Context) error
@@ -21,21 +36,64 @@ type (
 		Method  string
 		Path    string
 		Handler HandlerFunc
+		// 需要其中任一角色，和Permissions一起由分组的WithAuthz校验，
+		// 分组没有WithAuthz时使用token中的角色和scope校验
+		Roles []string
+		// 需要全部权限
+		Permissions []string
+	}
+
+	authzSetting struct {
+		enabled bool
+		policy  Policy
+		opts    AuthzOptions
 	}
 
 	Group struct {
 		Prefix   string
 		priority bool
 		jwt      jwtSetting
+		apiKey   apiKeySetting
+		hmac     hmacSetting
+		authz    authzSetting
 		static   staticSetting
 		// should open shedding
 		shedding      bool
 		enableBreaker bool
 		// should enable timeout middleware
 		timeoutDisabled bool
-		Routes          []Route
+		// 覆盖Conf.Timeout
+		timeout     time.Duration
+		rateLimit   rateLimitSetting
+		idempotency idempotencySetting
+		cache       cacheSetting
+		// SSE和WebSocket的长连接，不使用超时中间件
+		streaming bool
+		// 覆盖Conf.Security.Cors
+		cors *CorsConf
+		// 双重提交cookie的CSRF保护
+		csrf bool
+		// 按客户端地址过滤
+		ipFilter *IPFilter
+		// 覆盖Conf.MaxBytes
+		maxBytes int64
+		Routes   []Route
 		echo.Group
 		middlewares []Middleware
+		// openapi文档，key为method和path
+		docs map[string]RouteDoc
+		tags []string
+	}
+
+	// RouteDoc is the metadata of the route in the openapi document,
+	// Request and Response are the values of the Go types, described by reflection.
+	RouteDoc struct {
+		Summary     string
+		Description string
+		Tags        []string
+		Deprecated  bool
+		Request     interface{}
+		Response    interface{}
 	}
 
 	Validator = echo.Validator
@@ -48,6 +106,62 @@ type (
 
 	Middleware = MiddlewareFunc
 
+	rateLimitSetting struct {
+		enabled bool
+		keyFunc RateLimitKeyFunc
+		rate    int
+		burst   int
+		limiter limit.Limiter
+	}
+
+	RateLimitKeyFunc = middleware.RateLimitKeyFunc
+
+	idempotencySetting struct {
+		enabled bool
+		ttl     time.Duration
+	}
+
+	cacheSetting struct {
+		enabled bool
+		opts    CacheOptions
+	}
+
+	CacheOptions = middleware.CacheOptions
+
+	IPFilter = middleware.IPFilter
+
+	IPRules = middleware.IPRules
+
+	Principal = middleware.Principal
+
+	ApiKeyStore = middleware.ApiKeyStore
+
+	StaticApiKeys = middleware.StaticApiKeys
+
+	ApiKeyOptions = middleware.ApiKeyOptions
+
+	HmacKey = middleware.HmacKey
+
+	HmacKeyStore = middleware.HmacKeyStore
+
+	StaticHmacKeys = middleware.StaticHmacKeys
+
+	HmacOptions = middleware.HmacOptions
+
+	Policy = middleware.Policy
+
+	Requirement = middleware.Requirement
+
+	AuthzRequest = middleware.AuthzRequest
+
+	AuthzOptions = middleware.AuthzOptions
+
+	AuditEntry = middleware.AuditEntry
+
+	RbacRules = middleware.RbacRules
+
+	RbacPolicy = middleware.RbacPolicy
+
 	staticSetting struct {
 		enabled bool
 		prefix  string
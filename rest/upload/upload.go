@@ -0,0 +1,209 @@
+// Package upload reads the multipart requests part by part, the files are streamed to the writers
+// or the disk instead of being buffered in memory, with the limits of size, count and type.
+package upload
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/valeamoris/go-ezio/rest/errorx"
+)
+
+const (
+	CodeNotMultipart  = 40002
+	CodeFileTooLarge  = 41300
+	CodeTooManyFiles  = 41301
+	CodeFieldTooLarge = 41302
+	CodeFileType      = 41500
+
+	defaultMaxFieldBytes = 1 << 20
+	// http.DetectContentType最多使用512字节
+	sniffLen = 512
+)
+
+var (
+	ErrNotMultipart  = errorx.New(http.StatusBadRequest, CodeNotMultipart, "request is not multipart")
+	ErrFileTooLarge  = errorx.New(http.StatusRequestEntityTooLarge, CodeFileTooLarge, "file too large")
+	ErrTooManyFiles  = errorx.New(http.StatusRequestEntityTooLarge, CodeTooManyFiles, "too many files")
+	ErrFieldTooLarge = errorx.New(http.StatusRequestEntityTooLarge, CodeFieldTooLarge, "form fields too large")
+	ErrFileType      = errorx.New(http.StatusUnsupportedMediaType, CodeFileType, "file type not allowed")
+)
+
+type (
+	Options struct {
+		// 单个文件的最大字节数，0代表不限制，请求整体受分组的MaxBytes限制
+		MaxFileSize int64
+		// 最大文件数，0代表不限制
+		MaxFiles int
+		// 非文件字段的总字节数，默认1M
+		MaxFieldBytes int64
+		// 允许的文件类型，按内容检测而不是客户端声明的Content-Type，支持image/*，为空时不限制
+		AllowTypes []string
+	}
+
+	File struct {
+		// 表单字段名
+		Field string
+		// 客户端提供的文件名，不能直接用作路径
+		Filename string
+		// 按内容检测的类型
+		ContentType string
+		Size        int64
+		// SaveFiles保存的路径
+		Path string
+	}
+
+	Form struct {
+		Values url.Values
+		Files  []*File
+	}
+
+	// Opener returns the writer of the file, it's closed after the file is written if it's an io.Closer.
+	// The file.Size is not known yet when it's called.
+	Opener func(file *File) (io.Writer, error)
+)
+
+// Stream reads the multipart body in order, the fields are collected in Form.Values,
+// and the files are written to the writers returned by open.
+func Stream(ctx echo.Context, opts Options, open Opener) (*Form, error) {
+	if opts.MaxFieldBytes <= 0 {
+		opts.MaxFieldBytes = defaultMaxFieldBytes
+	}
+
+	reader, err := ctx.Request().MultipartReader()
+	if err != nil {
+		return nil, ErrNotMultipart.WithCause(err)
+	}
+
+	form := &Form{Values: make(url.Values)}
+	fieldBytes := opts.MaxFieldBytes
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return form, nil
+		}
+		if err != nil {
+			return form, err
+		}
+
+		if len(part.FileName()) == 0 {
+			value, err := ioutil.ReadAll(io.LimitReader(part, fieldBytes+1))
+			if err != nil {
+				return form, err
+			}
+			fieldBytes -= int64(len(value))
+			if fieldBytes < 0 {
+				return form, ErrFieldTooLarge
+			}
+			form.Values.Add(part.FormName(), string(value))
+			continue
+		}
+
+		if opts.MaxFiles > 0 && len(form.Files) >= opts.MaxFiles {
+			return form, ErrTooManyFiles
+		}
+		file := &File{
+			Field:    part.FormName(),
+			Filename: part.FileName(),
+		}
+		if err := writeFile(part, file, opts, open); err != nil {
+			return form, err
+		}
+		form.Files = append(form.Files, file)
+	}
+}
+
+// SaveFiles saves the files into dir with random names, the saved files are removed if any error occurs.
+func SaveFiles(ctx echo.Context, dir string, opts Options) (*Form, error) {
+	// 包括写入失败的文件
+	var saved []*File
+	form, err := Stream(ctx, opts, func(file *File) (io.Writer, error) {
+		f, err := ioutil.TempFile(dir, "upload-*")
+		if err != nil {
+			return nil, err
+		}
+
+		file.Path = f.Name()
+		saved = append(saved, file)
+		return f, nil
+	})
+	if err != nil {
+		RemoveFiles(saved)
+		return nil, err
+	}
+
+	return form, nil
+}
+
+// RemoveFiles removes the files saved by SaveFiles.
+func RemoveFiles(files []*File) {
+	for _, file := range files {
+		if len(file.Path) > 0 {
+			_ = os.Remove(file.Path)
+		}
+	}
+}
+
+func writeFile(part io.Reader, file *File, opts Options, open Opener) (err error) {
+	head := make([]byte, sniffLen)
+	n, err := io.ReadFull(part, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return err
+	}
+	head = head[:n]
+	file.ContentType = http.DetectContentType(head)
+	if !allowedType(file.ContentType, opts.AllowTypes) {
+		return ErrFileType
+	}
+
+	w, err := open(file)
+	if err != nil {
+		return err
+	}
+	if closer, ok := w.(io.Closer); ok {
+		defer func() {
+			if cerr := closer.Close(); err == nil {
+				err = cerr
+			}
+		}()
+	}
+
+	var reader io.Reader = io.MultiReader(bytes.NewReader(head), part)
+	if opts.MaxFileSize > 0 {
+		reader = io.LimitReader(reader, opts.MaxFileSize+1)
+	}
+	file.Size, err = io.Copy(w, reader)
+	if err != nil {
+		return err
+	}
+	if opts.MaxFileSize > 0 && file.Size > opts.MaxFileSize {
+		return ErrFileTooLarge
+	}
+
+	return nil
+}
+
+func allowedType(contentType string, allowTypes []string) bool {
+	if len(allowTypes) == 0 {
+		return true
+	}
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	for _, t := range allowTypes {
+		if t == mediaType || (strings.HasSuffix(t, "/*") && strings.HasPrefix(mediaType, strings.TrimSuffix(t, "*"))) {
+			return true
+		}
+	}
+
+	return false
+}
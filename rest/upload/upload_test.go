@@ -0,0 +1,131 @@
+package upload
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/valeamoris/go-ezio/rest/errorx"
+)
+
+var pngHeader = []byte("\x89PNG\x0D\x0A\x1A\x0A")
+
+type part struct {
+	field    string
+	filename string
+	content  []byte
+}
+
+func newContext(t *testing.T, parts ...part) echo.Context {
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	for _, p := range parts {
+		var pw io.Writer
+		var err error
+		if len(p.filename) > 0 {
+			pw, err = w.CreateFormFile(p.field, p.filename)
+		} else {
+			pw, err = w.CreateFormField(p.field)
+		}
+		assert.Nil(t, err)
+		_, err = pw.Write(p.content)
+		assert.Nil(t, err)
+	}
+	assert.Nil(t, w.Close())
+
+	req := httptest.NewRequest(http.MethodPost, "/upload", &body)
+	req.Header.Set(echo.HeaderContentType, w.FormDataContentType())
+	return echo.New().NewContext(req, httptest.NewRecorder())
+}
+
+func TestStream(t *testing.T) {
+	image := append(pngHeader, bytes.Repeat([]byte{0}, 1000)...)
+	ctx := newContext(t,
+		part{field: "name", content: []byte("kevin")},
+		part{field: "avatar", filename: "me.png", content: image},
+		part{field: "note", filename: "note.txt", content: []byte("hello")},
+	)
+
+	buffers := make(map[string]*bytes.Buffer)
+	form, err := Stream(ctx, Options{MaxFileSize: 2048}, func(file *File) (io.Writer, error) {
+		buf := new(bytes.Buffer)
+		buffers[file.Filename] = buf
+		return buf, nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "kevin", form.Values.Get("name"))
+	assert.Len(t, form.Files, 2)
+	assert.Equal(t, "avatar", form.Files[0].Field)
+	assert.Equal(t, "image/png", form.Files[0].ContentType)
+	assert.Equal(t, int64(len(image)), form.Files[0].Size)
+	assert.Equal(t, image, buffers["me.png"].Bytes())
+	assert.Equal(t, "text/plain; charset=utf-8", form.Files[1].ContentType)
+	assert.Equal(t, "hello", buffers["note.txt"].String())
+}
+
+func TestStreamLimits(t *testing.T) {
+	discard := func(file *File) (io.Writer, error) {
+		return ioutil.Discard, nil
+	}
+	image := part{field: "avatar", filename: "me.png", content: pngHeader}
+
+	// 按内容检测类型，而不是文件名
+	_, err := Stream(newContext(t, part{field: "avatar", filename: "me.png", content: []byte("text")}),
+		Options{AllowTypes: []string{"image/*"}}, discard)
+	assert.Equal(t, ErrFileType, err)
+	_, err = Stream(newContext(t, image), Options{AllowTypes: []string{"image/*"}}, discard)
+	assert.Nil(t, err)
+
+	_, err = Stream(newContext(t, part{field: "f", filename: "f", content: make([]byte, 11)}),
+		Options{MaxFileSize: 10}, discard)
+	assert.Equal(t, ErrFileTooLarge, err)
+	_, err = Stream(newContext(t, part{field: "f", filename: "f", content: make([]byte, 10)}),
+		Options{MaxFileSize: 10}, discard)
+	assert.Nil(t, err)
+
+	_, err = Stream(newContext(t, image, image), Options{MaxFiles: 1}, discard)
+	assert.Equal(t, ErrTooManyFiles, err)
+
+	_, err = Stream(newContext(t, part{field: "a", content: []byte("12")}, part{field: "b", content: []byte("345")}),
+		Options{MaxFieldBytes: 4}, discard)
+	assert.Equal(t, ErrFieldTooLarge, err)
+
+	req := httptest.NewRequest(http.MethodPost, "/upload", strings.NewReader("{}"))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	_, err = Stream(echo.New().NewContext(req, httptest.NewRecorder()), Options{}, discard)
+	assert.Equal(t, CodeNotMultipart, errorx.From(err).Code)
+}
+
+func TestSaveFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "upload")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	form, err := SaveFiles(newContext(t, part{field: "f", filename: "../../etc/passwd", content: []byte("hello")}),
+		dir, Options{})
+	assert.Nil(t, err)
+	assert.Len(t, form.Files, 1)
+	assert.True(t, strings.HasPrefix(form.Files[0].Path, dir))
+	content, err := ioutil.ReadFile(form.Files[0].Path)
+	assert.Nil(t, err)
+	assert.Equal(t, "hello", string(content))
+	RemoveFiles(form.Files)
+
+	// 失败时删除已保存的文件，包括写了一半的
+	_, err = SaveFiles(newContext(t,
+		part{field: "a", filename: "a", content: []byte("hello")},
+		part{field: "b", filename: "b", content: make([]byte, 100)},
+	), dir, Options{MaxFileSize: 10})
+	assert.Equal(t, ErrFileTooLarge, err)
+	entries, err := ioutil.ReadDir(dir)
+	assert.Nil(t, err)
+	assert.Empty(t, entries)
+}